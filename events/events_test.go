@@ -38,8 +38,9 @@ import (
 
 type Adapter struct {
 	sync.RWMutex
-	notfy chan struct{}
-	count int
+	notfy       chan struct{}
+	count       int
+	lastCCEvent *ehpb.ChaincodeEvent
 }
 
 var peerAddress string
@@ -47,7 +48,11 @@ var adapter *Adapter
 var obcEHClient *consumer.OpenchainEventsClient
 
 func (a *Adapter) GetInterestedEvents() ([]*ehpb.Interest, error) {
-	return []*ehpb.Interest{&ehpb.Interest{"block", ehpb.Interest_PROTOBUF}}, nil
+	return []*ehpb.Interest{
+		&ehpb.Interest{EventType: "block", ResponseType: ehpb.Interest_PROTOBUF},
+		&ehpb.Interest{EventType: "chaincode", ResponseType: ehpb.Interest_PROTOBUF, ChaincodeRegInfo: &ehpb.ChaincodeReg{ChaincodeID: "testcc", EventName: "testevent"}},
+		&ehpb.Interest{EventType: "chaincode", ResponseType: ehpb.Interest_PROTOBUF, ChaincodeRegInfo: &ehpb.ChaincodeReg{ChaincodeID: "testcc2"}},
+	}, nil
 	//return [] *ehpb.Interest{ &ehpb.InterestedEvent{"block", ehpb.Interest_JSON }}, nil
 }
 
@@ -55,6 +60,10 @@ func (a *Adapter) Recv(msg *ehpb.OpenchainEvent) (bool, error) {
 	//fmt.Printf("Adapter received %v\n", msg.Event)
 	switch x := msg.Event.(type) {
 	case *ehpb.OpenchainEvent_Block:
+	case *ehpb.OpenchainEvent_ChaincodeEvent:
+		a.Lock()
+		a.lastCCEvent = x.ChaincodeEvent
+		a.Unlock()
 	case *ehpb.OpenchainEvent_Generic:
 	case nil:
 		// The field is not set.
@@ -108,6 +117,62 @@ func TestReceiveMessage(t *testing.T) {
 	}
 }
 
+// Test that chaincode events are filtered by chaincode ID and event name.
+func TestReceiveChaincodeEvent(t *testing.T) {
+	adapter.count = 1
+	others := []*ehpb.ChaincodeEvent{
+		&ehpb.ChaincodeEvent{ChaincodeID: "othercc", TxUuid: "tx1", EventName: "testevent"},
+		&ehpb.ChaincodeEvent{ChaincodeID: "testcc", TxUuid: "tx2", EventName: "otherevent"},
+	}
+	for _, ccEvent := range others {
+		if err := producer.Send(producer.CreateChaincodeEvent(ccEvent)); err != nil {
+			t.Fatalf("Error sending message %s", err)
+		}
+	}
+	ccEvent := &ehpb.ChaincodeEvent{ChaincodeID: "testcc", TxUuid: "tx3", EventName: "testevent", Payload: []byte("payload")}
+	if err := producer.Send(producer.CreateChaincodeEvent(ccEvent)); err != nil {
+		t.Fatalf("Error sending message %s", err)
+	}
+
+	select {
+	case <-adapter.notfy:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out on messge")
+	}
+
+	adapter.RLock()
+	defer adapter.RUnlock()
+	if adapter.lastCCEvent == nil || adapter.lastCCEvent.TxUuid != "tx3" {
+		t.Fatalf("Expected chaincode event of tx3, got %v", adapter.lastCCEvent)
+	}
+}
+
+// Test that chaincode events matching any of the interests in the same event type are received.
+func TestReceiveChaincodeEventOfEachInterest(t *testing.T) {
+	for _, ccEvent := range []*ehpb.ChaincodeEvent{
+		&ehpb.ChaincodeEvent{ChaincodeID: "testcc", TxUuid: "tx4", EventName: "testevent"},
+		&ehpb.ChaincodeEvent{ChaincodeID: "testcc2", TxUuid: "tx5", EventName: "anyevent"},
+	} {
+		adapter.count = 1
+		if err := producer.Send(producer.CreateChaincodeEvent(ccEvent)); err != nil {
+			t.Fatalf("Error sending message %s", err)
+		}
+
+		select {
+		case <-adapter.notfy:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out on messge")
+		}
+
+		adapter.RLock()
+		lastCCEvent := adapter.lastCCEvent
+		adapter.RUnlock()
+		if lastCCEvent == nil || lastCCEvent.TxUuid != ccEvent.TxUuid {
+			t.Fatalf("Expected chaincode event of %s, got %v", ccEvent.TxUuid, lastCCEvent)
+		}
+	}
+}
+
 func BenchmarkMessages(b *testing.B) {
 	numMessages := 10000
 
@@ -27,3 +27,8 @@ import (
 func CreateBlockEvent(te *ehpb.Block) *ehpb.OpenchainEvent {
 	return &ehpb.OpenchainEvent{&ehpb.OpenchainEvent_Block{Block: te}}
 }
+
+//CreateChaincodeEvent creates a OpenchainEvent from a ChaincodeEvent
+func CreateChaincodeEvent(te *ehpb.ChaincodeEvent) *ehpb.OpenchainEvent {
+	return &ehpb.OpenchainEvent{&ehpb.OpenchainEvent_ChaincodeEvent{ChaincodeEvent: te}}
+}
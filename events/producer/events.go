@@ -84,7 +84,9 @@ func (ep *eventProcessor) start() {
 		ep.Unlock()
 
 		for h := range hl.handlers {
-			if rType := h.responseType(eType); rType != pb.Interest_DONTSEND {
+			if rType := h.responseType(eType, e); rType != pb.Interest_DONTSEND {
+				//convert into a new message, the event is shared by all the handlers
+				msg := e
 				//if OpenchainMessage is already a generic message, producer must have already converted
 				if eType != "generic" {
 					switch rType {
@@ -92,13 +94,13 @@ func (ep *eventProcessor) start() {
 						if b, err := json.Marshal(e.Event); err != nil {
 							producerLogger.Error(fmt.Sprintf("could not marshall JSON for eObject %v(%s)", e.Event, eType))
 						} else {
-							e.Event = &pb.OpenchainEvent_Generic{Generic: &pb.Generic{EventType: eType, Payload: b}}
+							msg = &pb.OpenchainEvent{Event: &pb.OpenchainEvent_Generic{Generic: &pb.Generic{EventType: eType, Payload: b}}}
 						}
 					case pb.Interest_PROTOBUF:
 					}
 				}
-				if e.Event != nil {
-					h.SendMessage(e)
+				if msg.Event != nil {
+					h.SendMessage(msg)
 				}
 			}
 		}
@@ -29,7 +29,7 @@ type handler struct {
 	ChatStream       pb.OpenchainEvents_ChatServer
 	doneChan         chan bool
 	registered       bool
-	interestedEvents map[string]*pb.Interest
+	interestedEvents map[string][]*pb.Interest
 }
 
 func newOpenchainEventHandler(stream pb.OpenchainEvents_ChatServer) (*handler, error) {
@@ -52,48 +52,62 @@ func (d *handler) Stop() error {
 func (d *handler) register(iEvents []*pb.Interest) error {
 	//TODO add the handler to the map for the interested events
 	//if successfully done, continue....
-	d.interestedEvents = make(map[string]*pb.Interest)
+	//the handler is registered once per event type, with all the interests in it
+	d.interestedEvents = make(map[string][]*pb.Interest)
 	for _, v := range iEvents {
-		if ie, ok := d.interestedEvents[v.EventType]; ok {
-			producerLogger.Error(fmt.Sprintf("event %s already registered", v.EventType))
-			ie.ResponseType = v.ResponseType
-			continue
-		}
-		if err := registerHandler(v, d); err != nil {
-			producerLogger.Error(fmt.Sprintf("could not register %s", v))
-			continue
+		if _, ok := d.interestedEvents[v.EventType]; !ok {
+			if err := registerHandler(v, d); err != nil {
+				producerLogger.Error(fmt.Sprintf("could not register %s", v))
+				continue
+			}
 		}
 
-		d.interestedEvents[v.EventType] = v
+		d.interestedEvents[v.EventType] = append(d.interestedEvents[v.EventType], v)
 	}
 	return nil
 }
 
 func (d *handler) deregister() {
 	for k, v := range d.interestedEvents {
-		var ie *pb.Interest
-		var ok bool
-		if ie, ok = d.interestedEvents[k]; !ok {
-			continue
-		}
-		if err := deRegisterHandler(v, d); err != nil {
+		if err := deRegisterHandler(v[0], d); err != nil {
 			producerLogger.Error(fmt.Sprintf("could not register %s", k))
 			continue
 		}
-		delete(d.interestedEvents, ie.EventType)
+		delete(d.interestedEvents, k)
 	}
 }
 
-func (d *handler) responseType(eventType string) pb.Interest_ResponseType {
+func (d *handler) responseType(eventType string, e *pb.OpenchainEvent) pb.Interest_ResponseType {
 	rType := pb.Interest_DONTSEND
 	if d.registered {
-		if ie, _ := d.interestedEvents[eventType]; ie != nil {
-			rType = ie.ResponseType
+		//the first interest matching the event decides the response type
+		for _, ie := range d.interestedEvents[eventType] {
+			if isInterested(ie, e) {
+				rType = ie.ResponseType
+				break
+			}
 		}
 	}
 	return rType
 }
 
+//isInterested applies the chaincode ID and event name restrictions of the
+//interest to chaincode events. An empty chaincode ID or event name matches any
+func isInterested(ie *pb.Interest, e *pb.OpenchainEvent) bool {
+	ccEvent := e.GetChaincodeEvent()
+	ccReg := ie.GetChaincodeRegInfo()
+	if ccEvent == nil || ccReg == nil {
+		return true
+	}
+	if ccReg.ChaincodeID != "" && ccReg.ChaincodeID != ccEvent.ChaincodeID {
+		return false
+	}
+	if ccReg.EventName != "" && ccReg.EventName != ccEvent.EventName {
+		return false
+	}
+	return true
+}
+
 // HandleMessage handles the Openchain messages for the Peer.
 func (d *handler) HandleMessage(msg *pb.OpenchainEvent) error {
 	producerLogger.Debug("Handling OpenchainEvent")
@@ -30,8 +30,9 @@ import (
 
 //----Event Types -----
 const (
-	RegisterType = "register"
-	BlockType    = "block"
+	RegisterType  = "register"
+	BlockType     = "block"
+	ChaincodeType = "chaincode"
 )
 
 func getMessageType(e *pb.OpenchainEvent) string {
@@ -42,6 +43,8 @@ func getMessageType(e *pb.OpenchainEvent) string {
 		return "block"
 	case *pb.OpenchainEvent_Generic:
 		return "generic"
+	case *pb.OpenchainEvent_ChaincodeEvent:
+		return "chaincode"
 	default:
 		return ""
 	}
@@ -51,4 +54,5 @@ func getMessageType(e *pb.OpenchainEvent) string {
 func addInternalEventTypes() {
 	AddEventType(BlockType)
 	AddEventType(RegisterType)
+	AddEventType(ChaincodeType)
 }
@@ -27,6 +27,7 @@ import (
 	"net"
 	"os"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -47,8 +48,12 @@ import (
 	"github.com/openblockchain/obc-peer/openchain"
 	"github.com/openblockchain/obc-peer/openchain/chaincode"
 	"github.com/openblockchain/obc-peer/openchain/consensus/helper"
+	"github.com/openblockchain/obc-peer/openchain/crypto"
+	"github.com/openblockchain/obc-peer/openchain/ledger"
 	"github.com/openblockchain/obc-peer/openchain/ledger/genesis"
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
 	"github.com/openblockchain/obc-peer/openchain/peer"
+	"github.com/openblockchain/obc-peer/openchain/replay"
 	"github.com/openblockchain/obc-peer/openchain/rest"
 	pb "github.com/openblockchain/obc-peer/protos"
 )
@@ -117,6 +122,40 @@ var vmPrimeCmd = &cobra.Command{
 	},
 }
 
+var ledgerCmd = &cobra.Command{
+	Use:   "ledger",
+	Short: "Ledger functionality of openchain.",
+	Long:  `Audit, export and import the ledger of openchain.`,
+}
+
+var ledgerReplayCmd = &cobra.Command{
+	Use:   "replay <dbPath>",
+	Short: "Replay a blockchain and audit its state hashes.",
+	Long: `Re-executes the transactions of every block stored in a copy of the db of a peer, starting with the genesis block, against an empty state and reports the first block whose recorded state hash differs from the replayed one, along with the chaincode keys whose updates differ.
+The chaincodes are run as for a validating peer listening on peer.address, so no peer may be running on that address.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ledgerReplay(cmd, args)
+	},
+}
+
+var ledgerExportCmd = &cobra.Command{
+	Use:   "export <archiveFile>",
+	Short: "Export the ledger to an archive.",
+	Long:  `Writes the blocks, the state deltas that are kept and a snapshot of the state of the ledger at peer.fileSystemPath to a versioned and checksummed archive. The peer must be stopped.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ledgerExport(cmd, args)
+	},
+}
+
+var ledgerImportCmd = &cobra.Command{
+	Use:   "import <archiveFile>",
+	Short: "Import the ledger from an archive.",
+	Long:  `Puts the content of an archive written by the export command into the empty ledger at peer.fileSystemPath, after verifying the archive. The imported state is checked against the state hash of the last block and the imported chain with its previous block hashes. The peer must be stopped.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ledgerImport(cmd, args)
+	},
+}
+
 // Chaincode-related variables.
 var (
 	chaincodeLang     string
@@ -125,6 +164,8 @@ var (
 	chaincodeName     string
 	chaincodeDevMode  bool
 	chaincodeUsr      string
+	chaincodeAttrs    []string
+	chaincodeTimeout  int32
 )
 
 var chaincodeCmd = &cobra.Command{
@@ -145,6 +186,26 @@ var chaincodeDeployCmd = &cobra.Command{
 	},
 }
 
+var chaincodeUpgradeCmd = &cobra.Command{
+	Use:       "upgrade",
+	Short:     fmt.Sprintf("Upgrade the code of the specified %s, keeping its state.", chainFuncName),
+	Long:      fmt.Sprintf(`Upgrade the deployed %s named by --name to the code at --path, keeping its state. The function given in the constructor message, if any, is called to migrate the state, e.g. -c '{"Function":"upgrade","Args":[]}'.`, chainFuncName),
+	ValidArgs: []string{"1"},
+	Run: func(cmd *cobra.Command, args []string) {
+		chaincodeUpgrade(cmd, args)
+	},
+}
+
+var chaincodeTerminateCmd = &cobra.Command{
+	Use:       "terminate",
+	Short:     fmt.Sprintf("Terminate the specified %s.", chainFuncName),
+	Long:      fmt.Sprintf(`Terminate the deployed %s named by --name. It can no longer be invoked or queried but its state remains readable.`, chainFuncName),
+	ValidArgs: []string{"1"},
+	Run: func(cmd *cobra.Command, args []string) {
+		chaincodeTerminate(cmd, args)
+	},
+}
+
 var chaincodeInvokeCmd = &cobra.Command{
 	Use:       "invoke",
 	Short:     fmt.Sprintf("Invoke the specified %s.", chainFuncName),
@@ -235,13 +296,22 @@ func main() {
 	vmCmd.AddCommand(vmPrimeCmd)
 	mainCmd.AddCommand(vmCmd)
 
+	ledgerCmd.AddCommand(ledgerReplayCmd)
+	ledgerCmd.AddCommand(ledgerExportCmd)
+	ledgerCmd.AddCommand(ledgerImportCmd)
+	mainCmd.AddCommand(ledgerCmd)
+
 	chaincodeCmd.PersistentFlags().StringVarP(&chaincodeLang, "lang", "l", "golang", fmt.Sprintf("Language the %s is written in", chainFuncName))
 	chaincodeCmd.PersistentFlags().StringVarP(&chaincodeCtorJSON, "ctor", "c", "{}", fmt.Sprintf("Constructor message for the %s in JSON format", chainFuncName))
 	chaincodeCmd.PersistentFlags().StringVarP(&chaincodePath, "path", "p", undefinedParamValue, fmt.Sprintf("Path to %s", chainFuncName))
 	chaincodeCmd.PersistentFlags().StringVarP(&chaincodeName, "name", "n", undefinedParamValue, fmt.Sprintf("Name of the chaincode returned by the deploy transaction"))
 	chaincodeCmd.PersistentFlags().StringVarP(&chaincodeUsr, "username", "u", undefinedParamValue, fmt.Sprintf("Username for chaincode operations when security is enabled"))
+	chaincodeCmd.PersistentFlags().Int32VarP(&chaincodeTimeout, "timeout", "t", 0, fmt.Sprintf("Timeout in milliseconds for executing the %s, 0 for the network default", chainFuncName))
+	chaincodeCmd.PersistentFlags().StringSliceVarP(&chaincodeAttrs, "attributes", "a", []string{}, fmt.Sprintf("Certificate attributes revealed to the %s when security is enabled", chainFuncName))
 
 	chaincodeCmd.AddCommand(chaincodeDeployCmd)
+	chaincodeCmd.AddCommand(chaincodeUpgradeCmd)
+	chaincodeCmd.AddCommand(chaincodeTerminateCmd)
 	chaincodeCmd.AddCommand(chaincodeInvokeCmd)
 	chaincodeCmd.AddCommand(chaincodeQueryCmd)
 
@@ -308,9 +378,21 @@ func serve(args []string) error {
 	}
 	logger.Info("Security enabled status: %t", viper.GetBool("security.enabled"))
 
+	var peerServer *peer.PeerImpl
+
+	if viper.GetBool("peer.validator.enabled") {
+		logger.Debug("Running as validating peer - installing consensus %s", viper.GetString("peer.validator.consensus"))
+		peerServer, _ = peer.NewPeerWithHandler(helper.NewConsensusHandler)
+	} else {
+		logger.Debug("Running as non-validating peer")
+		peerServer, _ = peer.NewPeerWithHandler(peer.NewPeerHandler)
+	}
+
+	// With security enabled the server presents the TLS certificate issued by the TLSCA to
+	// the other peers, and peer.tls.cert.file to the local clients
 	var opts []grpc.ServerOption
 	if viper.GetBool("peer.tls.enabled") {
-		creds, err := credentials.NewServerTLSFromFile(viper.GetString("peer.tls.cert.file"), viper.GetString("peer.tls.key.file"))
+		creds, err := peer.NewServerTLSCredentials(peerServer.GetSecHelper())
 		if err != nil {
 			grpclog.Fatalf("Failed to generate credentials %v", err)
 		}
@@ -321,15 +403,6 @@ func serve(args []string) error {
 
 	// Register the Peer server
 	//pb.RegisterPeerServer(grpcServer, openchain.NewPeer())
-	var peerServer *peer.PeerImpl
-
-	if viper.GetBool("peer.validator.enabled") {
-		logger.Debug("Running as validating peer - installing consensus %s", viper.GetString("peer.validator.consensus"))
-		peerServer, _ = peer.NewPeerWithHandler(helper.NewConsensusHandler)
-	} else {
-		logger.Debug("Running as non-validating peer")
-		peerServer, _ = peer.NewPeerWithHandler(peer.NewPeerHandler)
-	}
 	pb.RegisterPeerServer(grpcServer, peerServer)
 
 	// Register the Admin server
@@ -526,6 +599,155 @@ func registerChaincodeSupport(chainname chaincode.ChainName, grpcServer *grpc.Se
 	pb.RegisterChaincodeSupportServer(grpcServer, chaincode.NewChaincodeSupport(chainname, peer.GetPeerEndpoint, userRunsCC, ccStartupTimeout))
 }
 
+func ledgerReplay(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Out().Write([]byte("Error: must supply the path of the db to replay.\n"))
+		cmd.Usage()
+		return
+	}
+	source, err := ledger.OpenReadOnlyLedger(args[0])
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error opening db %s: %s", args[0], err))
+		return
+	}
+	defer source.Close()
+
+	// The crypto material is read from the configured path, before the ledger is moved away
+	var secHelper crypto.Peer
+	if viper.GetBool("security.enabled") {
+		enrollID := viper.GetString("security.enrollID")
+		if err = crypto.RegisterValidator(enrollID, nil, enrollID, viper.GetString("security.enrollSecret")); err != nil {
+			logger.Error(fmt.Sprintf("Error registering validator %s: %s", enrollID, err))
+			return
+		}
+		validator, err := crypto.InitValidator(enrollID, nil)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Error initializing validator %s: %s", enrollID, err))
+			return
+		}
+		defer crypto.CloseValidator(validator)
+		secHelper = validator
+	}
+
+	// Replay against an empty ledger in a scratch directory
+	scratchPath, err := ioutil.TempDir("", "obc-replay")
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error creating scratch directory: %s", err))
+		return
+	}
+	defer os.RemoveAll(scratchPath)
+	viper.Set("peer.fileSystemPath", scratchPath)
+
+	peerEndpoint, err := peer.GetPeerEndpoint()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to get Peer Endpoint: %s", err))
+		return
+	}
+	lis, err := net.Listen("tcp", peerEndpoint.Address)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to listen on %s: %s", peerEndpoint.Address, err))
+		return
+	}
+	grpcServer := grpc.NewServer()
+	registerChaincodeSupport(chaincode.DefaultChain, grpcServer)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	divergence, err := replay.Replay(source, secHelper)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error replaying the blockchain: %s", err))
+		return
+	}
+	if divergence == nil {
+		fmt.Printf("The state hashes of all blocks match.\n")
+		return
+	}
+	fmt.Printf("The state hash of block %d diverges: recorded %x, replayed %x\n",
+		divergence.BlockNumber, divergence.StateHash, divergence.ReplayedStateHash)
+	if divergence.ReplayedStateDelta == nil {
+		fmt.Printf("The state deltas are not kept, check ledger.state.deltaHistorySize.\n")
+		return
+	}
+	if divergence.StateDelta == nil {
+		fmt.Printf("The state delta of block %d is no longer recorded, the replayed updates are:\n", divergence.BlockNumber)
+		for _, chaincodeID := range divergence.ReplayedStateDelta.GetUpdatedChaincodeIds(true) {
+			updates := divergence.ReplayedStateDelta.GetUpdates(chaincodeID)
+			var keys []string
+			for key := range updates {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Printf("  %s/%s: %s\n", chaincodeID, key, formatUpdatedValue(updates[key]))
+			}
+		}
+		return
+	}
+	fmt.Printf("The updates that differ are:\n")
+	for _, diff := range divergence.Diff() {
+		fmt.Printf("  %s/%s: recorded %s, replayed %s\n", diff.ChaincodeID, diff.Key,
+			formatUpdatedValue(diff.UpdatedValue), formatUpdatedValue(diff.OtherUpdatedValue))
+	}
+}
+
+func ledgerExport(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Out().Write([]byte("Error: must supply the path of the archive file.\n"))
+		cmd.Usage()
+		return
+	}
+	ledgerPtr, err := ledger.GetLedger()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error getting ledger: %s", err))
+		return
+	}
+	archiveFile, err := os.Create(args[0])
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error creating archive file %s: %s", args[0], err))
+		return
+	}
+	defer archiveFile.Close()
+	if err = ledgerPtr.ExportArchive(archiveFile); err != nil {
+		logger.Error(fmt.Sprintf("Error exporting ledger: %s", err))
+		return
+	}
+	fmt.Printf("Exported %d blocks to %s\n", ledgerPtr.GetBlockchainSize(), args[0])
+}
+
+func ledgerImport(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Out().Write([]byte("Error: must supply the path of the archive file.\n"))
+		cmd.Usage()
+		return
+	}
+	archiveFile, err := os.Open(args[0])
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error opening archive file %s: %s", args[0], err))
+		return
+	}
+	defer archiveFile.Close()
+	ledgerPtr, err := ledger.GetLedger()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error getting ledger: %s", err))
+		return
+	}
+	if err = ledgerPtr.ImportArchive(archiveFile); err != nil {
+		logger.Error(fmt.Sprintf("Error importing ledger: %s", err))
+		return
+	}
+	fmt.Printf("Imported %d blocks from %s\n", ledgerPtr.GetBlockchainSize(), args[0])
+}
+
+func formatUpdatedValue(updatedValue *statemgmt.UpdatedValue) string {
+	if updatedValue == nil {
+		return "<unchanged>"
+	}
+	if updatedValue.IsDelete() {
+		return "<deleted>"
+	}
+	return fmt.Sprintf("%q", updatedValue.GetValue())
+}
+
 func checkChaincodeCmdParams(cmd *cobra.Command) error {
 
 	if chaincodeName == undefinedParamValue {
@@ -562,10 +784,22 @@ func getDevopsClient(cmd *cobra.Command) (pb.DevopsClient, error) {
 }
 
 func chaincodeDeploy(cmd *cobra.Command, args []string) {
+	chaincodeDeployOrUpgrade(cmd, args, false)
+}
+
+func chaincodeUpgrade(cmd *cobra.Command, args []string) {
+	chaincodeDeployOrUpgrade(cmd, args, true)
+}
+
+func chaincodeDeployOrUpgrade(cmd *cobra.Command, args []string, upgrade bool) {
 	if err := checkChaincodeCmdParams(cmd); err != nil {
 		logger.Error(fmt.Sprintf("Error building %s: %s", chainFuncName, err))
 		return
 	}
+	if upgrade && (chaincodeName == undefinedParamValue || chaincodePath == undefinedParamValue) {
+		logger.Error(fmt.Sprintf("Name and path not given for upgrade"))
+		return
+	}
 	devopsClient, err := getDevopsClient(cmd)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Error building %s: %s", chainFuncName, err))
@@ -578,7 +812,7 @@ func chaincodeDeploy(cmd *cobra.Command, args []string) {
 		return
 	}
 	spec := &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG,
-		ChaincodeID: &pb.ChaincodeID{Path: chaincodePath, Name: chaincodeName}, CtorMsg: input}
+		ChaincodeID: &pb.ChaincodeID{Path: chaincodePath, Name: chaincodeName}, CtorMsg: input, Timeout: chaincodeTimeout}
 
 	// If security is enabled, add client login token
 	if viper.GetBool("security.enabled") {
@@ -621,14 +855,82 @@ func chaincodeDeploy(cmd *cobra.Command, args []string) {
 		spec.ConfidentialityLevel = pb.ConfidentialityLevel_CONFIDENTIAL
 	}
 
-	chaincodeDeploymentSpec, err := devopsClient.Deploy(context.Background(), spec)
+	var chaincodeDeploymentSpec *pb.ChaincodeDeploymentSpec
+	if upgrade {
+		chaincodeDeploymentSpec, err = devopsClient.Update(context.Background(), spec)
+	} else {
+		chaincodeDeploymentSpec, err = devopsClient.Deploy(context.Background(), spec)
+	}
 	if err != nil {
 		errMsg := fmt.Sprintf("Error building %s: %s\n", chainFuncName, err)
 		cmd.Out().Write([]byte(errMsg))
 		cmd.Usage()
 		return
 	}
-	logger.Info("Deploy result: %s", chaincodeDeploymentSpec.ChaincodeSpec)
+	if upgrade {
+		logger.Info("Upgrade result: %s", chaincodeDeploymentSpec.ChaincodeSpec)
+	} else {
+		logger.Info("Deploy result: %s", chaincodeDeploymentSpec.ChaincodeSpec)
+	}
+}
+
+func chaincodeTerminate(cmd *cobra.Command, args []string) {
+	if chaincodeName == undefinedParamValue {
+		logger.Error(fmt.Sprintf("Name not given for terminate"))
+		return
+	}
+
+	devopsClient, err := getDevopsClient(cmd)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error terminating %s: %s", chainFuncName, err))
+		return
+	}
+	spec := &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG, ChaincodeID: &pb.ChaincodeID{Name: chaincodeName}}
+
+	// If security is enabled, add client login token
+	if viper.GetBool("security.enabled") {
+		if chaincodeUsr == undefinedParamValue {
+			err := fmt.Sprintf("Error: must supply username for chaincode when security is enabled.\n")
+			cmd.Out().Write([]byte(err))
+			cmd.Usage()
+			return
+		}
+
+		// Retrieve the CLI data storage path
+		// Returns /var/openchain/production/client/
+		localStore := getCliFilePath()
+
+		// Check if the user is logged in before sending transaction
+		if _, err := os.Stat(localStore + "loginToken_" + chaincodeUsr); err == nil {
+			logger.Info("Local user '%s' is already logged in. Retrieving login token.\n", chaincodeUsr)
+
+			// Read in the login token
+			token, err := ioutil.ReadFile(localStore + "loginToken_" + chaincodeUsr)
+			if err != nil {
+				panic(fmt.Errorf("Fatal error when reading client login token: %s\n", err))
+			}
+
+			// Add the login token to the chaincodeSpec
+			spec.SecureContext = string(token)
+		} else {
+			// Check if the token is not there and fail
+			if os.IsNotExist(err) {
+				logger.Error("Error: User not logged in. Use the 'login' command to obtain a security token.\n")
+				return
+			}
+			// Unexpected error
+			panic(fmt.Errorf("Fatal error when checking for client login token: %s\n", err))
+		}
+	}
+
+	resp, err := devopsClient.Terminate(context.Background(), spec)
+	if err != nil {
+		errMsg := fmt.Sprintf("Error terminating %s: %s\n", chainFuncName, err)
+		cmd.Out().Write([]byte(errMsg))
+		cmd.Usage()
+		return
+	}
+	logger.Info("Successfully terminated %s %s(%s)", chainFuncName, chaincodeName, string(resp.Msg))
 }
 
 func chaincodeInvoke(cmd *cobra.Command, args []string) {
@@ -662,7 +964,7 @@ func chaincodeInvokeOrQuery(cmd *cobra.Command, args []string, invoke bool) {
 		return
 	}
 	spec := &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG,
-		ChaincodeID: &pb.ChaincodeID{Name: chaincodeName}, CtorMsg: input}
+		ChaincodeID: &pb.ChaincodeID{Name: chaincodeName}, CtorMsg: input, Attributes: chaincodeAttrs, Timeout: chaincodeTimeout}
 
 	// If security is enabled, add client login token
 	if viper.GetBool("security.enabled") {
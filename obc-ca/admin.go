@@ -0,0 +1,251 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	pb "github.com/openblockchain/obc-peer/obc-ca/protos"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+var revocationReason string
+
+var adminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Manage the users of the ECA.",
+	Long:  `Manages the users of the ECA through its administrator interface at hosts.eca and ports.ecaA.`,
+}
+
+var adminRegisterCmd = &cobra.Command{
+	Use:   "register <id>",
+	Short: "Register a user and print its password.",
+	Run: func(cmd *cobra.Command, args []string) {
+		runAdmin(cmd, args, 1, adminRegister)
+	},
+}
+
+var adminListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the registered users.",
+	Run: func(cmd *cobra.Command, args []string) {
+		runAdmin(cmd, args, 0, adminList)
+	},
+}
+
+var adminDisableCmd = &cobra.Command{
+	Use:   "disable <id>",
+	Short: "Disable a user.",
+	Long:  `Prevents a user from enrolling, renewing its enrollment certificate or obtaining transaction certificates. Certificates already issued stay valid until revoked.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runAdmin(cmd, args, 1, adminDisable)
+	},
+}
+
+var adminEnableCmd = &cobra.Command{
+	Use:   "enable <id>",
+	Short: "Re-enable a disabled user.",
+	Run: func(cmd *cobra.Command, args []string) {
+		runAdmin(cmd, args, 1, adminEnable)
+	},
+}
+
+var adminResetSecretCmd = &cobra.Command{
+	Use:   "reset-secret <id>",
+	Short: "Replace the password of a user and print the new one.",
+	Run: func(cmd *cobra.Command, args []string) {
+		runAdmin(cmd, args, 1, adminResetSecret)
+	},
+}
+
+var adminListCertsCmd = &cobra.Command{
+	Use:   "list-certs <id>",
+	Short: "List the enrollment certificates issued to a user.",
+	Run: func(cmd *cobra.Command, args []string) {
+		runAdmin(cmd, args, 1, adminListCerts)
+	},
+}
+
+var adminRevokeCmd = &cobra.Command{
+	Use:   "revoke <id> [hash]",
+	Short: "Revoke enrollment certificates of a user.",
+	Long:  `Revokes the enrollment certificate of a user with the given hash, as printed by list-certs, or all unrevoked enrollment certificates of the user if no hash is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runAdmin(cmd, args, -1, adminRevoke)
+	},
+}
+
+func addAdminCommands(root *cobra.Command) {
+	adminRevokeCmd.Flags().StringVarP(&revocationReason, "reason", "r", "UNSPECIFIED", "Reason for the revocation, one of [UNSPECIFIED | KEY_COMPROMISE | CA_COMPROMISE | AFFILIATION_CHANGED | SUPERSEDED | CESSATION_OF_OPERATION]")
+
+	adminCmd.AddCommand(adminRegisterCmd)
+	adminCmd.AddCommand(adminListCmd)
+	adminCmd.AddCommand(adminDisableCmd)
+	adminCmd.AddCommand(adminEnableCmd)
+	adminCmd.AddCommand(adminResetSecretCmd)
+	adminCmd.AddCommand(adminListCertsCmd)
+	adminCmd.AddCommand(adminRevokeCmd)
+	root.AddCommand(adminCmd)
+}
+
+// runAdmin checks the number of arguments of an admin command, nargs or, if negative, one or
+// two, and runs it against the administrator interface of the ECA.
+func runAdmin(cmd *cobra.Command, args []string, nargs int, run func(pb.ECAAClient, []string) error) {
+	if nargs >= 0 && len(args) != nargs || nargs < 0 && (len(args) < 1 || len(args) > 2) {
+		cmd.Usage()
+		os.Exit(1)
+	}
+
+	opts := []grpc.DialOption{grpc.WithTimeout(time.Second * 3)}
+	if viper.GetString("eca.tls.certfile") != "" {
+		creds, err := credentials.NewClientTLSFromFile(viper.GetString("eca.tls.certfile"), "")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		opts = append(opts, grpc.WithTransportCredentials(creds))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+
+	conn, err := grpc.Dial(viper.GetString("hosts.eca")+viper.GetString("ports.ecaA"), opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	if err := run(pb.NewECAAClient(conn), args); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", grpc.ErrorDesc(err))
+		os.Exit(1)
+	}
+}
+
+func adminRegister(ecaa pb.ECAAClient, args []string) error {
+	pw, err := ecaa.RegisterUser(context.Background(), &pb.Identity{Id: args[0]})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(pw.Pw)
+
+	return nil
+}
+
+func adminList(ecaa pb.ECAAClient, args []string) error {
+	set, err := ecaa.ReadUserSet(context.Background(), &pb.UserSetReadReq{})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS")
+	for _, user := range set.Users {
+		status := "enabled"
+		if user.Disabled {
+			status = "disabled"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", user.Id.Id, status)
+	}
+
+	return w.Flush()
+}
+
+func adminDisable(ecaa pb.ECAAClient, args []string) error {
+	_, err := ecaa.DisableUser(context.Background(), &pb.Identity{Id: args[0]})
+
+	return err
+}
+
+func adminEnable(ecaa pb.ECAAClient, args []string) error {
+	_, err := ecaa.EnableUser(context.Background(), &pb.Identity{Id: args[0]})
+
+	return err
+}
+
+func adminResetSecret(ecaa pb.ECAAClient, args []string) error {
+	pw, err := ecaa.ResetPassword(context.Background(), &pb.Identity{Id: args[0]})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(pw.Pw)
+
+	return nil
+}
+
+func adminListCerts(ecaa pb.ECAAClient, args []string) error {
+	set, err := ecaa.ReadUserCertificates(context.Background(), &pb.Identity{Id: args[0]})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "HASH\tISSUED\tSTATUS")
+	for _, info := range set.Certs {
+		status := "valid"
+		if info.Revoked {
+			status = "revoked " + time.Unix(info.RevocationTs.Seconds, 0).UTC().Format(time.RFC3339) + " " + info.Reason.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", hex.EncodeToString(info.Hash), time.Unix(info.Ts.Seconds, int64(info.Ts.Nanos)).UTC().Format(time.RFC3339), status)
+	}
+
+	return w.Flush()
+}
+
+func adminRevoke(ecaa pb.ECAAClient, args []string) error {
+	reason, ok := pb.RevocationReason_value[revocationReason]
+	if !ok {
+		return errors.New("unknown revocation reason " + revocationReason)
+	}
+
+	set, err := ecaa.ReadUserCertificates(context.Background(), &pb.Identity{Id: args[0]})
+	if err != nil {
+		return err
+	}
+
+	revoked := 0
+	for _, info := range set.Certs {
+		if info.Revoked || len(args) > 1 && hex.EncodeToString(info.Hash) != args[1] {
+			continue
+		}
+
+		req := &pb.ECertRevokeReq{Id: &pb.Identity{Id: args[0]}, Cert: info.Cert, Reason: pb.RevocationReason(reason)}
+		if _, err := ecaa.RevokeCertificate(context.Background(), req); err != nil {
+			return err
+		}
+		fmt.Println("revoked " + hex.EncodeToString(info.Hash))
+		revoked++
+	}
+	if revoked == 0 {
+		return errors.New("no unrevoked certificate to revoke")
+	}
+
+	return nil
+}
@@ -1,21 +1,21 @@
-/* 
-Licensed to the Apache Software Foundation (ASF) under one 
-or more contributor license agreements.  See the NOTICE file 
-distributed with this work for additional information 
-regarding copyright ownership.  The ASF licenses this file 
-to you under the Apache License, Version 2.0 (the 
-"License"); you may not use this file except in compliance 
-with the License.  You may obtain a copy of the License at 
-
-  http://www.apache.org/licenses/LICENSE-2.0 
-
-Unless required by applicable law or agreed to in writing, 
-software distributed under the License is distributed on an 
-"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY 
-KIND, either express or implied.  See the License for the 
-specific language governing permissions and limitations 
-under the License. 
-*/ 
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
 
 package obcca
 
@@ -25,28 +25,45 @@ import (
 	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
-	"database/sql"
+	"encoding/asn1"
+	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"io/ioutil"
 	"math/big"
 	"os"
+	"path/filepath"
 	"time"
 
-	// Needed to use sqlite3
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/golang/protobuf/proto"
+	pb "github.com/openblockchain/obc-peer/obc-ca/protos"
 	"golang.org/x/crypto/sha3"
 )
 
+var (
+	// CRLReasonCode is the ASN1 object identifier of the CRL entry reason code extension.
+	//
+	CRLReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+	// CRLCertificateHash is the ASN1 object identifier of the CRL entry extension carrying the
+	// SHA3-384 hash of the revoked certificate.  Certificates issued before serial numbers were
+	// randomized all have serial number 1, only their hash tells them apart.
+	//
+	CRLCertificateHash = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 12}
+
+	// CRLValidity is how long a certificate revocation list is valid for.
+	//
+	CRLValidity = time.Hour * 24
+)
+
 // CA is the base certificate authority.
-//
 type CA struct {
-	db   *sql.DB
-	priv *ecdsa.PrivateKey
-	cert *x509.Certificate
+	store CAStore
+	priv  *ecdsa.PrivateKey
+	cert  *x509.Certificate
 }
 
 // NewCA sets up a new CA.
-//
 func NewCA(name string) *CA {
 	id := name + "-root"
 
@@ -61,34 +78,35 @@ func NewCA(name string) *CA {
 	ca := new(CA)
 
 	// open/create DB
-	db, err := sql.Open("sqlite3", RootPath+"/"+name+".db")
+	store, err := newCAStore(name)
 	if err != nil {
 		Panic.Panicln(err)
 	}
-
-	if err := db.Ping(); err != nil {
-		Panic.Panicln(err)
-	}
-	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS Certificates (row INTEGER PRIMARY KEY, id VARCHAR(64), timestamp INTEGER, cert BLOB, hash BLOB)"); err != nil {
-		Panic.Panicln(err)
-	}
-	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS Users (row INTEGER PRIMARY KEY, id VARCHAR(64), password VARCHAR(64))"); err != nil {
-		Panic.Panicln(err)
-	}
-	ca.db = db
+	ca.store = store
 
 	// open/create signing key
-	priv, err := ca.readCAPrivateKey(RootPath, name+".sign")
+	cooked := ca.readOrCreateKey(name+".sign", func() ([]byte, error) {
+		return newCAPrivateKey()
+	})
+	block, _ := pem.Decode(cooked)
+	if block == nil {
+		Panic.Panicln("invalid signing key " + name + ".sign")
+	}
+	priv, err := x509.ParseECPrivateKey(block.Bytes)
 	if err != nil {
-		priv = ca.newCAPrivateKey(RootPath, name+".sign")
+		Panic.Panicln(err)
 	}
 	ca.priv = priv
 
 	// open/create self-signed CA certificate
 	raw, err := ca.readCertificate(id)
 	if err != nil {
-		raw, err = ca.newCertificate(id, &ca.priv.PublicKey, time.Now().UnixNano())
-		if err != nil {
+		if _, err = ca.newCertificate(id, &ca.priv.PublicKey, time.Now().UnixNano()); err != nil {
+			Panic.Panicln(err)
+		}
+
+		// instances starting at the same time settle on the same certificate
+		if raw, err = ca.readCertificate(id); err != nil {
 			Panic.Panicln(err)
 		}
 	}
@@ -102,42 +120,74 @@ func NewCA(name string) *CA {
 }
 
 // Close closes down the CA.
-//
 func (ca *CA) Close() {
-	ca.db.Close()
+	ca.store.Close()
 }
 
-func (ca *CA) newCAPrivateKey(RootPath, name string) *ecdsa.PrivateKey {
-	curve := elliptic.P384()
+// readOrCreateKey returns the key material called name from the store. Key material kept in
+// RootPath by earlier versions is moved to the store, and new key material is created by
+// create otherwise.  CA instances sharing a store thus share their keys, too.
+//
+func (ca *CA) readOrCreateKey(name string, create func() ([]byte, error)) []byte {
+	key, err := ca.store.ReadKey(name)
+	if err == nil {
+		return key
+	}
+	if err != ErrNotFound {
+		Panic.Panicln(err)
+	}
 
-	priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+	path := filepath.Join(RootPath, name)
+	key, err = ioutil.ReadFile(path)
 	if err == nil {
-		raw, err := x509.MarshalECPrivateKey(priv)
-		cooked := pem.EncodeToMemory(
-			&pem.Block{
-				Type:  "ECDSA PRIVATE KEY",
-				Bytes: raw,
-			})
-		if err == nil {
-			err = ioutil.WriteFile(RootPath+"/"+name, cooked, 0644)
-		}
+		Info.Println("moving " + path + " to the CA store")
+	} else if os.IsNotExist(err) {
+		Info.Println("creating key " + name)
+		key, err = create()
+	}
+	if err != nil {
+		Panic.Panicln(err)
 	}
+
+	key, err = ca.store.InsertKey(name, key)
 	if err != nil {
 		Panic.Panicln(err)
 	}
 
-	return priv
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		Error.Println(err)
+	}
+
+	return key
 }
 
-func (ca *CA) readCAPrivateKey(RootPath, name string) (*ecdsa.PrivateKey, error) {
-	cooked, err := ioutil.ReadFile(RootPath + "/" + name)
+func newCAPrivateKey() ([]byte, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
 	if err != nil {
 		return nil, err
 	}
 
-	block, _ := pem.Decode(cooked)
+	raw, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
 
-	return x509.ParseECPrivateKey(block.Bytes)
+	return pem.EncodeToMemory(
+		&pem.Block{
+			Type:  "ECDSA PRIVATE KEY",
+			Bytes: raw,
+		}), nil
+}
+
+// newSymmetricKey returns a fresh base64 encoded random key of size bytes.
+//
+func newSymmetricKey(size int) ([]byte, error) {
+	key := make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	return []byte(base64.StdEncoding.EncodeToString(key)), nil
 }
 
 func (ca *CA) newCertificate(id string, pub *ecdsa.PublicKey, timestamp int64, opt ...pkix.Extension) ([]byte, error) {
@@ -147,8 +197,14 @@ func (ca *CA) newCertificate(id string, pub *ecdsa.PublicKey, timestamp int64, o
 	notAfter := notBefore.Add(time.Hour * 24 * 90)
 	isCA := ca.cert == nil
 
+	// serial numbers have to be unique for revocation lists to be meaningful
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
 	tmpl := x509.Certificate{
-		SerialNumber: big.NewInt(1),
+		SerialNumber: serial,
 		Subject: pkix.Name{
 			CommonName:   "OBC",
 			Organization: []string{"IBM"},
@@ -162,7 +218,7 @@ func (ca *CA) newCertificate(id string, pub *ecdsa.PublicKey, timestamp int64, o
 		KeyUsage:           x509.KeyUsageDigitalSignature,
 
 		BasicConstraintsValid: true,
-		IsCA: isCA,
+		IsCA:                  isCA,
 	}
 	if len(opt) > 0 {
 		tmpl.Extensions = opt
@@ -187,7 +243,7 @@ func (ca *CA) newCertificate(id string, pub *ecdsa.PublicKey, timestamp int64, o
 
 	hash := sha3.New384()
 	hash.Write(raw)
-	if _, err = ca.db.Exec("INSERT INTO Certificates (id, timestamp, cert, hash) VALUES (?, ?, ?, ?)", id, timestamp, raw, hash.Sum(nil)); err != nil {
+	if err = ca.store.InsertCertificate(id, timestamp, raw, hash.Sum(nil)); err != nil {
 		if isCA {
 			Panic.Panicln(err)
 		} else {
@@ -201,48 +257,158 @@ func (ca *CA) newCertificate(id string, pub *ecdsa.PublicKey, timestamp int64, o
 func (ca *CA) readCertificate(id string, opt ...int64) ([]byte, error) {
 	Trace.Println("reading certificate for " + id)
 
-	var raw []byte
-	var err error
-	if len(opt) > 0 && opt[0] != 0 {
-		row := ca.db.QueryRow("SELECT cert FROM Certificates WHERE id=? AND timestamp=?", id, opt[0])
-		err = row.Scan(&raw)
-	} else {
-		row := ca.db.QueryRow("SELECT cert FROM Certificates WHERE id=?", id)
-		err = row.Scan(&raw)
+	var timestamp int64
+	if len(opt) > 0 {
+		timestamp = opt[0]
 	}
 
-	return raw, err
+	return ca.store.ReadCertificate(id, timestamp)
 }
 
 func (ca *CA) readCertificateByHash(hash []byte) ([]byte, error) {
 	Trace.Println("reading certificate for hash " + string(hash))
 
-	var raw []byte
-	row := ca.db.QueryRow("SELECT cert FROM Certificates WHERE hash=?", hash)
-	err := row.Scan(&raw)
+	return ca.store.ReadCertificateByHash(hash)
+}
 
-	return raw, err
+func (ca *CA) readCertificateRecord(raw []byte) (*CertificateRecord, error) {
+	Trace.Println("reading certificate record")
+
+	hash := sha3.New384()
+	hash.Write(raw)
+
+	return ca.store.ReadCertificateRecord(hash.Sum(nil))
 }
 
 func (ca *CA) readCertificateSet(id string, timestamp int64, num int) ([][]byte, error) {
 	Trace.Println("reading certificate for "+id+" / %d", timestamp)
 
-	var raw []byte
-	rows, err := ca.db.Query("SELECT cert FROM Certificates WHERE id=? AND timestamp=?", id, timestamp)
+	return ca.store.ReadCertificateSet(id, timestamp)
+}
+
+func (ca *CA) readCertificates(id string) ([]*CertificateRecord, error) {
+	Trace.Println("reading certificates for " + id)
+
+	return ca.store.ReadCertificates(id)
+}
+
+func (ca *CA) revokeCertificate(raw []byte, reason pb.RevocationReason, opt ...string) error {
+	Trace.Println("revoking certificate")
+
+	hash := sha3.New384()
+	hash.Write(raw)
+
+	var id string
+	if len(opt) > 0 {
+		id = opt[0]
+	}
+	if err := ca.store.RevokeCertificate(hash.Sum(nil), id, time.Now().Unix(), int32(reason)); err != nil {
+		return err
+	}
+
+	_, err := ca.createCRL()
+
+	return err
+}
+
+func (ca *CA) revokeCertificateSet(id string, timestamp int64, reason pb.RevocationReason) error {
+	Trace.Printf("revoking certificate set for %s / %d\n", id, timestamp)
+
+	if err := ca.store.RevokeCertificateSet(id, timestamp, time.Now().Unix(), int32(reason)); err != nil {
+		if err == ErrNotRevoked {
+			return errors.New("certificate set not found or already revoked")
+		}
+		return err
+	}
+
+	_, err := ca.createCRL()
+
+	return err
+}
+
+func (ca *CA) revokeUserCertificates(id string, reason pb.RevocationReason) error {
+	Trace.Println("revoking all certificates of " + id)
+
+	if err := ca.store.RevokeCertificates(id, time.Now().Unix(), int32(reason)); err != nil {
+		if err == ErrNotRevoked {
+			return nil
+		}
+		return err
+	}
+
+	_, err := ca.createCRL()
+
+	return err
+}
+
+func (ca *CA) createCRL() ([]byte, error) {
+	Trace.Println("creating certificate revocation list")
+
+	records, err := ca.store.ReadRevokedCertificates()
 	if err != nil {
 		return nil, err
 	}
 
-	var set [][]byte
-	for rows.Next() {
-		err := rows.Scan(&raw)
+	now := time.Now().UTC()
+	var revoked []pkix.RevokedCertificate
+	for _, rec := range records {
+		cert, err := x509.ParseCertificate(rec.Cert)
 		if err != nil {
-			return set, err
+			return nil, err
+		}
+		if now.After(cert.NotAfter) {
+			// expired certificates need not be listed any longer
+			continue
 		}
-		set = append(set, raw)
+
+		hash, err := asn1.Marshal(rec.Hash)
+		if err != nil {
+			return nil, err
+		}
+		entry := pkix.RevokedCertificate{
+			SerialNumber:   cert.SerialNumber,
+			RevocationTime: time.Unix(rec.RevocationTime, 0).UTC(),
+			Extensions:     []pkix.Extension{{Id: CRLCertificateHash, Value: hash}},
+		}
+		if rec.RevocationReason != int32(pb.RevocationReason_UNSPECIFIED) {
+			value, err := asn1.Marshal(asn1.Enumerated(rec.RevocationReason))
+			if err != nil {
+				return nil, err
+			}
+			entry.Extensions = append(entry.Extensions, pkix.Extension{Id: CRLReasonCode, Value: value})
+		}
+		revoked = append(revoked, entry)
 	}
 
-	return set, err
+	raw, err := ca.cert.CreateCRL(rand.Reader, ca.priv, revoked, now, now.Add(CRLValidity))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ca.store.InsertCRL(now.UnixNano(), raw); err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+func (ca *CA) readCRL() ([]byte, error) {
+	Trace.Println("reading certificate revocation list")
+
+	raw, err := ca.store.ReadCRL()
+	if err != nil {
+		if err == ErrNotFound {
+			return ca.createCRL()
+		}
+		return nil, err
+	}
+
+	crl, err := x509.ParseCRL(raw)
+	if err != nil || crl.HasExpired(time.Now()) {
+		return ca.createCRL()
+	}
+
+	return raw, nil
 }
 
 func (ca *CA) newUser(id string, opt ...string) (string, error) {
@@ -256,7 +422,7 @@ func (ca *CA) newUser(id string, opt ...string) (string, error) {
 			pw = randomString(12)
 		}
 
-		_, err = ca.db.Exec("INSERT INTO Users (id, password) VALUES (?, ?)", id, pw)
+		err = ca.store.InsertUser(id, pw)
 		if err != nil {
 			Error.Println(err)
 
@@ -269,9 +435,83 @@ func (ca *CA) newUser(id string, opt ...string) (string, error) {
 func (ca *CA) readPassword(id string) (string, error) {
 	Trace.Println("reading password for " + id)
 
-	var pw string
-	row := ca.db.QueryRow("SELECT password FROM Users WHERE id=?", id)
-	err := row.Scan(&pw)
+	user, err := ca.store.ReadUser(id)
+	if err != nil {
+		return "", err
+	}
 
-	return pw, err
+	return user.Password, nil
+}
+
+func (ca *CA) readUser(id string) (*UserRecord, error) {
+	Trace.Println("reading user " + id)
+
+	return ca.store.ReadUser(id)
+}
+
+func (ca *CA) readUsers() ([]*UserRecord, error) {
+	Trace.Println("reading users")
+
+	return ca.store.ReadUsers()
+}
+
+func (ca *CA) resetPassword(id string) (string, error) {
+	Trace.Println("resetting password for " + id)
+
+	pw := randomString(12)
+	if err := ca.store.UpdatePassword(id, pw); err != nil {
+		return "", err
+	}
+
+	return pw, nil
+}
+
+func (ca *CA) setUserDisabled(id string, disabled bool) error {
+	Trace.Printf("setting disabled to %t for %s\n", disabled, id)
+
+	return ca.store.SetUserDisabled(id, disabled)
+}
+
+func (ca *CA) newAttribute(id, name string, value []byte) error {
+	Trace.Println("setting attribute " + name + " for " + id)
+
+	if err := ca.store.WriteAttribute(id, name, value); err != nil {
+		Error.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+func (ca *CA) readAttributes(id string) ([]string, [][]byte, error) {
+	Trace.Println("reading attributes for " + id)
+
+	return ca.store.ReadAttributes(id)
+}
+
+func verifySignature(pub interface{}, req proto.Message, sig *pb.Signature) error {
+	ecdsaPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return errors.New("unsupported key type")
+	}
+	if sig == nil {
+		return errors.New("missing signature")
+	}
+
+	r, s := big.NewInt(0), big.NewInt(0)
+	r.UnmarshalText(sig.R)
+	s.UnmarshalText(sig.S)
+
+	raw, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	hash := sha3.New384()
+	hash.Write(raw)
+	if ecdsa.Verify(ecdsaPub, hash.Sum(nil), r, s) == false {
+		return errors.New("signature does not verify")
+	}
+
+	return nil
 }
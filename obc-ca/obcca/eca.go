@@ -20,12 +20,13 @@ under the License.
 package obcca
 
 import (
+	"bytes"
 	"crypto/ecdsa"
-	"crypto/rand"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/base64"
 	"errors"
-	"io/ioutil"
 	"math/big"
 	"net"
 	"sync"
@@ -38,6 +39,17 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	google_protobuf "google/protobuf"
+)
+
+var (
+	// ECertAffiliation is the ASN1 object identifier of the organization an enrollment certificate belongs to.
+	//
+	ECertAffiliation = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 10}
+
+	// ECertEnrollmentID is the ASN1 object identifier of the enrollment id an enrollment certificate was issued to.
+	//
+	ECertEnrollmentID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 11}
 )
 
 // ECA is the enrollment certificate authority.
@@ -45,8 +57,9 @@ import (
 type ECA struct {
 	*CA
 	obcKey []byte
+	tca    *TCA
 	
-	sockp, socka net.Listener
+	sockp, socka, sockr net.Listener
 	srvp, srva *grpc.Server
 }
 
@@ -65,26 +78,14 @@ type ECAA struct {
 // NewECA sets up a new ECA.
 //
 func NewECA() *ECA {
-	var cooked string
-
-	eca := &ECA{NewCA("eca"), nil, nil, nil, nil, nil}
-
-	raw, err := ioutil.ReadFile(RootPath + "/obc.key")
-	if err != nil {
-		rand := rand.Reader
-		key := make([]byte, 32) // AES-256
-		rand.Read(key)
-		cooked = base64.StdEncoding.EncodeToString(key)
+	eca := &ECA{NewCA("eca"), nil, nil, nil, nil, nil, nil, nil}
 
-		err = ioutil.WriteFile(RootPath+"/obc.key", []byte(cooked), 0644)
-		if err != nil {
-			Panic.Panicln(err)
-		}
-	} else {
-		cooked = string(raw)
-	}
+	cooked := eca.readOrCreateKey("obc.key", func() ([]byte, error) {
+		return newSymmetricKey(32) // AES-256
+	})
 
-	eca.obcKey, err = base64.StdEncoding.DecodeString(cooked)
+	var err error
+	eca.obcKey, err = base64.StdEncoding.DecodeString(string(cooked))
 	if err != nil {
 		Panic.Panicln(err)
 	}
@@ -92,11 +93,38 @@ func NewECA() *ECA {
 	users := viper.GetStringMapString("eca.users")
 	for id, pw := range users {
 		eca.newUser(id, pw)
+
+		attrs := viper.GetStringMapString("eca.attributes." + id)
+		for name, value := range attrs {
+			if err := eca.newAttribute(id, name, []byte(value)); err != nil {
+				Panic.Panicln(err)
+			}
+		}
 	}
 
 	return eca
 }
 
+// revokeCertificate revokes an enrollment certificate.  Unless the certificate was superseded by
+// a renewed one, the transaction certificates issued to its owner are revoked as well, as they
+// would otherwise remain valid.
+//
+func (eca *ECA) revokeCertificate(raw []byte, reason pb.RevocationReason, opt ...string) error {
+	if err := eca.CA.revokeCertificate(raw, reason, opt...); err != nil {
+		return err
+	}
+	if reason == pb.RevocationReason_SUPERSEDED || eca.tca == nil {
+		return nil
+	}
+
+	rec, err := eca.readCertificateRecord(raw)
+	if err != nil {
+		return err
+	}
+
+	return eca.tca.revokeUserCertificates(rec.ID, reason)
+}
+
 // Start starts the ECA.
 //
 func (eca *ECA) Start(wg *sync.WaitGroup) {
@@ -113,6 +141,25 @@ func (eca *ECA) Start(wg *sync.WaitGroup) {
 	go eca.startECAP(wg, opts)
 	go eca.startECAA(wg, opts)
 
+	if viper.GetString("ports.ecaR") != "" {
+		// the administrators authenticate with their secret, which must not travel in clear
+		if viper.GetString("eca.tls.certfile") == "" {
+			Panic.Panicln("ECA REST interface requires eca.tls to be configured")
+		}
+		admins := viper.GetStringMapString("eca.rest.admins")
+		if len(admins) == 0 {
+			Panic.Panicln("ECA REST interface requires administrators in eca.rest.admins")
+		}
+		for id, secret := range admins {
+			if secret == "" {
+				Panic.Panicln("ECA REST administrator " + id + " has an empty secret")
+			}
+		}
+
+		wg.Add(1)
+		go eca.startECAR(wg)
+	}
+
 	Info.Println("ECA started.")
 }
 
@@ -121,6 +168,50 @@ func (eca *ECA) Start(wg *sync.WaitGroup) {
 func (eca *ECA) Stop() {
 	eca.srvp.Stop()	
 	eca.srva.Stop()
+	if eca.sockr != nil {
+		eca.sockr.Close()
+	}
+}
+
+// ecertExtensions returns the extensions binding the enrollment certificate of id to its enrollment id
+// and, if one is configured, to its organization.
+//
+func (eca *ECA) ecertExtensions(id string) []pkix.Extension {
+	exts := []pkix.Extension{{Id: ECertEnrollmentID, Critical: false, Value: []byte(id)}}
+
+	affiliation := viper.GetString("eca.affiliations." + id)
+	if affiliation != "" {
+		exts = append(exts, pkix.Extension{Id: ECertAffiliation, Critical: false, Value: []byte(affiliation)})
+	}
+
+	return exts
+}
+
+// checkUserEnabled returns an error if id is a user who has been disabled.
+//
+func (eca *ECA) checkUserEnabled(id string) error {
+	if user, err := eca.readUser(id); err == nil && user.Disabled {
+		return errors.New("user " + id + " is disabled")
+	}
+
+	return nil
+}
+
+// isSupersededWithinGracePeriod returns true if raw is an enrollment certificate of id that
+// has been superseded by a renewal less than eca.renewal.gracePeriod ago.
+//
+func (eca *ECA) isSupersededWithinGracePeriod(id string, raw []byte) bool {
+	rec, err := eca.readCertificateRecord(raw)
+	if err != nil || rec.ID != id || !rec.Revoked || rec.RevocationReason != int32(pb.RevocationReason_SUPERSEDED) {
+		return false
+	}
+
+	grace, err := time.ParseDuration(viper.GetString("eca.renewal.gracePeriod"))
+	if err != nil {
+		return false
+	}
+
+	return time.Now().Before(time.Unix(rec.RevocationTime, 0).Add(grace))
 }
 
 func (eca *ECA) startECAP(wg *sync.WaitGroup, opts []grpc.ServerOption) {
@@ -161,10 +252,15 @@ func (ecap *ECAP) CreateCertificate(ctx context.Context, req *pb.ECertCreateReq)
 	Trace.Println("grpc ECAP:CreateCertificate")
 
 	id := req.Id.Id
-	if pw, err := ecap.eca.readPassword(id); err != nil || pw != req.Pw.Pw {
+	user, err := ecap.eca.readUser(id)
+	if err != nil || user.Password != req.Pw.Pw {
 		Error.Println("identity or password do not match")
 		return nil, errors.New("identity or password do not match")
 	}
+	if user.Disabled {
+		Error.Println("user " + id + " is disabled")
+		return nil, errors.New("user " + id + " is disabled")
+	}
 
 	sig := req.Sig
 	req.Sig = nil
@@ -194,7 +290,7 @@ func (ecap *ECAP) CreateCertificate(ctx context.Context, req *pb.ECertCreateReq)
 
 	raw, err = ecap.eca.readCertificate(id)
 	if err != nil {
-		if raw, err = ecap.eca.newCertificate(id, pub.(*ecdsa.PublicKey), time.Now().UnixNano()); err != nil {
+		if raw, err = ecap.eca.newCertificate(id, pub.(*ecdsa.PublicKey), time.Now().UnixNano(), ecap.eca.ecertExtensions(id)...); err != nil {
 			Error.Println(err)
 			return nil, err
 		}
@@ -224,12 +320,124 @@ func (ecap *ECAP) ReadCertificate(ctx context.Context, req *pb.ECertReadReq) (*p
 	return &pb.Cert{raw}, nil
 }
 
-// RevokeCertificate revokes a certificate from the ECA.  Not yet implemented.
+// RevokeCertificate revokes an enrollment certificate from the ECA.  The request has to be signed
+// with the private key of the certificate being revoked.
 //
-func (ecap *ECAP) RevokeCertificate(context.Context, *pb.ECertRevokeReq) (*pb.CAStatus, error) {
+func (ecap *ECAP) RevokeCertificate(ctx context.Context, req *pb.ECertRevokeReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc ECAP:RevokeCertificate")
 
-	return nil, errors.New("not yet implemented")
+	if req.Id == nil || req.Cert == nil {
+		Error.Println("invalid request")
+		return nil, errors.New("invalid request")
+	}
+
+	cert, err := x509.ParseCertificate(req.Cert.Cert)
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	sig := req.Sig
+	req.Sig = nil
+	if err := verifySignature(cert.PublicKey, req, sig); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	if err := ecap.eca.revokeCertificate(req.Cert.Cert, req.Reason, req.Id.Id); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
+}
+
+// ReadCRL reads the latest certificate revocation list issued by the ECA.
+//
+func (ecap *ECAP) ReadCRL(ctx context.Context, req *pb.CRLReadReq) (*pb.CRL, error) {
+	Trace.Println("grpc ECAP:ReadCRL")
+
+	raw, err := ecap.eca.readCRL()
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.CRL{Crl: raw}, nil
+}
+
+// RenewCertificate binds a new public key to the enrollment ID of a current enrollment certificate.
+// The request has to be signed with both the private key of the current certificate and the new
+// private key.  The current certificate is revoked as superseded.  A node which did not store the
+// certificate of its last renewal may renew again with the certificate superseded by it, during
+// the grace period of eca.renewal.gracePeriod.
+//
+func (ecap *ECAP) RenewCertificate(ctx context.Context, req *pb.ECertRenewReq) (*pb.Creds, error) {
+	Trace.Println("grpc ECAP:RenewCertificate")
+
+	if req.Id == nil || req.Cert == nil || req.Pub == nil {
+		Error.Println("invalid request")
+		return nil, errors.New("invalid request")
+	}
+
+	id := req.Id.Id
+	if err := ecap.eca.checkUserEnabled(id); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	current, err := ecap.eca.readCertificate(id)
+	if err != nil || (!bytes.Equal(current, req.Cert.Cert) && !ecap.eca.isSupersededWithinGracePeriod(id, req.Cert.Cert)) {
+		Error.Println("certificate is not the current certificate of " + id)
+		return nil, errors.New("certificate is not the current certificate of " + id)
+	}
+
+	cert, err := x509.ParseCertificate(req.Cert.Cert)
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+	if time.Now().After(cert.NotAfter) {
+		Error.Println("certificate expired")
+		return nil, errors.New("certificate expired")
+	}
+
+	if req.Pub.Type != pb.CryptoType_ECDSA {
+		Error.Println("unsupported key type")
+		return nil, errors.New("unsupported key type")
+	}
+	pub, err := x509.ParsePKIXPublicKey(req.Pub.Key)
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	sig := req.Sig
+	req.Sig = nil
+	if err := verifySignature(cert.PublicKey, req, sig); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	pop := req.Pop
+	req.Pop = nil
+	if err := verifySignature(pub, req, pop); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	raw, err := ecap.eca.newCertificate(id, pub.(*ecdsa.PublicKey), time.Now().UnixNano(), ecap.eca.ecertExtensions(id)...)
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	if err := ecap.eca.revokeCertificate(current, pb.RevocationReason_SUPERSEDED, id); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.Creds{&pb.Cert{Cert: raw}, ecap.eca.obcKey}, nil
 }
 
 // RegisterUser registers a new user with the ECA.
@@ -245,18 +453,123 @@ func (ecaa *ECAA) RegisterUser(ctx context.Context, id *pb.Identity) (*pb.Passwo
 	return &pb.Password{pw}, err
 }
 
-// RevokeCertificate revokes a certificate from the ECA.  Not yet implemented.
+// RevokeCertificate revokes any enrollment certificate from the ECA.  Like all administrator
+// requests it is trusted on the grounds of reaching the administrator interface.
 //
-func (ecaa *ECAA) RevokeCertificate(context.Context, *pb.ECertRevokeReq) (*pb.CAStatus, error) {
+func (ecaa *ECAA) RevokeCertificate(ctx context.Context, req *pb.ECertRevokeReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc ECAA:RevokeCertificate")
 
-	return nil, errors.New("not yet implemented")
+	if req.Cert == nil {
+		Error.Println("invalid request")
+		return nil, errors.New("invalid request")
+	}
+
+	if err := ecaa.eca.revokeCertificate(req.Cert.Cert, req.Reason); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
 
-// CreateCRL requests the creation of a certificate revocation list from the ECA.  Not yet implemented.
+// CreateCRL requests the creation of a certificate revocation list from the ECA.
 //
-func (ecaa *ECAA) CreateCRL(context.Context, *pb.ECertCRLReq) (*pb.CAStatus, error) {
+func (ecaa *ECAA) CreateCRL(ctx context.Context, req *pb.ECertCRLReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc ECAA:CreateCRL")
 
-	return nil, errors.New("not yet implemented")
+	if _, err := ecaa.eca.createCRL(); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
+}
+
+// ReadUserSet returns the users registered with the ECA.
+//
+func (ecaa *ECAA) ReadUserSet(ctx context.Context, req *pb.UserSetReadReq) (*pb.UserSet, error) {
+	Trace.Println("grpc ECAA:ReadUserSet")
+
+	users, err := ecaa.eca.readUsers()
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	set := &pb.UserSet{}
+	for _, user := range users {
+		set.Users = append(set.Users, &pb.User{Id: &pb.Identity{Id: user.ID}, Disabled: user.Disabled})
+	}
+
+	return set, nil
+}
+
+// DisableUser prevents a user from enrolling, renewing its enrollment certificate or obtaining
+// transaction certificates.  Certificates already issued to the user stay valid until revoked.
+//
+func (ecaa *ECAA) DisableUser(ctx context.Context, id *pb.Identity) (*pb.CAStatus, error) {
+	Trace.Println("grpc ECAA:DisableUser")
+
+	if err := ecaa.eca.setUserDisabled(id.Id, true); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
+}
+
+// EnableUser re-enables a disabled user.
+//
+func (ecaa *ECAA) EnableUser(ctx context.Context, id *pb.Identity) (*pb.CAStatus, error) {
+	Trace.Println("grpc ECAA:EnableUser")
+
+	if err := ecaa.eca.setUserDisabled(id.Id, false); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
+}
+
+// ResetPassword replaces the password of a user with a new random one.
+//
+func (ecaa *ECAA) ResetPassword(ctx context.Context, id *pb.Identity) (*pb.Password, error) {
+	Trace.Println("grpc ECAA:ResetPassword")
+
+	pw, err := ecaa.eca.resetPassword(id.Id)
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.Password{Pw: pw}, nil
+}
+
+// ReadUserCertificates returns all enrollment certificates issued to a user, revoked or not.
+//
+func (ecaa *ECAA) ReadUserCertificates(ctx context.Context, id *pb.Identity) (*pb.CertInfoSet, error) {
+	Trace.Println("grpc ECAA:ReadUserCertificates")
+
+	records, err := ecaa.eca.readCertificates(id.Id)
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	set := &pb.CertInfoSet{}
+	for _, rec := range records {
+		info := &pb.CertInfo{
+			Cert:    &pb.Cert{Cert: rec.Cert},
+			Hash:    rec.Hash,
+			Ts:      &google_protobuf.Timestamp{Seconds: rec.Timestamp / int64(time.Second), Nanos: int32(rec.Timestamp % int64(time.Second))},
+			Revoked: rec.Revoked,
+		}
+		if rec.Revoked {
+			info.RevocationTs = &google_protobuf.Timestamp{Seconds: rec.RevocationTime}
+			info.Reason = pb.RevocationReason(rec.RevocationReason)
+		}
+		set.Certs = append(set.Certs, info)
+	}
+
+	return set, nil
 }
@@ -0,0 +1,266 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package obcca
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gocraft/web"
+	pb "github.com/openblockchain/obc-peer/obc-ca/protos"
+	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+)
+
+// ECAR serves the administrator REST interface of the ECA.  It mirrors the administrator GRPC
+// interface for those who manage users without a GRPC client.  Requests are authenticated with
+// HTTP basic authentication against the administrators listed in eca.rest.admins and are only
+// served over TLS.  The interface is disabled unless ports.ecaR is set.
+type ECAR struct {
+	ecaa *ECAA
+}
+
+// RESTUser is the JSON representation of a user.
+type RESTUser struct {
+	ID       string `json:"id"`
+	Password string `json:"password,omitempty"`
+	Disabled bool   `json:"disabled"`
+}
+
+// RESTCertificate is the JSON representation of a certificate and its revocation status.
+type RESTCertificate struct {
+	Hash           string     `json:"hash"`
+	Issued         time.Time  `json:"issued"`
+	Revoked        bool       `json:"revoked"`
+	RevocationTime *time.Time `json:"revocationTime,omitempty"`
+	Reason         string     `json:"reason,omitempty"`
+	Cert           string     `json:"cert"`
+}
+
+type restError struct {
+	Error string
+}
+
+type restRevocation struct {
+	Reason string `json:"reason"`
+}
+
+func (eca *ECA) startECAR(wg *sync.WaitGroup) {
+	cert, err := tls.LoadX509KeyPair(viper.GetString("eca.tls.certfile"), viper.GetString("eca.tls.keyfile"))
+	if err != nil {
+		Panic.Panicln(err)
+	}
+	eca.sockr, err = net.Listen("tcp", viper.GetString("ports.ecaR"))
+	if err != nil {
+		Panic.Panicln(err)
+	}
+	eca.sockr = tls.NewListener(eca.sockr, &tls.Config{Certificates: []tls.Certificate{cert}})
+
+	ecaa := &ECAA{eca}
+	router := web.New(ECAR{})
+	router.Middleware(func(ecar *ECAR, rw web.ResponseWriter, req *web.Request, next web.NextMiddlewareFunc) {
+		ecar.ecaa = ecaa
+		next(rw, req)
+	})
+	router.Middleware((*ECAR).Authenticate)
+
+	router.Get("/users", (*ECAR).ReadUserSet)
+	router.Post("/users", (*ECAR).RegisterUser)
+	router.Post("/users/:id/disable", (*ECAR).DisableUser)
+	router.Post("/users/:id/enable", (*ECAR).EnableUser)
+	router.Post("/users/:id/secret", (*ECAR).ResetPassword)
+	router.Get("/users/:id/certificates", (*ECAR).ReadUserCertificates)
+	router.Post("/users/:id/certificates/:hash/revoke", (*ECAR).RevokeCertificate)
+
+	Info.Println("ECA REST interface listening on " + viper.GetString("ports.ecaR"))
+	http.Serve(eca.sockr, router)
+
+	wg.Done()
+}
+
+// Authenticate rejects requests not carrying the credentials of an administrator.
+func (ecar *ECAR) Authenticate(rw web.ResponseWriter, req *web.Request, next web.NextMiddlewareFunc) {
+	rw.Header().Set("Content-Type", "application/json")
+
+	id, pw, ok := req.BasicAuth()
+	secret, found := viper.GetStringMapString("eca.rest.admins")[id]
+	if !ok || !found || secret == "" || subtle.ConstantTimeCompare([]byte(pw), []byte(secret)) != 1 {
+		Error.Println("REST request from unauthenticated administrator " + id)
+		rw.Header().Set("WWW-Authenticate", `Basic realm="obcca"`)
+		writeREST(rw, http.StatusUnauthorized, &restError{"authentication required"})
+		return
+	}
+
+	Trace.Println("REST " + req.Method + " " + req.URL.Path + " by " + id)
+	next(rw, req)
+}
+
+// ReadUserSet lists the users registered with the ECA.
+func (ecar *ECAR) ReadUserSet(rw web.ResponseWriter, req *web.Request) {
+	set, err := ecar.ecaa.ReadUserSet(context.Background(), &pb.UserSetReadReq{})
+	if err != nil {
+		writeRESTError(rw, err)
+		return
+	}
+
+	users := []*RESTUser{}
+	for _, user := range set.Users {
+		users = append(users, &RESTUser{ID: user.Id.Id, Disabled: user.Disabled})
+	}
+
+	writeREST(rw, http.StatusOK, users)
+}
+
+// RegisterUser registers the user given in the body of the request and returns its password.
+func (ecar *ECAR) RegisterUser(rw web.ResponseWriter, req *web.Request) {
+	var user RESTUser
+	if err := json.NewDecoder(req.Body).Decode(&user); err != nil || user.ID == "" {
+		writeREST(rw, http.StatusBadRequest, &restError{"invalid request"})
+		return
+	}
+
+	pw, err := ecar.ecaa.RegisterUser(context.Background(), &pb.Identity{Id: user.ID})
+	if err != nil {
+		writeRESTError(rw, err)
+		return
+	}
+
+	writeREST(rw, http.StatusOK, &RESTUser{ID: user.ID, Password: pw.Pw})
+}
+
+// DisableUser disables a user.
+func (ecar *ECAR) DisableUser(rw web.ResponseWriter, req *web.Request) {
+	id := req.PathParams["id"]
+	if _, err := ecar.ecaa.DisableUser(context.Background(), &pb.Identity{Id: id}); err != nil {
+		writeRESTError(rw, err)
+		return
+	}
+
+	writeREST(rw, http.StatusOK, &RESTUser{ID: id, Disabled: true})
+}
+
+// EnableUser re-enables a disabled user.
+func (ecar *ECAR) EnableUser(rw web.ResponseWriter, req *web.Request) {
+	id := req.PathParams["id"]
+	if _, err := ecar.ecaa.EnableUser(context.Background(), &pb.Identity{Id: id}); err != nil {
+		writeRESTError(rw, err)
+		return
+	}
+
+	writeREST(rw, http.StatusOK, &RESTUser{ID: id})
+}
+
+// ResetPassword replaces the password of a user and returns the new one.
+func (ecar *ECAR) ResetPassword(rw web.ResponseWriter, req *web.Request) {
+	id := req.PathParams["id"]
+	pw, err := ecar.ecaa.ResetPassword(context.Background(), &pb.Identity{Id: id})
+	if err != nil {
+		writeRESTError(rw, err)
+		return
+	}
+
+	writeREST(rw, http.StatusOK, &RESTUser{ID: id, Password: pw.Pw})
+}
+
+// ReadUserCertificates lists the enrollment certificates issued to a user.
+func (ecar *ECAR) ReadUserCertificates(rw web.ResponseWriter, req *web.Request) {
+	set, err := ecar.ecaa.ReadUserCertificates(context.Background(), &pb.Identity{Id: req.PathParams["id"]})
+	if err != nil {
+		writeRESTError(rw, err)
+		return
+	}
+
+	certs := []*RESTCertificate{}
+	for _, info := range set.Certs {
+		cert := &RESTCertificate{
+			Hash:    hex.EncodeToString(info.Hash),
+			Issued:  time.Unix(info.Ts.Seconds, int64(info.Ts.Nanos)).UTC(),
+			Revoked: info.Revoked,
+			Cert:    string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: info.Cert.Cert})),
+		}
+		if info.Revoked {
+			revocationTime := time.Unix(info.RevocationTs.Seconds, 0).UTC()
+			cert.RevocationTime = &revocationTime
+			cert.Reason = info.Reason.String()
+		}
+		certs = append(certs, cert)
+	}
+
+	writeREST(rw, http.StatusOK, certs)
+}
+
+// RevokeCertificate revokes a certificate issued to a user, identified by its hex encoded hash.
+// The body of the request may give the reason for the revocation.
+func (ecar *ECAR) RevokeCertificate(rw web.ResponseWriter, req *web.Request) {
+	id := req.PathParams["id"]
+
+	var revocation restRevocation
+	if err := json.NewDecoder(req.Body).Decode(&revocation); err != nil && err != io.EOF {
+		writeREST(rw, http.StatusBadRequest, &restError{"invalid request"})
+		return
+	}
+	reason, ok := pb.RevocationReason_value[revocation.Reason]
+	if revocation.Reason != "" && !ok {
+		writeREST(rw, http.StatusBadRequest, &restError{"unknown revocation reason " + revocation.Reason})
+		return
+	}
+
+	hash, err := hex.DecodeString(req.PathParams["hash"])
+	if err != nil {
+		writeREST(rw, http.StatusBadRequest, &restError{"invalid certificate hash"})
+		return
+	}
+	raw, err := ecar.ecaa.eca.readCertificateByHash(hash)
+	if err != nil {
+		writeRESTError(rw, err)
+		return
+	}
+
+	if err := ecar.ecaa.eca.revokeCertificate(raw, pb.RevocationReason(reason), id); err != nil {
+		writeRESTError(rw, err)
+		return
+	}
+
+	writeREST(rw, http.StatusOK, &struct{ OK string }{"certificate revoked"})
+}
+
+func writeREST(rw web.ResponseWriter, status int, v interface{}) {
+	rw.WriteHeader(status)
+	if err := json.NewEncoder(rw).Encode(v); err != nil {
+		Error.Println(err)
+	}
+}
+
+func writeRESTError(rw web.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if err == ErrNotFound || err == ErrNotRevoked {
+		status = http.StatusNotFound
+	}
+
+	writeREST(rw, status, &restError{err.Error()})
+}
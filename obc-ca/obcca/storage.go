@@ -0,0 +1,180 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package obcca
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	sqliteStorageBackend  = "sqlite"
+	sqlStorageBackend     = "sql"
+	rocksdbStorageBackend = "rocksdb"
+)
+
+var (
+	// ErrNotFound is returned by a CAStore if the requested record does not exist.
+	//
+	ErrNotFound = errors.New("not found")
+
+	// ErrNotRevoked is returned by a CAStore if there is no unrevoked certificate to revoke.
+	//
+	ErrNotRevoked = errors.New("certificate not found or already revoked")
+)
+
+// CertificateRecord is a certificate issued by a CA together with its revocation status.
+type CertificateRecord struct {
+	ID               string
+	Timestamp        int64
+	Cert             []byte
+	Hash             []byte
+	Revoked          bool
+	RevocationTime   int64
+	RevocationReason int32
+}
+
+// UserRecord is a user registered with a CA.
+//
+type UserRecord struct {
+	ID       string
+	Password string
+	Disabled bool
+}
+
+// CAStore is the storage backend of a CA. It holds the keys of the CA, the certificates it
+// issued, its certificate revocation lists, and the users and attributes registered with it.
+// Backends are selected by the storage.backend property.
+type CAStore interface {
+	// InsertCertificate stores a newly issued certificate.
+	//
+	InsertCertificate(id string, timestamp int64, cert, hash []byte) error
+
+	// ReadCertificate returns an unrevoked certificate issued to id, at timestamp unless 0.
+	//
+	ReadCertificate(id string, timestamp int64) ([]byte, error)
+
+	// ReadCertificateByHash returns the certificate with the given hash, revoked or not.
+	//
+	ReadCertificateByHash(hash []byte) ([]byte, error)
+
+	// ReadCertificateRecord returns the record of the certificate with the given hash, revoked or not.
+	//
+	ReadCertificateRecord(hash []byte) (*CertificateRecord, error)
+
+	// ReadCertificateSet returns the unrevoked certificates issued to id at timestamp.
+	//
+	ReadCertificateSet(id string, timestamp int64) ([][]byte, error)
+
+	// ReadCertificates returns the records of all certificates issued to id, revoked or not,
+	// sorted by timestamp.
+	//
+	ReadCertificates(id string) ([]*CertificateRecord, error)
+
+	// RevokeCertificate revokes the unrevoked certificate with the given hash, issued to id
+	// unless empty.
+	//
+	RevokeCertificate(hash []byte, id string, revocationTime int64, reason int32) error
+
+	// RevokeCertificateSet revokes the unrevoked certificates issued to id at timestamp, or
+	// at the latest timestamp if 0.
+	//
+	RevokeCertificateSet(id string, timestamp int64, revocationTime int64, reason int32) error
+
+	// RevokeCertificates revokes all unrevoked certificates issued to id.
+	//
+	RevokeCertificates(id string, revocationTime int64, reason int32) error
+
+	// ReadRevokedCertificates returns the records of all revoked certificates.
+	//
+	ReadRevokedCertificates() ([]*CertificateRecord, error)
+
+	// InsertCRL stores a newly created certificate revocation list.
+	//
+	InsertCRL(timestamp int64, crl []byte) error
+
+	// ReadCRL returns the latest certificate revocation list.
+	//
+	ReadCRL() ([]byte, error)
+
+	// InsertUser registers user id with password pw.
+	//
+	InsertUser(id, pw string) error
+
+	// ReadUser returns user id.
+	//
+	ReadUser(id string) (*UserRecord, error)
+
+	// ReadUsers returns all users, sorted by id.
+	//
+	ReadUsers() ([]*UserRecord, error)
+
+	// UpdatePassword replaces the password of user id with pw.
+	//
+	UpdatePassword(id, pw string) error
+
+	// SetUserDisabled disables or re-enables user id.
+	//
+	SetUserDisabled(id string, disabled bool) error
+
+	// WriteAttribute sets attribute name of user id, replacing any previous value.
+	//
+	WriteAttribute(id, name string, value []byte) error
+
+	// ReadAttributes returns the names and values of the attributes of user id, sorted by name.
+	//
+	ReadAttributes(id string) ([]string, [][]byte, error)
+
+	// ReadKey returns the key material called name.
+	//
+	ReadKey(name string) ([]byte, error)
+
+	// InsertKey stores key as the key material called name unless there is one already, and
+	// returns the key material stored under name. CA instances sharing a store thus agree on
+	// their keys even if they start at the same time.
+	//
+	InsertKey(name string, key []byte) ([]byte, error)
+
+	// Close releases the resources held by the backend.
+	//
+	Close() error
+}
+
+// newCAStore opens the storage backend of the CA called name, creating or migrating it as needed.
+func newCAStore(name string) (CAStore, error) {
+	backend := viper.GetString("storage.backend")
+	if backend == "" {
+		backend = sqliteStorageBackend
+	}
+
+	switch backend {
+	case sqliteStorageBackend:
+		return newSQLCAStore("sqlite3", filepath.Join(RootPath, name+".db"), "")
+	case sqlStorageBackend:
+		// the CAs share the database, each with its own tables
+		return newSQLCAStore(viper.GetString("storage.sql.driver"), viper.GetString("storage.sql.datasource"), name+"_")
+	case rocksdbStorageBackend:
+		return newRocksDBCAStore(filepath.Join(RootPath, name+".rocksdb"))
+	default:
+		return nil, errors.New("unknown storage backend " + backend)
+	}
+}
@@ -0,0 +1,542 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package obcca
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/tecbot/gorocksdb"
+)
+
+// Layout of the keys of the rocksdb store. Certificates are keyed by their hash and indexed
+// by the id and timestamp they were issued for; CRLs are keyed by their timestamp so that
+// the latest one sorts last. Keys of the CA are keyed by their name.
+const (
+	rocksdbCertificatePrefix = 'c'
+	rocksdbIndexPrefix       = 'i'
+	rocksdbCRLPrefix         = 'r'
+	rocksdbUserPrefix        = 'u'
+	rocksdbAttributePrefix   = 'a'
+	rocksdbKeyPrefix         = 'k'
+)
+
+var rocksdbSchemaVersionKey = []byte("v")
+
+// rocksdbMigrations upgrade the key layout of the rocksdb store, one version each.
+var rocksdbMigrations = []func(store *rocksdbCAStore) error{
+	// 1: initial layout
+	func(store *rocksdbCAStore) error {
+		return nil
+	},
+
+	// 2: users are stored as records rather than as bare passwords
+	func(store *rocksdbCAStore) error {
+		batch := gorocksdb.NewWriteBatch()
+		defer batch.Destroy()
+
+		err := store.forEach([]byte{rocksdbUserPrefix}, func(key, value []byte) (bool, error) {
+			raw, err := json.Marshal(&UserRecord{ID: string(key[1:]), Password: string(value)})
+			if err != nil {
+				return false, err
+			}
+			batch.Put(key, raw)
+
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return store.db.Write(store.wo, batch)
+	},
+}
+
+// rocksdbCAStore keeps the state of a CA in an embedded rocksdb database.
+type rocksdbCAStore struct {
+	db *gorocksdb.DB
+	ro *gorocksdb.ReadOptions
+	wo *gorocksdb.WriteOptions
+
+	// mutex serializes the read-modify-write operations
+	mutex sync.Mutex
+}
+
+func newRocksDBCAStore(path string) (*rocksdbCAStore, error) {
+	opts := gorocksdb.NewDefaultOptions()
+	opts.SetCreateIfMissing(true)
+
+	db, err := gorocksdb.OpenDb(opts, path)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &rocksdbCAStore{
+		db: db,
+		ro: gorocksdb.NewDefaultReadOptions(),
+		wo: gorocksdb.NewDefaultWriteOptions(),
+	}
+	if err := store.migrate(); err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (store *rocksdbCAStore) migrate() error {
+	raw, err := store.db.GetBytes(store.ro, rocksdbSchemaVersionKey)
+	if err != nil {
+		return err
+	}
+
+	version := 0
+	if raw != nil {
+		if version, err = strconv.Atoi(string(raw)); err != nil {
+			return err
+		}
+	}
+	if version > len(rocksdbMigrations) {
+		return errors.New("database schema version " + strconv.Itoa(version) + " is newer than supported")
+	}
+
+	for v := version; v < len(rocksdbMigrations); v++ {
+		Info.Printf("migrating database schema to version %d\n", v+1)
+
+		if err := rocksdbMigrations[v](store); err != nil {
+			return err
+		}
+		if err := store.db.Put(store.wo, rocksdbSchemaVersionKey, []byte(strconv.Itoa(v+1))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func rocksdbKey(prefix byte, parts ...[]byte) []byte {
+	key := []byte{prefix}
+	for i, part := range parts {
+		if i > 0 {
+			key = append(key, 0)
+		}
+		key = append(key, part...)
+	}
+
+	return key
+}
+
+func rocksdbTimestamp(timestamp int64) []byte {
+	raw := make([]byte, 8)
+	binary.BigEndian.PutUint64(raw, uint64(timestamp))
+
+	return raw
+}
+
+// rocksdbIndexKey is the key indexing the certificate with the given hash under id and
+// timestamp; the hash is also its value. Ids cannot contain 0 bytes, so the keys of an id
+// form a contiguous range.
+func rocksdbIndexKey(id string, timestamp int64, hash []byte) []byte {
+	return append(append(rocksdbKey(rocksdbIndexPrefix, []byte(id), rocksdbTimestamp(timestamp)), 0), hash...)
+}
+
+func (store *rocksdbCAStore) readRecord(hash []byte) (*CertificateRecord, error) {
+	raw, err := store.db.GetBytes(store.ro, rocksdbKey(rocksdbCertificatePrefix, hash))
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, ErrNotFound
+	}
+
+	rec := new(CertificateRecord)
+	if err := json.Unmarshal(raw, rec); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// forEach calls f with a copy of the key and value of every entry starting with prefix,
+// until f returns false or an error.
+func (store *rocksdbCAStore) forEach(prefix []byte, f func(key, value []byte) (bool, error)) error {
+	it := store.db.NewIterator(store.ro)
+	defer it.Close()
+
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		key, value := it.Key(), it.Value()
+		more, err := f(copySlice(key), copySlice(value))
+		if err != nil || !more {
+			return err
+		}
+	}
+
+	return it.Err()
+}
+
+func copySlice(slice *gorocksdb.Slice) []byte {
+	defer slice.Free()
+
+	data := slice.Data()
+	raw := make([]byte, len(data))
+	copy(raw, data)
+
+	return raw
+}
+
+// readIndexed returns the unrevoked certificates indexed under prefix, at most max of
+// them unless max is 0.
+func (store *rocksdbCAStore) readIndexed(prefix []byte, max int) ([]*CertificateRecord, error) {
+	var records []*CertificateRecord
+	err := store.forEach(prefix, func(key, value []byte) (bool, error) {
+		rec, err := store.readRecord(value)
+		if err != nil {
+			return false, err
+		}
+		if !rec.Revoked {
+			records = append(records, rec)
+		}
+
+		return max == 0 || len(records) < max, nil
+	})
+
+	return records, err
+}
+
+func (store *rocksdbCAStore) InsertCertificate(id string, timestamp int64, cert, hash []byte) error {
+	raw, err := json.Marshal(&CertificateRecord{ID: id, Timestamp: timestamp, Cert: cert, Hash: hash})
+	if err != nil {
+		return err
+	}
+
+	batch := gorocksdb.NewWriteBatch()
+	defer batch.Destroy()
+	batch.Put(rocksdbKey(rocksdbCertificatePrefix, hash), raw)
+	batch.Put(rocksdbIndexKey(id, timestamp, hash), hash)
+
+	return store.db.Write(store.wo, batch)
+}
+
+func (store *rocksdbCAStore) ReadCertificate(id string, timestamp int64) ([]byte, error) {
+	prefix := append(rocksdbKey(rocksdbIndexPrefix, []byte(id)), 0)
+	if timestamp != 0 {
+		prefix = append(prefix, rocksdbTimestamp(timestamp)...)
+	}
+
+	records, err := store.readIndexed(prefix, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return records[0].Cert, nil
+}
+
+func (store *rocksdbCAStore) ReadCertificateByHash(hash []byte) ([]byte, error) {
+	rec, err := store.readRecord(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return rec.Cert, nil
+}
+
+func (store *rocksdbCAStore) ReadCertificateRecord(hash []byte) (*CertificateRecord, error) {
+	return store.readRecord(hash)
+}
+
+func (store *rocksdbCAStore) ReadCertificateSet(id string, timestamp int64) ([][]byte, error) {
+	records, err := store.readIndexed(append(append(rocksdbKey(rocksdbIndexPrefix, []byte(id)), 0), rocksdbTimestamp(timestamp)...), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var set [][]byte
+	for _, rec := range records {
+		set = append(set, rec.Cert)
+	}
+
+	return set, nil
+}
+
+func (store *rocksdbCAStore) ReadCertificates(id string) ([]*CertificateRecord, error) {
+	var records []*CertificateRecord
+	err := store.forEach(append(rocksdbKey(rocksdbIndexPrefix, []byte(id)), 0), func(key, value []byte) (bool, error) {
+		rec, err := store.readRecord(value)
+		if err != nil {
+			return false, err
+		}
+		records = append(records, rec)
+
+		return true, nil
+	})
+
+	return records, err
+}
+
+func (store *rocksdbCAStore) revoke(records []*CertificateRecord, revocationTime int64, reason int32) error {
+	if len(records) == 0 {
+		return ErrNotRevoked
+	}
+
+	batch := gorocksdb.NewWriteBatch()
+	defer batch.Destroy()
+	for _, rec := range records {
+		rec.Revoked = true
+		rec.RevocationTime = revocationTime
+		rec.RevocationReason = reason
+
+		raw, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		batch.Put(rocksdbKey(rocksdbCertificatePrefix, rec.Hash), raw)
+	}
+
+	return store.db.Write(store.wo, batch)
+}
+
+func (store *rocksdbCAStore) RevokeCertificate(hash []byte, id string, revocationTime int64, reason int32) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	rec, err := store.readRecord(hash)
+	if err == ErrNotFound || err == nil && (rec.Revoked || len(id) > 0 && rec.ID != id) {
+		return ErrNotRevoked
+	}
+	if err != nil {
+		return err
+	}
+
+	return store.revoke([]*CertificateRecord{rec}, revocationTime, reason)
+}
+
+func (store *rocksdbCAStore) RevokeCertificateSet(id string, timestamp int64, revocationTime int64, reason int32) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	records, err := store.readIndexed(append(rocksdbKey(rocksdbIndexPrefix, []byte(id)), 0), 0)
+	if err != nil {
+		return err
+	}
+
+	if timestamp == 0 {
+		for _, rec := range records {
+			if rec.Timestamp > timestamp {
+				timestamp = rec.Timestamp
+			}
+		}
+	}
+
+	var set []*CertificateRecord
+	for _, rec := range records {
+		if rec.Timestamp == timestamp {
+			set = append(set, rec)
+		}
+	}
+
+	return store.revoke(set, revocationTime, reason)
+}
+
+func (store *rocksdbCAStore) RevokeCertificates(id string, revocationTime int64, reason int32) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	records, err := store.readIndexed(append(rocksdbKey(rocksdbIndexPrefix, []byte(id)), 0), 0)
+	if err != nil {
+		return err
+	}
+
+	return store.revoke(records, revocationTime, reason)
+}
+
+func (store *rocksdbCAStore) ReadRevokedCertificates() ([]*CertificateRecord, error) {
+	var records []*CertificateRecord
+	err := store.forEach([]byte{rocksdbCertificatePrefix}, func(key, value []byte) (bool, error) {
+		rec := new(CertificateRecord)
+		if err := json.Unmarshal(value, rec); err != nil {
+			return false, err
+		}
+		if rec.Revoked {
+			records = append(records, rec)
+		}
+
+		return true, nil
+	})
+
+	return records, err
+}
+
+func (store *rocksdbCAStore) InsertCRL(timestamp int64, crl []byte) error {
+	return store.db.Put(store.wo, rocksdbKey(rocksdbCRLPrefix, rocksdbTimestamp(timestamp)), crl)
+}
+
+func (store *rocksdbCAStore) ReadCRL() ([]byte, error) {
+	it := store.db.NewIterator(store.ro)
+	defer it.Close()
+
+	// the latest CRL is the last key before the next prefix
+	if it.Seek([]byte{rocksdbCRLPrefix + 1}); it.Valid() {
+		it.Prev()
+	} else {
+		it.SeekToLast()
+	}
+	if !it.ValidForPrefix([]byte{rocksdbCRLPrefix}) {
+		if err := it.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
+
+	return copySlice(it.Value()), nil
+}
+
+func (store *rocksdbCAStore) writeUser(user *UserRecord) error {
+	raw, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	return store.db.Put(store.wo, rocksdbKey(rocksdbUserPrefix, []byte(user.ID)), raw)
+}
+
+func (store *rocksdbCAStore) InsertUser(id, pw string) error {
+	return store.writeUser(&UserRecord{ID: id, Password: pw})
+}
+
+func (store *rocksdbCAStore) ReadUser(id string) (*UserRecord, error) {
+	raw, err := store.db.GetBytes(store.ro, rocksdbKey(rocksdbUserPrefix, []byte(id)))
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, ErrNotFound
+	}
+
+	user := new(UserRecord)
+	if err := json.Unmarshal(raw, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (store *rocksdbCAStore) ReadUsers() ([]*UserRecord, error) {
+	var users []*UserRecord
+	err := store.forEach([]byte{rocksdbUserPrefix}, func(key, value []byte) (bool, error) {
+		user := new(UserRecord)
+		if err := json.Unmarshal(value, user); err != nil {
+			return false, err
+		}
+		users = append(users, user)
+
+		return true, nil
+	})
+
+	return users, err
+}
+
+func (store *rocksdbCAStore) UpdatePassword(id, pw string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	user, err := store.ReadUser(id)
+	if err != nil {
+		return err
+	}
+	user.Password = pw
+
+	return store.writeUser(user)
+}
+
+func (store *rocksdbCAStore) SetUserDisabled(id string, disabled bool) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	user, err := store.ReadUser(id)
+	if err != nil {
+		return err
+	}
+	user.Disabled = disabled
+
+	return store.writeUser(user)
+}
+
+func (store *rocksdbCAStore) WriteAttribute(id, name string, value []byte) error {
+	return store.db.Put(store.wo, rocksdbKey(rocksdbAttributePrefix, []byte(id), []byte(name)), value)
+}
+
+func (store *rocksdbCAStore) ReadAttributes(id string) ([]string, [][]byte, error) {
+	prefix := append(rocksdbKey(rocksdbAttributePrefix, []byte(id)), 0)
+
+	var names []string
+	var values [][]byte
+	err := store.forEach(prefix, func(key, value []byte) (bool, error) {
+		names = append(names, string(key[len(prefix):]))
+		values = append(values, value)
+
+		return true, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return names, values, nil
+}
+
+func (store *rocksdbCAStore) ReadKey(name string) ([]byte, error) {
+	key, err := store.db.GetBytes(store.ro, rocksdbKey(rocksdbKeyPrefix, []byte(name)))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrNotFound
+	}
+
+	return key, nil
+}
+
+func (store *rocksdbCAStore) InsertKey(name string, key []byte) ([]byte, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	stored, err := store.ReadKey(name)
+	if err != ErrNotFound {
+		return stored, err
+	}
+	if err := store.db.Put(store.wo, rocksdbKey(rocksdbKeyPrefix, []byte(name)), key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (store *rocksdbCAStore) Close() error {
+	store.ro.Destroy()
+	store.wo.Destroy()
+	store.db.Close()
+
+	return nil
+}
@@ -0,0 +1,548 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package obcca
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Needed to use sqlite3
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqlDialect captures how the SQL dialects supported by the sql store differ.
+type sqlDialect struct {
+	// types maps the column types used in the schema to the ones of the dialect
+	types *strings.Replacer
+
+	// numberedParams is true if query parameters are written $1, $2, ... rather than ?
+	numberedParams bool
+
+	// hasColumn checks whether a table has a column
+	hasColumn func(tx *sql.Tx, table, column string) (bool, error)
+}
+
+var (
+	// sqliteDialect is the dialect of sqlite3 databases.
+	//
+	sqliteDialect = &sqlDialect{
+		types: strings.NewReplacer("{rowid}", "INTEGER PRIMARY KEY", "{int64}", "INTEGER", "{blob}", "BLOB"),
+		hasColumn: func(tx *sql.Tx, table, column string) (bool, error) {
+			rows, err := tx.Query("PRAGMA table_info(" + table + ")")
+			if err != nil {
+				return false, err
+			}
+			defer rows.Close()
+
+			for rows.Next() {
+				var cid, notnull, pk int
+				var name, ctype string
+				var dflt sql.NullString
+				if err := rows.Scan(&cid, &name, &ctype, &notnull, &dflt, &pk); err != nil {
+					return false, err
+				}
+				if name == column {
+					return true, nil
+				}
+			}
+
+			return false, rows.Err()
+		},
+	}
+
+	// postgresDialect is the dialect of PostgreSQL and of the databases compatible with it.
+	//
+	postgresDialect = &sqlDialect{
+		types:          strings.NewReplacer("{rowid}", "BIGSERIAL PRIMARY KEY", "{int64}", "BIGINT", "{blob}", "BYTEA"),
+		numberedParams: true,
+		hasColumn: func(tx *sql.Tx, table, column string) (bool, error) {
+			// unquoted identifiers are folded to lower case
+			var n int
+			err := tx.QueryRow("SELECT COUNT(*) FROM information_schema.columns WHERE table_schema=current_schema() AND table_name=$1 AND column_name=$2",
+				strings.ToLower(table), strings.ToLower(column)).Scan(&n)
+
+			return n > 0, err
+		},
+	}
+)
+
+// sqlMigrations upgrade the schema of the sql store, one version each. Databases created
+// before the schema was versioned are at version 0, so the migrations up to 3 have to
+// cope with the tables and columns they add being present already.
+var sqlMigrations = []func(store *sqlCAStore, tx *sql.Tx) error{
+	// 1: certificates and users
+	func(store *sqlCAStore, tx *sql.Tx) error {
+		return store.execAll(tx,
+			"CREATE TABLE IF NOT EXISTS %sCertificates (row {rowid}, id VARCHAR(64), timestamp {int64}, cert {blob}, hash {blob})",
+			"CREATE TABLE IF NOT EXISTS %sUsers (row {rowid}, id VARCHAR(64), password VARCHAR(64))",
+		)
+	},
+
+	// 2: certificate revocation
+	func(store *sqlCAStore, tx *sql.Tx) error {
+		for _, column := range []string{"revoked", "revocationTime", "revocationReason"} {
+			if err := store.addColumnIfMissing(tx, "Certificates", column, "INTEGER DEFAULT 0"); err != nil {
+				return err
+			}
+		}
+		return store.execAll(tx,
+			"CREATE TABLE IF NOT EXISTS %sCRLs (row {rowid}, timestamp {int64}, crl {blob})",
+		)
+	},
+
+	// 3: user attributes
+	func(store *sqlCAStore, tx *sql.Tx) error {
+		return store.execAll(tx,
+			"CREATE TABLE IF NOT EXISTS %sAttributes (row {rowid}, id VARCHAR(64), name VARCHAR(64), value {blob})",
+		)
+	},
+
+	// 4: indexes for the lookups by id and by hash
+	func(store *sqlCAStore, tx *sql.Tx) error {
+		return store.execAll(tx,
+			"CREATE INDEX %[1]sCertificates_id ON %[1]sCertificates (id, timestamp)",
+			"CREATE INDEX %[1]sCertificates_hash ON %[1]sCertificates (hash)",
+			"CREATE INDEX %[1]sCRLs_timestamp ON %[1]sCRLs (timestamp)",
+			"CREATE INDEX %[1]sUsers_id ON %[1]sUsers (id)",
+			"CREATE INDEX %[1]sAttributes_id ON %[1]sAttributes (id, name)",
+		)
+	},
+
+	// 5: disabled users
+	func(store *sqlCAStore, tx *sql.Tx) error {
+		return store.addColumnIfMissing(tx, "Users", "disabled", "INTEGER DEFAULT 0")
+	},
+
+	// 6: CA keys
+	func(store *sqlCAStore, tx *sql.Tx) error {
+		return store.execAll(tx,
+			"CREATE TABLE %sKeys (name VARCHAR(64) PRIMARY KEY, value {blob})",
+		)
+	},
+}
+
+// sqlCAStore keeps the state of a CA in a database/sql database. The tables of a CA are
+// prefixed, so that several CAs can share a database.
+type sqlCAStore struct {
+	db      *sql.DB
+	dialect *sqlDialect
+	prefix  string
+}
+
+func newSQLCAStore(driver, dataSource, prefix string) (*sqlCAStore, error) {
+	dialect := postgresDialect
+	if driver == "sqlite3" {
+		dialect = sqliteDialect
+	}
+
+	db, err := sql.Open(driver, dataSource)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	store := &sqlCAStore{db, dialect, prefix}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// query expands the table prefix and the column types into q, and rewrites its parameters
+// for the dialect of the store.
+func (store *sqlCAStore) query(q string) string {
+	q = store.dialect.types.Replace(fmt.Sprintf(q, store.prefix))
+	if !store.dialect.numberedParams {
+		return q
+	}
+
+	parts := strings.Split(q, "?")
+	q = parts[0]
+	for i, part := range parts[1:] {
+		q += "$" + strconv.Itoa(i+1) + part
+	}
+
+	return q
+}
+
+func (store *sqlCAStore) execAll(tx *sql.Tx, queries ...string) error {
+	for _, q := range queries {
+		if _, err := tx.Exec(store.query(q)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (store *sqlCAStore) addColumnIfMissing(tx *sql.Tx, table, column, decl string) error {
+	found, err := store.dialect.hasColumn(tx, store.prefix+table, column)
+	if err != nil || found {
+		return err
+	}
+
+	_, err = tx.Exec(store.query("ALTER TABLE %s" + table + " ADD COLUMN " + column + " " + decl))
+
+	return err
+}
+
+// migrate brings the schema of the store up to the latest version. Each migration runs in
+// its own transaction and is recorded in the SchemaVersion table.
+func (store *sqlCAStore) migrate() error {
+	if _, err := store.db.Exec(store.query("CREATE TABLE IF NOT EXISTS %sSchemaVersion (version INTEGER, timestamp {int64})")); err != nil {
+		return err
+	}
+
+	var version sql.NullInt64
+	if err := store.db.QueryRow(store.query("SELECT MAX(version) FROM %sSchemaVersion")).Scan(&version); err != nil {
+		return err
+	}
+	if int(version.Int64) > len(sqlMigrations) {
+		return errors.New("database schema version " + strconv.FormatInt(version.Int64, 10) + " is newer than supported")
+	}
+
+	for v := int(version.Int64); v < len(sqlMigrations); v++ {
+		Info.Printf("migrating %sdatabase schema to version %d\n", store.prefix, v+1)
+
+		tx, err := store.db.Begin()
+		if err != nil {
+			return err
+		}
+		if err := sqlMigrations[v](store, tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.Exec(store.query("INSERT INTO %sSchemaVersion (version, timestamp) VALUES (?, ?)"), v+1, time.Now().Unix()); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (store *sqlCAStore) InsertCertificate(id string, timestamp int64, cert, hash []byte) error {
+	_, err := store.db.Exec(store.query("INSERT INTO %sCertificates (id, timestamp, cert, hash) VALUES (?, ?, ?, ?)"), id, timestamp, cert, hash)
+
+	return err
+}
+
+func (store *sqlCAStore) ReadCertificate(id string, timestamp int64) ([]byte, error) {
+	var raw []byte
+	var err error
+	if timestamp != 0 {
+		err = store.db.QueryRow(store.query("SELECT cert FROM %sCertificates WHERE id=? AND timestamp=? AND revoked=0"), id, timestamp).Scan(&raw)
+	} else {
+		err = store.db.QueryRow(store.query("SELECT cert FROM %sCertificates WHERE id=? AND revoked=0"), id).Scan(&raw)
+	}
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return raw, err
+}
+
+func (store *sqlCAStore) ReadCertificateByHash(hash []byte) ([]byte, error) {
+	var raw []byte
+	err := store.db.QueryRow(store.query("SELECT cert FROM %sCertificates WHERE hash=?"), hash).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return raw, err
+}
+
+func (store *sqlCAStore) ReadCertificateRecord(hash []byte) (*CertificateRecord, error) {
+	rows, err := store.db.Query(store.query("SELECT id, timestamp, cert, hash, revoked, revocationTime, revocationReason FROM %sCertificates WHERE hash=?"), hash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records, err := scanCertificateRecords(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return records[0], nil
+}
+
+func (store *sqlCAStore) ReadCertificateSet(id string, timestamp int64) ([][]byte, error) {
+	rows, err := store.db.Query(store.query("SELECT cert FROM %sCertificates WHERE id=? AND timestamp=? AND revoked=0"), id, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var set [][]byte
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return set, err
+		}
+		set = append(set, raw)
+	}
+
+	return set, rows.Err()
+}
+
+func (store *sqlCAStore) ReadCertificates(id string) ([]*CertificateRecord, error) {
+	rows, err := store.db.Query(store.query("SELECT id, timestamp, cert, hash, revoked, revocationTime, revocationReason FROM %sCertificates WHERE id=? ORDER BY timestamp"), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCertificateRecords(rows)
+}
+
+func scanCertificateRecords(rows *sql.Rows) ([]*CertificateRecord, error) {
+	var records []*CertificateRecord
+	for rows.Next() {
+		rec := new(CertificateRecord)
+		var revoked int
+		if err := rows.Scan(&rec.ID, &rec.Timestamp, &rec.Cert, &rec.Hash, &revoked, &rec.RevocationTime, &rec.RevocationReason); err != nil {
+			return nil, err
+		}
+		rec.Revoked = revoked != 0
+		records = append(records, rec)
+	}
+
+	return records, rows.Err()
+}
+
+func (store *sqlCAStore) RevokeCertificate(hash []byte, id string, revocationTime int64, reason int32) error {
+	var res sql.Result
+	var err error
+	if len(id) > 0 {
+		res, err = store.db.Exec(store.query("UPDATE %sCertificates SET revoked=1, revocationTime=?, revocationReason=? WHERE hash=? AND id=? AND revoked=0"), revocationTime, reason, hash, id)
+	} else {
+		res, err = store.db.Exec(store.query("UPDATE %sCertificates SET revoked=1, revocationTime=?, revocationReason=? WHERE hash=? AND revoked=0"), revocationTime, reason, hash)
+	}
+
+	return checkRevoked(res, err)
+}
+
+func (store *sqlCAStore) RevokeCertificateSet(id string, timestamp int64, revocationTime int64, reason int32) error {
+	if timestamp == 0 {
+		var latest sql.NullInt64
+		if err := store.db.QueryRow(store.query("SELECT MAX(timestamp) FROM %sCertificates WHERE id=? AND revoked=0"), id).Scan(&latest); err != nil {
+			return err
+		}
+		if !latest.Valid {
+			return ErrNotRevoked
+		}
+		timestamp = latest.Int64
+	}
+
+	res, err := store.db.Exec(store.query("UPDATE %sCertificates SET revoked=1, revocationTime=?, revocationReason=? WHERE id=? AND timestamp=? AND revoked=0"), revocationTime, reason, id, timestamp)
+
+	return checkRevoked(res, err)
+}
+
+func (store *sqlCAStore) RevokeCertificates(id string, revocationTime int64, reason int32) error {
+	res, err := store.db.Exec(store.query("UPDATE %sCertificates SET revoked=1, revocationTime=?, revocationReason=? WHERE id=? AND revoked=0"), revocationTime, reason, id)
+
+	return checkRevoked(res, err)
+}
+
+func checkRevoked(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrNotRevoked
+	}
+
+	return nil
+}
+
+func (store *sqlCAStore) ReadRevokedCertificates() ([]*CertificateRecord, error) {
+	rows, err := store.db.Query(store.query("SELECT id, timestamp, cert, hash, revoked, revocationTime, revocationReason FROM %sCertificates WHERE revoked=1"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCertificateRecords(rows)
+}
+
+func (store *sqlCAStore) InsertCRL(timestamp int64, crl []byte) error {
+	_, err := store.db.Exec(store.query("INSERT INTO %sCRLs (timestamp, crl) VALUES (?, ?)"), timestamp, crl)
+
+	return err
+}
+
+func (store *sqlCAStore) ReadCRL() ([]byte, error) {
+	var raw []byte
+	err := store.db.QueryRow(store.query("SELECT crl FROM %sCRLs ORDER BY timestamp DESC LIMIT 1")).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return raw, err
+}
+
+func (store *sqlCAStore) InsertUser(id, pw string) error {
+	_, err := store.db.Exec(store.query("INSERT INTO %sUsers (id, password) VALUES (?, ?)"), id, pw)
+
+	return err
+}
+
+func (store *sqlCAStore) ReadUser(id string) (*UserRecord, error) {
+	user := &UserRecord{ID: id}
+	var disabled int
+	err := store.db.QueryRow(store.query("SELECT password, disabled FROM %sUsers WHERE id=?"), id).Scan(&user.Password, &disabled)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	user.Disabled = disabled != 0
+
+	return user, err
+}
+
+func (store *sqlCAStore) ReadUsers() ([]*UserRecord, error) {
+	rows, err := store.db.Query(store.query("SELECT id, password, disabled FROM %sUsers ORDER BY id"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*UserRecord
+	for rows.Next() {
+		user := new(UserRecord)
+		var disabled int
+		if err := rows.Scan(&user.ID, &user.Password, &disabled); err != nil {
+			return nil, err
+		}
+		user.Disabled = disabled != 0
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (store *sqlCAStore) UpdatePassword(id, pw string) error {
+	res, err := store.db.Exec(store.query("UPDATE %sUsers SET password=? WHERE id=?"), pw, id)
+
+	return checkUpdated(res, err)
+}
+
+func (store *sqlCAStore) SetUserDisabled(id string, disabled bool) error {
+	flag := 0
+	if disabled {
+		flag = 1
+	}
+	res, err := store.db.Exec(store.query("UPDATE %sUsers SET disabled=? WHERE id=?"), flag, id)
+
+	return checkUpdated(res, err)
+}
+
+func checkUpdated(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (store *sqlCAStore) WriteAttribute(id, name string, value []byte) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(store.query("DELETE FROM %sAttributes WHERE id=? AND name=?"), id, name); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(store.query("INSERT INTO %sAttributes (id, name, value) VALUES (?, ?, ?)"), id, name, value); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (store *sqlCAStore) ReadAttributes(id string) ([]string, [][]byte, error) {
+	rows, err := store.db.Query(store.query("SELECT name, value FROM %sAttributes WHERE id=? ORDER BY name"), id)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	var values [][]byte
+	for rows.Next() {
+		var name string
+		var value []byte
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, nil, err
+		}
+		names = append(names, name)
+		values = append(values, value)
+	}
+
+	return names, values, rows.Err()
+}
+
+func (store *sqlCAStore) ReadKey(name string) ([]byte, error) {
+	var key []byte
+	err := store.db.QueryRow(store.query("SELECT value FROM %sKeys WHERE name=?"), name).Scan(&key)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return key, err
+}
+
+func (store *sqlCAStore) InsertKey(name string, key []byte) ([]byte, error) {
+	_, err := store.db.Exec(store.query("INSERT INTO %sKeys (name, value) VALUES (?, ?)"), name, key)
+	if err != nil {
+		// the primary key is violated if another instance stored the key first
+		if stored, errRead := store.ReadKey(name); errRead == nil {
+			return stored, nil
+		}
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (store *sqlCAStore) Close() error {
+	return store.db.Close()
+}
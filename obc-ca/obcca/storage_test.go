@@ -0,0 +1,394 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package obcca
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	LogInit(ioutil.Discard, ioutil.Discard, os.Stdout, os.Stderr, os.Stdout)
+
+	os.Exit(m.Run())
+}
+
+func newTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "obcca")
+	if err != nil {
+		t.Fatalf("Failed creating temporary directory [%s].", err)
+	}
+
+	return dir
+}
+
+func TestSQLCAStore(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	store, err := newSQLCAStore("sqlite3", filepath.Join(dir, "test.db"), "")
+	if err != nil {
+		t.Fatalf("Failed opening sql store [%s].", err)
+	}
+	defer store.Close()
+
+	testCAStore(t, store)
+}
+
+func TestRocksDBCAStore(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	store, err := newRocksDBCAStore(filepath.Join(dir, "test.rocksdb"))
+	if err != nil {
+		t.Fatalf("Failed opening rocksdb store [%s].", err)
+	}
+	defer store.Close()
+
+	testCAStore(t, store)
+}
+
+// TestSQLCAStoreMigration opens a database created before the schema was versioned.
+func TestSQLCAStoreMigration(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.db")
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed opening database [%s].", err)
+	}
+	for _, q := range []string{
+		"CREATE TABLE Certificates (row INTEGER PRIMARY KEY, id VARCHAR(64), timestamp INTEGER, cert BLOB, hash BLOB)",
+		"CREATE TABLE Users (row INTEGER PRIMARY KEY, id VARCHAR(64), password VARCHAR(64))",
+		"INSERT INTO Certificates (id, timestamp, cert, hash) VALUES ('alice', 1, x'0a', x'1a')",
+		"INSERT INTO Users (id, password) VALUES ('alice', 'secret')",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("Failed creating legacy schema [%s].", err)
+		}
+	}
+	db.Close()
+
+	store, err := newSQLCAStore("sqlite3", path, "")
+	if err != nil {
+		t.Fatalf("Failed migrating sql store [%s].", err)
+	}
+
+	raw, err := store.ReadCertificate("alice", 1)
+	if err != nil || !bytes.Equal(raw, []byte{0x0a}) {
+		t.Fatalf("Certificate lost by the migration [%v] [%s].", raw, err)
+	}
+	user, err := store.ReadUser("alice")
+	if err != nil || user.Password != "secret" || user.Disabled {
+		t.Fatalf("User lost by the migration [%v] [%s].", user, err)
+	}
+
+	// the columns and tables added by the migrations are usable
+	testCAStore(t, store)
+	store.Close()
+
+	// reopening does not migrate again
+	store, err = newSQLCAStore("sqlite3", path, "")
+	if err != nil {
+		t.Fatalf("Failed reopening sql store [%s].", err)
+	}
+	defer store.Close()
+
+	var version int
+	if err := store.db.QueryRow("SELECT MAX(version) FROM SchemaVersion").Scan(&version); err != nil || version != len(sqlMigrations) {
+		t.Fatalf("Wrong schema version [%d] [%v].", version, err)
+	}
+	if key, err := store.ReadKey("test.key"); err != nil || !bytes.Equal(key, []byte("key")) {
+		t.Fatalf("Key lost when reopening [%s].", err)
+	}
+}
+
+// recordingDriver is a database/sql driver that records the statements run through it
+// and answers every query with a single NULL or zero count, as an empty database would.
+type recordingDriver struct {
+	statements []string
+}
+
+type recordingConn struct {
+	driver *recordingDriver
+}
+
+type recordingStmt struct {
+	conn  *recordingConn
+	query string
+}
+
+type recordingRows struct {
+	value driver.Value
+	done  bool
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) {
+	return &recordingConn{d}, nil
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	c.driver.statements = append(c.driver.statements, query)
+	return &recordingStmt{c, query}, nil
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *recordingConn) Commit() error {
+	return nil
+}
+
+func (c *recordingConn) Rollback() error {
+	return nil
+}
+
+func (s *recordingStmt) Close() error {
+	return nil
+}
+
+func (s *recordingStmt) NumInput() int {
+	return -1
+}
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if strings.Contains(s.query, "COUNT(*)") {
+		return &recordingRows{value: int64(0)}, nil
+	}
+	return &recordingRows{}, nil
+}
+
+func (r *recordingRows) Columns() []string {
+	return []string{"value"}
+}
+
+func (r *recordingRows) Close() error {
+	return nil
+}
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+
+	return nil
+}
+
+var postgresRecorder = &recordingDriver{}
+
+func init() {
+	sql.Register("postgres-recorder", postgresRecorder)
+}
+
+func TestPostgresDialectQuery(t *testing.T) {
+	store := &sqlCAStore{nil, postgresDialect, "ECA_"}
+
+	q := store.query("UPDATE %sCertificates SET revoked=?, revocationTime=? WHERE hash=? AND id=?")
+	if q != "UPDATE ECA_Certificates SET revoked=$1, revocationTime=$2 WHERE hash=$3 AND id=$4" {
+		t.Fatalf("Wrong postgres query [%s].", q)
+	}
+	q = store.query("CREATE TABLE IF NOT EXISTS %sCRLs (row {rowid}, timestamp {int64}, crl {blob})")
+	if q != "CREATE TABLE IF NOT EXISTS ECA_CRLs (row BIGSERIAL PRIMARY KEY, timestamp BIGINT, crl BYTEA)" {
+		t.Fatalf("Wrong postgres schema [%s].", q)
+	}
+	if q := store.query("SELECT MAX(version) FROM %sSchemaVersion"); q != "SELECT MAX(version) FROM ECA_SchemaVersion" {
+		t.Fatalf("Wrong postgres query without parameters [%s].", q)
+	}
+}
+
+// TestPostgresDialectMigrations runs the migrations of an empty database through a driver
+// that records the SQL, which must be fully translated to the postgres dialect.
+func TestPostgresDialectMigrations(t *testing.T) {
+	postgresRecorder.statements = nil
+
+	store, err := newSQLCAStore("postgres-recorder", "", "TCA_")
+	if err != nil {
+		t.Fatalf("Failed migrating sql store [%s].", err)
+	}
+	defer store.Close()
+
+	var creates, inserts, columns int
+	for _, q := range postgresRecorder.statements {
+		if strings.ContainsAny(q, "{}?") {
+			t.Fatalf("Untranslated postgres statement [%s].", q)
+		}
+		if strings.Contains(q, "BLOB") || strings.Contains(q, "INTEGER PRIMARY KEY") {
+			t.Fatalf("sqlite type in postgres statement [%s].", q)
+		}
+		switch {
+		case strings.HasPrefix(q, "CREATE TABLE") || strings.HasPrefix(q, "CREATE INDEX"):
+			if !strings.Contains(q, " TCA_") {
+				t.Fatalf("Table prefix missing [%s].", q)
+			}
+			creates++
+		case strings.HasPrefix(q, "INSERT INTO TCA_SchemaVersion"):
+			if !strings.HasSuffix(q, "VALUES ($1, $2)") {
+				t.Fatalf("Wrong postgres parameters [%s].", q)
+			}
+			inserts++
+		case strings.HasPrefix(q, "SELECT COUNT(*) FROM information_schema.columns"):
+			columns++
+		}
+	}
+
+	// the schema version table, 5 tables and 5 indexes
+	if creates != 11 || inserts != len(sqlMigrations) || columns != 4 {
+		t.Fatalf("Unexpected migration statements [%d] [%d] [%d]:\n%s", creates, inserts, columns, strings.Join(postgresRecorder.statements, "\n"))
+	}
+}
+
+// testCAStore checks the behavior common to all CAStore backends.
+func testCAStore(t *testing.T, store CAStore) {
+	// certificates
+	for i, hash := range [][]byte{{1}, {2}, {3}} {
+		if err := store.InsertCertificate("bob", int64(i/2+1), []byte{0xc0 + hash[0]}, hash); err != nil {
+			t.Fatalf("Failed inserting certificate [%s].", err)
+		}
+	}
+
+	raw, err := store.ReadCertificateByHash([]byte{3})
+	if err != nil || !bytes.Equal(raw, []byte{0xc3}) {
+		t.Fatalf("Failed reading certificate by hash [%v] [%s].", raw, err)
+	}
+	if _, err := store.ReadCertificateByHash([]byte{4}); err != ErrNotFound {
+		t.Fatalf("Unknown certificate must not be found [%v].", err)
+	}
+	set, err := store.ReadCertificateSet("bob", 1)
+	if err != nil || len(set) != 2 {
+		t.Fatalf("Failed reading certificate set [%d] [%v].", len(set), err)
+	}
+
+	// revocation
+	if err := store.RevokeCertificate([]byte{1}, "carol", 10, 1); err != ErrNotRevoked {
+		t.Fatalf("Certificate of another user must not be revoked [%v].", err)
+	}
+	if err := store.RevokeCertificate([]byte{1}, "bob", 10, 1); err != nil {
+		t.Fatalf("Failed revoking certificate [%s].", err)
+	}
+	if err := store.RevokeCertificate([]byte{1}, "", 11, 1); err != ErrNotRevoked {
+		t.Fatalf("Certificate must not be revoked twice [%v].", err)
+	}
+	rec, err := store.ReadCertificateRecord([]byte{1})
+	if err != nil || !rec.Revoked || rec.RevocationTime != 10 || rec.RevocationReason != 1 {
+		t.Fatalf("Wrong certificate record [%v] [%v].", rec, err)
+	}
+	if err := store.RevokeCertificateSet("bob", 0, 12, 2); err != nil {
+		t.Fatalf("Failed revoking latest certificate set [%s].", err)
+	}
+	if _, err := store.ReadCertificate("bob", 2); err != ErrNotFound {
+		t.Fatalf("Revoked certificate must not be read [%v].", err)
+	}
+	if err := store.RevokeCertificates("bob", 13, 3); err != nil {
+		t.Fatalf("Failed revoking certificates [%s].", err)
+	}
+	if err := store.RevokeCertificates("bob", 14, 3); err != ErrNotRevoked {
+		t.Fatalf("Certificates must not be revoked twice [%v].", err)
+	}
+	records, err := store.ReadCertificates("bob")
+	if err != nil || len(records) != 3 {
+		t.Fatalf("Failed reading certificates [%d] [%v].", len(records), err)
+	}
+	revoked, err := store.ReadRevokedCertificates()
+	if err != nil || len(revoked) < 3 {
+		t.Fatalf("Failed reading revoked certificates [%d] [%v].", len(revoked), err)
+	}
+
+	// CRLs
+	if err := store.InsertCRL(1, []byte("crl1")); err != nil {
+		t.Fatalf("Failed inserting CRL [%s].", err)
+	}
+	if err := store.InsertCRL(2, []byte("crl2")); err != nil {
+		t.Fatalf("Failed inserting CRL [%s].", err)
+	}
+	if crl, err := store.ReadCRL(); err != nil || string(crl) != "crl2" {
+		t.Fatalf("Failed reading latest CRL [%s] [%v].", crl, err)
+	}
+
+	// users
+	if err := store.InsertUser("bob", "pw"); err != nil {
+		t.Fatalf("Failed inserting user [%s].", err)
+	}
+	if err := store.UpdatePassword("bob", "pw2"); err != nil {
+		t.Fatalf("Failed updating password [%s].", err)
+	}
+	if err := store.SetUserDisabled("bob", true); err != nil {
+		t.Fatalf("Failed disabling user [%s].", err)
+	}
+	user, err := store.ReadUser("bob")
+	if err != nil || user.Password != "pw2" || !user.Disabled {
+		t.Fatalf("Wrong user record [%v] [%v].", user, err)
+	}
+	if err := store.UpdatePassword("dave", "pw"); err != ErrNotFound {
+		t.Fatalf("Unknown user must not be updated [%v].", err)
+	}
+	if _, err := store.ReadUser("dave"); err != ErrNotFound {
+		t.Fatalf("Unknown user must not be found [%v].", err)
+	}
+
+	// attributes
+	if err := store.WriteAttribute("bob", "role", []byte("teller")); err != nil {
+		t.Fatalf("Failed writing attribute [%s].", err)
+	}
+	if err := store.WriteAttribute("bob", "role", []byte("admin")); err != nil {
+		t.Fatalf("Failed writing attribute [%s].", err)
+	}
+	if err := store.WriteAttribute("bob", "company", []byte("ACompany")); err != nil {
+		t.Fatalf("Failed writing attribute [%s].", err)
+	}
+	names, values, err := store.ReadAttributes("bob")
+	if err != nil || len(names) != 2 || names[0] != "company" || string(values[1]) != "admin" {
+		t.Fatalf("Wrong attributes [%v] [%s] [%v].", names, values, err)
+	}
+
+	// keys
+	if _, err := store.ReadKey("test.key"); err != ErrNotFound {
+		t.Fatalf("Unknown key must not be found [%v].", err)
+	}
+	key, err := store.InsertKey("test.key", []byte("key"))
+	if err != nil || string(key) != "key" {
+		t.Fatalf("Failed inserting key [%s] [%v].", key, err)
+	}
+	key, err = store.InsertKey("test.key", []byte("other"))
+	if err != nil || string(key) != "key" {
+		t.Fatalf("Key must not be replaced [%s] [%v].", key, err)
+	}
+	if key, err := store.ReadKey("test.key"); err != nil || string(key) != "key" {
+		t.Fatalf("Failed reading key [%s] [%v].", key, err)
+	}
+}
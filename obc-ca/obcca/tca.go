@@ -30,7 +30,6 @@ import (
 	"encoding/binary"
 	"errors"
 	"io"
-	"io/ioutil"
 	"math/big"
 	"net"
 	"strconv"
@@ -50,14 +49,33 @@ var (
 	// TCertEncTCertIndex is the ASN1 object identifier of the TCert index.
 	//
 	TCertEncTCertIndex = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7}
+
+	// TCertEncEnrollmentID is the ASN1 object identifier of the enrollment ID encrypted under the audit key.
+	//
+	TCertEncEnrollmentID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 8}
+
+	// TCertEncAttributesBase is the ASN1 object identifier prefix of the encrypted TCert attributes.
+	// The i-th attribute of a TCert is stored under TCertEncAttributesBase.i.
+	//
+	TCertEncAttributesBase = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 9}
 )
 
+// TCertAttribute is the ASN1 structure of an attribute embedded in a TCert.
+// The value is encrypted under a key derived from the TCert owner's KDF key,
+// the TCert index, and the attribute name.
+//
+type TCertAttribute struct {
+	Name  string
+	Value []byte
+}
+
 // TCA is the transaction certificate authority.
 //
 type TCA struct {
 	*CA
-	eca     *ECA
-	hmacKey []byte
+	eca      *ECA
+	hmacKey  []byte
+	auditKey []byte
 
 	rand io.Reader
 		
@@ -80,26 +98,22 @@ type TCAA struct {
 // NewTCA sets up a new TCA.
 //
 func NewTCA(eca *ECA) *TCA {
-	var cooked string
-
-	tca := &TCA{NewCA("tca"), eca, nil, rand.Reader, nil, nil, nil, nil}
+	tca := &TCA{NewCA("tca"), eca, nil, nil, rand.Reader, nil, nil, nil, nil}
+	eca.tca = tca
 	
-	raw, err := ioutil.ReadFile(RootPath + "/tca.hmac")
+	var err error
+	cooked := tca.readOrCreateKey("tca.hmac", func() ([]byte, error) {
+		return newSymmetricKey(49)
+	})
+	tca.hmacKey, err = base64.StdEncoding.DecodeString(string(cooked))
 	if err != nil {
-		rand := rand.Reader
-		key := make([]byte, 49)
-		rand.Read(key)
-		cooked = base64.StdEncoding.EncodeToString(key)
-
-		err = ioutil.WriteFile(RootPath+"/tca.hmac", []byte(cooked), 0644)
-		if err != nil {
-			Panic.Panicln(err)
-		}
-	} else {
-		cooked = string(raw)
+		Panic.Panicln(err)
 	}
 
-	tca.hmacKey, err = base64.StdEncoding.DecodeString(cooked)
+	cooked = tca.readOrCreateKey("tca.audit", func() ([]byte, error) {
+		return newSymmetricKey(32) // AES-256
+	})
+	tca.auditKey, err = base64.StdEncoding.DecodeString(string(cooked))
 	if err != nil {
 		Panic.Panicln(err)
 	}
@@ -171,6 +185,11 @@ func (tcap *TCAP) CreateCertificate(ctx context.Context, req *pb.TCertCreateReq)
 	Trace.Println("grpc TCAP:CreateCertificate")
 
 	id := req.Id.Id
+	if err := tcap.tca.eca.checkUserEnabled(id); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
 	raw, err := tcap.tca.eca.readCertificate(id)
 	if err != nil {
 		return nil, err
@@ -206,7 +225,13 @@ func (tcap *TCAP) CreateCertificate(ctx context.Context, req *pb.TCertCreateReq)
 		return nil, errors.New("signature does not verify")
 	}
 
-	if raw, err = tcap.tca.newCertificate(id, pub.(*ecdsa.PublicKey), req.Ts.Seconds); err != nil {
+	enrollID, err := CBCEncrypt(tcap.tca.auditKey, []byte(id))
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	if raw, err = tcap.tca.newCertificate(id, pub.(*ecdsa.PublicKey), req.Ts.Seconds, pkix.Extension{Id: TCertEncEnrollmentID, Critical: false, Value: enrollID}); err != nil {
 		Error.Println(err)
 		return nil, err
 	}
@@ -220,6 +245,11 @@ func (tcap *TCAP) CreateCertificateSet(ctx context.Context, req *pb.TCertCreateS
 	Trace.Println("grpc TCAP:CreateCertificateSet")
 
 	id := req.Id.Id
+	if err := tcap.tca.eca.checkUserEnabled(id); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
 	raw, err := tcap.tca.eca.readCertificate(id)
 	if err != nil {
 		return nil, err
@@ -254,6 +284,12 @@ func (tcap *TCAP) CreateCertificateSet(ctx context.Context, req *pb.TCertCreateS
 	mac.Write(raw)
 	kdfKey := mac.Sum(nil)
 
+	names, values, err := tcap.tca.eca.readAttributes(id)
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
 	num := int(req.Num)
 	if num == 0 {
 		num = 1
@@ -287,7 +323,23 @@ func (tcap *TCAP) CreateCertificateSet(ctx context.Context, req *pb.TCertCreateS
 			return nil, err
 		}
 
-		if raw, err = tcap.tca.newCertificate(id, &txPub, req.Ts.Seconds, pkix.Extension{Id: TCertEncTCertIndex, Critical: true, Value: ext}); err != nil {
+		enrollID, err := CBCEncrypt(tcap.tca.auditKey, []byte(id))
+		if err != nil {
+			return nil, err
+		}
+
+		exts := []pkix.Extension{
+			{Id: TCertEncTCertIndex, Critical: true, Value: ext},
+			{Id: TCertEncEnrollmentID, Critical: false, Value: enrollID},
+		}
+		attrs, err := newAttributeExtensions(kdfKey, tidx, names, values)
+		if err != nil {
+			Error.Println(err)
+			return nil, err
+		}
+		exts = append(exts, attrs...)
+
+		if raw, err = tcap.tca.newCertificate(id, &txPub, req.Ts.Seconds, exts...); err != nil {
 			Error.Println(err)
 			return nil, err
 		}
@@ -349,42 +401,207 @@ func (tcap *TCAP) ReadCertificateSet(context.Context, *pb.TCertReadSetReq) (*pb.
 	return nil, errors.New("not yet implemented")
 }
 
-// RevokeCertificate revokes a certificate from the TCA.  Not yet implemented.
+// RevokeCertificate revokes a transaction certificate from the TCA.  The request has to be signed
+// with the enrollment key of the owner of the certificate.
 //
-func (tcap *TCAP) RevokeCertificate(context.Context, *pb.TCertRevokeReq) (*pb.CAStatus, error) {
+func (tcap *TCAP) RevokeCertificate(ctx context.Context, req *pb.TCertRevokeReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc TCAP:RevokeCertificate")
 
-	return nil, errors.New("not yet implemented")
+	if req.Id == nil || req.Cert == nil {
+		Error.Println("invalid request")
+		return nil, errors.New("invalid request")
+	}
+
+	sig := req.Sig
+	req.Sig = nil
+	if err := tcap.tca.verifyEnrollmentSignature(req.Id.Id, req, sig); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	if err := tcap.tca.revokeCertificate(req.Cert.Cert, req.Reason, req.Id.Id); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
 
-// RevokeCertificateSet revokes a certificate set from the TCA.  Not yet implemented.
+// RevokeCertificateSet revokes a transaction certificate set from the TCA.  The request has to be
+// signed with the enrollment key of the owner of the certificate set.
 //
-func (tcap *TCAP) RevokeCertificateSet(context.Context, *pb.TCertRevokeSetReq) (*pb.CAStatus, error) {
+func (tcap *TCAP) RevokeCertificateSet(ctx context.Context, req *pb.TCertRevokeSetReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc TCAP:RevokeCertificateSet")
 
-	return nil, errors.New("not yet implemented")
+	if req.Id == nil {
+		Error.Println("invalid request")
+		return nil, errors.New("invalid request")
+	}
+
+	sig := req.Sig
+	req.Sig = nil
+	if err := tcap.tca.verifyEnrollmentSignature(req.Id.Id, req, sig); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	var ts int64
+	if req.Ts != nil {
+		ts = req.Ts.Seconds
+	}
+	if err := tcap.tca.revokeCertificateSet(req.Id.Id, ts, req.Reason); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
 
-// RevokeCertificate revokes a certificate from the TCA.  Not yet implemented.
+// ReadCRL reads the latest certificate revocation list issued by the TCA.
 //
-func (tcaa *TCAA) RevokeCertificate(context.Context, *pb.TCertRevokeReq) (*pb.CAStatus, error) {
+func (tcap *TCAP) ReadCRL(ctx context.Context, req *pb.CRLReadReq) (*pb.CRL, error) {
+	Trace.Println("grpc TCAP:ReadCRL")
+
+	raw, err := tcap.tca.readCRL()
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.CRL{Crl: raw}, nil
+}
+
+// ReadAuditKey reads the key opening the enrollment ID extension of transaction certificates.  Only
+// the auditors listed in the TCA configuration are given the key.
+//
+func (tcap *TCAP) ReadAuditKey(ctx context.Context, req *pb.TCertAuditKeyReq) (*pb.AuditKey, error) {
+	Trace.Println("grpc TCAP:ReadAuditKey")
+
+	if req.Id == nil {
+		Error.Println("invalid request")
+		return nil, errors.New("invalid request")
+	}
+
+	sig := req.Sig
+	req.Sig = nil
+	if err := tcap.tca.verifyEnrollmentSignature(req.Id.Id, req, sig); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	for _, auditor := range viper.GetStringSlice("tca.auditors") {
+		if auditor == req.Id.Id {
+			return &pb.AuditKey{Key: tcap.tca.auditKey}, nil
+		}
+	}
+
+	Error.Println("identity is not an auditor")
+	return nil, errors.New("identity is not an auditor")
+}
+
+// RevokeCertificate revokes any transaction certificate from the TCA.  Like all administrator
+// requests it is trusted on the grounds of reaching the administrator interface.
+//
+func (tcaa *TCAA) RevokeCertificate(ctx context.Context, req *pb.TCertRevokeReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc TCAA:RevokeCertificate")
 
-	return nil, errors.New("not yet implemented")
+	if req.Cert == nil {
+		Error.Println("invalid request")
+		return nil, errors.New("invalid request")
+	}
+
+	if err := tcaa.tca.revokeCertificate(req.Cert.Cert, req.Reason); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
 
-// RevokeCertificateSet revokes a certificate set from the TCA.  Not yet implemented.
+// RevokeCertificateSet revokes the transaction certificate set of any user from the TCA.  The
+// request identifies the owner of the certificate set.
 //
-func (tcaa *TCAA) RevokeCertificateSet(context.Context, *pb.TCertRevokeSetReq) (*pb.CAStatus, error) {
+func (tcaa *TCAA) RevokeCertificateSet(ctx context.Context, req *pb.TCertRevokeSetReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc TCAA:RevokeCertificateSet")
 
-	return nil, errors.New("not yet implemented")
+	if req.Id == nil {
+		Error.Println("invalid request")
+		return nil, errors.New("invalid request")
+	}
+
+	var ts int64
+	if req.Ts != nil {
+		ts = req.Ts.Seconds
+	}
+	if err := tcaa.tca.revokeCertificateSet(req.Id.Id, ts, req.Reason); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
 
-// CreateCRL requests the creation of a certificate revocation list from the TCA.  Not yet implemented.
+// CreateCRL requests the creation of a certificate revocation list from the TCA.
 //
-func (tcaa *TCAA) CreateCRL(context.Context, *pb.TCertCRLReq) (*pb.CAStatus, error) {
+func (tcaa *TCAA) CreateCRL(ctx context.Context, req *pb.TCertCRLReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc TCAA:CreateCRL")
 
-	return nil, errors.New("not yet implemented")
+	if _, err := tcaa.tca.createCRL(); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
+}
+
+// newAttributeExtensions encrypts each attribute under its own key and wraps
+// it as a non-critical TCert extension.
+//
+func newAttributeExtensions(kdfKey, tidx []byte, names []string, values [][]byte) ([]pkix.Extension, error) {
+	var exts []pkix.Extension
+
+	for i, name := range names {
+		enc, err := CBCEncrypt(AttributeKey(kdfKey, tidx, name), values[i])
+		if err != nil {
+			return nil, err
+		}
+
+		raw, err := asn1.Marshal(TCertAttribute{name, enc})
+		if err != nil {
+			return nil, err
+		}
+
+		oid := append(asn1.ObjectIdentifier{}, TCertEncAttributesBase...)
+		oid = append(oid, i+1)
+		exts = append(exts, pkix.Extension{Id: oid, Critical: false, Value: raw})
+	}
+
+	return exts, nil
+}
+
+// AttributeKey derives the key encrypting the attribute name of the TCert
+// with index tidx.  The TCert owner knows both kdfKey and tidx and can thus
+// reveal the key of a single attribute of a single TCert.
+//
+func AttributeKey(kdfKey, tidx []byte, name string) []byte {
+	mac := hmac.New(sha3.New384, kdfKey)
+	mac.Write([]byte{3})
+	mac = hmac.New(sha3.New384, mac.Sum(nil))
+	mac.Write(tidx)
+	mac.Write([]byte(name))
+
+	return mac.Sum(nil)[:32]
+}
+
+func (tca *TCA) verifyEnrollmentSignature(id string, req proto.Message, sig *pb.Signature) error {
+	raw, err := tca.eca.readCertificate(id)
+	if err != nil {
+		return err
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return err
+	}
+
+	return verifySignature(cert.PublicKey, req, sig)
 }
@@ -192,16 +192,53 @@ func (tlscap *TLSCAP) ReadCertificate(ctx context.Context, req *pb.TLSCertReadRe
 	return &pb.Cert{raw}, nil
 }
 
-// RevokeCertificate revokes a certificate from the TLSCA.  Not yet implemented.
+// RevokeCertificate revokes a TLS certificate from the TLSCA.  The request has to be signed
+// with the private key of the certificate being revoked.
 //
-func (tlscap *TLSCAP) RevokeCertificate(context.Context, *pb.TLSCertRevokeReq) (*pb.CAStatus, error) {
-	// TODO: We are not going to provide revocation support for now
-	return nil, nil
+func (tlscap *TLSCAP) RevokeCertificate(ctx context.Context, req *pb.TLSCertRevokeReq) (*pb.CAStatus, error) {
+	Trace.Println("grpc TLSCAP:RevokeCertificate")
+
+	if req.Id == nil || req.Cert == nil {
+		Error.Println("invalid request")
+		return nil, errors.New("invalid request")
+	}
+
+	cert, err := x509.ParseCertificate(req.Cert.Cert)
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	sig := req.Sig
+	req.Sig = nil
+	if err := verifySignature(cert.PublicKey, req, sig); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	if err := tlscap.tlsca.revokeCertificate(req.Cert.Cert, req.Reason, req.Id.Id); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
 
-// RevokeCertificate revokes a certificate from the TLSCA.  Not yet implemented.
+// RevokeCertificate revokes any TLS certificate from the TLSCA.  Like all administrator
+// requests it is trusted on the grounds of reaching the administrator interface.
 //
-func (tlscaa *TLSCAA) RevokeCertificate(context.Context, *pb.TLSCertRevokeReq) (*pb.CAStatus, error) {
-	// TODO: We are not going to provide revocation support for now
-	return nil, nil
+func (tlscaa *TLSCAA) RevokeCertificate(ctx context.Context, req *pb.TLSCertRevokeReq) (*pb.CAStatus, error) {
+	Trace.Println("grpc TLSCAA:RevokeCertificate")
+
+	if req.Cert == nil {
+		Error.Println("invalid request")
+		return nil, errors.New("invalid request")
+	}
+
+	if err := tlscaa.tlsca.revokeCertificate(req.Cert.Cert, req.Reason); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
\ No newline at end of file
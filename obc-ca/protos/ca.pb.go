@@ -12,12 +12,16 @@ It has these top-level messages:
 	CAStatus
 	Identity
 	Password
+	UserSetReadReq
+	User
+	UserSet
 	PublicKey
 	PrivateKey
 	Signature
 	ECertCreateReq
 	ECertReadReq
 	ECertRevokeReq
+	ECertRenewReq
 	ECertCRLReq
 	TCertCreateReq
 	TCertCreateSetReq
@@ -26,12 +30,18 @@ It has these top-level messages:
 	TCertRevokeReq
 	TCertRevokeSetReq
 	TCertCRLReq
+	TCertAuditKeyReq
 	TLSCertCreateReq
 	TLSCertReadReq
 	TLSCertRevokeReq
 	Cert
 	CertSet
 	Creds
+	CertInfo
+	CertInfoSet
+	CRLReadReq
+	CRL
+	AuditKey
 */
 package protos
 
@@ -75,6 +85,40 @@ func (x CryptoType) String() string {
 	return proto.EnumName(CryptoType_name, int32(x))
 }
 
+// Reasons for revoking a certificate (see RFC 5280, section 5.3.1).
+//
+type RevocationReason int32
+
+const (
+	RevocationReason_UNSPECIFIED            RevocationReason = 0
+	RevocationReason_KEY_COMPROMISE         RevocationReason = 1
+	RevocationReason_CA_COMPROMISE          RevocationReason = 2
+	RevocationReason_AFFILIATION_CHANGED    RevocationReason = 3
+	RevocationReason_SUPERSEDED             RevocationReason = 4
+	RevocationReason_CESSATION_OF_OPERATION RevocationReason = 5
+)
+
+var RevocationReason_name = map[int32]string{
+	0: "UNSPECIFIED",
+	1: "KEY_COMPROMISE",
+	2: "CA_COMPROMISE",
+	3: "AFFILIATION_CHANGED",
+	4: "SUPERSEDED",
+	5: "CESSATION_OF_OPERATION",
+}
+var RevocationReason_value = map[string]int32{
+	"UNSPECIFIED":            0,
+	"KEY_COMPROMISE":         1,
+	"CA_COMPROMISE":          2,
+	"AFFILIATION_CHANGED":    3,
+	"SUPERSEDED":             4,
+	"CESSATION_OF_OPERATION": 5,
+}
+
+func (x RevocationReason) String() string {
+	return proto.EnumName(RevocationReason_name, int32(x))
+}
+
 type CAStatus_StatusCode int32
 
 const (
@@ -123,6 +167,46 @@ func (m *Password) Reset()         { *m = Password{} }
 func (m *Password) String() string { return proto.CompactTextString(m) }
 func (*Password) ProtoMessage()    {}
 
+// Users registered with the ECA.
+//
+type UserSetReadReq struct {
+}
+
+func (m *UserSetReadReq) Reset()         { *m = UserSetReadReq{} }
+func (m *UserSetReadReq) String() string { return proto.CompactTextString(m) }
+func (*UserSetReadReq) ProtoMessage()    {}
+
+type User struct {
+	Id       *Identity `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Disabled bool      `protobuf:"varint,2,opt,name=disabled" json:"disabled,omitempty"`
+}
+
+func (m *User) Reset()         { *m = User{} }
+func (m *User) String() string { return proto.CompactTextString(m) }
+func (*User) ProtoMessage()    {}
+
+func (m *User) GetId() *Identity {
+	if m != nil {
+		return m.Id
+	}
+	return nil
+}
+
+type UserSet struct {
+	Users []*User `protobuf:"bytes,1,rep,name=users" json:"users,omitempty"`
+}
+
+func (m *UserSet) Reset()         { *m = UserSet{} }
+func (m *UserSet) String() string { return proto.CompactTextString(m) }
+func (*UserSet) ProtoMessage()    {}
+
+func (m *UserSet) GetUsers() []*User {
+	if m != nil {
+		return m.Users
+	}
+	return nil
+}
+
 type PublicKey struct {
 	Type CryptoType `protobuf:"varint,1,opt,name=type,enum=protos.CryptoType" json:"type,omitempty"`
 	Key  []byte     `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
@@ -219,9 +303,10 @@ func (m *ECertReadReq) GetId() *Identity {
 }
 
 type ECertRevokeReq struct {
-	Id   *Identity  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	Cert *Cert      `protobuf:"bytes,2,opt,name=cert" json:"cert,omitempty"`
-	Sig  *Signature `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Id     *Identity        `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Cert   *Cert            `protobuf:"bytes,2,opt,name=cert" json:"cert,omitempty"`
+	Sig    *Signature       `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Reason RevocationReason `protobuf:"varint,4,opt,name=reason,enum=protos.RevocationReason" json:"reason,omitempty"`
 }
 
 func (m *ECertRevokeReq) Reset()         { *m = ECertRevokeReq{} }
@@ -249,6 +334,61 @@ func (m *ECertRevokeReq) GetSig() *Signature {
 	return nil
 }
 
+type ECertRenewReq struct {
+	Ts   *google_protobuf.Timestamp `protobuf:"bytes,1,opt,name=ts" json:"ts,omitempty"`
+	Id   *Identity                  `protobuf:"bytes,2,opt,name=id" json:"id,omitempty"`
+	Cert *Cert                      `protobuf:"bytes,3,opt,name=cert" json:"cert,omitempty"`
+	Pub  *PublicKey                 `protobuf:"bytes,4,opt,name=pub" json:"pub,omitempty"`
+	Pop  *Signature                 `protobuf:"bytes,5,opt,name=pop" json:"pop,omitempty"`
+	Sig  *Signature                 `protobuf:"bytes,6,opt,name=sig" json:"sig,omitempty"`
+}
+
+func (m *ECertRenewReq) Reset()         { *m = ECertRenewReq{} }
+func (m *ECertRenewReq) String() string { return proto.CompactTextString(m) }
+func (*ECertRenewReq) ProtoMessage()    {}
+
+func (m *ECertRenewReq) GetTs() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Ts
+	}
+	return nil
+}
+
+func (m *ECertRenewReq) GetId() *Identity {
+	if m != nil {
+		return m.Id
+	}
+	return nil
+}
+
+func (m *ECertRenewReq) GetCert() *Cert {
+	if m != nil {
+		return m.Cert
+	}
+	return nil
+}
+
+func (m *ECertRenewReq) GetPub() *PublicKey {
+	if m != nil {
+		return m.Pub
+	}
+	return nil
+}
+
+func (m *ECertRenewReq) GetPop() *Signature {
+	if m != nil {
+		return m.Pop
+	}
+	return nil
+}
+
+func (m *ECertRenewReq) GetSig() *Signature {
+	if m != nil {
+		return m.Sig
+	}
+	return nil
+}
+
 type ECertCRLReq struct {
 	Id  *Identity  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
 	Sig *Signature `protobuf:"bytes,2,opt,name=sig" json:"sig,omitempty"`
@@ -407,9 +547,10 @@ func (m *TCertReadSetReq) GetSig() *Signature {
 }
 
 type TCertRevokeReq struct {
-	Id   *Identity  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	Cert *Cert      `protobuf:"bytes,2,opt,name=cert" json:"cert,omitempty"`
-	Sig  *Signature `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Id     *Identity        `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Cert   *Cert            `protobuf:"bytes,2,opt,name=cert" json:"cert,omitempty"`
+	Sig    *Signature       `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Reason RevocationReason `protobuf:"varint,4,opt,name=reason,enum=protos.RevocationReason" json:"reason,omitempty"`
 }
 
 func (m *TCertRevokeReq) Reset()         { *m = TCertRevokeReq{} }
@@ -438,9 +579,10 @@ func (m *TCertRevokeReq) GetSig() *Signature {
 }
 
 type TCertRevokeSetReq struct {
-	Id  *Identity                  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	Ts  *google_protobuf.Timestamp `protobuf:"bytes,2,opt,name=ts" json:"ts,omitempty"`
-	Sig *Signature                 `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Id     *Identity                  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Ts     *google_protobuf.Timestamp `protobuf:"bytes,2,opt,name=ts" json:"ts,omitempty"`
+	Sig    *Signature                 `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Reason RevocationReason           `protobuf:"varint,4,opt,name=reason,enum=protos.RevocationReason" json:"reason,omitempty"`
 }
 
 func (m *TCertRevokeSetReq) Reset()         { *m = TCertRevokeSetReq{} }
@@ -491,6 +633,37 @@ func (m *TCertCRLReq) GetSig() *Signature {
 	return nil
 }
 
+type TCertAuditKeyReq struct {
+	Ts  *google_protobuf.Timestamp `protobuf:"bytes,1,opt,name=ts" json:"ts,omitempty"`
+	Id  *Identity                  `protobuf:"bytes,2,opt,name=id" json:"id,omitempty"`
+	Sig *Signature                 `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+}
+
+func (m *TCertAuditKeyReq) Reset()         { *m = TCertAuditKeyReq{} }
+func (m *TCertAuditKeyReq) String() string { return proto.CompactTextString(m) }
+func (*TCertAuditKeyReq) ProtoMessage()    {}
+
+func (m *TCertAuditKeyReq) GetTs() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Ts
+	}
+	return nil
+}
+
+func (m *TCertAuditKeyReq) GetId() *Identity {
+	if m != nil {
+		return m.Id
+	}
+	return nil
+}
+
+func (m *TCertAuditKeyReq) GetSig() *Signature {
+	if m != nil {
+		return m.Sig
+	}
+	return nil
+}
+
 // TLSCA Certificate requests.
 //
 type TLSCertCreateReq struct {
@@ -557,9 +730,10 @@ func (m *TLSCertReadReq) GetId() *Identity {
 }
 
 type TLSCertRevokeReq struct {
-	Id   *Identity  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	Cert *Cert      `protobuf:"bytes,2,opt,name=cert" json:"cert,omitempty"`
-	Sig  *Signature `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Id     *Identity        `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Cert   *Cert            `protobuf:"bytes,2,opt,name=cert" json:"cert,omitempty"`
+	Sig    *Signature       `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Reason RevocationReason `protobuf:"varint,4,opt,name=reason,enum=protos.RevocationReason" json:"reason,omitempty"`
 }
 
 func (m *TLSCertRevokeReq) Reset()         { *m = TLSCertRevokeReq{} }
@@ -622,8 +796,87 @@ func (m *Creds) GetCert() *Cert {
 	return nil
 }
 
+// Certificate together with its revocation status.
+//
+type CertInfo struct {
+	Cert         *Cert                      `protobuf:"bytes,1,opt,name=cert" json:"cert,omitempty"`
+	Hash         []byte                     `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	Ts           *google_protobuf.Timestamp `protobuf:"bytes,3,opt,name=ts" json:"ts,omitempty"`
+	Revoked      bool                       `protobuf:"varint,4,opt,name=revoked" json:"revoked,omitempty"`
+	RevocationTs *google_protobuf.Timestamp `protobuf:"bytes,5,opt,name=revocationTs" json:"revocationTs,omitempty"`
+	Reason       RevocationReason           `protobuf:"varint,6,opt,name=reason,enum=protos.RevocationReason" json:"reason,omitempty"`
+}
+
+func (m *CertInfo) Reset()         { *m = CertInfo{} }
+func (m *CertInfo) String() string { return proto.CompactTextString(m) }
+func (*CertInfo) ProtoMessage()    {}
+
+func (m *CertInfo) GetCert() *Cert {
+	if m != nil {
+		return m.Cert
+	}
+	return nil
+}
+
+func (m *CertInfo) GetTs() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Ts
+	}
+	return nil
+}
+
+func (m *CertInfo) GetRevocationTs() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.RevocationTs
+	}
+	return nil
+}
+
+type CertInfoSet struct {
+	Certs []*CertInfo `protobuf:"bytes,1,rep,name=certs" json:"certs,omitempty"`
+}
+
+func (m *CertInfoSet) Reset()         { *m = CertInfoSet{} }
+func (m *CertInfoSet) String() string { return proto.CompactTextString(m) }
+func (*CertInfoSet) ProtoMessage()    {}
+
+func (m *CertInfoSet) GetCerts() []*CertInfo {
+	if m != nil {
+		return m.Certs
+	}
+	return nil
+}
+
+// Certificate revocation list issued by either the ECA or TCA.
+//
+type CRLReadReq struct {
+}
+
+func (m *CRLReadReq) Reset()         { *m = CRLReadReq{} }
+func (m *CRLReadReq) String() string { return proto.CompactTextString(m) }
+func (*CRLReadReq) ProtoMessage()    {}
+
+type CRL struct {
+	Crl []byte `protobuf:"bytes,1,opt,name=crl,proto3" json:"crl,omitempty"`
+}
+
+func (m *CRL) Reset()         { *m = CRL{} }
+func (m *CRL) String() string { return proto.CompactTextString(m) }
+func (*CRL) ProtoMessage()    {}
+
+// Key opening the enrollment ID extension of transaction certificates.
+//
+type AuditKey struct {
+	Key []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+}
+
+func (m *AuditKey) Reset()         { *m = AuditKey{} }
+func (m *AuditKey) String() string { return proto.CompactTextString(m) }
+func (*AuditKey) ProtoMessage()    {}
+
 func init() {
 	proto.RegisterEnum("protos.CryptoType", CryptoType_name, CryptoType_value)
+	proto.RegisterEnum("protos.RevocationReason", RevocationReason_name, RevocationReason_value)
 	proto.RegisterEnum("protos.CAStatus_StatusCode", CAStatus_StatusCode_name, CAStatus_StatusCode_value)
 }
 
@@ -637,6 +890,8 @@ type ECAPClient interface {
 	CreateCertificate(ctx context.Context, in *ECertCreateReq, opts ...grpc.CallOption) (*Creds, error)
 	ReadCertificate(ctx context.Context, in *ECertReadReq, opts ...grpc.CallOption) (*Cert, error)
 	RevokeCertificate(ctx context.Context, in *ECertRevokeReq, opts ...grpc.CallOption) (*CAStatus, error)
+	ReadCRL(ctx context.Context, in *CRLReadReq, opts ...grpc.CallOption) (*CRL, error)
+	RenewCertificate(ctx context.Context, in *ECertRenewReq, opts ...grpc.CallOption) (*Creds, error)
 }
 
 type eCAPClient struct {
@@ -674,12 +929,32 @@ func (c *eCAPClient) RevokeCertificate(ctx context.Context, in *ECertRevokeReq,
 	return out, nil
 }
 
+func (c *eCAPClient) ReadCRL(ctx context.Context, in *CRLReadReq, opts ...grpc.CallOption) (*CRL, error) {
+	out := new(CRL)
+	err := grpc.Invoke(ctx, "/protos.ECAP/ReadCRL", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eCAPClient) RenewCertificate(ctx context.Context, in *ECertRenewReq, opts ...grpc.CallOption) (*Creds, error) {
+	out := new(Creds)
+	err := grpc.Invoke(ctx, "/protos.ECAP/RenewCertificate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for ECAP service
 
 type ECAPServer interface {
 	CreateCertificate(context.Context, *ECertCreateReq) (*Creds, error)
 	ReadCertificate(context.Context, *ECertReadReq) (*Cert, error)
 	RevokeCertificate(context.Context, *ECertRevokeReq) (*CAStatus, error)
+	ReadCRL(context.Context, *CRLReadReq) (*CRL, error)
+	RenewCertificate(context.Context, *ECertRenewReq) (*Creds, error)
 }
 
 func RegisterECAPServer(s *grpc.Server, srv ECAPServer) {
@@ -722,6 +997,30 @@ func _ECAP_RevokeCertificate_Handler(srv interface{}, ctx context.Context, dec f
 	return out, nil
 }
 
+func _ECAP_ReadCRL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(CRLReadReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(ECAPServer).ReadCRL(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _ECAP_RenewCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(ECertRenewReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(ECAPServer).RenewCertificate(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _ECAP_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.ECAP",
 	HandlerType: (*ECAPServer)(nil),
@@ -738,6 +1037,14 @@ var _ECAP_serviceDesc = grpc.ServiceDesc{
 			MethodName: "RevokeCertificate",
 			Handler:    _ECAP_RevokeCertificate_Handler,
 		},
+		{
+			MethodName: "ReadCRL",
+			Handler:    _ECAP_ReadCRL_Handler,
+		},
+		{
+			MethodName: "RenewCertificate",
+			Handler:    _ECAP_RenewCertificate_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{},
 }
@@ -748,6 +1055,11 @@ type ECAAClient interface {
 	RegisterUser(ctx context.Context, in *Identity, opts ...grpc.CallOption) (*Password, error)
 	RevokeCertificate(ctx context.Context, in *ECertRevokeReq, opts ...grpc.CallOption) (*CAStatus, error)
 	CreateCRL(ctx context.Context, in *ECertCRLReq, opts ...grpc.CallOption) (*CAStatus, error)
+	ReadUserSet(ctx context.Context, in *UserSetReadReq, opts ...grpc.CallOption) (*UserSet, error)
+	DisableUser(ctx context.Context, in *Identity, opts ...grpc.CallOption) (*CAStatus, error)
+	EnableUser(ctx context.Context, in *Identity, opts ...grpc.CallOption) (*CAStatus, error)
+	ResetPassword(ctx context.Context, in *Identity, opts ...grpc.CallOption) (*Password, error)
+	ReadUserCertificates(ctx context.Context, in *Identity, opts ...grpc.CallOption) (*CertInfoSet, error)
 }
 
 type eCAAClient struct {
@@ -785,12 +1097,62 @@ func (c *eCAAClient) CreateCRL(ctx context.Context, in *ECertCRLReq, opts ...grp
 	return out, nil
 }
 
+func (c *eCAAClient) ReadUserSet(ctx context.Context, in *UserSetReadReq, opts ...grpc.CallOption) (*UserSet, error) {
+	out := new(UserSet)
+	err := grpc.Invoke(ctx, "/protos.ECAA/ReadUserSet", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eCAAClient) DisableUser(ctx context.Context, in *Identity, opts ...grpc.CallOption) (*CAStatus, error) {
+	out := new(CAStatus)
+	err := grpc.Invoke(ctx, "/protos.ECAA/DisableUser", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eCAAClient) EnableUser(ctx context.Context, in *Identity, opts ...grpc.CallOption) (*CAStatus, error) {
+	out := new(CAStatus)
+	err := grpc.Invoke(ctx, "/protos.ECAA/EnableUser", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eCAAClient) ResetPassword(ctx context.Context, in *Identity, opts ...grpc.CallOption) (*Password, error) {
+	out := new(Password)
+	err := grpc.Invoke(ctx, "/protos.ECAA/ResetPassword", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eCAAClient) ReadUserCertificates(ctx context.Context, in *Identity, opts ...grpc.CallOption) (*CertInfoSet, error) {
+	out := new(CertInfoSet)
+	err := grpc.Invoke(ctx, "/protos.ECAA/ReadUserCertificates", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for ECAA service
 
 type ECAAServer interface {
 	RegisterUser(context.Context, *Identity) (*Password, error)
 	RevokeCertificate(context.Context, *ECertRevokeReq) (*CAStatus, error)
 	CreateCRL(context.Context, *ECertCRLReq) (*CAStatus, error)
+	ReadUserSet(context.Context, *UserSetReadReq) (*UserSet, error)
+	DisableUser(context.Context, *Identity) (*CAStatus, error)
+	EnableUser(context.Context, *Identity) (*CAStatus, error)
+	ResetPassword(context.Context, *Identity) (*Password, error)
+	ReadUserCertificates(context.Context, *Identity) (*CertInfoSet, error)
 }
 
 func RegisterECAAServer(s *grpc.Server, srv ECAAServer) {
@@ -833,6 +1195,66 @@ func _ECAA_CreateCRL_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return out, nil
 }
 
+func _ECAA_ReadUserSet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(UserSetReadReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(ECAAServer).ReadUserSet(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _ECAA_DisableUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(Identity)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(ECAAServer).DisableUser(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _ECAA_EnableUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(Identity)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(ECAAServer).EnableUser(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _ECAA_ResetPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(Identity)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(ECAAServer).ResetPassword(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _ECAA_ReadUserCertificates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(Identity)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(ECAAServer).ReadUserCertificates(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _ECAA_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.ECAA",
 	HandlerType: (*ECAAServer)(nil),
@@ -849,6 +1271,26 @@ var _ECAA_serviceDesc = grpc.ServiceDesc{
 			MethodName: "CreateCRL",
 			Handler:    _ECAA_CreateCRL_Handler,
 		},
+		{
+			MethodName: "ReadUserSet",
+			Handler:    _ECAA_ReadUserSet_Handler,
+		},
+		{
+			MethodName: "DisableUser",
+			Handler:    _ECAA_DisableUser_Handler,
+		},
+		{
+			MethodName: "EnableUser",
+			Handler:    _ECAA_EnableUser_Handler,
+		},
+		{
+			MethodName: "ResetPassword",
+			Handler:    _ECAA_ResetPassword_Handler,
+		},
+		{
+			MethodName: "ReadUserCertificates",
+			Handler:    _ECAA_ReadUserCertificates_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{},
 }
@@ -862,6 +1304,8 @@ type TCAPClient interface {
 	ReadCertificateSet(ctx context.Context, in *TCertReadSetReq, opts ...grpc.CallOption) (*CertSet, error)
 	RevokeCertificate(ctx context.Context, in *TCertRevokeReq, opts ...grpc.CallOption) (*CAStatus, error)
 	RevokeCertificateSet(ctx context.Context, in *TCertRevokeSetReq, opts ...grpc.CallOption) (*CAStatus, error)
+	ReadCRL(ctx context.Context, in *CRLReadReq, opts ...grpc.CallOption) (*CRL, error)
+	ReadAuditKey(ctx context.Context, in *TCertAuditKeyReq, opts ...grpc.CallOption) (*AuditKey, error)
 }
 
 type tCAPClient struct {
@@ -926,6 +1370,24 @@ func (c *tCAPClient) RevokeCertificateSet(ctx context.Context, in *TCertRevokeSe
 	return out, nil
 }
 
+func (c *tCAPClient) ReadCRL(ctx context.Context, in *CRLReadReq, opts ...grpc.CallOption) (*CRL, error) {
+	out := new(CRL)
+	err := grpc.Invoke(ctx, "/protos.TCAP/ReadCRL", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tCAPClient) ReadAuditKey(ctx context.Context, in *TCertAuditKeyReq, opts ...grpc.CallOption) (*AuditKey, error) {
+	out := new(AuditKey)
+	err := grpc.Invoke(ctx, "/protos.TCAP/ReadAuditKey", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for TCAP service
 
 type TCAPServer interface {
@@ -935,6 +1397,8 @@ type TCAPServer interface {
 	ReadCertificateSet(context.Context, *TCertReadSetReq) (*CertSet, error)
 	RevokeCertificate(context.Context, *TCertRevokeReq) (*CAStatus, error)
 	RevokeCertificateSet(context.Context, *TCertRevokeSetReq) (*CAStatus, error)
+	ReadCRL(context.Context, *CRLReadReq) (*CRL, error)
+	ReadAuditKey(context.Context, *TCertAuditKeyReq) (*AuditKey, error)
 }
 
 func RegisterTCAPServer(s *grpc.Server, srv TCAPServer) {
@@ -1013,6 +1477,30 @@ func _TCAP_RevokeCertificateSet_Handler(srv interface{}, ctx context.Context, de
 	return out, nil
 }
 
+func _TCAP_ReadCRL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(CRLReadReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(TCAPServer).ReadCRL(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _TCAP_ReadAuditKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(TCertAuditKeyReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(TCAPServer).ReadAuditKey(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _TCAP_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.TCAP",
 	HandlerType: (*TCAPServer)(nil),
@@ -1041,6 +1529,14 @@ var _TCAP_serviceDesc = grpc.ServiceDesc{
 			MethodName: "RevokeCertificateSet",
 			Handler:    _TCAP_RevokeCertificateSet_Handler,
 		},
+		{
+			MethodName: "ReadCRL",
+			Handler:    _TCAP_ReadCRL_Handler,
+		},
+		{
+			MethodName: "ReadAuditKey",
+			Handler:    _TCAP_ReadAuditKey_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{},
 }
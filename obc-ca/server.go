@@ -25,9 +25,19 @@ import (
 	"sync"
 
 	"github.com/openblockchain/obc-peer/obc-ca/obcca"
+	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+// The main command runs the CAs.
+var mainCmd = &cobra.Command{
+	Use:   "obcca",
+	Short: "Run the ECA, TCA and TLSCA.",
+	Run: func(cmd *cobra.Command, args []string) {
+		serve()
+	},
+}
+
 func main() {
 	viper.AutomaticEnv()
 	viper.SetConfigName("obcca")
@@ -38,6 +48,13 @@ func main() {
 		panic(err)
 	}
 
+	addAdminCommands(mainCmd)
+	if err := mainCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func serve() {
 	obcca.LogInit(ioutil.Discard, os.Stdout, os.Stdout, os.Stderr, os.Stdout)
 
 	eca := obcca.NewECA()
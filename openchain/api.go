@@ -101,6 +101,21 @@ func (s *ServerOpenchain) GetState(ctx context.Context, chaincodeID, key string)
 	return s.ledger.GetState(chaincodeID, key, true)
 }
 
+// GetStateAsOf returns the value for a particular chaincode ID and key as it
+// was right after the specified block was committed.
+func (s *ServerOpenchain) GetStateAsOf(ctx context.Context, req *pb.StateAsOfRequest) (*pb.StateValue, error) {
+	value, err := s.ledger.GetStateAsOf(req.ChaincodeID, req.Key, req.BlockNumber)
+	if err != nil {
+		switch err {
+		case ledger.ErrOutOfBounds, ledger.ErrResourceNotFound:
+			return nil, ErrNotFound
+		default:
+			return nil, fmt.Errorf("Error retrieving state as of block %d: %s", req.BlockNumber, err)
+		}
+	}
+	return &pb.StateValue{Value: value}, nil
+}
+
 // GetTransactionByUUID returns a transaction matching the specified UUID
 func (s *ServerOpenchain) GetTransactionByUUID(ctx context.Context, txUUID string) (*pb.Transaction, error) {
 	transaction, err := s.ledger.GetTransactionByUUID(txUUID)
@@ -114,3 +129,34 @@ func (s *ServerOpenchain) GetTransactionByUUID(ctx context.Context, txUUID strin
 	}
 	return transaction, nil
 }
+
+// GetTransactionReceipt returns whether the transaction matching the specified
+// UUID is pending, was committed, or failed.
+func (s *ServerOpenchain) GetTransactionReceipt(ctx context.Context, req *pb.TransactionReceiptRequest) (*pb.TransactionReceipt, error) {
+	receipt, err := s.ledger.GetTransactionReceipt(req.Uuid)
+	if err != nil {
+		switch err {
+		case ledger.ErrResourceNotFound:
+			return nil, ErrNotFound
+		default:
+			return nil, fmt.Errorf("Error retrieving transaction receipt: %s", err)
+		}
+	}
+	return receipt, nil
+}
+
+// GetStateWithProof returns the committed value for a particular chaincode ID
+// and key along with a proof that it belongs to the state recorded in the
+// last block, which clients can check against that block on their own.
+func (s *ServerOpenchain) GetStateWithProof(ctx context.Context, req *pb.StateProofRequest) (*pb.StateValueWithProof, error) {
+	stateValue, err := s.ledger.GetStateValueWithProof(req.ChaincodeID, req.Key)
+	if err != nil {
+		switch err {
+		case ledger.ErrResourceNotFound:
+			return nil, ErrNotFound
+		default:
+			return nil, fmt.Errorf("Error retrieving state with proof: %s", err)
+		}
+	}
+	return stateValue, nil
+}
@@ -231,6 +231,40 @@ func TestServerOpenchain_API_GetState(t *testing.T) {
 
 }
 
+func TestServerOpenchain_API_GetStateAsOf(t *testing.T) {
+	ledger1 := ledger.InitTestLedger(t)
+	// Construct a blockchain with 3 blocks.
+	buildTestLedger1(ledger1, t)
+
+	// Initialize the OpenchainServer object.
+	server, err := NewOpenchainServer()
+	if err != nil {
+		t.Logf("Error creating OpenchainServer: %s", err)
+		t.Fail()
+	}
+
+	// The contract code is stored in block 1 and must not exist as of block 0.
+	stateValue, err := server.GetStateAsOf(context.Background(), &protos.StateAsOfRequest{ChaincodeID: "MyContract1", Key: "code", BlockNumber: 0})
+	if err != nil {
+		t.Fatalf("Error retrieving state as of block 0: %s", err)
+	} else if stateValue.Value != nil {
+		t.Fatalf("Expected no value as of block 0, but got %s", stateValue.Value)
+	}
+
+	stateValue, err = server.GetStateAsOf(context.Background(), &protos.StateAsOfRequest{ChaincodeID: "MyContract1", Key: "code", BlockNumber: 1})
+	if err != nil {
+		t.Fatalf("Error retrieving state as of block 1: %s", err)
+	} else if bytes.Compare(stateValue.Value, []byte("code example")) != 0 {
+		t.Fatalf("Expected %s, but got %s", []byte("code example"), stateValue.Value)
+	}
+
+	// Retrieve state as of a block that does not exist.
+	stateValue, err = server.GetStateAsOf(context.Background(), &protos.StateAsOfRequest{ChaincodeID: "MyContract1", Key: "code", BlockNumber: 3})
+	if err != ErrNotFound {
+		t.Fatalf("Expected error %s when retrieving state as of a non-existent block, but got %v", ErrNotFound, err)
+	}
+}
+
 // buildTestLedger1 builds a simple ledger data structure that contains a blockchain with 3 blocks.
 func buildTestLedger1(ledger1 *ledger.Ledger, t *testing.T) {
 	// -----------------------------<Block #0>---------------------
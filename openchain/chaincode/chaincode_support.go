@@ -35,6 +35,7 @@ import (
 	google_protobuf "google/protobuf"
 
 	"github.com/openblockchain/obc-peer/openchain/container"
+	"github.com/openblockchain/obc-peer/openchain/ledger"
 	pb "github.com/openblockchain/obc-peer/protos"
 )
 
@@ -47,10 +48,12 @@ const (
 	// DefaultChain is the name of the default chain.
 	DefaultChain ChainName = "default"
 	// DevModeUserRunsChaincode property allows user to run chaincode in development environment
-	DevModeUserRunsChaincode       string = "dev"
-	chaincodeStartupTimeoutDefault int    = 5000
-	chaincodeInstallPathDefault    string = "/go/bin/"
-	peerAddressDefault             string = "0.0.0.0:30303"
+	DevModeUserRunsChaincode          string = "dev"
+	chaincodeStartupTimeoutDefault    int    = 5000
+	chaincodeExecuteTimeoutDefault    int    = 30000
+	chaincodeMaxExecuteTimeoutDefault int    = 120000
+	chaincodeInstallPathDefault       string = "/go/bin/"
+	peerAddressDefault                string = "0.0.0.0:30303"
 )
 
 // chains is a map between different blockchains and their ChaincodeSupport.
@@ -66,6 +69,8 @@ type handlerMap struct {
 	sync.RWMutex
 	// Handlers for each chaincode
 	chaincodeMap map[string]*Handler
+	// Containers launched for each chaincode
+	vmMap map[string]string
 }
 
 // GetChain returns the name of the chain to which this chaincode support belongs
@@ -92,7 +97,7 @@ func (chaincodeSupport *ChaincodeSupport) chaincodeHasBeenLaunched(chaincode str
 // NewChaincodeSupport creates a new ChaincodeSupport instance
 func NewChaincodeSupport(chainname ChainName, getPeerEndpoint func() (*pb.PeerEndpoint, error), userrunsCC bool, ccstartuptimeout time.Duration) *ChaincodeSupport {
 	//we need to pass chainname when we do multiple chains...till then use DefaultChain
-	s := &ChaincodeSupport{name: chainname, handlerMap: &handlerMap{chaincodeMap: make(map[string]*Handler)}}
+	s := &ChaincodeSupport{name: chainname, handlerMap: &handlerMap{chaincodeMap: make(map[string]*Handler), vmMap: make(map[string]string)}}
 
 	//initialize global chain
 	chains[DefaultChain] = s
@@ -113,8 +118,26 @@ func NewChaincodeSupport(chainname ChainName, getPeerEndpoint func() (*pb.PeerEn
 
 	s.userRunsCC = userrunsCC
 
+	s.vmType = viper.GetString("vm.type")
+	if s.vmType == "" {
+		s.vmType = container.DOCKER
+	}
+
 	s.ccStartupTimeout = ccstartuptimeout * time.Millisecond
 
+	//the execution timeouts have to be the same on all validators of the network
+	s.ccMaxExecuteTimeout = time.Duration(viper.GetInt("chaincode.maxexecutetimeout")) * time.Millisecond
+	if s.ccMaxExecuteTimeout <= 0 {
+		s.ccMaxExecuteTimeout = time.Duration(chaincodeMaxExecuteTimeoutDefault) * time.Millisecond
+	}
+	s.ccExecuteTimeout = time.Duration(viper.GetInt("chaincode.executetimeout")) * time.Millisecond
+	if s.ccExecuteTimeout <= 0 {
+		s.ccExecuteTimeout = time.Duration(chaincodeExecuteTimeoutDefault) * time.Millisecond
+	}
+	if s.ccExecuteTimeout > s.ccMaxExecuteTimeout {
+		s.ccExecuteTimeout = s.ccMaxExecuteTimeout
+	}
+
 	//TODO I'm not sure if this needs to be on a per chain basis... too lowel and just needs to be a global default ?
 	s.chaincodeInstallPath = chaincodeInstallPathDefault
 
@@ -133,8 +156,11 @@ type ChaincodeSupport struct {
 	handlerMap           *handlerMap
 	peerAddress          string
 	ccStartupTimeout     time.Duration
+	ccExecuteTimeout     time.Duration
+	ccMaxExecuteTimeout  time.Duration
 	chaincodeInstallPath string
 	userRunsCC           bool
+	vmType               string
 }
 
 // DuplicateChaincodeHandlerError returned if attempt to register same chaincodeID while a stream already exists.
@@ -150,6 +176,16 @@ func newDuplicateChaincodeHandlerError(chaincodeHandler *Handler) error {
 	return &DuplicateChaincodeHandlerError{ChaincodeID: chaincodeHandler.ChaincodeID}
 }
 
+// ExecuteTimeoutError returned if the chaincode does not complete a transaction or query in time.
+type ExecuteTimeoutError struct {
+	UUID    string
+	Timeout time.Duration
+}
+
+func (e *ExecuteTimeoutError) Error() string {
+	return fmt.Sprintf("Timeout of %s expired while executing transaction %s", e.Timeout, e.UUID)
+}
+
 func (chaincodeSupport *ChaincodeSupport) registerHandler(chaincodehandler *Handler) error {
 	key := chaincodehandler.ChaincodeID.Name
 
@@ -177,6 +213,7 @@ func (chaincodeSupport *ChaincodeSupport) registerHandler(chaincodehandler *Hand
 	chaincodehandler.responseNotifiers = make(map[string]chan *pb.ChaincodeMessage)
 	chaincodehandler.uuidMap = make(map[string]bool)
 	chaincodehandler.isTransaction = make(map[string]bool)
+	chaincodehandler.rangeQueryIteratorMap = make(map[string]*rangeQueryIterator)
 
 	chaincodeLogger.Debug("registered handler complete for chaincode %s", key)
 
@@ -241,7 +278,17 @@ func (chaincodeSupport *ChaincodeSupport) sendInitOrReady(context context.Contex
 }
 
 // launchAndWaitForRegister will launch container if not already running
-func (chaincodeSupport *ChaincodeSupport) launchAndWaitForRegister(context context.Context, cID *pb.ChaincodeID, uuid string) (bool, error) {
+// getVMName returns the name of the image and container of the given version of the chaincode. Each
+// version has an image of its own so that the code of a chaincode is only replaced once its update
+// succeeded. The first version keeps the name used before chaincodes could be updated.
+func getVMName(chaincode string, version uint32) string {
+	if version <= 1 {
+		return container.GetVMFromName(chaincode)
+	}
+	return container.GetVMFromName(fmt.Sprintf("%s-v%d", chaincode, version))
+}
+
+func (chaincodeSupport *ChaincodeSupport) launchAndWaitForRegister(context context.Context, cID *pb.ChaincodeID, uuid string, vmname string) (bool, error) {
 	chaincode := cID.Name
 	if chaincode == "" {
 		return false, fmt.Errorf("chaincode name not set")
@@ -257,14 +304,14 @@ func (chaincodeSupport *ChaincodeSupport) launchAndWaitForRegister(context conte
 	}
 	alreadyRunning := false
 	notfy := chaincodeSupport.preLaunchSetup(chaincode)
+	chaincodeSupport.handlerMap.vmMap[chaincode] = vmname
 	chaincodeSupport.handlerMap.Unlock()
 
 	//launch the chaincode
 	//creat a StartImageReq obj and send it to VMCProcess
-	vmname := container.GetVMFromName(chaincode)
 	chaincodeLog.Debug("start container: %s", vmname)
 	sir := container.StartImageReq{ID: vmname, Detach: true}
-	resp, err := container.VMCProcess(context, "Docker", sir)
+	resp, err := container.VMCProcess(context, chaincodeSupport.vmType, sir)
 	if err != nil || (resp != nil && resp.(container.VMCResp).Err != nil) {
 		if err == nil {
 			err = resp.(container.VMCResp).Err
@@ -272,6 +319,7 @@ func (chaincodeSupport *ChaincodeSupport) launchAndWaitForRegister(context conte
 		err = fmt.Errorf("Error starting container: %s", err)
 		chaincodeSupport.handlerMap.Lock()
 		delete(chaincodeSupport.handlerMap.chaincodeMap, chaincode)
+		delete(chaincodeSupport.handlerMap.vmMap, chaincode)
 		chaincodeSupport.handlerMap.Unlock()
 		return alreadyRunning, err
 	}
@@ -301,12 +349,23 @@ func (chaincodeSupport *ChaincodeSupport) stopChaincode(context context.Context,
 		return fmt.Errorf("chaincode name not set")
 	}
 
-	vmname := container.GetVMFromName(chaincode)
+	//stop the container launched for the chaincode, or else the one of the installed version
+	chaincodeSupport.handlerMap.Lock()
+	vmname, ok := chaincodeSupport.handlerMap.vmMap[chaincode]
+	delete(chaincodeSupport.handlerMap.vmMap, chaincode)
+	chaincodeSupport.handlerMap.Unlock()
+	if !ok {
+		var version uint32
+		if ledgerObj, ledgerErr := ledger.GetLedger(); ledgerErr == nil {
+			version, _ = getChaincodeVersion(ledgerObj, chaincode)
+		}
+		vmname = getVMName(chaincode, version)
+	}
 
 	//stop the chaincode
 	sir := container.StopImageReq{ID: vmname, Timeout: 0}
 
-	_, err := container.VMCProcess(context, "Docker", sir)
+	_, err := container.VMCProcess(context, chaincodeSupport.vmType, sir)
 	if err != nil {
 		err = fmt.Errorf("Error stopping container: %s", err)
 		//but proceed to cleanup
@@ -333,6 +392,8 @@ func (chaincodeSupport *ChaincodeSupport) LaunchChaincode(context context.Contex
 	var cMsg *pb.ChaincodeInput
 	var f *string
 	var initargs []string
+	//the version of the code to launch
+	var version uint32
 	if t.Type == pb.Transaction_CHAINCODE_NEW {
 		cds := &pb.ChaincodeDeploymentSpec{}
 		err := proto.Unmarshal(t.Payload, cds)
@@ -343,6 +404,30 @@ func (chaincodeSupport *ChaincodeSupport) LaunchChaincode(context context.Contex
 		cMsg = cds.ChaincodeSpec.CtorMsg
 		f = &cMsg.Function
 		initargs = cMsg.Args
+		version = 1
+	} else if t.Type == pb.Transaction_CHAINCODE_UPDATE {
+		cds := &pb.ChaincodeDeploymentSpec{}
+		err := proto.Unmarshal(t.Payload, cds)
+		if err != nil {
+			return nil, nil, err
+		}
+		cID = cds.ChaincodeSpec.ChaincodeID
+		cMsg = cds.ChaincodeSpec.CtorMsg
+		//the upgrade function migrating the state is optional
+		if cMsg != nil && cMsg.Function != "" {
+			f = &cMsg.Function
+			initargs = cMsg.Args
+		}
+
+		//the new code is registered once the upgrade succeeded
+		ledgerObj, ledgerErr := ledger.GetLedger()
+		if ledgerErr != nil {
+			return nil, nil, fmt.Errorf("Failed to get handle to ledger (%s)", ledgerErr)
+		}
+		if version, err = getChaincodeVersion(ledgerObj, cID.Name); err != nil {
+			return cID, cMsg, err
+		}
+		version++
 	} else if t.Type == pb.Transaction_CHAINCODE_EXECUTE || t.Type == pb.Transaction_CHAINCODE_QUERY {
 		ci := &pb.ChaincodeInvocationSpec{}
 		err := proto.Unmarshal(t.Payload, ci)
@@ -351,8 +436,19 @@ func (chaincodeSupport *ChaincodeSupport) LaunchChaincode(context context.Contex
 		}
 		cID = ci.ChaincodeSpec.ChaincodeID
 		cMsg = ci.ChaincodeSpec.CtorMsg
+
+		//terminated chaincodes can no longer be invoked or queried
+		ledgerObj, ledgerErr := ledger.GetLedger()
+		if ledgerErr != nil {
+			return nil, nil, fmt.Errorf("Failed to get handle to ledger (%s)", ledgerErr)
+		}
+		if err = checkChaincodeActive(ledgerObj, cID.Name); err != nil {
+			return cID, cMsg, err
+		}
+		if version, err = getChaincodeVersion(ledgerObj, cID.Name); err != nil {
+			return cID, cMsg, err
+		}
 	} else {
-		chaincodeSupport.handlerMap.Unlock()
 		return nil, nil, fmt.Errorf("invalid transaction type: %d", t.Type)
 	}
 	chaincode := cID.Name
@@ -379,7 +475,7 @@ func (chaincodeSupport *ChaincodeSupport) LaunchChaincode(context context.Contex
 
 	//from here on : if we launch the container and get an error, we need to stop the container
 	if !chaincodeSupport.userRunsCC && handler == nil {
-		_, err = chaincodeSupport.launchAndWaitForRegister(context, cID, t.Uuid)
+		_, err = chaincodeSupport.launchAndWaitForRegister(context, cID, t.Uuid, getVMName(chaincode, version))
 		if err != nil {
 			chaincodeLog.Debug("launchAndWaitForRegister failed %s", err)
 			return cID, cMsg, err
@@ -407,12 +503,6 @@ func (chaincodeSupport *ChaincodeSupport) LaunchChaincode(context context.Contex
 
 // DeployChaincode deploys the chaincode if not in development mode where user is running the chaincode.
 func (chaincodeSupport *ChaincodeSupport) DeployChaincode(context context.Context, t *pb.Transaction) (*pb.ChaincodeDeploymentSpec, error) {
-	if chaincodeSupport.userRunsCC {
-		chaincodeLog.Debug("user runs chaincode, not deploying chaincode")
-		return nil, nil
-	}
-
-	//build the chaincode
 	cds := &pb.ChaincodeDeploymentSpec{}
 	err := proto.Unmarshal(t.Payload, cds)
 	if err != nil {
@@ -420,9 +510,16 @@ func (chaincodeSupport *ChaincodeSupport) DeployChaincode(context context.Contex
 	}
 	cID := cds.ChaincodeSpec.ChaincodeID
 	chaincode := cID.Name
-	if err != nil {
+	if err = checkChaincodeName(chaincode); err != nil {
 		return cds, err
 	}
+
+	if chaincodeSupport.userRunsCC {
+		chaincodeLog.Debug("user runs chaincode, not deploying chaincode")
+		return nil, nil
+	}
+
+	//build the chaincode
 	chaincodeSupport.handlerMap.Lock()
 	//if its in the map, there must be a connected stream...and we are trying to build the code ?!
 	if _, ok := chaincodeSupport.chaincodeHasBeenLaunched(chaincode); ok {
@@ -432,13 +529,58 @@ func (chaincodeSupport *ChaincodeSupport) DeployChaincode(context context.Contex
 	}
 	chaincodeSupport.handlerMap.Unlock()
 
+	return cds, chaincodeSupport.createImage(context, cds, getVMName(chaincode, 1))
+}
+
+// UpdateChaincode builds the new code of a deployed chaincode if not in development mode where user is
+// running the chaincode. The image of the new code is built under the name of the next version of the
+// chaincode, then the container running the old code is stopped and removed. The new code is launched
+// by LaunchChaincode. The image of the old code is kept, it is launched again if the update fails.
+func (chaincodeSupport *ChaincodeSupport) UpdateChaincode(context context.Context, t *pb.Transaction) (*pb.ChaincodeDeploymentSpec, error) {
+	if chaincodeSupport.userRunsCC {
+		chaincodeLog.Debug("user runs chaincode, not updating chaincode")
+		return nil, nil
+	}
+
+	cds := &pb.ChaincodeDeploymentSpec{}
+	err := proto.Unmarshal(t.Payload, cds)
+	if err != nil {
+		return nil, err
+	}
+	cID := cds.ChaincodeSpec.ChaincodeID
+
+	ledgerObj, err := ledger.GetLedger()
+	if err != nil {
+		return cds, fmt.Errorf("Failed to get handle to ledger (%s)", err)
+	}
+	version, err := getChaincodeVersion(ledgerObj, cID.Name)
+	if err != nil {
+		return cds, err
+	}
+	if err = chaincodeSupport.createImage(context, cds, getVMName(cID.Name, version+1)); err != nil {
+		return cds, err
+	}
+
+	//the container may not be running on this peer (ie, not invoked since the peer started)
+	if err = chaincodeSupport.stopChaincode(context, cID); err != nil {
+		chaincodeLog.Debug("stop before update of %s failed(%s)", cID.Name, err)
+	}
+
+	return cds, nil
+}
+
+// createImage builds the image vmname and creates the container of the chaincode from its code package
+func (chaincodeSupport *ChaincodeSupport) createImage(context context.Context, cds *pb.ChaincodeDeploymentSpec, vmname string) error {
+	cID := cds.ChaincodeSpec.ChaincodeID
+	chaincode := cID.Name
+
 	//openchain.yaml in the container likely will not have the right url:version. We know the right
 	//values, lets construct and pass as envs
 	var targz io.Reader = bytes.NewBuffer(cds.CodePackage)
 	envs := []string{"OPENCHAIN_CHAINCODE_ID_NAME=" + chaincode, "OPENCHAIN_PEER_ADDRESS=" + chaincodeSupport.peerAddress}
 	toks := strings.Split(cID.Path, "/")
 	if toks == nil {
-		return cds, fmt.Errorf("cannot get path components from %s", chaincode)
+		return fmt.Errorf("cannot get path components from %s", chaincode)
 	}
 
 	//TODO : chaincode executable will be same as the name of the last folder (golang thing...)
@@ -448,18 +590,16 @@ func (chaincodeSupport *ChaincodeSupport) DeployChaincode(context context.Contex
 	exec := []string{chaincodeSupport.chaincodeInstallPath + toks[len(toks)-1]}
 	chaincodeLog.Debug("Executable is %s", exec[0])
 
-	vmname := container.GetVMFromName(chaincode)
-
 	cir := &container.CreateImageReq{ID: vmname, Args: exec, Reader: targz, Env: envs}
 
 	chaincodeLog.Debug("deploying chaincode %s", vmname)
 	//create image and create container
-	_, err = container.VMCProcess(context, "Docker", cir)
+	_, err := container.VMCProcess(context, chaincodeSupport.vmType, cir)
 	if err != nil {
 		err = fmt.Errorf("Error starting container: %s", err)
 	}
 
-	return cds, err
+	return err
 }
 
 // Register the bidi stream entry point called by chaincode to register with the Peer.
@@ -504,7 +644,11 @@ func (chaincodeSupport *ChaincodeSupport) Execute(ctxt context.Context, chaincod
 		return nil, fmt.Errorf("Error sending %s: %s", msg.Type.String(), err)
 	}
 	select {
-	case ccresp := <-notfy:
+	case ccresp, ok := <-notfy:
+		if !ok {
+			//the notifier is closed when the execution is cancelled
+			return nil, fmt.Errorf("Execution of transaction %s cancelled", msg.Uuid)
+		}
 		//we delete the notifier now that it has been delivered
 		handler.deleteNotifier(msg.Uuid)
 		if ccresp.Type == pb.ChaincodeMessage_ERROR || ccresp.Type == pb.ChaincodeMessage_QUERY_ERROR {
@@ -512,8 +656,58 @@ func (chaincodeSupport *ChaincodeSupport) Execute(ctxt context.Context, chaincod
 		}
 		return ccresp, nil
 	case <-time.After(timeout):
-		//we delete the now that we are going away (under lock, in case chaincode comes back JIT)
-		handler.deleteNotifier(msg.Uuid)
-		return nil, fmt.Errorf("Timeout expired while executing transaction")
+		//we cancel the execution now that we are going away (under lock, in case chaincode comes back JIT)
+		handler.cancelExecution(msg.Uuid)
+		return nil, &ExecuteTimeoutError{UUID: msg.Uuid, Timeout: timeout}
+	}
+}
+
+// cancelTransaction cancels the execution of the transaction or query uuid by
+// every chaincode, including the chaincodes invoked by other chaincodes. Once
+// it returns none of them changes the state on behalf of uuid anymore.
+func (chaincodeSupport *ChaincodeSupport) cancelTransaction(uuid string) {
+	chaincodeSupport.handlerMap.Lock()
+	handlers := make([]*Handler, 0, len(chaincodeSupport.handlerMap.chaincodeMap))
+	for _, handler := range chaincodeSupport.handlerMap.chaincodeMap {
+		handlers = append(handlers, handler)
+	}
+	chaincodeSupport.handlerMap.Unlock()
+
+	for _, handler := range handlers {
+		handler.cancelExecution(uuid)
+	}
+}
+
+// getTimeout returns how long the chaincode may take to execute the transaction
+// or query t: the timeout requested by t if any, else the one set when the
+// chaincode was deployed or last updated, else the network default. It never
+// exceeds the network maximum.
+func (chaincodeSupport *ChaincodeSupport) getTimeout(ledger *ledger.Ledger, t *pb.Transaction, cID *pb.ChaincodeID) (time.Duration, error) {
+	ci := &pb.ChaincodeInvocationSpec{}
+	if err := proto.Unmarshal(t.Payload, ci); err != nil {
+		return 0, err
+	}
+	var requested int32
+	if ci.ChaincodeSpec != nil {
+		requested = ci.ChaincodeSpec.Timeout
+	}
+	if requested <= 0 {
+		entry, err := getChaincodeRegistryEntry(ledger, cID.Name)
+		if err != nil {
+			return 0, err
+		}
+		if entry != nil && len(entry.Versions) > 0 {
+			requested = entry.Versions[len(entry.Versions)-1].Timeout
+		}
+	}
+
+	timeout := chaincodeSupport.ccExecuteTimeout
+	if requested > 0 {
+		timeout = time.Duration(requested) * time.Millisecond
+	}
+	if timeout > chaincodeSupport.ccMaxExecuteTimeout {
+		chaincodeLog.Debug("timeout %s of %s reduced to the maximum %s", timeout, t.Uuid, chaincodeSupport.ccMaxExecuteTimeout)
+		timeout = chaincodeSupport.ccMaxExecuteTimeout
 	}
+	return timeout, nil
 }
@@ -20,11 +20,8 @@ under the License.
 package chaincode
 
 import (
-	"errors"
 	"fmt"
-	"time"
 
-	"github.com/golang/protobuf/proto"
 	"golang.org/x/net/context"
 
 	"github.com/openblockchain/obc-peer/openchain/crypto"
@@ -53,6 +50,15 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction, s
 	}
 
 	if t.Type == pb.Transaction_CHAINCODE_NEW {
+		// a terminated chaincode cannot be brought back by deploying its code again
+		if cID := t.GetChaincodeID(); cID != nil {
+			if err := checkChaincodeName(cID.Name); err != nil {
+				return nil, fmt.Errorf("Failed to deploy chaincode(%s)", err)
+			}
+			if err := checkChaincodeActive(ledger, cID.Name); err != nil {
+				return nil, fmt.Errorf("Failed to deploy chaincode(%s)", err)
+			}
+		}
 		_, err := chain.DeployChaincode(ctxt, t)
 		if err != nil {
 			return nil, fmt.Errorf("Failed to deploy chaincode spec(%s)", err)
@@ -66,7 +72,52 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction, s
 			markTxFinish(ledger, t, false)
 			return nil, fmt.Errorf("%s", err)
 		}
+		if err = registerChaincodeVersion(ledger, t); err != nil {
+			markTxFinish(ledger, t, false)
+			return nil, fmt.Errorf("Failed to register chaincode(%s)", err)
+		}
+		markTxFinish(ledger, t, true)
+	} else if t.Type == pb.Transaction_CHAINCODE_UPDATE {
+		if err := checkChaincodeUpdate(ledger, t); err != nil {
+			return nil, fmt.Errorf("Failed to update chaincode(%s)", err)
+		}
+		_, err := chain.UpdateChaincode(ctxt, t)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to update chaincode spec(%s)", err)
+		}
+
+		//launch the new code against the state of the chaincode, calling the
+		//upgrade function if any, and wait for ready. The new code is stopped
+		//on failure, the registry keeps the old code which is launched again
+		//by the next invocation.
+		markTxBegin(ledger, t)
+		cID, _, err := chain.LaunchChaincode(ctxt, t)
+		if err != nil {
+			// Rollback the state migrated by the upgrade function
+			markTxFinish(ledger, t, false)
+			return nil, fmt.Errorf("%s", err)
+		}
+		if err = registerChaincodeVersion(ledger, t); err != nil {
+			markTxFinish(ledger, t, false)
+			if errIgnore := chain.stopChaincode(ctxt, cID); errIgnore != nil {
+				chaincodeLog.Debug("stop of failed update of %s failed(%s)", cID.Name, errIgnore)
+			}
+			return nil, fmt.Errorf("Failed to register chaincode(%s)", err)
+		}
+		markTxFinish(ledger, t, true)
+	} else if t.Type == pb.Transaction_CHAINCODE_TERMINATE {
+		markTxBegin(ledger, t)
+		cID, err := terminateChaincode(ledger, t)
+		if err != nil {
+			markTxFinish(ledger, t, false)
+			return nil, fmt.Errorf("Failed to terminate chaincode(%s)", err)
+		}
 		markTxFinish(ledger, t, true)
+
+		//the state is kept for audit, only the container goes away
+		if err = chain.stopChaincode(ctxt, cID); err != nil {
+			chaincodeLog.Debug("stop of terminated chaincode %s failed(%s)", cID.Name, err)
+		}
 	} else if t.Type == pb.Transaction_CHAINCODE_EXECUTE || t.Type == pb.Transaction_CHAINCODE_QUERY {
 		//will launch if necessary (and wait for ready)
 		cID, cMsg, err := chain.LaunchChaincode(ctxt, t)
@@ -81,12 +132,9 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction, s
 			return nil, fmt.Errorf("Failed to stablish stream to container %s", chaincode)
 		}
 
-		// TODO: Need to comment next line and uncomment call to getTimeout, when transaction blocks are being created
-		timeout := time.Duration(30000) * time.Millisecond
-		//timeout, err := getTimeout(cID)
-
+		timeout, err := chain.getTimeout(ledger, t, cID)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to retrieve chaincode spec(%s)", err)
+			return nil, fmt.Errorf("Failed to retrieve chaincode timeout(%s)", err)
 		}
 
 		var ccMsg *pb.ChaincodeMessage
@@ -102,9 +150,20 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction, s
 			}
 		}
 
+		// Hand the caller certificate and the attribute keys it revealed to the chaincode
+		if t.Cert != nil {
+			ccMsg.SecurityContext = &pb.ChaincodeSecurityContext{CallerCert: t.Cert, AttributeKeys: t.AttributeKeys}
+		}
+
 		markTxBegin(ledger, t)
 		resp, err := chain.Execute(ctxt, chaincode, ccMsg, timeout)
-		if err != nil {
+		if _, ok := err.(*ExecuteTimeoutError); ok {
+			// Keep the chaincodes still working on the transaction from
+			// changing the state any further before rolling it back
+			chain.cancelTransaction(t.Uuid)
+			markTxFinish(ledger, t, false)
+			return nil, fmt.Errorf("Transaction or query %s timed out after %s, its changes were rolled back", t.Uuid, timeout)
+		} else if err != nil {
 			// Rollback transaction
 			markTxFinish(ledger, t, false)
 			fmt.Printf("Got ERROR inside execute")
@@ -116,6 +175,11 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction, s
 		} else {
 			if resp.Type == pb.ChaincodeMessage_COMPLETED || resp.Type == pb.ChaincodeMessage_QUERY_COMPLETED {
 				// Success
+				if resp.Type == pb.ChaincodeMessage_COMPLETED && resp.ChaincodeEvent != nil {
+					resp.ChaincodeEvent.ChaincodeID = chaincode
+					resp.ChaincodeEvent.TxUuid = t.Uuid
+					ledger.SetChaincodeEvent(resp.ChaincodeEvent)
+				}
 				markTxFinish(ledger, t, true)
 				return resp.Payload, nil
 			} else if resp.Type == pb.ChaincodeMessage_ERROR || resp.Type == pb.ChaincodeMessage_QUERY_ERROR {
@@ -148,34 +212,18 @@ func ExecuteTransactions(ctxt context.Context, cname ChainName, xacts []*pb.Tran
 	ledger, hasherr := ledger.GetLedger()
 	var statehash []byte
 	if hasherr == nil {
+		// Record the failures so that they show in the receipts of the transactions
+		for i, t := range xacts {
+			if errs[i] != nil && t.Type != pb.Transaction_CHAINCODE_QUERY {
+				ledger.SetTxError(t.Uuid, errs[i])
+			}
+		}
 		statehash, hasherr = ledger.GetTempStateHash()
 	}
 	errs[len(errs)-1] = hasherr
 	return statehash, errs
 }
 
-var errFailedToGetChainCodeSpecForTransaction = errors.New("Failed to get ChainCodeSpec from Transaction")
-
-func getTimeout(cID *pb.ChaincodeID) (time.Duration, error) {
-	ledger, err := ledger.GetLedger()
-	if err == nil {
-		chaincodeID := cID.Name
-		txUUID, err := ledger.GetState(chaincodeID, "github.com_openblockchain_obc-peer_chaincode_id", true)
-		if err == nil {
-			tx, err := ledger.GetTransactionByUUID(string(txUUID))
-			if err == nil {
-				chaincodeDeploymentSpec := &pb.ChaincodeDeploymentSpec{}
-				proto.Unmarshal(tx.Payload, chaincodeDeploymentSpec)
-				chaincodeSpec := chaincodeDeploymentSpec.GetChaincodeSpec()
-				timeout := time.Duration(time.Duration(chaincodeSpec.Timeout) * time.Millisecond)
-				return timeout, nil
-			}
-		}
-	}
-
-	return -1, errFailedToGetChainCodeSpecForTransaction
-}
-
 func markTxBegin(ledger *ledger.Ledger, t *pb.Transaction) {
 	if t.Type == pb.Transaction_CHAINCODE_QUERY {
 		return
@@ -71,6 +71,27 @@ func deploy(ctx context.Context, spec *pb.ChaincodeSpec) ([]byte, error) {
 	return b, err
 }
 
+// Update the code of a deployed chaincode.
+func update(ctx context.Context, spec *pb.ChaincodeSpec) ([]byte, error) {
+	codePackageBytes, err := container.GetChaincodeUpdatePackageBytes(spec)
+	if err != nil {
+		return nil, err
+	}
+	chaincodeDeploymentSpec := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec, CodePackage: codePackageBytes}
+
+	uuid, uuidErr := util.GenerateUUID()
+	if uuidErr != nil {
+		return nil, uuidErr
+	}
+
+	transaction, err := pb.NewChaincodeUpdateTransaction(chaincodeDeploymentSpec, uuid)
+	if err != nil {
+		return nil, fmt.Errorf("Error updating chaincode: %s ", err)
+	}
+
+	return Execute(ctx, GetChain(DefaultChain), transaction, nil)
+}
+
 // Invoke or query a chaincode.
 func invoke(ctx context.Context, spec *pb.ChaincodeSpec, typ pb.Transaction_Type) (string, []byte, error) {
 	chaincodeInvocationSpec := &pb.ChaincodeInvocationSpec{ChaincodeSpec: spec}
@@ -91,6 +112,25 @@ func invoke(ctx context.Context, spec *pb.ChaincodeSpec, typ pb.Transaction_Type
 	return uuid, retval, err
 }
 
+// Terminate a chaincode.
+func terminate(ctx context.Context, cID *pb.ChaincodeID) error {
+	chaincodeInvocationSpec := &pb.ChaincodeInvocationSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: 1, ChaincodeID: cID}}
+
+	uuid, uuidErr := util.GenerateUUID()
+	if uuidErr != nil {
+		return uuidErr
+	}
+
+	transaction, err := pb.NewChaincodeExecute(chaincodeInvocationSpec, uuid, pb.Transaction_CHAINCODE_TERMINATE)
+	if err != nil {
+		return fmt.Errorf("Error terminating chaincode: %s ", err)
+	}
+
+	_, err = Execute(ctx, GetChain(DefaultChain), transaction, nil)
+
+	return err
+}
+
 func closeListenerAndSleep(l net.Listener) {
 	l.Close()
 	time.Sleep(2 * time.Second)
@@ -299,6 +339,309 @@ func TestExecuteInvokeTransaction(t *testing.T) {
 	closeListenerAndSleep(lis)
 }
 
+// Test the update of a chaincode keeping its state.
+func TestExecuteUpdateTransaction(t *testing.T) {
+	var opts []grpc.ServerOption
+	if viper.GetBool("peer.tls.enabled") {
+		creds, err := credentials.NewServerTLSFromFile(viper.GetString("peer.tls.cert.file"), viper.GetString("peer.tls.key.file"))
+		if err != nil {
+			grpclog.Fatalf("Failed to generate credentials %v", err)
+		}
+		opts = []grpc.ServerOption{grpc.Creds(creds)}
+	}
+	grpcServer := grpc.NewServer(opts...)
+	viper.Set("peer.fileSystemPath", "/var/openchain/test/tmpdb")
+
+	//use a different address than what we usually use for "peer"
+	//we override the peerAddress set in chaincode_support.go
+	peerAddress := "0.0.0.0:40303"
+
+	lis, err := net.Listen("tcp", peerAddress)
+	if err != nil {
+		t.Fail()
+		t.Logf("Error starting peer listener %s", err)
+		return
+	}
+
+	getPeerEndpoint := func() (*pb.PeerEndpoint, error) {
+		return &pb.PeerEndpoint{ID: &pb.PeerID{Name: "testpeer"}, Address: peerAddress}, nil
+	}
+
+	ccStartupTimeout := time.Duration(chaincodeStartupTimeoutDefault) * time.Millisecond
+	pb.RegisterChaincodeSupportServer(grpcServer, NewChaincodeSupport(DefaultChain, getPeerEndpoint, false, ccStartupTimeout))
+
+	go grpcServer.Serve(lis)
+
+	var ctxt = context.Background()
+
+	url := "github.com/openblockchain/obc-peer/openchain/example/chaincode/chaincode_example02"
+	cID := &pb.ChaincodeID{Path: url}
+
+	// Only deployed chaincodes can be updated
+	spec := &pb.ChaincodeSpec{Type: 1, ChaincodeID: &pb.ChaincodeID{Path: url, Name: "notdeployed"}}
+	if _, err = update(ctxt, spec); err == nil {
+		t.Fail()
+		t.Logf("Update of a chaincode not deployed should have failed")
+	}
+
+	spec = &pb.ChaincodeSpec{Type: 1, ChaincodeID: cID, CtorMsg: &pb.ChaincodeInput{Function: "init", Args: []string{"a", "100", "b", "200"}}}
+	_, err = deploy(ctxt, spec)
+	chaincodeID := cID.Name
+	if err != nil {
+		GetChain(DefaultChain).stopChaincode(ctxt, cID)
+		closeListenerAndSleep(lis)
+		t.Fail()
+		t.Logf("Error deploying <%s>: %s", chaincodeID, err)
+		return
+	}
+
+	time.Sleep(time.Second)
+
+	// Replace the code without upgrade function, the state must be preserved
+	spec = &pb.ChaincodeSpec{Type: 1, ChaincodeID: &pb.ChaincodeID{Path: url, Name: chaincodeID}}
+	_, err = update(ctxt, spec)
+	if err != nil {
+		GetChain(DefaultChain).stopChaincode(ctxt, cID)
+		closeListenerAndSleep(lis)
+		t.Fail()
+		t.Logf("Error updating <%s>: %s", chaincodeID, err)
+		return
+	}
+
+	spec = &pb.ChaincodeSpec{Type: 1, ChaincodeID: cID, CtorMsg: &pb.ChaincodeInput{Function: "invoke", Args: []string{"a", "b", "10"}}}
+	uuid, _, err := invoke(ctxt, spec, pb.Transaction_CHAINCODE_EXECUTE)
+	if err != nil {
+		t.Fail()
+		t.Logf("Error invoking <%s>: %s", chaincodeID, err)
+	} else if err = checkFinalState(uuid, chaincodeID); err != nil {
+		t.Fail()
+		t.Logf("Incorrect final state after update for <%s>: %s", chaincodeID, err)
+	}
+
+	ledgerObj, _ := ledger.GetLedger()
+	entry, err := getChaincodeRegistryEntry(ledgerObj, chaincodeID)
+	if err != nil || entry == nil || len(entry.Versions) != 2 {
+		t.Fail()
+		t.Logf("Expected 2 versions of <%s> in the registry: %v (%v)", chaincodeID, entry, err)
+	}
+	// The update keeps the deployment details
+	if entry != nil && (entry.Type != pb.ChaincodeSpec_GOLANG || entry.DeployerCert != nil || entry.Status != pb.ChaincodeRegistryEntry_ACTIVE) {
+		t.Fail()
+		t.Logf("Unexpected deployment details of <%s> in the registry: %v", chaincodeID, entry)
+	}
+
+	// A failed upgrade function keeps the installed code, which is launched again
+	spec = &pb.ChaincodeSpec{Type: 1, ChaincodeID: &pb.ChaincodeID{Path: url, Name: chaincodeID}, CtorMsg: &pb.ChaincodeInput{Function: "init", Args: []string{"a"}}}
+	if _, err = update(ctxt, spec); err == nil {
+		t.Fail()
+		t.Logf("Update of <%s> with a failing upgrade function should have failed", chaincodeID)
+	}
+	if version, err := getChaincodeVersion(ledgerObj, chaincodeID); err != nil || version != 2 {
+		t.Fail()
+		t.Logf("Expected version 2 of <%s> after failed update: %d (%v)", chaincodeID, version, err)
+	}
+	spec = &pb.ChaincodeSpec{Type: 1, ChaincodeID: cID, CtorMsg: &pb.ChaincodeInput{Function: "invoke", Args: []string{"a", "b", "10"}}}
+	if _, _, err = invoke(ctxt, spec, pb.Transaction_CHAINCODE_EXECUTE); err != nil {
+		t.Fail()
+		t.Logf("Error invoking <%s> after failed update: %s", chaincodeID, err)
+	}
+
+	// The namespace of the registry cannot be taken by a chaincode
+	spec = &pb.ChaincodeSpec{Type: 1, ChaincodeID: &pb.ChaincodeID{Path: url}, CtorMsg: &pb.ChaincodeInput{Function: "init", Args: []string{"a", "100", "b", "200"}}}
+	cds, err := getDeploymentSpec(ctxt, spec)
+	if err == nil {
+		cds.ChaincodeSpec.ChaincodeID.Name = registryNamespace
+		var transaction *pb.Transaction
+		if transaction, err = pb.NewChaincodeDeployTransaction(cds, registryNamespace); err == nil {
+			_, err = Execute(ctxt, GetChain(DefaultChain), transaction, nil)
+		}
+	}
+	if err == nil {
+		t.Fail()
+		t.Logf("Deploy of a chaincode named %s should have failed", registryNamespace)
+	}
+	spec = &pb.ChaincodeSpec{Type: 1, ChaincodeID: &pb.ChaincodeID{Path: url, Name: registryNamespace}}
+	if _, err = update(ctxt, spec); err == nil {
+		t.Fail()
+		t.Logf("Update of a chaincode named %s should have failed", registryNamespace)
+	}
+
+	GetChain(DefaultChain).stopChaincode(ctxt, cID)
+
+	closeListenerAndSleep(lis)
+}
+
+// Test the termination of a chaincode keeping its state.
+func TestExecuteTerminateTransaction(t *testing.T) {
+	var opts []grpc.ServerOption
+	if viper.GetBool("peer.tls.enabled") {
+		creds, err := credentials.NewServerTLSFromFile(viper.GetString("peer.tls.cert.file"), viper.GetString("peer.tls.key.file"))
+		if err != nil {
+			grpclog.Fatalf("Failed to generate credentials %v", err)
+		}
+		opts = []grpc.ServerOption{grpc.Creds(creds)}
+	}
+	grpcServer := grpc.NewServer(opts...)
+	viper.Set("peer.fileSystemPath", "/var/openchain/test/tmpdb")
+
+	//use a different address than what we usually use for "peer"
+	//we override the peerAddress set in chaincode_support.go
+	peerAddress := "0.0.0.0:40303"
+
+	lis, err := net.Listen("tcp", peerAddress)
+	if err != nil {
+		t.Fail()
+		t.Logf("Error starting peer listener %s", err)
+		return
+	}
+
+	getPeerEndpoint := func() (*pb.PeerEndpoint, error) {
+		return &pb.PeerEndpoint{ID: &pb.PeerID{Name: "testpeer"}, Address: peerAddress}, nil
+	}
+
+	ccStartupTimeout := time.Duration(chaincodeStartupTimeoutDefault) * time.Millisecond
+	pb.RegisterChaincodeSupportServer(grpcServer, NewChaincodeSupport(DefaultChain, getPeerEndpoint, false, ccStartupTimeout))
+
+	go grpcServer.Serve(lis)
+
+	var ctxt = context.Background()
+
+	//the other tests deploy chaincode_example02 with other args, the terminated chaincode must not be theirs
+	url := "github.com/openblockchain/obc-peer/openchain/example/chaincode/chaincode_example02"
+	cID := &pb.ChaincodeID{Path: url}
+	spec := &pb.ChaincodeSpec{Type: 1, ChaincodeID: cID, CtorMsg: &pb.ChaincodeInput{Function: "init", Args: []string{"c", "100", "d", "200"}}}
+	_, err = deploy(ctxt, spec)
+	chaincodeID := cID.Name
+	if err != nil {
+		GetChain(DefaultChain).stopChaincode(ctxt, cID)
+		closeListenerAndSleep(lis)
+		t.Fail()
+		t.Logf("Error deploying <%s>: %s", chaincodeID, err)
+		return
+	}
+
+	time.Sleep(time.Second)
+
+	if err = terminate(ctxt, cID); err != nil {
+		GetChain(DefaultChain).stopChaincode(ctxt, cID)
+		closeListenerAndSleep(lis)
+		t.Fail()
+		t.Logf("Error terminating <%s>: %s", chaincodeID, err)
+		return
+	}
+
+	spec = &pb.ChaincodeSpec{Type: 1, ChaincodeID: cID, CtorMsg: &pb.ChaincodeInput{Function: "invoke", Args: []string{"c", "d", "10"}}}
+	if _, _, err = invoke(ctxt, spec, pb.Transaction_CHAINCODE_EXECUTE); err == nil {
+		t.Fail()
+		t.Logf("Invoke of terminated <%s> should have failed", chaincodeID)
+	}
+	spec = &pb.ChaincodeSpec{Type: 1, ChaincodeID: cID, CtorMsg: &pb.ChaincodeInput{Function: "query", Args: []string{"c"}}}
+	if _, _, err = invoke(ctxt, spec, pb.Transaction_CHAINCODE_QUERY); err == nil {
+		t.Fail()
+		t.Logf("Query of terminated <%s> should have failed", chaincodeID)
+	}
+	if err = terminate(ctxt, cID); err == nil {
+		t.Fail()
+		t.Logf("Second termination of <%s> should have failed", chaincodeID)
+	}
+
+	// The state remains readable
+	ledgerObj, _ := ledger.GetLedger()
+	value, err := ledgerObj.GetState(chaincodeID, "c", false)
+	if err != nil || string(value) != "100" {
+		t.Fail()
+		t.Logf("State of terminated <%s> should remain readable: %s (%v)", chaincodeID, value, err)
+	}
+
+	closeListenerAndSleep(lis)
+}
+
+// Test the timeout set when deploying a chaincode and its override by an invocation.
+func TestExecuteTimeoutTransaction(t *testing.T) {
+	var opts []grpc.ServerOption
+	if viper.GetBool("peer.tls.enabled") {
+		creds, err := credentials.NewServerTLSFromFile(viper.GetString("peer.tls.cert.file"), viper.GetString("peer.tls.key.file"))
+		if err != nil {
+			grpclog.Fatalf("Failed to generate credentials %v", err)
+		}
+		opts = []grpc.ServerOption{grpc.Creds(creds)}
+	}
+	grpcServer := grpc.NewServer(opts...)
+	viper.Set("peer.fileSystemPath", "/var/openchain/test/tmpdb")
+
+	//use a different address than what we usually use for "peer"
+	//we override the peerAddress set in chaincode_support.go
+	peerAddress := "0.0.0.0:40303"
+
+	lis, err := net.Listen("tcp", peerAddress)
+	if err != nil {
+		t.Fail()
+		t.Logf("Error starting peer listener %s", err)
+		return
+	}
+
+	getPeerEndpoint := func() (*pb.PeerEndpoint, error) {
+		return &pb.PeerEndpoint{ID: &pb.PeerID{Name: "testpeer"}, Address: peerAddress}, nil
+	}
+
+	ccStartupTimeout := time.Duration(chaincodeStartupTimeoutDefault) * time.Millisecond
+	pb.RegisterChaincodeSupportServer(grpcServer, NewChaincodeSupport(DefaultChain, getPeerEndpoint, false, ccStartupTimeout))
+
+	go grpcServer.Serve(lis)
+
+	var ctxt = context.Background()
+
+	//a timeout of 1ms at deploy time is too short for any transaction to complete
+	url := "github.com/openblockchain/obc-peer/openchain/example/chaincode/chaincode_example02"
+	cID := &pb.ChaincodeID{Path: url}
+	spec := &pb.ChaincodeSpec{Type: 1, ChaincodeID: cID, CtorMsg: &pb.ChaincodeInput{Function: "init", Args: []string{"e", "100", "f", "200"}}, Timeout: 1}
+	_, err = deploy(ctxt, spec)
+	chaincodeID := cID.Name
+	if err != nil {
+		GetChain(DefaultChain).stopChaincode(ctxt, cID)
+		closeListenerAndSleep(lis)
+		t.Fail()
+		t.Logf("Error deploying <%s>: %s", chaincodeID, err)
+		return
+	}
+
+	time.Sleep(time.Second)
+
+	spec = &pb.ChaincodeSpec{Type: 1, ChaincodeID: cID, CtorMsg: &pb.ChaincodeInput{Function: "invoke", Args: []string{"e", "f", "10"}}}
+	if _, _, err = invoke(ctxt, spec, pb.Transaction_CHAINCODE_EXECUTE); err == nil {
+		t.Fail()
+		t.Logf("Invoke of <%s> should have timed out", chaincodeID)
+	} else {
+		t.Logf("Got error %s", err)
+	}
+
+	//let the chaincode finish the abandoned transaction, its changes must be refused
+	time.Sleep(time.Second)
+
+	ledgerObj, _ := ledger.GetLedger()
+	value, err := ledgerObj.GetState(chaincodeID, "e", false)
+	if err != nil || string(value) != "100" {
+		t.Fail()
+		t.Logf("Timed out transaction of <%s> should have been rolled back: %s (%v)", chaincodeID, value, err)
+	}
+
+	//the invocation overrides the timeout of the deployment
+	spec.Timeout = 30000
+	if _, _, err = invoke(ctxt, spec, pb.Transaction_CHAINCODE_EXECUTE); err != nil {
+		t.Fail()
+		t.Logf("Error invoking <%s> with a longer timeout: %s", chaincodeID, err)
+	}
+	value, err = ledgerObj.GetState(chaincodeID, "e", false)
+	if err != nil || string(value) != "90" {
+		t.Fail()
+		t.Logf("Expected e of <%s> to be 90: %s (%v)", chaincodeID, value, err)
+	}
+
+	GetChain(DefaultChain).stopChaincode(ctxt, cID)
+
+	closeListenerAndSleep(lis)
+}
+
 // Execute multiple transactions and queries.
 func exec(ctxt context.Context, chaincodeID string, numTrans int, numQueries int) []error {
 	var wg sync.WaitGroup
@@ -23,7 +23,6 @@ import (
 	"fmt"
 	"io"
 	"sync"
-	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/looplab/fsm"
@@ -32,6 +31,8 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/openblockchain/obc-peer/openchain/ledger"
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+	"github.com/openblockchain/obc-peer/openchain/util"
 )
 
 const (
@@ -46,6 +47,10 @@ const (
 
 )
 
+// maxRangeQueryStateLimit is the number of key-values returned to the chaincode per RANGE_QUERY_STATE
+// or RANGE_QUERY_STATE_NEXT request
+const maxRangeQueryStateLimit = 100
+
 var chaincodeLogger = logging.MustGetLogger("chaincode")
 
 // PeerChaincodeStream interface for stream between Peer and chaincode instance.
@@ -74,10 +79,23 @@ type Handler struct {
 	uuidMap map[string]bool
 	// Track which UUIDs are queries; Although the shim maintains this, it cannot be trusted.
 	isTransaction map[string]bool
+	// Range query iterators opened by the chaincode, by iterator ID
+	rangeQueryIteratorMap map[string]*rangeQueryIterator
+}
+
+// rangeQueryIterator is a range query iterator opened by the chaincode for the
+// transaction or query uuid. The iterator is moved one key-value ahead of the
+// ones sent to the chaincode to know whether more remain; pending is true when
+// the current key-value has not been sent yet.
+type rangeQueryIterator struct {
+	statemgmt.RangeScanIterator
+	uuid    string
+	pending bool
 }
 
 func (handler *Handler) deregister() error {
 	if handler.registered {
+		handler.closeRangeQueryIterators()
 		handler.chaincodeSupport.deregisterHandler(handler)
 	}
 	return nil
@@ -137,6 +155,21 @@ func newChaincodeSupportHandler(chaincodeSupport *ChaincodeSupport, peerChatStre
 			{Name: pb.ChaincodeMessage_GET_STATE.String(), Src: []string{busyinitstate}, Dst: busyinitstate},
 			{Name: pb.ChaincodeMessage_GET_STATE.String(), Src: []string{transactionstate}, Dst: transactionstate},
 			{Name: pb.ChaincodeMessage_GET_STATE.String(), Src: []string{busyxactstate}, Dst: busyxactstate},
+			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE.String(), Src: []string{readystate}, Dst: readystate},
+			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE.String(), Src: []string{initstate}, Dst: initstate},
+			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE.String(), Src: []string{busyinitstate}, Dst: busyinitstate},
+			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE.String(), Src: []string{transactionstate}, Dst: transactionstate},
+			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE.String(), Src: []string{busyxactstate}, Dst: busyxactstate},
+			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE_NEXT.String(), Src: []string{readystate}, Dst: readystate},
+			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE_NEXT.String(), Src: []string{initstate}, Dst: initstate},
+			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE_NEXT.String(), Src: []string{busyinitstate}, Dst: busyinitstate},
+			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE_NEXT.String(), Src: []string{transactionstate}, Dst: transactionstate},
+			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE_NEXT.String(), Src: []string{busyxactstate}, Dst: busyxactstate},
+			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE_CLOSE.String(), Src: []string{readystate}, Dst: readystate},
+			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE_CLOSE.String(), Src: []string{initstate}, Dst: initstate},
+			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE_CLOSE.String(), Src: []string{busyinitstate}, Dst: busyinitstate},
+			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE_CLOSE.String(), Src: []string{transactionstate}, Dst: transactionstate},
+			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE_CLOSE.String(), Src: []string{busyxactstate}, Dst: busyxactstate},
 			{Name: pb.ChaincodeMessage_ERROR.String(), Src: []string{initstate}, Dst: endstate},
 			{Name: pb.ChaincodeMessage_ERROR.String(), Src: []string{transactionstate}, Dst: readystate},
 			{Name: pb.ChaincodeMessage_ERROR.String(), Src: []string{busyinitstate}, Dst: initstate},
@@ -145,20 +178,23 @@ func newChaincodeSupportHandler(chaincodeSupport *ChaincodeSupport, peerChatStre
 			{Name: pb.ChaincodeMessage_RESPONSE.String(), Src: []string{busyxactstate}, Dst: transactionstate},
 		},
 		fsm.Callbacks{
-			"before_" + pb.ChaincodeMessage_REGISTER.String():        func(e *fsm.Event) { v.beforeRegisterEvent(e, v.FSM.Current()) },
-			"before_" + pb.ChaincodeMessage_COMPLETED.String():       func(e *fsm.Event) { v.beforeCompletedEvent(e, v.FSM.Current()) },
-			"before_" + pb.ChaincodeMessage_INIT.String():            func(e *fsm.Event) { v.beforeInitState(e, v.FSM.Current()) },
-			"after_" + pb.ChaincodeMessage_GET_STATE.String():        func(e *fsm.Event) { v.afterGetState(e, v.FSM.Current()) },
-			"after_" + pb.ChaincodeMessage_PUT_STATE.String():        func(e *fsm.Event) { v.afterPutState(e, v.FSM.Current()) },
-			"after_" + pb.ChaincodeMessage_DEL_STATE.String():        func(e *fsm.Event) { v.afterDelState(e, v.FSM.Current()) },
-			"after_" + pb.ChaincodeMessage_INVOKE_CHAINCODE.String(): func(e *fsm.Event) { v.afterInvokeChaincode(e, v.FSM.Current()) },
-			"enter_" + establishedstate:                              func(e *fsm.Event) { v.enterEstablishedState(e, v.FSM.Current()) },
-			"enter_" + initstate:                                     func(e *fsm.Event) { v.enterInitState(e, v.FSM.Current()) },
-			"enter_" + readystate:                                    func(e *fsm.Event) { v.enterReadyState(e, v.FSM.Current()) },
-			"enter_" + busyinitstate:                                 func(e *fsm.Event) { v.enterBusyState(e, v.FSM.Current()) },
-			"enter_" + busyxactstate:                                 func(e *fsm.Event) { v.enterBusyState(e, v.FSM.Current()) },
-			"enter_" + transactionstate:                              func(e *fsm.Event) { v.enterTransactionState(e, v.FSM.Current()) },
-			"enter_" + endstate:                                      func(e *fsm.Event) { v.enterEndState(e, v.FSM.Current()) },
+			"before_" + pb.ChaincodeMessage_REGISTER.String():               func(e *fsm.Event) { v.beforeRegisterEvent(e, v.FSM.Current()) },
+			"before_" + pb.ChaincodeMessage_COMPLETED.String():              func(e *fsm.Event) { v.beforeCompletedEvent(e, v.FSM.Current()) },
+			"before_" + pb.ChaincodeMessage_INIT.String():                   func(e *fsm.Event) { v.beforeInitState(e, v.FSM.Current()) },
+			"after_" + pb.ChaincodeMessage_GET_STATE.String():               func(e *fsm.Event) { v.afterGetState(e, v.FSM.Current()) },
+			"after_" + pb.ChaincodeMessage_RANGE_QUERY_STATE.String():       func(e *fsm.Event) { v.afterRangeQueryState(e, v.FSM.Current()) },
+			"after_" + pb.ChaincodeMessage_RANGE_QUERY_STATE_NEXT.String():  func(e *fsm.Event) { v.afterRangeQueryStateNext(e, v.FSM.Current()) },
+			"after_" + pb.ChaincodeMessage_RANGE_QUERY_STATE_CLOSE.String(): func(e *fsm.Event) { v.afterRangeQueryStateClose(e, v.FSM.Current()) },
+			"after_" + pb.ChaincodeMessage_PUT_STATE.String():               func(e *fsm.Event) { v.afterPutState(e, v.FSM.Current()) },
+			"after_" + pb.ChaincodeMessage_DEL_STATE.String():               func(e *fsm.Event) { v.afterDelState(e, v.FSM.Current()) },
+			"after_" + pb.ChaincodeMessage_INVOKE_CHAINCODE.String():        func(e *fsm.Event) { v.afterInvokeChaincode(e, v.FSM.Current()) },
+			"enter_" + establishedstate:                                     func(e *fsm.Event) { v.enterEstablishedState(e, v.FSM.Current()) },
+			"enter_" + initstate:                                            func(e *fsm.Event) { v.enterInitState(e, v.FSM.Current()) },
+			"enter_" + readystate:                                           func(e *fsm.Event) { v.enterReadyState(e, v.FSM.Current()) },
+			"enter_" + busyinitstate:                                        func(e *fsm.Event) { v.enterBusyState(e, v.FSM.Current()) },
+			"enter_" + busyxactstate:                                        func(e *fsm.Event) { v.enterBusyState(e, v.FSM.Current()) },
+			"enter_" + transactionstate:                                     func(e *fsm.Event) { v.enterTransactionState(e, v.FSM.Current()) },
+			"enter_" + endstate:                                             func(e *fsm.Event) { v.enterEndState(e, v.FSM.Current()) },
 		},
 	)
 
@@ -197,14 +233,89 @@ func (handler *Handler) markIsTransaction(uuid string, isTrans bool) bool {
 	return true
 }
 
+// deleteIsTransaction forgets the transaction or query uuid once it finished
+// and closes the range query iterators it left open.
 func (handler *Handler) deleteIsTransaction(uuid string) {
 	handler.Lock()
 	if handler.isTransaction != nil {
 		delete(handler.isTransaction, uuid)
 	}
+	handler.closeTxRangeQueryIterators(uuid)
 	handler.Unlock()
 }
 
+// cancelExecution abandons the execution of the transaction or query uuid: the
+// waiter for its completion is released and the state changes the chaincode
+// still requests for uuid are refused.
+func (handler *Handler) cancelExecution(uuid string) {
+	handler.Lock()
+	defer handler.Unlock()
+	if notfy := handler.responseNotifiers[uuid]; notfy != nil {
+		delete(handler.responseNotifiers, uuid)
+		close(notfy)
+	}
+	if handler.isTransaction != nil {
+		delete(handler.isTransaction, uuid)
+	}
+	handler.closeTxRangeQueryIterators(uuid)
+}
+
+// changeState applies a state change requested by the chaincode for the
+// transaction uuid unless the execution has been cancelled. The lock keeps
+// cancelExecution from returning while the change is applied, so the
+// transaction is never rolled back under it.
+func (handler *Handler) changeState(uuid string, change func() error) error {
+	handler.RLock()
+	defer handler.RUnlock()
+	if !handler.isTransaction[uuid] {
+		return fmt.Errorf("Execution of transaction %s cancelled", uuid)
+	}
+	return change()
+}
+
+func (handler *Handler) putRangeQueryIterator(iterID string, rangeIter *rangeQueryIterator) {
+	handler.Lock()
+	defer handler.Unlock()
+	handler.rangeQueryIteratorMap[iterID] = rangeIter
+}
+
+func (handler *Handler) getRangeQueryIterator(iterID string) *rangeQueryIterator {
+	handler.Lock()
+	defer handler.Unlock()
+	return handler.rangeQueryIteratorMap[iterID]
+}
+
+// closeRangeQueryIterator closes the iterator iterID unless it is already closed
+func (handler *Handler) closeRangeQueryIterator(iterID string) {
+	handler.Lock()
+	defer handler.Unlock()
+	if rangeIter, ok := handler.rangeQueryIteratorMap[iterID]; ok {
+		rangeIter.Close()
+		delete(handler.rangeQueryIteratorMap, iterID)
+	}
+}
+
+// closeTxRangeQueryIterators closes the iterators opened for the transaction or
+// query uuid. The handler lock must be held.
+func (handler *Handler) closeTxRangeQueryIterators(uuid string) {
+	for iterID, rangeIter := range handler.rangeQueryIteratorMap {
+		if rangeIter.uuid == uuid {
+			rangeIter.Close()
+			delete(handler.rangeQueryIteratorMap, iterID)
+		}
+	}
+}
+
+// closeRangeQueryIterators releases all the iterators left open by the chaincode
+func (handler *Handler) closeRangeQueryIterators() {
+	handler.Lock()
+	defer handler.Unlock()
+	for iterID, rangeIter := range handler.rangeQueryIteratorMap {
+		rangeIter.Close()
+		delete(handler.rangeQueryIteratorMap, iterID)
+	}
+}
+
 func (handler *Handler) notifyDuringStartup(val bool) {
 	//if USER_RUNS_CC readyNotify will be nil
 	if handler.readyNotify != nil {
@@ -344,6 +455,191 @@ func (handler *Handler) handleGetState(msg *pb.ChaincodeMessage) {
 	}()
 }
 
+// afterRangeQueryState handles a RANGE_QUERY_STATE request from the chaincode.
+func (handler *Handler) afterRangeQueryState(e *fsm.Event, state string) {
+	msg, ok := e.Args[0].(*pb.ChaincodeMessage)
+	if !ok {
+		e.Cancel(fmt.Errorf("Received unexpected message type"))
+		return
+	}
+	chaincodeLogger.Debug("Received %s, invoking get state from ledger", pb.ChaincodeMessage_RANGE_QUERY_STATE)
+
+	// Query ledger for state
+	defer handler.handleRangeQueryState(msg)
+	chaincodeLogger.Debug("Exiting RANGE_QUERY_STATE")
+}
+
+// sendRangeQueryStateError sends an ERROR in response to a range query request from the chaincode.
+func (handler *Handler) sendRangeQueryStateError(msg *pb.ChaincodeMessage, err error) {
+	payload := []byte(err.Error())
+	chaincodeLogger.Debug("Failed to handle %s. Sending %s", msg.Type.String(), pb.ChaincodeMessage_ERROR)
+	// Remove uuid from current set
+	handler.deleteUUIDEntry(msg.Uuid)
+	errMsg := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+	handler.ChatStream.Send(errMsg)
+}
+
+// sendRangeQueryStateResponse reads up to maxRangeQueryStateLimit key-values from the iterator and sends them
+// to the chaincode. HasMore is only set if the iterator has a key-value left after them. The iterator is closed
+// and forgotten once it is exhausted.
+func (handler *Handler) sendRangeQueryStateResponse(msg *pb.ChaincodeMessage, iterID string, rangeIter *rangeQueryIterator) {
+	var keysAndValues []*pb.RangeQueryStateKeyValue
+	hasMore := rangeIter.pending || rangeIter.Next()
+	for hasMore && len(keysAndValues) < maxRangeQueryStateLimit {
+		key, value := rangeIter.GetKeyValue()
+		keysAndValues = append(keysAndValues, &pb.RangeQueryStateKeyValue{Key: key, Value: value})
+		hasMore = rangeIter.Next()
+	}
+	rangeIter.pending = hasMore
+	if !hasMore {
+		handler.closeRangeQueryIterator(iterID)
+	}
+
+	payload := &pb.RangeQueryStateResponse{KeysAndValues: keysAndValues, HasMore: hasMore, ID: iterID}
+	payloadBytes, err := proto.Marshal(payload)
+	if err != nil {
+		handler.closeRangeQueryIterator(iterID)
+		handler.sendRangeQueryStateError(msg, fmt.Errorf("Failed to marshal response: %s", err))
+		return
+	}
+
+	chaincodeLogger.Debug("Got keys and values. Sending %s", pb.ChaincodeMessage_RESPONSE)
+	// Remove uuid from current set
+	handler.deleteUUIDEntry(msg.Uuid)
+	responseMsg := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_RESPONSE, Payload: payloadBytes, Uuid: msg.Uuid}
+	handler.ChatStream.Send(responseMsg)
+}
+
+// Handles query to ledger for a range of state
+func (handler *Handler) handleRangeQueryState(msg *pb.ChaincodeMessage) {
+	// The defer followed by triggering a go routine dance is needed to ensure that the previous state transition
+	// is completed before the next one is triggered. The previous state transition is deemed complete only when
+	// the afterRangeQueryState function is exited. Interesting bug fix!!
+	go func() {
+		// Check if this is the unique state request from this chaincode uuid
+		uniqueReq := handler.createUUIDEntry(msg.Uuid)
+		if !uniqueReq {
+			// Drop this request
+			chaincodeLogger.Debug("Another state request pending for this Uuid. Cannot process.")
+			return
+		}
+
+		rangeQueryState := &pb.RangeQueryState{}
+		unmarshalErr := proto.Unmarshal(msg.Payload, rangeQueryState)
+		if unmarshalErr != nil {
+			handler.sendRangeQueryStateError(msg, unmarshalErr)
+			return
+		}
+
+		ledgerObj, ledgerErr := ledger.GetLedger()
+		if ledgerErr != nil {
+			handler.sendRangeQueryStateError(msg, ledgerErr)
+			return
+		}
+
+		iterID, err := util.GenerateUUID()
+		if err != nil {
+			handler.sendRangeQueryStateError(msg, err)
+			return
+		}
+
+		// Invoke ledger to get the range of state
+		chaincodeID := handler.ChaincodeID.Name
+		// ToDo: Eventually, once consensus is plugged in, we need to set bool to true for ledger
+		rangeIter, err := ledgerObj.GetStateRangeScanIterator(chaincodeID, rangeQueryState.StartKey, rangeQueryState.EndKey, false)
+		if err != nil {
+			handler.sendRangeQueryStateError(msg, err)
+			return
+		}
+		iter := &rangeQueryIterator{RangeScanIterator: rangeIter, uuid: msg.Uuid}
+		handler.putRangeQueryIterator(iterID, iter)
+		handler.sendRangeQueryStateResponse(msg, iterID, iter)
+	}()
+}
+
+// afterRangeQueryStateNext handles a RANGE_QUERY_STATE_NEXT request from the chaincode.
+func (handler *Handler) afterRangeQueryStateNext(e *fsm.Event, state string) {
+	msg, ok := e.Args[0].(*pb.ChaincodeMessage)
+	if !ok {
+		e.Cancel(fmt.Errorf("Received unexpected message type"))
+		return
+	}
+	chaincodeLogger.Debug("Received %s, invoking query state next from ledger", pb.ChaincodeMessage_RANGE_QUERY_STATE_NEXT)
+
+	// Query ledger for state
+	defer handler.handleRangeQueryStateNext(msg)
+	chaincodeLogger.Debug("Exiting RANGE_QUERY_STATE_NEXT")
+}
+
+// Handles query to ledger for the next batch of a range query
+func (handler *Handler) handleRangeQueryStateNext(msg *pb.ChaincodeMessage) {
+	go func() {
+		// Check if this is the unique state request from this chaincode uuid
+		uniqueReq := handler.createUUIDEntry(msg.Uuid)
+		if !uniqueReq {
+			// Drop this request
+			chaincodeLogger.Debug("Another state request pending for this Uuid. Cannot process.")
+			return
+		}
+
+		rangeQueryStateNext := &pb.RangeQueryStateNext{}
+		unmarshalErr := proto.Unmarshal(msg.Payload, rangeQueryStateNext)
+		if unmarshalErr != nil {
+			handler.sendRangeQueryStateError(msg, unmarshalErr)
+			return
+		}
+
+		rangeIter := handler.getRangeQueryIterator(rangeQueryStateNext.ID)
+		if rangeIter == nil {
+			handler.sendRangeQueryStateError(msg, fmt.Errorf("Range query iterator %s not found", rangeQueryStateNext.ID))
+			return
+		}
+		handler.sendRangeQueryStateResponse(msg, rangeQueryStateNext.ID, rangeIter)
+	}()
+}
+
+// afterRangeQueryStateClose handles a RANGE_QUERY_STATE_CLOSE request from the chaincode.
+func (handler *Handler) afterRangeQueryStateClose(e *fsm.Event, state string) {
+	msg, ok := e.Args[0].(*pb.ChaincodeMessage)
+	if !ok {
+		e.Cancel(fmt.Errorf("Received unexpected message type"))
+		return
+	}
+	chaincodeLogger.Debug("Received %s, invoking query state close on ledger", pb.ChaincodeMessage_RANGE_QUERY_STATE_CLOSE)
+
+	defer handler.handleRangeQueryStateClose(msg)
+	chaincodeLogger.Debug("Exiting RANGE_QUERY_STATE_CLOSE")
+}
+
+// Handles the closing of a range query iterator
+func (handler *Handler) handleRangeQueryStateClose(msg *pb.ChaincodeMessage) {
+	go func() {
+		// Check if this is the unique state request from this chaincode uuid
+		uniqueReq := handler.createUUIDEntry(msg.Uuid)
+		if !uniqueReq {
+			// Drop this request
+			chaincodeLogger.Debug("Another state request pending for this Uuid. Cannot process.")
+			return
+		}
+
+		rangeQueryStateClose := &pb.RangeQueryStateClose{}
+		unmarshalErr := proto.Unmarshal(msg.Payload, rangeQueryStateClose)
+		if unmarshalErr != nil {
+			handler.sendRangeQueryStateError(msg, unmarshalErr)
+			return
+		}
+
+		// The iterator is already gone if it was exhausted
+		handler.closeRangeQueryIterator(rangeQueryStateClose.ID)
+
+		chaincodeLogger.Debug("Closed range query iterator. Sending %s", pb.ChaincodeMessage_RESPONSE)
+		// Remove uuid from current set
+		handler.deleteUUIDEntry(msg.Uuid)
+		responseMsg := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_RESPONSE, Uuid: msg.Uuid}
+		handler.ChatStream.Send(responseMsg)
+	}()
+}
+
 // afterPutState handles a PUT_STATE request from the chaincode.
 func (handler *Handler) afterPutState(e *fsm.Event, state string) {
 	_, ok := e.Args[0].(*pb.ChaincodeMessage)
@@ -447,11 +743,15 @@ func (handler *Handler) enterBusyState(e *fsm.Event, state string) {
 			}
 
 			// Invoke ledger to put state
-			err = ledgerObj.SetState(chaincodeID, putStateInfo.Key, putStateInfo.Value)
+			err = handler.changeState(msg.Uuid, func() error {
+				return ledgerObj.SetState(chaincodeID, putStateInfo.Key, putStateInfo.Value)
+			})
 		} else if msg.Type.String() == pb.ChaincodeMessage_DEL_STATE.String() {
 			// Invoke ledger to delete state
 			key := string(msg.Payload)
-			err = ledgerObj.DeleteState(chaincodeID, key)
+			err = handler.changeState(msg.Uuid, func() error {
+				return ledgerObj.DeleteState(chaincodeID, key)
+			})
 		} else if msg.Type.String() == pb.ChaincodeMessage_INVOKE_CHAINCODE.String() {
 			chaincodeSpec := &pb.ChaincodeSpec{}
 			unmarshalErr := proto.Unmarshal(msg.Payload, chaincodeSpec)
@@ -494,15 +794,17 @@ func (handler *Handler) enterBusyState(e *fsm.Event, state string) {
 				return
 			}
 
-			// TODO: Need to handle timeout correctly
-			timeout := time.Duration(30000) * time.Millisecond
+			// The calling transaction cancels the invocation when its own timeout expires
+			timeout := handler.chaincodeSupport.ccMaxExecuteTimeout
 
 			ccMsg, _ := createTransactionMessage(transaction.Uuid, chaincodeInput)
 
 			// Execute the chaincode
 			response, execErr := handler.chaincodeSupport.Execute(context.Background(), newChaincodeID, ccMsg, timeout)
 			err = execErr
-			res = response.Payload
+			if execErr == nil {
+				res = response.Payload
+			}
 		}
 
 		if err != nil {
@@ -678,8 +980,8 @@ func (handler *Handler) handleQueryChaincode(msg *pb.ChaincodeMessage) {
 			return
 		}
 
-		// TODO: Need to handle timeout correctly
-		timeout := time.Duration(30000) * time.Millisecond
+		// The calling transaction or query cancels the query when its own timeout expires
+		timeout := handler.chaincodeSupport.ccMaxExecuteTimeout
 
 		ccMsg, _ := createQueryMessage(transaction.Uuid, chaincodeInput)
 
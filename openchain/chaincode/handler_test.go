@@ -0,0 +1,148 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package chaincode
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+	pb "github.com/openblockchain/obc-peer/protos"
+)
+
+// mockChaincodeStream records the messages sent to the chaincode
+type mockChaincodeStream struct {
+	sent []*pb.ChaincodeMessage
+}
+
+func (stream *mockChaincodeStream) Send(msg *pb.ChaincodeMessage) error {
+	stream.sent = append(stream.sent, msg)
+	return nil
+}
+
+func (stream *mockChaincodeStream) Recv() (*pb.ChaincodeMessage, error) {
+	return nil, fmt.Errorf("Not supported by the mock stream")
+}
+
+// closeRecordingIterator records whether the iterator was closed
+type closeRecordingIterator struct {
+	statemgmt.RangeScanIterator
+	closed bool
+}
+
+func (itr *closeRecordingIterator) Close() {
+	itr.closed = true
+	itr.RangeScanIterator.Close()
+}
+
+func newTestRangeQueryIterator(keyCount int, uuid string) *rangeQueryIterator {
+	delta := statemgmt.NewStateDelta()
+	for i := 0; i < keyCount; i++ {
+		delta.Set("chaincode1", fmt.Sprintf("key%03d", i), []byte("value"), nil)
+	}
+	rangeIter := &closeRecordingIterator{RangeScanIterator: statemgmt.NewStateDeltaRangeScanIterator(delta, "chaincode1", "", "")}
+	return &rangeQueryIterator{RangeScanIterator: rangeIter, uuid: uuid}
+}
+
+func newTestHandler() (*Handler, *mockChaincodeStream) {
+	stream := &mockChaincodeStream{}
+	handler := &Handler{
+		ChatStream:            stream,
+		uuidMap:               make(map[string]bool),
+		isTransaction:         make(map[string]bool),
+		rangeQueryIteratorMap: make(map[string]*rangeQueryIterator),
+	}
+	return handler, stream
+}
+
+func lastRangeQueryStateResponse(t *testing.T, stream *mockChaincodeStream) *pb.RangeQueryStateResponse {
+	msg := stream.sent[len(stream.sent)-1]
+	if msg.Type != pb.ChaincodeMessage_RESPONSE {
+		t.Fatalf("Expected %s, got %s", pb.ChaincodeMessage_RESPONSE, msg.Type)
+	}
+	response := &pb.RangeQueryStateResponse{}
+	if err := proto.Unmarshal(msg.Payload, response); err != nil {
+		t.Fatalf("Failed to unmarshal the response: %s", err)
+	}
+	return response
+}
+
+func TestRangeQueryStateResponseLimit(t *testing.T) {
+	for _, keyCount := range []int{0, 1, maxRangeQueryStateLimit, maxRangeQueryStateLimit + 1, 2 * maxRangeQueryStateLimit} {
+		handler, stream := newTestHandler()
+		rangeIter := newTestRangeQueryIterator(keyCount, "uuid1")
+		handler.putRangeQueryIterator("iter1", rangeIter)
+		msg := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_RANGE_QUERY_STATE, Uuid: "uuid1"}
+
+		var keys []string
+		for {
+			handler.sendRangeQueryStateResponse(msg, "iter1", rangeIter)
+			response := lastRangeQueryStateResponse(t, stream)
+			if len(response.KeysAndValues) > maxRangeQueryStateLimit {
+				t.Fatalf("Expected at most %d key-values per response, got %d", maxRangeQueryStateLimit, len(response.KeysAndValues))
+			}
+			if response.HasMore && len(response.KeysAndValues) == 0 {
+				t.Fatalf("Expected no more key-values after an empty response for %d keys", keyCount)
+			}
+			for _, kv := range response.KeysAndValues {
+				keys = append(keys, kv.Key)
+			}
+			if !response.HasMore {
+				break
+			}
+		}
+
+		if len(keys) != keyCount {
+			t.Errorf("Expected %d keys, got %d", keyCount, len(keys))
+		}
+		for i, key := range keys {
+			if key != fmt.Sprintf("key%03d", i) {
+				t.Errorf("Expected key%03d, got %s", i, key)
+				break
+			}
+		}
+		if handler.getRangeQueryIterator("iter1") != nil || !rangeIter.RangeScanIterator.(*closeRecordingIterator).closed {
+			t.Errorf("Expected the exhausted iterator to be closed for %d keys", keyCount)
+		}
+	}
+}
+
+func TestRangeQueryIteratorsClosedWithTransaction(t *testing.T) {
+	handler, _ := newTestHandler()
+	iter1 := newTestRangeQueryIterator(2*maxRangeQueryStateLimit, "uuid1")
+	iter2 := newTestRangeQueryIterator(2*maxRangeQueryStateLimit, "uuid2")
+	handler.putRangeQueryIterator("iter1", iter1)
+	handler.putRangeQueryIterator("iter2", iter2)
+	handler.markIsTransaction("uuid1", true)
+
+	handler.deleteIsTransaction("uuid1")
+	if handler.getRangeQueryIterator("iter1") != nil || !iter1.RangeScanIterator.(*closeRecordingIterator).closed {
+		t.Errorf("Expected the iterator of the finished transaction to be closed")
+	}
+	if handler.getRangeQueryIterator("iter2") == nil || iter2.RangeScanIterator.(*closeRecordingIterator).closed {
+		t.Errorf("Expected the iterator of another transaction to be kept")
+	}
+
+	handler.cancelExecution("uuid2")
+	if handler.getRangeQueryIterator("iter2") != nil || !iter2.RangeScanIterator.(*closeRecordingIterator).closed {
+		t.Errorf("Expected the iterator of the cancelled transaction to be closed")
+	}
+}
@@ -0,0 +1,215 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package chaincode
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/openblockchain/obc-peer/openchain/ledger"
+	pb "github.com/openblockchain/obc-peer/protos"
+)
+
+// The registry of the deployed chaincodes is kept in the ledger state, under
+// a namespace reserved to the validators. The entries are keyed by the name
+// of the chaincode and are part of the state hash like any other state.
+const registryNamespace = "obc_chaincode_registry"
+
+// checkChaincodeName verifies that the chaincode does not use the namespace of
+// the registry, which would let it overwrite the registry entries
+func checkChaincodeName(chaincode string) error {
+	if chaincode == registryNamespace {
+		return fmt.Errorf("chaincode name %s is reserved", chaincode)
+	}
+	return nil
+}
+
+// getChaincodeRegistryEntry returns the registry entry of the chaincode, nil if
+// no chaincode with that name was deployed
+func getChaincodeRegistryEntry(ledger *ledger.Ledger, chaincode string) (*pb.ChaincodeRegistryEntry, error) {
+	value, err := ledger.GetState(registryNamespace, chaincode, false)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalChaincodeRegistryEntry(chaincode, value)
+}
+
+// getChaincodeVersion returns the version of the code installed for the
+// chaincode, 0 if no chaincode with that name was deployed
+func getChaincodeVersion(ledger *ledger.Ledger, chaincode string) (uint32, error) {
+	entry, err := getChaincodeRegistryEntry(ledger, chaincode)
+	if err != nil || entry == nil {
+		return 0, err
+	}
+	return uint32(len(entry.Versions)), nil
+}
+
+// GetChaincodeRegistryEntry returns the committed registry entry of the
+// chaincode, nil if no chaincode with that name was deployed
+func GetChaincodeRegistryEntry(ledger *ledger.Ledger, chaincode string) (*pb.ChaincodeRegistryEntry, error) {
+	value, err := ledger.GetState(registryNamespace, chaincode, true)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalChaincodeRegistryEntry(chaincode, value)
+}
+
+// GetChaincodeRegistry returns the committed registry entries of all the
+// chaincodes ever deployed, terminated ones included, ordered by name
+func GetChaincodeRegistry(ledger *ledger.Ledger) (*pb.ChaincodeRegistry, error) {
+	iter, err := ledger.GetStateRangeScanIterator(registryNamespace, "", "", true)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	registry := &pb.ChaincodeRegistry{}
+	for iter.Next() {
+		chaincode, value := iter.GetKeyValue()
+		entry, err := unmarshalChaincodeRegistryEntry(chaincode, value)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			registry.Entries = append(registry.Entries, entry)
+		}
+	}
+	return registry, nil
+}
+
+func unmarshalChaincodeRegistryEntry(chaincode string, value []byte) (*pb.ChaincodeRegistryEntry, error) {
+	if value == nil {
+		return nil, nil
+	}
+	entry := &pb.ChaincodeRegistryEntry{}
+	if err := proto.Unmarshal(value, entry); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling registry entry of %s: %s", chaincode, err)
+	}
+	return entry, nil
+}
+
+func putChaincodeRegistryEntry(ledger *ledger.Ledger, entry *pb.ChaincodeRegistryEntry) error {
+	value, err := proto.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("Error marshalling registry entry of %s: %s", entry.Name, err)
+	}
+	return ledger.SetState(registryNamespace, entry.Name, value)
+}
+
+// checkChaincodeUpdate verifies that the update transaction t replaces the code
+// of a deployed chaincode
+func checkChaincodeUpdate(ledger *ledger.Ledger, t *pb.Transaction) error {
+	cds := &pb.ChaincodeDeploymentSpec{}
+	if err := proto.Unmarshal(t.Payload, cds); err != nil {
+		return err
+	}
+	cID := cds.GetChaincodeSpec().GetChaincodeID()
+	if cID == nil || cID.Name == "" || t.ChaincodeID == nil || cID.Name != t.ChaincodeID.Name {
+		return fmt.Errorf("chaincode to update not named by transaction %s", t.Uuid)
+	}
+	chaincode := cID.Name
+	if err := checkChaincodeName(chaincode); err != nil {
+		return err
+	}
+	entry, err := getChaincodeRegistryEntry(ledger, chaincode)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return fmt.Errorf("chaincode %s not deployed", chaincode)
+	}
+	if entry.Status == pb.ChaincodeRegistryEntry_TERMINATED {
+		return fmt.Errorf("chaincode %s terminated", chaincode)
+	}
+	return nil
+}
+
+// checkChaincodeActive verifies that the chaincode has not been terminated.
+// Chaincodes missing from the registry are considered active.
+func checkChaincodeActive(ledger *ledger.Ledger, chaincode string) error {
+	entry, err := getChaincodeRegistryEntry(ledger, chaincode)
+	if err != nil {
+		return err
+	}
+	if entry != nil && entry.Status == pb.ChaincodeRegistryEntry_TERMINATED {
+		return fmt.Errorf("chaincode %s terminated by transaction %s", chaincode, entry.TerminationUuid)
+	}
+	return nil
+}
+
+// terminateChaincode marks the chaincode terminated by t in the registry. The
+// state of the chaincode is left untouched. It must be called while t is in
+// progress so that the registry entry is rolled back with t.
+func terminateChaincode(ledger *ledger.Ledger, t *pb.Transaction) (*pb.ChaincodeID, error) {
+	ci := &pb.ChaincodeInvocationSpec{}
+	if err := proto.Unmarshal(t.Payload, ci); err != nil {
+		return nil, err
+	}
+	cID := ci.GetChaincodeSpec().GetChaincodeID()
+	if cID == nil || cID.Name == "" {
+		return nil, fmt.Errorf("chaincode name not set")
+	}
+
+	entry, err := getChaincodeRegistryEntry(ledger, cID.Name)
+	if err != nil {
+		return cID, err
+	}
+	if entry == nil {
+		return cID, fmt.Errorf("chaincode %s not deployed", cID.Name)
+	}
+	if entry.Status == pb.ChaincodeRegistryEntry_TERMINATED {
+		return cID, fmt.Errorf("chaincode %s already terminated", cID.Name)
+	}
+	entry.Status = pb.ChaincodeRegistryEntry_TERMINATED
+	entry.TerminationUuid = t.Uuid
+	chaincodeLog.Debug("terminating chaincode %s (tx:%s)", cID.Name, t.Uuid)
+
+	return cID, putChaincodeRegistryEntry(ledger, entry)
+}
+
+// registerChaincodeVersion records the code installed by t, a deploy or an update
+// transaction, as the next version of the chaincode. A deploy starts the history
+// of the chaincode afresh, recording its type, deployer and block. It must be
+// called while t is in progress so that the registry entry is rolled back with t.
+func registerChaincodeVersion(ledger *ledger.Ledger, t *pb.Transaction) error {
+	cds := &pb.ChaincodeDeploymentSpec{}
+	if err := proto.Unmarshal(t.Payload, cds); err != nil {
+		return err
+	}
+	cID := cds.GetChaincodeSpec().GetChaincodeID()
+	if cID == nil || cID.Name == "" {
+		return fmt.Errorf("chaincode name not set")
+	}
+
+	entry, err := getChaincodeRegistryEntry(ledger, cID.Name)
+	if err != nil {
+		return err
+	}
+	if entry == nil || t.Type == pb.Transaction_CHAINCODE_NEW {
+		// t is part of the block that follows the last committed one
+		entry = &pb.ChaincodeRegistryEntry{Name: cID.Name, Type: cds.ChaincodeSpec.Type, DeployerCert: t.Cert, DeployBlock: ledger.GetBlockchainSize()}
+	}
+	version := &pb.ChaincodeVersion{Version: uint32(len(entry.Versions) + 1), Uuid: t.Uuid, Path: cID.Path, Timestamp: t.Timestamp, Timeout: cds.ChaincodeSpec.Timeout}
+	entry.Versions = append(entry.Versions, version)
+	chaincodeLog.Debug("registering version %d of chaincode %s (tx:%s)", version.Version, cID.Name, t.Uuid)
+
+	return putChaincodeRegistryEntry(ledger, entry)
+}
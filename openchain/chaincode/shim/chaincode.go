@@ -20,6 +20,7 @@ under the License.
 package shim
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -30,6 +31,7 @@ import (
 
 	"github.com/golang/protobuf/proto"
 	"github.com/op/go-logging"
+	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
 	pb "github.com/openblockchain/obc-peer/protos"
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
@@ -55,7 +57,32 @@ type Chaincode interface {
 
 // ChaincodeStub for shim side handling.
 type ChaincodeStub struct {
-	UUID string
+	UUID            string
+	backend         StubBackend
+	chaincodeEvent  *pb.ChaincodeEvent
+	securityContext *pb.ChaincodeSecurityContext
+}
+
+// StubBackend serves the state and chaincode invocation requests of a
+// ChaincodeStub for a single transaction or query. Chaincodes started with
+// Start are served by the validating peer; tests can drive a Chaincode against
+// another backend, such as the in-memory one of package shimtest.
+type StubBackend interface {
+	GetState(key string) ([]byte, error)
+	PutState(key string, value []byte) error
+	DelState(key string) error
+	RangeQueryState(startKey, endKey string) (*pb.RangeQueryStateResponse, error)
+	RangeQueryStateNext(id string) (*pb.RangeQueryStateResponse, error)
+	RangeQueryStateClose(id string) error
+	InvokeChaincode(chaincodeName string, function string, args []string) ([]byte, error)
+	QueryChaincode(chaincodeName string, function string, args []string) ([]byte, error)
+}
+
+// NewChaincodeStub returns a stub for the transaction or query uuid whose
+// requests are served by backend. The security context may be nil when the
+// transaction was submitted without security enabled.
+func NewChaincodeStub(uuid string, backend StubBackend, securityContext *pb.ChaincodeSecurityContext) *ChaincodeStub {
+	return &ChaincodeStub{UUID: uuid, backend: backend, securityContext: securityContext}
 }
 
 // Start entry point for chaincodes bootstrap.
@@ -185,25 +212,149 @@ func chatWithPeer(chaincodeSupportClient pb.ChaincodeSupportClient, cc Chaincode
 
 // GetState function can be invoked by a chaincode to get a state from the ledger.
 func (stub *ChaincodeStub) GetState(key string) ([]byte, error) {
-	return handler.handleGetState(key, stub.UUID)
+	return stub.backend.GetState(key)
 }
 
 // PutState function can be invoked by a chaincode to put state into the ledger.
 func (stub *ChaincodeStub) PutState(key string, value []byte) error {
-	return handler.handlePutState(key, value, stub.UUID)
+	return stub.backend.PutState(key, value)
 }
 
 // DelState function can be invoked by a chaincode to del state from the ledger.
 func (stub *ChaincodeStub) DelState(key string) error {
-	return handler.handleDelState(key, stub.UUID)
+	return stub.backend.DelState(key)
+}
+
+// StateRangeQueryIterator allows a chaincode to iterate over a range of
+// key/value pairs in the state.
+type StateRangeQueryIterator struct {
+	backend    StubBackend
+	response   *pb.RangeQueryStateResponse
+	currentLoc int
+}
+
+// RangeQueryState function can be invoked by a chaincode to query of a range
+// of keys in the state. An iterator is returned that can be used to iterate
+// over all the keys between startKey and endKey, inclusive, in lexical order.
+// An empty startKey (or endKey) leaves that end of the range open, so an empty
+// endKey iterates to the last key of the chaincode. The iterator sees the
+// changes made by the current transaction that are not yet committed. Close
+// must be called on the iterator when it is not read to the end.
+func (stub *ChaincodeStub) RangeQueryState(startKey, endKey string) (*StateRangeQueryIterator, error) {
+	response, err := stub.backend.RangeQueryState(startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	return &StateRangeQueryIterator{stub.backend, response, 0}, nil
+}
+
+// HasNext returns true if the range query iterator contains additional keys
+// and values.
+func (iter *StateRangeQueryIterator) HasNext() bool {
+	if iter.currentLoc < len(iter.response.KeysAndValues) || iter.response.HasMore {
+		return true
+	}
+	return false
+}
+
+// Next returns the next key and value in the range query iterator.
+func (iter *StateRangeQueryIterator) Next() (string, []byte, error) {
+	if iter.currentLoc < len(iter.response.KeysAndValues) {
+		keyValue := iter.response.KeysAndValues[iter.currentLoc]
+		iter.currentLoc++
+		return keyValue.Key, keyValue.Value, nil
+	}
+	if !iter.response.HasMore {
+		return "", nil, errors.New("No such key")
+	}
+	response, err := iter.backend.RangeQueryStateNext(iter.response.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	iter.currentLoc = 0
+	iter.response = response
+	if len(iter.response.KeysAndValues) == 0 {
+		return "", nil, errors.New("No such key")
+	}
+	keyValue := iter.response.KeysAndValues[iter.currentLoc]
+	iter.currentLoc++
+	return keyValue.Key, keyValue.Value, nil
+}
+
+// Close closes the range query iterator. This should be called when done
+// reading from the iterator to free up resources on the validator.
+func (iter *StateRangeQueryIterator) Close() error {
+	if !iter.response.HasMore {
+		// the validator has already released the iterator
+		return nil
+	}
+	return iter.backend.RangeQueryStateClose(iter.response.ID)
+}
+
+// SetEvent function can be invoked by a chaincode to set an event to be sent
+// to the event hub consumers once the transaction is committed. Only one event
+// is kept per transaction, calling SetEvent again replaces it. Events set while
+// initializing the chaincode or executing a query are discarded.
+func (stub *ChaincodeStub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("Event name can not be empty")
+	}
+	stub.chaincodeEvent = &pb.ChaincodeEvent{EventName: name, Payload: payload}
+	return nil
+}
+
+// GetEvent returns the event set through SetEvent by the current transaction,
+// or nil if none was set.
+func (stub *ChaincodeStub) GetEvent() *pb.ChaincodeEvent {
+	return stub.chaincodeEvent
 }
 
 // InvokeChaincode function can be invoked by a chaincode to execute another chaincode.
 func (stub *ChaincodeStub) InvokeChaincode(chaincodeName string, function string, args []string) ([]byte, error) {
-	return handler.handleInvokeChaincode(chaincodeName, function, args, stub.UUID)
+	return stub.backend.InvokeChaincode(chaincodeName, function, args)
 }
 
 // QueryChaincode function can be invoked by a chaincode to query another chaincode.
 func (stub *ChaincodeStub) QueryChaincode(chaincodeName string, function string, args []string) ([]byte, error) {
-	return handler.handleQueryChaincode(chaincodeName, function, args, stub.UUID)
+	return stub.backend.QueryChaincode(chaincodeName, function, args)
+}
+
+// GetCallerCertificate returns the DER encoded transaction certificate of the
+// caller of the current transaction or query. It fails if the transaction was
+// submitted without security enabled.
+func (stub *ChaincodeStub) GetCallerCertificate() ([]byte, error) {
+	if stub.securityContext == nil || stub.securityContext.CallerCert == nil {
+		return nil, errors.New("Caller certificate not available")
+	}
+	return stub.securityContext.CallerCert, nil
+}
+
+// ReadCertAttribute returns the value of the named attribute embedded in the
+// certificate of the caller. The attribute can only be read if the caller
+// revealed its key when submitting the transaction.
+func (stub *ChaincodeStub) ReadCertAttribute(name string) ([]byte, error) {
+	raw, err := stub.GetCallerCertificate()
+	if err != nil {
+		return nil, err
+	}
+	cert, err := utils.DERToX509Certificate(raw)
+	if err != nil {
+		return nil, fmt.Errorf("Failed parsing caller certificate: %s", err)
+	}
+	ct, err := utils.GetTCertAttribute(cert, name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed reading attribute %s: %s", name, err)
+	}
+
+	for _, attributeKey := range stub.securityContext.AttributeKeys {
+		if attributeKey.Name == name {
+			value, err := utils.CBCPKCS7Decrypt(attributeKey.Key, ct)
+			if err != nil {
+				return nil, fmt.Errorf("Failed decrypting attribute %s: %s", name, err)
+			}
+			return value, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Key of attribute %s not revealed by the caller", name)
 }
@@ -168,8 +168,7 @@ func (handler *Handler) handleInit(msg *pb.ChaincodeMessage) {
 
 		// Call chaincode's Run
 		// Create the ChaincodeStub which the chaincode can use to callback
-		stub := new(ChaincodeStub)
-		stub.UUID = msg.Uuid
+		stub := NewChaincodeStub(msg.Uuid, &peerStubBackend{handler, msg.Uuid}, msg.SecurityContext)
 		res, err := handler.cc.Run(stub, input.Function, input.Args)
 		if err != nil {
 			payload := []byte(err.Error())
@@ -231,8 +230,7 @@ func (handler *Handler) handleTransaction(msg *pb.ChaincodeMessage) {
 
 		// Call chaincode's Run
 		// Create the ChaincodeStub which the chaincode can use to callback
-		stub := new(ChaincodeStub)
-		stub.UUID = msg.Uuid
+		stub := NewChaincodeStub(msg.Uuid, &peerStubBackend{handler, msg.Uuid}, msg.SecurityContext)
 		res, err := handler.cc.Run(stub, input.Function, input.Args)
 		if err != nil {
 			payload := []byte(err.Error())
@@ -249,7 +247,7 @@ func (handler *Handler) handleTransaction(msg *pb.ChaincodeMessage) {
 
 		// Send COMPLETED message to chaincode support and change state
 		chaincodeLogger.Debug("Transaction completed. Sending %s", pb.ChaincodeMessage_COMPLETED)
-		completedMsg := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_COMPLETED, Payload: res, Uuid: msg.Uuid}
+		completedMsg := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_COMPLETED, Payload: res, Uuid: msg.Uuid, ChaincodeEvent: stub.chaincodeEvent}
 		handler.FSM.Event(completedMsg.Type.String(), completedMsg)
 		//there's still a timing window .... user could send another transaction
 		//before the state transitions to ready.... so we really have to do the send
@@ -279,8 +277,7 @@ func (handler *Handler) handleQuery(msg *pb.ChaincodeMessage) {
 
 		// Call chaincode's Query
 		// Create the ChaincodeStub which the chaincode can use to callback
-		stub := new(ChaincodeStub)
-		stub.UUID = msg.Uuid
+		stub := NewChaincodeStub(msg.Uuid, &peerStubBackend{handler, msg.Uuid}, msg.SecurityContext)
 		res, err := handler.cc.Query(stub, input.Function, input.Args)
 		if err != nil {
 			payload := []byte(err.Error())
@@ -424,6 +421,90 @@ func (handler *Handler) handleGetState(key string, uuid string) ([]byte, error)
 	return nil, errors.New("Incorrect chaincode message received")
 }
 
+// handleRangeQueryState communicates with the validator to fetch a range of keys and values from the state in the ledger.
+func (handler *Handler) handleRangeQueryState(startKey, endKey string, uuid string) (*pb.RangeQueryStateResponse, error) {
+	payload := &pb.RangeQueryState{StartKey: startKey, EndKey: endKey}
+	responsePayload, err := handler.sendRangeQueryMessage(pb.ChaincodeMessage_RANGE_QUERY_STATE, payload, uuid)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalRangeQueryResponse(responsePayload)
+}
+
+// handleRangeQueryStateNext communicates with the validator to fetch the next batch of keys and values of an open range query.
+func (handler *Handler) handleRangeQueryStateNext(id string, uuid string) (*pb.RangeQueryStateResponse, error) {
+	payload := &pb.RangeQueryStateNext{ID: id}
+	responsePayload, err := handler.sendRangeQueryMessage(pb.ChaincodeMessage_RANGE_QUERY_STATE_NEXT, payload, uuid)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalRangeQueryResponse(responsePayload)
+}
+
+// handleRangeQueryStateClose communicates with the validator to release an open range query on the validator.
+func (handler *Handler) handleRangeQueryStateClose(id string, uuid string) error {
+	payload := &pb.RangeQueryStateClose{ID: id}
+	_, err := handler.sendRangeQueryMessage(pb.ChaincodeMessage_RANGE_QUERY_STATE_CLOSE, payload, uuid)
+	return err
+}
+
+// sendRangeQueryMessage sends a range query message of type msgType to the validator and waits for the payload of its response.
+func (handler *Handler) sendRangeQueryMessage(msgType pb.ChaincodeMessage_Type, payload proto.Message, uuid string) ([]byte, error) {
+	// Create the channel on which to communicate the response from validating peer
+	uniqueReqErr := handler.createChannel(uuid)
+	if uniqueReqErr != nil {
+		chaincodeLogger.Debug("Another state request pending for this Uuid. Cannot process.")
+		return nil, uniqueReqErr
+	}
+
+	payloadBytes, err := proto.Marshal(payload)
+	if err != nil {
+		handler.deleteChannel(uuid)
+		return nil, fmt.Errorf("Failed to process %s request", msgType)
+	}
+
+	// Send the range query message to validator chaincode support
+	msg := &pb.ChaincodeMessage{Type: msgType, Payload: payloadBytes, Uuid: uuid}
+	handler.ChatStream.Send(msg)
+	chaincodeLogger.Debug("Sending %s", msgType)
+
+	// Wait on responseChannel for response
+	responseMsg, ok := <-handler.responseChannel[uuid]
+	if !ok {
+		chaincodeLogger.Debug("Received unexpected message type")
+		handler.deleteChannel(uuid)
+		return nil, errors.New("Received unexpected message type")
+	}
+
+	if responseMsg.Type.String() == pb.ChaincodeMessage_RESPONSE.String() {
+		// Success response
+		chaincodeLogger.Debug("Received %s. Successfully processed %s", pb.ChaincodeMessage_RESPONSE, msgType)
+		handler.deleteChannel(uuid)
+		return responseMsg.Payload, nil
+	}
+	if responseMsg.Type.String() == pb.ChaincodeMessage_ERROR.String() {
+		// Error response
+		chaincodeLogger.Debug("Received %s. Payload: %s", pb.ChaincodeMessage_ERROR, responseMsg.Payload)
+		handler.deleteChannel(uuid)
+		return nil, errors.New(string(responseMsg.Payload[:]))
+	}
+
+	// Incorrect chaincode message received
+	chaincodeLogger.Debug("Incorrect chaincode message %s recieved. Expecting %s or %s", responseMsg.Type, pb.ChaincodeMessage_RESPONSE, pb.ChaincodeMessage_ERROR)
+	handler.deleteChannel(uuid)
+	return nil, errors.New("Incorrect chaincode message received")
+}
+
+func unmarshalRangeQueryResponse(payload []byte) (*pb.RangeQueryStateResponse, error) {
+	rangeQueryResponse := &pb.RangeQueryStateResponse{}
+	unmarshalErr := proto.Unmarshal(payload, rangeQueryResponse)
+	if unmarshalErr != nil {
+		chaincodeLogger.Error(fmt.Sprintf("Failed to unmarshal range query response: %s", unmarshalErr))
+		return nil, errors.New("Error unmarshalling RangeQueryStateResponse")
+	}
+	return rangeQueryResponse, nil
+}
+
 // handlePutState communicates with the validator to put state information into the ledger.
 func (handler *Handler) handlePutState(key string, value []byte, uuid string) error {
 	// Check if this is a transaction
@@ -627,6 +708,45 @@ func (handler *Handler) handleQueryChaincode(chaincodeName string, function stri
 	return nil, errors.New("Incorrect chaincode message received")
 }
 
+// peerStubBackend serves the requests of a ChaincodeStub by forwarding them
+// to the validating peer as part of the transaction or query uuid.
+type peerStubBackend struct {
+	handler *Handler
+	uuid    string
+}
+
+func (b *peerStubBackend) GetState(key string) ([]byte, error) {
+	return b.handler.handleGetState(key, b.uuid)
+}
+
+func (b *peerStubBackend) PutState(key string, value []byte) error {
+	return b.handler.handlePutState(key, value, b.uuid)
+}
+
+func (b *peerStubBackend) DelState(key string) error {
+	return b.handler.handleDelState(key, b.uuid)
+}
+
+func (b *peerStubBackend) RangeQueryState(startKey, endKey string) (*pb.RangeQueryStateResponse, error) {
+	return b.handler.handleRangeQueryState(startKey, endKey, b.uuid)
+}
+
+func (b *peerStubBackend) RangeQueryStateNext(id string) (*pb.RangeQueryStateResponse, error) {
+	return b.handler.handleRangeQueryStateNext(id, b.uuid)
+}
+
+func (b *peerStubBackend) RangeQueryStateClose(id string) error {
+	return b.handler.handleRangeQueryStateClose(id, b.uuid)
+}
+
+func (b *peerStubBackend) InvokeChaincode(chaincodeName string, function string, args []string) ([]byte, error) {
+	return b.handler.handleInvokeChaincode(chaincodeName, function, args, b.uuid)
+}
+
+func (b *peerStubBackend) QueryChaincode(chaincodeName string, function string, args []string) ([]byte, error) {
+	return b.handler.handleQueryChaincode(chaincodeName, function, args, b.uuid)
+}
+
 // handleMessage message handles loop for shim side of chaincode/validator stream.
 func (handler *Handler) handleMessage(msg *pb.ChaincodeMessage) error {
 	chaincodeLogger.Debug("Handling ChaincodeMessage of type: %s(state:%s)", msg.Type, handler.FSM.Current())
@@ -0,0 +1,241 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+// Package shimtest provides an in-memory stand-in for the validating peer so
+// that a shim.Chaincode can be unit tested with plain go test, without
+// deploying it.
+package shimtest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/op/go-logging"
+	"github.com/openblockchain/obc-peer/openchain/chaincode/shim"
+	pb "github.com/openblockchain/obc-peer/protos"
+)
+
+var mockLogger = logging.MustGetLogger("mock")
+
+// MockStub executes the Run and Query functions of a chaincode against an
+// in-memory state. The changes made by a transaction are only committed to
+// State when Run returns without error, as on the validating peer. A MockStub
+// is not safe for concurrent use.
+type MockStub struct {
+	// Name of the chaincode, used in log messages.
+	Name string
+
+	// State holds the committed state of the chaincode. Tests can seed it
+	// before running the chaincode and inspect it afterwards.
+	State map[string][]byte
+
+	// SecurityContext is handed to the chaincode with every transaction and
+	// query. It is nil unless set, as for transactions submitted without
+	// security enabled.
+	SecurityContext *pb.ChaincodeSecurityContext
+
+	// Event is the event set by the last committed transaction, nil if none.
+	Event *pb.ChaincodeEvent
+
+	cc         shim.Chaincode
+	invokables map[string]*MockStub
+}
+
+// NewMockStub returns a MockStub with an empty state for the chaincode cc.
+func NewMockStub(name string, cc shim.Chaincode) *MockStub {
+	return &MockStub{
+		Name:       name,
+		State:      make(map[string][]byte),
+		cc:         cc,
+		invokables: make(map[string]*MockStub),
+	}
+}
+
+// RegisterChaincode makes the mock chaincode other available to the chaincode
+// of this stub under chaincodeName for InvokeChaincode and QueryChaincode.
+func (stub *MockStub) RegisterChaincode(chaincodeName string, other *MockStub) {
+	stub.invokables[chaincodeName] = other
+}
+
+// MockRun calls Run of the chaincode as transaction uuid, which is also how
+// the chaincode is initialized at deploy time. The state changes of the
+// transaction, including those made by invoked chaincodes, are committed if
+// Run succeeds and discarded otherwise.
+func (stub *MockStub) MockRun(uuid string, function string, args []string) ([]byte, error) {
+	tx := &mockTx{uuid: uuid, writes: make(map[*MockStub]map[string][]byte)}
+	ccStub := shim.NewChaincodeStub(uuid, &mockBackend{stub, tx, false}, stub.SecurityContext)
+	res, err := stub.cc.Run(ccStub, function, args)
+	if err != nil {
+		mockLogger.Debug("Transaction %s of %s failed, rolling back: %s", uuid, stub.Name, err)
+		return nil, err
+	}
+	tx.commit()
+	stub.Event = ccStub.GetEvent()
+	return res, nil
+}
+
+// MockQuery calls Query of the chaincode against the committed state. The
+// chaincode can not change the state while querying.
+func (stub *MockStub) MockQuery(function string, args []string) ([]byte, error) {
+	ccStub := shim.NewChaincodeStub("", &mockBackend{stub, nil, true}, stub.SecurityContext)
+	return stub.cc.Query(ccStub, function, args)
+}
+
+// mockTx buffers the state changes of a transaction per chaincode. A nil
+// value marks a deleted key.
+type mockTx struct {
+	uuid   string
+	writes map[*MockStub]map[string][]byte
+}
+
+func (tx *mockTx) commit() {
+	for stub, writes := range tx.writes {
+		for key, value := range writes {
+			if value == nil {
+				delete(stub.State, key)
+			} else {
+				stub.State[key] = value
+			}
+		}
+	}
+}
+
+// mockBackend serves the requests of the chaincode of stub. Reads see the
+// uncommitted changes of tx, if any; the query context of the validating peer
+// is modeled by readOnly.
+type mockBackend struct {
+	stub     *MockStub
+	tx       *mockTx
+	readOnly bool
+}
+
+func (b *mockBackend) GetState(key string) ([]byte, error) {
+	if b.tx != nil {
+		if value, ok := b.tx.writes[b.stub][key]; ok {
+			return value, nil
+		}
+	}
+	return b.stub.State[key], nil
+}
+
+func (b *mockBackend) PutState(key string, value []byte) error {
+	if b.readOnly {
+		return errors.New("Cannot put state in query context")
+	}
+	// Copy the value, nil is reserved for deleted keys
+	b.write(key, append([]byte{}, value...))
+	return nil
+}
+
+func (b *mockBackend) DelState(key string) error {
+	if b.readOnly {
+		return errors.New("Cannot del state in query context")
+	}
+	b.write(key, nil)
+	return nil
+}
+
+func (b *mockBackend) write(key string, value []byte) {
+	writes, ok := b.tx.writes[b.stub]
+	if !ok {
+		writes = make(map[string][]byte)
+		b.tx.writes[b.stub] = writes
+	}
+	writes[key] = value
+}
+
+// RangeQueryState returns all the keys of the range at once, so the iterator
+// never asks for a next batch.
+func (b *mockBackend) RangeQueryState(startKey, endKey string) (*pb.RangeQueryStateResponse, error) {
+	inRange := func(key string) bool {
+		return (startKey == "" || key >= startKey) && (endKey == "" || key <= endKey)
+	}
+	var keys []string
+	for key := range b.stub.State {
+		if inRange(key) {
+			keys = append(keys, key)
+		}
+	}
+	if b.tx != nil {
+		for key, value := range b.tx.writes[b.stub] {
+			if _, ok := b.stub.State[key]; !ok && value != nil && inRange(key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	response := &pb.RangeQueryStateResponse{}
+	for _, key := range keys {
+		value, _ := b.GetState(key)
+		if value == nil {
+			// deleted by the transaction
+			continue
+		}
+		response.KeysAndValues = append(response.KeysAndValues, &pb.RangeQueryStateKeyValue{Key: key, Value: value})
+	}
+	return response, nil
+}
+
+func (b *mockBackend) RangeQueryStateNext(id string) (*pb.RangeQueryStateResponse, error) {
+	return nil, fmt.Errorf("Range query iterator %s not found", id)
+}
+
+func (b *mockBackend) RangeQueryStateClose(id string) error {
+	return fmt.Errorf("Range query iterator %s not found", id)
+}
+
+// InvokeChaincode runs the registered chaincode as part of the current
+// transaction, its changes are committed or discarded together with those of
+// the caller.
+func (b *mockBackend) InvokeChaincode(chaincodeName string, function string, args []string) ([]byte, error) {
+	if b.readOnly {
+		return nil, errors.New("Cannot invoke chaincode in query context")
+	}
+	other, err := b.lookup(chaincodeName)
+	if err != nil {
+		return nil, err
+	}
+	ccStub := shim.NewChaincodeStub(b.tx.uuid, &mockBackend{other, b.tx, false}, b.stub.SecurityContext)
+	return other.cc.Run(ccStub, function, args)
+}
+
+// QueryChaincode queries the registered chaincode, which sees the uncommitted
+// changes of the current transaction.
+func (b *mockBackend) QueryChaincode(chaincodeName string, function string, args []string) ([]byte, error) {
+	other, err := b.lookup(chaincodeName)
+	if err != nil {
+		return nil, err
+	}
+	uuid := ""
+	if b.tx != nil {
+		uuid = b.tx.uuid
+	}
+	ccStub := shim.NewChaincodeStub(uuid, &mockBackend{other, b.tx, true}, b.stub.SecurityContext)
+	return other.cc.Query(ccStub, function, args)
+}
+
+func (b *mockBackend) lookup(chaincodeName string) (*MockStub, error) {
+	other, ok := b.stub.invokables[chaincodeName]
+	if !ok {
+		return nil, fmt.Errorf("Chaincode %s not registered with mock stub %s", chaincodeName, b.stub.Name)
+	}
+	return other, nil
+}
@@ -0,0 +1,237 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package shimtest
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/openblockchain/obc-peer/openchain/chaincode/shim"
+)
+
+// assetChaincode keeps integer balances and moves them between accounts.
+type assetChaincode struct {
+}
+
+func (t *assetChaincode) Run(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	switch function {
+	case "init":
+		for i := 0; i+1 < len(args); i += 2 {
+			if err := stub.PutState(args[i], []byte(args[i+1])); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case "transfer":
+		if len(args) != 3 {
+			return nil, errors.New("Incorrect number of arguments. Expecting 3")
+		}
+		amount, _ := strconv.Atoi(args[2])
+		from, err := t.balance(stub, args[0])
+		if err != nil {
+			return nil, err
+		}
+		to, err := t.balance(stub, args[1])
+		if err != nil {
+			return nil, err
+		}
+		// Write the debit before checking the balance so that a failed
+		// transfer leaves changes to roll back
+		if err = stub.PutState(args[0], []byte(strconv.Itoa(from-amount))); err != nil {
+			return nil, err
+		}
+		if from < amount {
+			return nil, errors.New("Insufficient funds")
+		}
+		if err = stub.PutState(args[1], []byte(strconv.Itoa(to+amount))); err != nil {
+			return nil, err
+		}
+		return nil, stub.SetEvent("transfer", []byte(args[2]))
+	case "delete":
+		return nil, stub.DelState(args[0])
+	}
+	return nil, errors.New("Unknown function " + function)
+}
+
+func (t *assetChaincode) Query(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	switch function {
+	case "query":
+		return stub.GetState(args[0])
+	case "put":
+		return nil, stub.PutState(args[0], []byte(args[1]))
+	case "list":
+		iter, err := stub.RangeQueryState(args[0], args[1])
+		if err != nil {
+			return nil, err
+		}
+		defer iter.Close()
+		var keys string
+		for iter.HasNext() {
+			key, _, err := iter.Next()
+			if err != nil {
+				return nil, err
+			}
+			keys += key
+		}
+		return []byte(keys), nil
+	}
+	return nil, errors.New("Unknown function " + function)
+}
+
+func (t *assetChaincode) balance(stub *shim.ChaincodeStub, account string) (int, error) {
+	value, err := stub.GetState(account)
+	if err != nil {
+		return 0, err
+	}
+	if value == nil {
+		return 0, errors.New("Unknown account " + account)
+	}
+	return strconv.Atoi(string(value))
+}
+
+// proxyChaincode forwards to the chaincode named by its first argument. The
+// transactions of function "fail" forward a transfer and fail afterwards.
+type proxyChaincode struct {
+}
+
+func (t *proxyChaincode) Run(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	if function != "fail" {
+		return stub.InvokeChaincode(args[0], function, args[1:])
+	}
+	if _, err := stub.InvokeChaincode(args[0], "transfer", args[1:]); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("Failed after invocation")
+}
+
+func (t *proxyChaincode) Query(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	return stub.QueryChaincode(args[0], function, args[1:])
+}
+
+func checkState(t *testing.T, stub *MockStub, key string, expected string) {
+	res, err := stub.MockQuery("query", []string{key})
+	if err != nil {
+		t.Fatalf("Failed querying %s: %s", key, err)
+	}
+	if string(res) != expected {
+		t.Fatalf("Expected %s to be %q, got %q", key, expected, string(res))
+	}
+}
+
+func TestMockRunCommitsState(t *testing.T) {
+	stub := NewMockStub("asset", new(assetChaincode))
+	if _, err := stub.MockRun("1", "init", []string{"a", "100", "b", "200"}); err != nil {
+		t.Fatalf("Failed init: %s", err)
+	}
+	if _, err := stub.MockRun("2", "transfer", []string{"a", "b", "10"}); err != nil {
+		t.Fatalf("Failed transfer: %s", err)
+	}
+	checkState(t, stub, "a", "90")
+	checkState(t, stub, "b", "210")
+	if stub.Event == nil || stub.Event.EventName != "transfer" || string(stub.Event.Payload) != "10" {
+		t.Fatalf("Expected transfer event, got %v", stub.Event)
+	}
+
+	if _, err := stub.MockRun("3", "delete", []string{"a"}); err != nil {
+		t.Fatalf("Failed delete: %s", err)
+	}
+	if _, ok := stub.State["a"]; ok {
+		t.Fatalf("Expected a to be deleted")
+	}
+}
+
+func TestMockRunRollsBackOnError(t *testing.T) {
+	stub := NewMockStub("asset", new(assetChaincode))
+	stub.State["a"] = []byte("100")
+	stub.State["b"] = []byte("200")
+	if _, err := stub.MockRun("1", "transfer", []string{"a", "b", "150"}); err == nil {
+		t.Fatalf("Expected transfer to fail")
+	}
+	checkState(t, stub, "a", "100")
+	checkState(t, stub, "b", "200")
+	if stub.Event != nil {
+		t.Fatalf("Expected no event for failed transaction, got %v", stub.Event)
+	}
+}
+
+func TestMockQueryIsReadOnly(t *testing.T) {
+	stub := NewMockStub("asset", new(assetChaincode))
+	if _, err := stub.MockQuery("put", []string{"a", "1"}); err == nil {
+		t.Fatalf("Expected put state in query to fail")
+	}
+	if len(stub.State) != 0 {
+		t.Fatalf("Expected empty state, got %v", stub.State)
+	}
+}
+
+func TestRangeQueryState(t *testing.T) {
+	stub := NewMockStub("asset", new(assetChaincode))
+	if _, err := stub.MockRun("1", "init", []string{"d", "4", "b", "2", "a", "1", "c", "3"}); err != nil {
+		t.Fatalf("Failed init: %s", err)
+	}
+	for _, test := range []struct{ start, end, expected string }{
+		{"", "", "abcd"},
+		{"b", "c", "bc"},
+		{"b", "", "bcd"},
+		{"", "b", "ab"},
+	} {
+		res, err := stub.MockQuery("list", []string{test.start, test.end})
+		if err != nil {
+			t.Fatalf("Failed range query: %s", err)
+		}
+		if string(res) != test.expected {
+			t.Fatalf("Expected range [%s, %s] to be %s, got %s", test.start, test.end, test.expected, string(res))
+		}
+	}
+}
+
+func TestInvokeChaincode(t *testing.T) {
+	asset := NewMockStub("asset", new(assetChaincode))
+	asset.State["a"] = []byte("100")
+	asset.State["b"] = []byte("200")
+	proxy := NewMockStub("proxy", new(proxyChaincode))
+	proxy.RegisterChaincode("asset", asset)
+
+	if _, err := proxy.MockRun("1", "transfer", []string{"asset", "a", "b", "10"}); err != nil {
+		t.Fatalf("Failed transfer through proxy: %s", err)
+	}
+	checkState(t, asset, "a", "90")
+
+	res, err := proxy.MockQuery("query", []string{"asset", "b"})
+	if err != nil {
+		t.Fatalf("Failed query through proxy: %s", err)
+	}
+	if string(res) != "210" {
+		t.Fatalf("Expected b to be 210, got %s", string(res))
+	}
+
+	// The invoked chaincode succeeds but the caller fails, so the transfer
+	// must be rolled back
+	if _, err = proxy.MockRun("2", "fail", []string{"asset", "a", "b", "10"}); err == nil {
+		t.Fatalf("Expected transfer through proxy to fail")
+	}
+	checkState(t, asset, "a", "90")
+	checkState(t, asset, "b", "210")
+
+	if _, err = proxy.MockRun("3", "transfer", []string{"unknown", "a", "b", "10"}); err == nil {
+		t.Fatalf("Expected invoking an unregistered chaincode to fail")
+	}
+}
@@ -19,7 +19,10 @@ under the License.
 
 package consensus
 
-import pb "github.com/openblockchain/obc-peer/protos"
+import (
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+	pb "github.com/openblockchain/obc-peer/protos"
+)
 
 // Consenter is implemented by every consensus plugin package
 type Consenter interface {
@@ -42,4 +45,14 @@ type CPI interface {
 
 	GetBlock(id uint64) (block *pb.Block, err error)
 	GetCurrentStateHash() (stateHash []byte, err error)
+	GetBlockchainSize() (uint64, error)
+
+	GetRemoteBlocks(replicaID uint64, start, finish uint64) (<-chan *pb.SyncBlocks, error)
+	GetRemoteStateSnapshot(replicaID uint64) (<-chan *pb.SyncStateSnapshot, error)
+	PutBlock(blockNumber uint64, block *pb.Block) error
+	TruncateBlockchain(blockNumber uint64) error
+	ApplyStateSnapshot(id interface{}, snapshot *statemgmt.StateDelta) error
+	ApplyStateDelta(id interface{}, delta *statemgmt.StateDelta) error
+	CommitStateDelta(id interface{}) error
+	RollbackStateDelta(id interface{}) error
 }
@@ -73,6 +73,10 @@ func NewConsensusHandler(coord peer.MessageHandlerCoordinator,
 // HandleMessage handles the incoming Openchain messages for the Peer.
 func (handler *ConsensusHandler) HandleMessage(msg *pb.OpenchainMessage) error {
 	if msg.Type == pb.OpenchainMessage_CONSENSUS {
+		// Only peers whose hello was accepted take part in consensus
+		if _, err := handler.peerHandler.To(); err != nil {
+			return fmt.Errorf("Dropping %s message from unidentified peer: %s", msg.Type, err)
+		}
 		return handler.consenter.RecvMsg(msg)
 	}
 	if msg.Type == pb.OpenchainMessage_CHAIN_TRANSACTION {
@@ -190,3 +194,8 @@ func (handler *ConsensusHandler) To() (pb.PeerEndpoint, error) {
 func (handler *ConsensusHandler) GetBlocks(syncBlockRange *pb.SyncBlockRange) (<-chan *pb.SyncBlocks, error) {
 	return handler.peerHandler.GetBlocks(syncBlockRange)
 }
+
+// GetStateSnapshot returns the state snapshot of the remote Peer by delegating to the contained PeerHandler
+func (handler *ConsensusHandler) GetStateSnapshot() (<-chan *pb.SyncStateSnapshot, error) {
+	return handler.peerHandler.GetStateSnapshot()
+}
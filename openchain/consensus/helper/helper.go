@@ -28,6 +28,7 @@ import (
 	"github.com/openblockchain/obc-peer/openchain/chaincode"
 	"github.com/openblockchain/obc-peer/openchain/consensus"
 	"github.com/openblockchain/obc-peer/openchain/ledger"
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
 	"github.com/openblockchain/obc-peer/openchain/peer"
 	pb "github.com/openblockchain/obc-peer/protos"
 )
@@ -183,3 +184,112 @@ func (h *Helper) GetCurrentStateHash() (stateHash []byte, err error) {
 	}
 	return ledger.GetTempStateHash()
 }
+
+// GetBlockchainSize returns the number of blocks in the local blockchain
+func (h *Helper) GetBlockchainSize() (uint64, error) {
+	ledger, err := ledger.GetLedger()
+	if err != nil {
+		return 0, fmt.Errorf("Failed to get the ledger :%v", err)
+	}
+	return ledger.GetBlockchainSize(), nil
+}
+
+// getRemoteLedger returns the RemoteLedger of the validating peer with the given replica ID
+func (h *Helper) getRemoteLedger(replicaID uint64) (peer.RemoteLedger, error) {
+	_, network, err := h.GetReplicaHash()
+	if err != nil {
+		return nil, err
+	}
+	if replicaID >= uint64(len(network)) {
+		return nil, fmt.Errorf("Replica ID %d out of range, there are %d VPs given in config", replicaID, len(network))
+	}
+	return h.coordinator.GetRemoteLedger(network[replicaID])
+}
+
+// GetRemoteBlocks retrieves the blocks in the range [start, finish] from the
+// validating peer with the given replica ID. If start > finish, the blocks are
+// delivered in reverse order. Blocks that the local chain prunes once it holds
+// the range are retrieved pruned, with only their headers.
+func (h *Helper) GetRemoteBlocks(replicaID uint64, start, finish uint64) (<-chan *pb.SyncBlocks, error) {
+	remoteLedger, err := h.getRemoteLedger(replicaID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get the remote ledger of replica %d: %v", replicaID, err)
+	}
+	ledger, err := ledger.GetLedger()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get the ledger :%v", err)
+	}
+	size := finish + 1
+	if start > finish {
+		size = start + 1
+	}
+	return remoteLedger.GetBlocks(&pb.SyncBlockRange{Start: start, End: finish, PruneBelow: ledger.GetPrunedBlockCount(size)})
+}
+
+// GetRemoteStateSnapshot retrieves the current state snapshot of the
+// validating peer with the given replica ID
+func (h *Helper) GetRemoteStateSnapshot(replicaID uint64) (<-chan *pb.SyncStateSnapshot, error) {
+	remoteLedger, err := h.getRemoteLedger(replicaID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get the remote ledger of replica %d: %v", replicaID, err)
+	}
+	return remoteLedger.GetStateSnapshot()
+}
+
+// PutBlock puts a block retrieved from another validating peer on the local chain
+func (h *Helper) PutBlock(blockNumber uint64, block *pb.Block) error {
+	ledger, err := ledger.GetLedger()
+	if err != nil {
+		return fmt.Errorf("Failed to get the ledger :%v", err)
+	}
+	return ledger.PutRawBlock(block, blockNumber)
+}
+
+// TruncateBlockchain removes the blocks from blockNumber on from the local
+// chain, undoing PutBlock for blocks that could not be used
+func (h *Helper) TruncateBlockchain(blockNumber uint64) error {
+	ledger, err := ledger.GetLedger()
+	if err != nil {
+		return fmt.Errorf("Failed to get the ledger :%v", err)
+	}
+	return ledger.TruncateBlockchain(blockNumber)
+}
+
+// ApplyStateSnapshot applies the state snapshot of another validating peer in
+// place of the local state. This is an in memory change until
+// CommitStateDelta is called.
+func (h *Helper) ApplyStateSnapshot(id interface{}, snapshot *statemgmt.StateDelta) error {
+	ledger, err := ledger.GetLedger()
+	if err != nil {
+		return fmt.Errorf("Failed to get the ledger :%v", err)
+	}
+	return ledger.ApplyStateSnapshot(id, snapshot)
+}
+
+// ApplyStateDelta applies a state delta to the local state. This is an in
+// memory change until CommitStateDelta is called.
+func (h *Helper) ApplyStateDelta(id interface{}, delta *statemgmt.StateDelta) error {
+	ledger, err := ledger.GetLedger()
+	if err != nil {
+		return fmt.Errorf("Failed to get the ledger :%v", err)
+	}
+	return ledger.ApplyStateDelta(id, delta)
+}
+
+// CommitStateDelta persists the state delta passed to ApplyStateDelta
+func (h *Helper) CommitStateDelta(id interface{}) error {
+	ledger, err := ledger.GetLedger()
+	if err != nil {
+		return fmt.Errorf("Failed to get the ledger :%v", err)
+	}
+	return ledger.CommitStateDelta(id)
+}
+
+// RollbackStateDelta discards the state delta passed to ApplyStateDelta
+func (h *Helper) RollbackStateDelta(id interface{}) error {
+	ledger, err := ledger.GetLedger()
+	if err != nil {
+		return fmt.Errorf("Failed to get the ledger :%v", err)
+	}
+	return ledger.RollbackStateDelta(id)
+}
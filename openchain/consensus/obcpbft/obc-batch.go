@@ -35,6 +35,7 @@ import (
 type obcBatch struct {
 	cpi  consensus.CPI
 	pbft *pbftCore
+	sts  *stateTransfer
 
 	batchSize        int
 	batchStore       map[string]*Request
@@ -47,6 +48,7 @@ func newObcBatch(id uint64, config *viper.Viper, cpi consensus.CPI) *obcBatch {
 	var err error
 	op := &obcBatch{cpi: cpi}
 	op.pbft = newPbftCore(id, config, op)
+	op.sts = newStateTransfer(config, cpi)
 	op.batchSize = config.GetInt("general.batchSize")
 	op.batchStore = make(map[string]*Request)
 	op.batchTimeout, err = time.ParseDuration(config.GetString("general.timeout.batch"))
@@ -226,6 +228,19 @@ func (op *obcBatch) getStateHash(blockNumber ...uint64) (stateHash []byte, err e
 	return
 }
 
+// fetch the state of checkpoint seqNo from the given replicas; the transfer
+// runs in the background and reports back to pbft-core once done
+func (op *obcBatch) skipTo(seqNo uint64, stateHash []byte, replicas []uint64) {
+	go func() {
+		if _, err := op.sts.syncToState(stateHash, replicas); err != nil {
+			logger.Error("State transfer to seqNo %d failed: %s", seqNo, err)
+			op.pbft.stateUpdateFailed(seqNo)
+			return
+		}
+		op.pbft.stateUpdated(seqNo, stateHash)
+	}()
+}
+
 // =============================================================================
 // functions specific to batch mode
 // =============================================================================
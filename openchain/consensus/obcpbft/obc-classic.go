@@ -34,11 +34,13 @@ import (
 type obcClassic struct {
 	cpi  consensus.CPI
 	pbft *pbftCore
+	sts  *stateTransfer
 }
 
 func newObcClassic(id uint64, config *viper.Viper, cpi consensus.CPI) *obcClassic {
 	op := &obcClassic{cpi: cpi}
 	op.pbft = newPbftCore(id, config, op)
+	op.sts = newStateTransfer(config, cpi)
 	return op
 }
 
@@ -177,3 +179,16 @@ func (op *obcClassic) getStateHash(blockNumber ...uint64) (stateHash []byte, err
 	}
 	return
 }
+
+// fetch the state of checkpoint seqNo from the given replicas; the transfer
+// runs in the background and reports back to pbft-core once done
+func (op *obcClassic) skipTo(seqNo uint64, stateHash []byte, replicas []uint64) {
+	go func() {
+		if _, err := op.sts.syncToState(stateHash, replicas); err != nil {
+			logger.Error("State transfer to seqNo %d failed: %s", seqNo, err)
+			op.pbft.stateUpdateFailed(seqNo)
+			return
+		}
+		op.pbft.stateUpdated(seqNo, stateHash)
+	}()
+}
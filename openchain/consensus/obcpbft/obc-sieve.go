@@ -35,6 +35,7 @@ import (
 type obcSieve struct {
 	cpi  consensus.CPI
 	pbft *pbftCore
+	sts  *stateTransfer
 
 	id            uint64
 	epoch         uint64
@@ -54,6 +55,7 @@ func newObcSieve(id uint64, config *viper.Viper, cpi consensus.CPI) *obcSieve {
 	op := &obcSieve{cpi: cpi, id: id}
 	op.queuedExec = make(map[uint64]*Execute)
 	op.pbft = newPbftCore(id, config, op)
+	op.sts = newStateTransfer(config, cpi)
 
 	return op
 }
@@ -155,6 +157,33 @@ func (op *obcSieve) getStateHash(blockNumber ...uint64) (stateHash []byte, err e
 	return
 }
 
+// fetch the state of checkpoint seqNo from the given replicas; the transfer
+// runs in the background and reports back to pbft-core once done
+func (op *obcSieve) skipTo(seqNo uint64, stateHash []byte, replicas []uint64) {
+	// the transferred state supersedes the block we are executing
+	if op.currentReq != "" {
+		op.rollback()
+		op.currentReq = ""
+		op.blockNumber--
+	}
+
+	go func() {
+		blockchainSize, err := op.sts.syncToState(stateHash, replicas)
+		if err != nil {
+			logger.Error("State transfer to seqNo %d failed: %s", seqNo, err)
+			op.pbft.stateUpdateFailed(seqNo)
+			return
+		}
+
+		// continue executing after the last transferred block
+		op.pbft.lock.Lock()
+		op.blockNumber = blockchainSize - 1
+		op.pbft.lock.Unlock()
+
+		op.pbft.stateUpdated(seqNo, stateHash)
+	}()
+}
+
 func (op *obcSieve) broadcastMsg(svMsg *SieveMessage) {
 	msgPayload, _ := proto.Marshal(svMsg)
 	ocMsg := &pb.OpenchainMessage{
@@ -232,7 +261,7 @@ func (op *obcSieve) recvExecute(exec *Execute) {
 }
 
 func (op *obcSieve) processExecute() {
-	if op.currentReq != "" {
+	if op.currentReq != "" || op.pbft.skipInProgress {
 		return
 	}
 
@@ -53,6 +53,12 @@ type innerCPI interface {
 	viewChange(curView uint64)
 
 	getStateHash(blockNumber ...uint64) (stateHash []byte, err error)
+
+	// skipTo asks the consumer to bring its state to the one of the
+	// checkpoint seqNo, fetching it from the given replicas. The
+	// consumer reports the outcome through stateUpdated or
+	// stateUpdateFailed.
+	skipTo(seqNo uint64, stateHash []byte, replicas []uint64)
 }
 
 type pbftCore struct {
@@ -77,6 +83,9 @@ type pbftCore struct {
 	pset         map[uint64]*ViewChange_PQ
 	qset         map[qidx]*ViewChange_PQ
 
+	skipInProgress bool                   // state transfer happening
+	hChkpts        map[uint64]*Checkpoint // highest checkpoint above our high watermark, per replica
+
 	newViewTimer       *time.Timer         // timeout triggering a view change
 	timerActive        bool                // is the timer running?
 	requestTimeout     time.Duration       // progress timeout for requests
@@ -156,6 +165,7 @@ func newPbftCore(id uint64, config *viper.Viper, consumer innerCPI) *pbftCore {
 	instance.pset = make(map[uint64]*ViewChange_PQ)
 	instance.qset = make(map[qidx]*ViewChange_PQ)
 	instance.newViewStore = make(map[uint64]*NewView)
+	instance.hChkpts = make(map[uint64]*Checkpoint)
 
 	// load genesis checkpoint
 	stateHash, err := instance.consumer.getStateHash(0)
@@ -561,6 +571,12 @@ func (instance *pbftCore) executeOne(idx msgID) bool {
 		return false
 	}
 
+	if instance.skipInProgress {
+		logger.Debug("Replica %d not executing seqNo=%d while state transfer is in progress",
+			instance.id, idx.n)
+		return false
+	}
+
 	// we now have the right sequence number that doesn't create holes
 
 	digest := cert.prePrepare.RequestDigest
@@ -615,6 +631,9 @@ func (instance *pbftCore) recvCheckpoint(chkpt *Checkpoint) error {
 		instance.id, chkpt.ReplicaId, chkpt.SequenceNumber, chkpt.StateDigest)
 
 	if !instance.inW(chkpt.SequenceNumber) {
+		if chkpt.SequenceNumber > instance.h+instance.L {
+			return instance.witnessCheckpointAboveWatermark(chkpt)
+		}
 		logger.Warning("Checkpoint sequence number outside watermarks: seqNo %d, low-mark %d", chkpt.SequenceNumber, instance.h)
 		return nil
 	}
@@ -642,17 +661,27 @@ func (instance *pbftCore) recvCheckpoint(chkpt *Checkpoint) error {
 	logger.Debug("Replica %d found checkpoint quorum for seqNo %d, digest %s",
 		instance.id, chkpt.SequenceNumber, chkpt.StateDigest)
 
+	instance.moveWatermarks(chkpt.SequenceNumber)
+
+	return instance.processNewView()
+}
+
+// garbage collect the logs up to the stable checkpoint seqNo and move the
+// watermarks accordingly
+func (instance *pbftCore) moveWatermarks(seqNo uint64) {
 	for idx, cert := range instance.certStore {
-		if idx.n <= chkpt.SequenceNumber {
+		if idx.n <= seqNo {
 			logger.Debug("Replica %d cleaning quorum certificate for view=%d/seqNo=%d",
 				instance.id, idx.v, idx.n)
-			delete(instance.reqStore, cert.prePrepare.RequestDigest)
+			if cert.prePrepare != nil {
+				delete(instance.reqStore, cert.prePrepare.RequestDigest)
+			}
 			delete(instance.certStore, idx)
 		}
 	}
 
 	for testChkpt := range instance.checkpointStore {
-		if testChkpt.SequenceNumber <= chkpt.SequenceNumber {
+		if testChkpt.SequenceNumber <= seqNo {
 			logger.Debug("Replica %d cleaning checkpoint message from replica %d, seqNo %d, state digest %s",
 				instance.id, testChkpt.ReplicaId,
 				testChkpt.SequenceNumber, testChkpt.StateDigest)
@@ -661,20 +690,20 @@ func (instance *pbftCore) recvCheckpoint(chkpt *Checkpoint) error {
 	}
 
 	for n := range instance.pset {
-		if n <= chkpt.SequenceNumber {
+		if n <= seqNo {
 			delete(instance.pset, n)
 		}
 	}
 
 	for idx := range instance.qset {
-		if idx.n <= chkpt.SequenceNumber {
+		if idx.n <= seqNo {
 			delete(instance.qset, idx)
 		}
 	}
 
 	instance.h = 0
 	for n := range instance.chkpts {
-		if n < chkpt.SequenceNumber {
+		if n < seqNo {
 			delete(instance.chkpts, n)
 		} else {
 			if instance.h == 0 || n < instance.h {
@@ -683,10 +712,95 @@ func (instance *pbftCore) recvCheckpoint(chkpt *Checkpoint) error {
 		}
 	}
 
+	for replicaID, testChkpt := range instance.hChkpts {
+		if testChkpt.SequenceNumber <= instance.h+instance.L {
+			delete(instance.hChkpts, replicaID)
+		}
+	}
+
 	logger.Debug("Replica %d updated low watermark to %d",
 		instance.id, instance.h)
+}
 
-	return instance.processNewView()
+// =============================================================================
+// state transfer
+// =============================================================================
+
+// witnessCheckpointAboveWatermark tracks checkpoints beyond our high
+// watermark. Once f+1 replicas report the same checkpoint, at least one
+// correct replica has reached it, and we have fallen too far behind to catch
+// up through the normal protocol. We then ask the consumer to fetch the state.
+func (instance *pbftCore) witnessCheckpointAboveWatermark(chkpt *Checkpoint) error {
+	if prevChkpt, ok := instance.hChkpts[chkpt.ReplicaId]; ok && prevChkpt.SequenceNumber >= chkpt.SequenceNumber {
+		return nil
+	}
+	instance.hChkpts[chkpt.ReplicaId] = chkpt
+
+	if instance.skipInProgress {
+		logger.Debug("Replica %d received checkpoint above high watermark for seqNo %d, state transfer already in progress",
+			instance.id, chkpt.SequenceNumber)
+		return nil
+	}
+
+	var replicas []uint64
+	for id := uint64(0); id < uint64(instance.replicaCount); id++ {
+		if testChkpt, ok := instance.hChkpts[id]; ok && testChkpt.SequenceNumber == chkpt.SequenceNumber && testChkpt.StateDigest == chkpt.StateDigest {
+			replicas = append(replicas, id)
+		}
+	}
+
+	if len(replicas) <= instance.f {
+		return nil
+	}
+
+	stateHash, err := base64.StdEncoding.DecodeString(chkpt.StateDigest)
+	if err != nil {
+		return fmt.Errorf("Cannot decode state digest of checkpoint for seqNo %d: %s", chkpt.SequenceNumber, err)
+	}
+
+	logger.Warning("Replica %d found weak checkpoint certificate for seqNo %d above high watermark %d, initiating state transfer",
+		instance.id, chkpt.SequenceNumber, instance.h+instance.L)
+
+	instance.skipInProgress = true
+	instance.stopTimer()
+	instance.consumer.skipTo(chkpt.SequenceNumber, stateHash, replicas)
+
+	return nil
+}
+
+// stateUpdated is called by the consumer once the state transfer to the
+// checkpoint seqNo completed; it resumes consensus from that checkpoint.
+func (instance *pbftCore) stateUpdated(seqNo uint64, stateHash []byte) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+
+	logger.Info("Replica %d completed state transfer to seqNo %d", instance.id, seqNo)
+
+	instance.skipInProgress = false
+	instance.lastExec = seqNo
+	if instance.seqNo < seqNo {
+		instance.seqNo = seqNo
+	}
+	instance.chkpts[seqNo] = base64.StdEncoding.EncodeToString(stateHash)
+	instance.moveWatermarks(seqNo)
+
+	if len(instance.outstandingReqs) > 0 {
+		instance.startTimer(instance.requestTimeout)
+	}
+
+	instance.executeOutstanding()
+}
+
+// stateUpdateFailed is called by the consumer if the state transfer to the
+// checkpoint seqNo did not succeed. A later checkpoint above our high
+// watermark will trigger another attempt.
+func (instance *pbftCore) stateUpdateFailed(seqNo uint64) {
+	instance.lock.Lock()
+	defer instance.lock.Unlock()
+
+	logger.Warning("Replica %d failed state transfer to seqNo %d", instance.id, seqNo)
+
+	instance.skipInProgress = false
 }
 
 // =============================================================================
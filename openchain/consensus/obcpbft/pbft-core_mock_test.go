@@ -26,6 +26,7 @@ import (
 	"sync"
 
 	"github.com/openblockchain/obc-peer/openchain/consensus"
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
 	pb "github.com/openblockchain/obc-peer/protos"
 )
 
@@ -61,6 +62,9 @@ func (mock *mockCPI) getStateHash(blockNumber ...uint64) (stateHash []byte, err
 	return []byte("nil"), nil
 }
 
+func (mock *mockCPI) skipTo(seqNo uint64, stateHash []byte, replicas []uint64) {
+}
+
 // =============================================================================
 // Fake network structures
 // =============================================================================
@@ -100,6 +104,9 @@ type instance struct {
 
 	deliver      func([]byte)
 	execTxResult func([]*pb.Transaction) ([]byte, []error)
+
+	skipSeqNo    uint64
+	skipReplicas []uint64
 }
 
 func (inst *instance) broadcast(payload []byte) {
@@ -125,6 +132,11 @@ func (inst *instance) getStateHash(blockNumber ...uint64) (stateHash []byte, err
 	return []byte("nil"), nil
 }
 
+func (inst *instance) skipTo(seqNo uint64, stateHash []byte, replicas []uint64) {
+	inst.skipSeqNo = seqNo
+	inst.skipReplicas = replicas
+}
+
 func (inst *instance) GetReplicaHash() (self string, network []string, err error) {
 	return inst.addr, inst.net.addresses, nil
 }
@@ -204,6 +216,42 @@ func (inst *instance) GetCurrentStateHash() (stateHash []byte, err error) {
 	return []byte("nil"), nil
 }
 
+func (inst *instance) GetBlockchainSize() (uint64, error) {
+	return uint64(len(inst.blocks)), nil
+}
+
+func (inst *instance) GetRemoteBlocks(replicaID uint64, start, finish uint64) (<-chan *pb.SyncBlocks, error) {
+	return nil, fmt.Errorf("Remote ledgers are not supported by the testnet")
+}
+
+func (inst *instance) GetRemoteStateSnapshot(replicaID uint64) (<-chan *pb.SyncStateSnapshot, error) {
+	return nil, fmt.Errorf("Remote ledgers are not supported by the testnet")
+}
+
+func (inst *instance) PutBlock(blockNumber uint64, block *pb.Block) error {
+	return fmt.Errorf("Remote ledgers are not supported by the testnet")
+}
+
+func (inst *instance) TruncateBlockchain(blockNumber uint64) error {
+	return fmt.Errorf("Remote ledgers are not supported by the testnet")
+}
+
+func (inst *instance) ApplyStateSnapshot(id interface{}, snapshot *statemgmt.StateDelta) error {
+	return fmt.Errorf("Remote ledgers are not supported by the testnet")
+}
+
+func (inst *instance) ApplyStateDelta(id interface{}, delta *statemgmt.StateDelta) error {
+	return fmt.Errorf("Remote ledgers are not supported by the testnet")
+}
+
+func (inst *instance) CommitStateDelta(id interface{}) error {
+	return fmt.Errorf("Remote ledgers are not supported by the testnet")
+}
+
+func (inst *instance) RollbackStateDelta(id interface{}) error {
+	return fmt.Errorf("Remote ledgers are not supported by the testnet")
+}
+
 func (net *testnet) broadcastFilter(inst *instance, payload []byte) {
 	if net.filterFn != nil {
 		payload = net.filterFn(inst.id, -1, payload)
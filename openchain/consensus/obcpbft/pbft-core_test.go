@@ -200,6 +200,81 @@ func TestCheckpoint(t *testing.T) {
 	}
 }
 
+func TestFallBehind(t *testing.T) {
+	net := makeTestnet(1, func(inst *instance) {
+		makeTestnetPbftCore(inst)
+		inst.pbft.K = 2
+		inst.pbft.L = 2 * inst.pbft.K
+	})
+	defer net.close()
+
+	partitioned := true
+	net.filterFn = func(src int, dst int, payload []byte) []byte {
+		if partitioned && dst == 3 {
+			return nil
+		}
+		return payload
+	}
+
+	execReq := func(iter int64) {
+		txTime := &gp.Timestamp{Seconds: iter, Nanos: 0}
+		tx := &pb.Transaction{Type: pb.Transaction_CHAINCODE_NEW, Timestamp: txTime}
+		txPacked, err := proto.Marshal(tx)
+		if err != nil {
+			t.Fatalf("Failed to marshal TX block: %s", err)
+		}
+		msg := &Message{&Message_Request{&Request{Payload: txPacked}}}
+		err = net.replicas[0].pbft.recvMsgSync(msg)
+		if err != nil {
+			t.Fatalf("Request failed: %s", err)
+		}
+
+		err = net.process()
+		if err != nil {
+			t.Fatalf("Processing failed: %s", err)
+		}
+	}
+
+	for i := int64(1); i <= 6; i++ {
+		execReq(i)
+	}
+
+	behind := net.replicas[3]
+	if len(behind.executed) != 0 {
+		t.Fatalf("Expected partitioned replica to execute nothing, executed %d requests", len(behind.executed))
+	}
+
+	partitioned = false
+	execReq(7)
+	execReq(8)
+
+	if behind.skipSeqNo != 8 {
+		t.Fatalf("Expected replica 3 to skip to seqNo 8, got %d", behind.skipSeqNo)
+	}
+	if !reflect.DeepEqual(behind.skipReplicas, []uint64{0, 1}) {
+		t.Fatalf("Expected replica 3 to fetch state from replicas [0 1], got %v", behind.skipReplicas)
+	}
+	if !behind.pbft.skipInProgress {
+		t.Fatalf("Expected replica 3 to have a state transfer in progress")
+	}
+
+	// simulate the stack completing the state transfer
+	behind.executed = append(behind.executed, net.replicas[0].executed...)
+	behind.pbft.stateUpdated(8, []byte("nil"))
+
+	if behind.pbft.h != 8 || behind.pbft.lastExec != 8 {
+		t.Fatalf("Expected replica 3 to be at h=8, lastExec=8, got h=%d, lastExec=%d", behind.pbft.h, behind.pbft.lastExec)
+	}
+
+	execReq(9)
+
+	for _, inst := range net.replicas {
+		if len(inst.executed) != 9 {
+			t.Errorf("Replica %d: expected 9 executed requests, got %d", inst.id, len(inst.executed))
+		}
+	}
+}
+
 func TestLostPrePrepare(t *testing.T) {
 	net := makeTestnet(1, makeTestnetPbftCore)
 	defer net.close()
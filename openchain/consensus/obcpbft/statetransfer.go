@@ -0,0 +1,246 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package obcpbft
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/openblockchain/obc-peer/openchain/consensus"
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+	pb "github.com/openblockchain/obc-peer/protos"
+
+	"github.com/spf13/viper"
+)
+
+// stateTransfer brings the local ledger to the state of a stable checkpoint
+// by fetching the state snapshot and the missing blocks from the replicas
+// that vouched for the checkpoint.
+type stateTransfer struct {
+	cpi     consensus.CPI
+	timeout time.Duration // how long to wait for the next message from a replica
+}
+
+func newStateTransfer(config *viper.Viper, cpi consensus.CPI) *stateTransfer {
+	sts := &stateTransfer{cpi: cpi}
+	var err error
+	sts.timeout, err = time.ParseDuration(config.GetString("general.timeout.statetransfer"))
+	if err != nil {
+		panic(fmt.Errorf("Cannot parse state transfer timeout: %s", err))
+	}
+	return sts
+}
+
+// syncToState tries the replicas in turn until the local state matches
+// stateHash. It returns the resulting blockchain size.
+func (sts *stateTransfer) syncToState(stateHash []byte, replicas []uint64) (uint64, error) {
+	for _, replicaID := range replicas {
+		size, err := sts.syncFromReplica(stateHash, replicaID)
+		if err == nil {
+			return size, nil
+		}
+		logger.Warning("State transfer from replica %d failed: %s", replicaID, err)
+	}
+	return 0, fmt.Errorf("Could not transfer state %s from any of the replicas %v",
+		base64.StdEncoding.EncodeToString(stateHash), replicas)
+}
+
+func (sts *stateTransfer) syncFromReplica(stateHash []byte, replicaID uint64) (uint64, error) {
+	delta, blockchainSize, err := sts.fetchStateSnapshot(replicaID)
+	if err != nil {
+		return 0, err
+	}
+
+	// The snapshot replaces the local state in memory only, the local state
+	// is left untouched unless the snapshot matches the checkpoint
+	id := fmt.Sprintf("statetransfer-%d-%d", replicaID, blockchainSize)
+	if err := sts.cpi.ApplyStateSnapshot(id, delta); err != nil {
+		return 0, fmt.Errorf("Cannot apply the state snapshot: %s", err)
+	}
+
+	// Check the resulting state against the checkpoint before persisting it,
+	// the replica may already have moved past the checkpoint
+	currentStateHash, err := sts.cpi.GetCurrentStateHash()
+	if err != nil {
+		sts.cpi.RollbackStateDelta(id)
+		return 0, fmt.Errorf("Cannot compute the state hash: %s", err)
+	}
+	if !bytes.Equal(currentStateHash, stateHash) {
+		sts.cpi.RollbackStateDelta(id)
+		return 0, fmt.Errorf("State hash %s does not match checkpoint state hash %s",
+			base64.StdEncoding.EncodeToString(currentStateHash), base64.StdEncoding.EncodeToString(stateHash))
+	}
+
+	// Only extend the local chain with blocks of a replica which proved to
+	// have the checkpoint state, and only once they are tied to the checkpoint
+	localSize, blocks, err := sts.fetchBlocks(replicaID, blockchainSize, stateHash)
+	if err != nil {
+		sts.cpi.RollbackStateDelta(id)
+		return 0, err
+	}
+
+	for i, block := range blocks {
+		blockNumber := localSize + uint64(i)
+		if err := sts.cpi.PutBlock(blockNumber, block); err != nil {
+			sts.rollback(id, localSize)
+			return 0, fmt.Errorf("Cannot put block %d: %s", blockNumber, err)
+		}
+	}
+
+	if err := sts.cpi.CommitStateDelta(id); err != nil {
+		sts.rollback(id, localSize)
+		return 0, fmt.Errorf("Cannot commit the state snapshot: %s", err)
+	}
+
+	logger.Info("Transferred state %s and %d blocks from replica %d",
+		base64.StdEncoding.EncodeToString(stateHash), len(blocks), replicaID)
+	return blockchainSize, nil
+}
+
+// rollback discards the state snapshot id and removes the blocks put on the
+// local chain from blockNumber on.
+func (sts *stateTransfer) rollback(id string, blockNumber uint64) {
+	sts.cpi.RollbackStateDelta(id)
+	if err := sts.cpi.TruncateBlockchain(blockNumber); err != nil {
+		logger.Error("Cannot remove the transferred blocks from block %d on: %s", blockNumber, err)
+	}
+}
+
+// fetchStateSnapshot collects the state snapshot of the replica into a single
+// state delta. It also returns the size of the blockchain of the replica at
+// the time of the snapshot.
+func (sts *stateTransfer) fetchStateSnapshot(replicaID uint64) (*statemgmt.StateDelta, uint64, error) {
+	snapshotChan, err := sts.cpi.GetRemoteStateSnapshot(replicaID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	delta := statemgmt.NewStateDelta()
+	for {
+		select {
+		case syncStateSnapshot, ok := <-snapshotChan:
+			if !ok {
+				return nil, 0, fmt.Errorf("State snapshot stream closed before completion")
+			}
+			// The terminating message carries an empty delta
+			if len(syncStateSnapshot.Delta) == 0 {
+				return delta, syncStateSnapshot.BlockNumber, nil
+			}
+			snapshotDelta := statemgmt.NewStateDelta()
+			snapshotDelta.Unmarshal(syncStateSnapshot.Delta)
+			delta.ApplyChanges(snapshotDelta)
+		case <-time.After(sts.timeout):
+			return nil, 0, fmt.Errorf("Timed out waiting for state snapshot")
+		}
+	}
+}
+
+// fetchBlocks fetches the blocks of the replica that are missing from the
+// local chain, up to blockchainSize, and returns them along with the number of
+// the first one. Nothing is put on the local chain: the blocks are only
+// returned once they are tied to the checkpoint. The last block must carry the
+// checkpoint state hash, and going down from it each block must be the
+// previous block of the verified block after it, down to the last local block.
+func (sts *stateTransfer) fetchBlocks(replicaID uint64, blockchainSize uint64, stateHash []byte) (uint64, []*pb.Block, error) {
+	if blockchainSize == 0 {
+		return 0, nil, fmt.Errorf("Replica %d has no blocks", replicaID)
+	}
+	localSize, err := sts.cpi.GetBlockchainSize()
+	if err != nil {
+		return 0, nil, err
+	}
+	if localSize >= blockchainSize {
+		lastBlock, err := sts.cpi.GetBlock(blockchainSize - 1)
+		if err != nil {
+			return 0, nil, fmt.Errorf("Cannot get local block %d: %s", blockchainSize-1, err)
+		}
+		if !bytes.Equal(lastBlock.StateHash, stateHash) {
+			return 0, nil, fmt.Errorf("State hash of local block %d does not match checkpoint state hash", blockchainSize-1)
+		}
+		return localSize, nil, nil
+	}
+
+	blocksChan, err := sts.cpi.GetRemoteBlocks(replicaID, localSize, blockchainSize-1)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var blocks []*pb.Block
+	for next := localSize; next < blockchainSize; {
+		select {
+		case syncBlocks, ok := <-blocksChan:
+			if !ok {
+				return 0, nil, fmt.Errorf("Block stream closed before block %d was received", next)
+			}
+			for i, block := range syncBlocks.Blocks {
+				blockNumber := syncBlocks.Range.Start + uint64(i)
+				if blockNumber != next {
+					return 0, nil, fmt.Errorf("Received block %d, expected block %d", blockNumber, next)
+				}
+				blocks = append(blocks, block)
+				next++
+			}
+		case <-time.After(sts.timeout):
+			return 0, nil, fmt.Errorf("Timed out waiting for block %d", next)
+		}
+	}
+
+	// The hash of a pruned block is the one recorded in its header, so the
+	// block at the checkpoint must be kept for its state hash to be bound
+	// to its hash
+	lastBlock := blocks[len(blocks)-1]
+	if lastBlock.IsPruned() {
+		return 0, nil, fmt.Errorf("Block %d at the checkpoint is pruned", blockchainSize-1)
+	}
+	if !bytes.Equal(lastBlock.StateHash, stateHash) {
+		return 0, nil, fmt.Errorf("State hash of block %d does not match checkpoint state hash", blockchainSize-1)
+	}
+
+	// A pruned header is accepted only through the previous block hash of the
+	// verified block after it
+	for i := len(blocks) - 1; i > 0; i-- {
+		blockNumber := localSize + uint64(i)
+		previousBlockHash, err := blocks[i-1].GetHash()
+		if err != nil {
+			return 0, nil, fmt.Errorf("Cannot hash block %d: %s", blockNumber-1, err)
+		}
+		if !bytes.Equal(blocks[i].PreviousBlockHash, previousBlockHash) {
+			return 0, nil, fmt.Errorf("Previous block hash of block %d does not match block %d", blockNumber, blockNumber-1)
+		}
+	}
+
+	if localSize > 0 {
+		localBlock, err := sts.cpi.GetBlock(localSize - 1)
+		if err != nil {
+			return 0, nil, fmt.Errorf("Cannot get local block %d: %s", localSize-1, err)
+		}
+		localBlockHash, err := localBlock.GetHash()
+		if err != nil {
+			return 0, nil, fmt.Errorf("Cannot hash local block %d: %s", localSize-1, err)
+		}
+		if !bytes.Equal(blocks[0].PreviousBlockHash, localBlockHash) {
+			return 0, nil, fmt.Errorf("Previous block hash of block %d does not match local block %d", localSize, localSize-1)
+		}
+	}
+
+	return localSize, blocks, nil
+}
@@ -0,0 +1,413 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package obcpbft
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+	"github.com/openblockchain/obc-peer/openchain/util"
+	pb "github.com/openblockchain/obc-peer/protos"
+)
+
+// mockLedger is an in-memory ledger which serves its blocks and state to
+// the other mock ledgers it knows as remotes
+type mockLedger struct {
+	*instance
+	blocks       []*pb.Block
+	state        map[string]map[string][]byte
+	pending      *statemgmt.StateDelta
+	remotes      map[uint64]*mockLedger
+	unresponsive bool
+	failPutBlock uint64 // PutBlock fails for this block number, if not 0
+}
+
+func newMockLedger(blockCount int, state map[string]map[string][]byte) *mockLedger {
+	ml := &mockLedger{
+		instance: &instance{},
+		state:    state,
+		remotes:  make(map[uint64]*mockLedger),
+	}
+	var previousBlockHash []byte
+	for i := 0; i < blockCount; i++ {
+		block := &pb.Block{StateHash: []byte(fmt.Sprintf("block%d", i)), PreviousBlockHash: previousBlockHash}
+		previousBlockHash, _ = block.GetHash()
+		ml.blocks = append(ml.blocks, block)
+	}
+	return ml
+}
+
+// newMockReplica returns a mock ledger whose last block carries the hash of
+// its state, as the ledger of a replica at a checkpoint does
+func newMockReplica(blockCount int, state map[string]map[string][]byte) *mockLedger {
+	ml := newMockLedger(blockCount, state)
+	ml.blocks[blockCount-1].StateHash = ml.hashState(state)
+	return ml
+}
+
+func (ml *mockLedger) hashState(state map[string]map[string][]byte) []byte {
+	var chaincodeIDs []string
+	for chaincodeID := range state {
+		chaincodeIDs = append(chaincodeIDs, chaincodeID)
+	}
+	sort.Strings(chaincodeIDs)
+
+	var buffer bytes.Buffer
+	for _, chaincodeID := range chaincodeIDs {
+		var keys []string
+		for key := range state[chaincodeID] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			buffer.WriteString(chaincodeID)
+			buffer.WriteString(key)
+			buffer.Write(state[chaincodeID][key])
+		}
+	}
+	return util.ComputeCryptoHash(buffer.Bytes())
+}
+
+func (ml *mockLedger) applyDelta(state map[string]map[string][]byte, delta *statemgmt.StateDelta) map[string]map[string][]byte {
+	result := make(map[string]map[string][]byte)
+	for chaincodeID, kvs := range state {
+		result[chaincodeID] = make(map[string][]byte)
+		for key, value := range kvs {
+			result[chaincodeID][key] = value
+		}
+	}
+	if delta == nil {
+		return result
+	}
+	for _, chaincodeID := range delta.GetUpdatedChaincodeIds(false) {
+		if _, ok := result[chaincodeID]; !ok {
+			result[chaincodeID] = make(map[string][]byte)
+		}
+		for key, updatedValue := range delta.GetUpdates(chaincodeID) {
+			if updatedValue.IsDelete() {
+				delete(result[chaincodeID], key)
+			} else {
+				result[chaincodeID][key] = updatedValue.GetValue()
+			}
+		}
+	}
+	return result
+}
+
+func (ml *mockLedger) GetCurrentStateHash() ([]byte, error) {
+	return ml.hashState(ml.applyDelta(ml.state, ml.pending)), nil
+}
+
+func (ml *mockLedger) GetBlock(id uint64) (*pb.Block, error) {
+	if id >= uint64(len(ml.blocks)) {
+		return nil, fmt.Errorf("Block %d does not exist", id)
+	}
+	return ml.blocks[id], nil
+}
+
+func (ml *mockLedger) GetBlockchainSize() (uint64, error) {
+	return uint64(len(ml.blocks)), nil
+}
+
+func (ml *mockLedger) GetRemoteBlocks(replicaID uint64, start, finish uint64) (<-chan *pb.SyncBlocks, error) {
+	remote, ok := ml.remotes[replicaID]
+	if !ok {
+		return nil, fmt.Errorf("Unknown replica %d", replicaID)
+	}
+	blocksChan := make(chan *pb.SyncBlocks, finish-start+1)
+	if remote.unresponsive {
+		return blocksChan, nil
+	}
+	for i := start; i <= finish; i++ {
+		blocksChan <- &pb.SyncBlocks{
+			Range:  &pb.SyncBlockRange{Start: i, End: i},
+			Blocks: []*pb.Block{remote.blocks[i]},
+		}
+	}
+	close(blocksChan)
+	return blocksChan, nil
+}
+
+func (ml *mockLedger) GetRemoteStateSnapshot(replicaID uint64) (<-chan *pb.SyncStateSnapshot, error) {
+	remote, ok := ml.remotes[replicaID]
+	if !ok {
+		return nil, fmt.Errorf("Unknown replica %d", replicaID)
+	}
+	snapshotChan := make(chan *pb.SyncStateSnapshot, 100)
+	if remote.unresponsive {
+		return snapshotChan, nil
+	}
+	blockNumber := uint64(len(remote.blocks))
+	for chaincodeID, kvs := range remote.state {
+		for key, value := range kvs {
+			delta := statemgmt.NewStateDelta()
+			delta.Set(chaincodeID, key, value, nil)
+			snapshotChan <- &pb.SyncStateSnapshot{Delta: delta.Marshal(), BlockNumber: blockNumber}
+		}
+	}
+	snapshotChan <- &pb.SyncStateSnapshot{Delta: []byte{}, BlockNumber: blockNumber}
+	close(snapshotChan)
+	return snapshotChan, nil
+}
+
+func (ml *mockLedger) PutBlock(blockNumber uint64, block *pb.Block) error {
+	switch {
+	case blockNumber != 0 && blockNumber == ml.failPutBlock:
+		return fmt.Errorf("Cannot put block %d", blockNumber)
+	case blockNumber < uint64(len(ml.blocks)):
+		ml.blocks[blockNumber] = block
+	case blockNumber == uint64(len(ml.blocks)):
+		ml.blocks = append(ml.blocks, block)
+	default:
+		return fmt.Errorf("Cannot put block %d on a chain of size %d", blockNumber, len(ml.blocks))
+	}
+	return nil
+}
+
+func (ml *mockLedger) TruncateBlockchain(blockNumber uint64) error {
+	if blockNumber < uint64(len(ml.blocks)) {
+		ml.blocks = ml.blocks[:blockNumber]
+	}
+	return nil
+}
+
+func (ml *mockLedger) ApplyStateSnapshot(id interface{}, snapshot *statemgmt.StateDelta) error {
+	delta := statemgmt.NewStateDelta()
+	delta.ApplyChanges(snapshot)
+	for chaincodeID, kvs := range ml.state {
+		for key, value := range kvs {
+			if !snapshot.IsUpdatedValueSet(chaincodeID, key) {
+				delta.Delete(chaincodeID, key, value)
+			}
+		}
+	}
+	ml.pending = delta
+	return nil
+}
+
+func (ml *mockLedger) ApplyStateDelta(id interface{}, delta *statemgmt.StateDelta) error {
+	ml.pending = delta
+	return nil
+}
+
+func (ml *mockLedger) CommitStateDelta(id interface{}) error {
+	ml.state = ml.applyDelta(ml.state, ml.pending)
+	ml.pending = nil
+	return nil
+}
+
+func (ml *mockLedger) RollbackStateDelta(id interface{}) error {
+	ml.pending = nil
+	return nil
+}
+
+func makeTestStateTransfer(ml *mockLedger) *stateTransfer {
+	sts := newStateTransfer(readConfig(), ml)
+	sts.timeout = 100 * time.Millisecond
+	return sts
+}
+
+func TestStateTransfer(t *testing.T) {
+	remoteState := map[string]map[string][]byte{
+		"chaincode1": {"key1": []byte("value1"), "key2": []byte("value2")},
+		"chaincode2": {"key1": []byte("value3")},
+	}
+	remote := newMockReplica(5, remoteState)
+	local := newMockLedger(2, map[string]map[string][]byte{
+		"chaincode1": {"key1": []byte("stale"), "key3": []byte("removed")},
+	})
+	local.remotes[1] = remote
+	stateHash, _ := remote.GetCurrentStateHash()
+
+	size, err := makeTestStateTransfer(local).syncToState(stateHash, []uint64{1})
+	if err != nil {
+		t.Fatalf("State transfer failed: %s", err)
+	}
+	if size != 5 {
+		t.Errorf("Expected blockchain size 5, got %d", size)
+	}
+	if !reflect.DeepEqual(local.blocks, remote.blocks) {
+		t.Errorf("Expected the blocks of the remote replica, got %v", local.blocks)
+	}
+	if !reflect.DeepEqual(local.state, remoteState) {
+		t.Errorf("Expected the state of the remote replica %v, got %v", remoteState, local.state)
+	}
+}
+
+func TestStateTransferFallback(t *testing.T) {
+	remoteState := map[string]map[string][]byte{
+		"chaincode1": {"key1": []byte("value1")},
+	}
+	moved := newMockLedger(4, map[string]map[string][]byte{
+		"chaincode1": {"key1": []byte("newer")},
+	})
+	silent := newMockReplica(3, remoteState)
+	silent.unresponsive = true
+	good := newMockReplica(3, remoteState)
+
+	local := newMockLedger(0, map[string]map[string][]byte{})
+	local.remotes[0] = moved
+	local.remotes[1] = silent
+	local.remotes[2] = good
+	stateHash, _ := good.GetCurrentStateHash()
+
+	sts := makeTestStateTransfer(local)
+	if _, err := sts.syncToState(stateHash, []uint64{0, 1}); err == nil {
+		t.Fatalf("Expected state transfer to fail when no replica has the checkpoint state")
+	}
+	if local.pending != nil {
+		t.Fatalf("Expected mismatching state snapshot to be rolled back")
+	}
+	if len(local.blocks) != 0 {
+		t.Fatalf("Expected no blocks from replicas without the checkpoint state, got %d", len(local.blocks))
+	}
+
+	size, err := sts.syncToState(stateHash, []uint64{0, 1, 2})
+	if err != nil {
+		t.Fatalf("State transfer failed: %s", err)
+	}
+	if size != 3 {
+		t.Errorf("Expected blockchain size 3, got %d", size)
+	}
+	if !reflect.DeepEqual(local.blocks, good.blocks) {
+		t.Errorf("Expected the blocks of replica 2, got %v", local.blocks)
+	}
+	if !reflect.DeepEqual(local.state, remoteState) {
+		t.Errorf("Expected the state of replica 2 %v, got %v", remoteState, local.state)
+	}
+}
+
+func TestStateTransferKeepsLocalState(t *testing.T) {
+	localState := map[string]map[string][]byte{
+		"chaincode1": {"key1": []byte("stale")},
+	}
+	remoteState := map[string]map[string][]byte{
+		"chaincode1": {"key1": []byte("value1")},
+	}
+	moved := newMockLedger(4, map[string]map[string][]byte{
+		"chaincode1": {"key1": []byte("newer")},
+	})
+	forged := newMockReplica(3, remoteState)
+	forged.blocks[1] = &pb.Block{StateHash: []byte("forged"), PreviousBlockHash: []byte("forged")}
+
+	local := newMockLedger(1, localState)
+	local.remotes[0] = moved
+	local.remotes[1] = forged
+	stateHash, _ := forged.GetCurrentStateHash()
+
+	if _, err := makeTestStateTransfer(local).syncToState(stateHash, []uint64{0, 1}); err == nil {
+		t.Fatalf("Expected state transfer to fail from replicas with a different state or unlinked blocks")
+	}
+	if !reflect.DeepEqual(local.state, localState) {
+		t.Errorf("Expected the local state %v to be kept, got %v", localState, local.state)
+	}
+	if local.pending != nil {
+		t.Errorf("Expected the state snapshot to be rolled back")
+	}
+	if len(local.blocks) != 1 {
+		t.Errorf("Expected no block of the replicas to be put on the local chain, got %d blocks", len(local.blocks))
+	}
+}
+
+func TestStateTransferChainNotAtCheckpoint(t *testing.T) {
+	remoteState := map[string]map[string][]byte{
+		"chaincode1": {"key1": []byte("value1")},
+	}
+	remote := newMockLedger(3, remoteState)
+
+	local := newMockLedger(1, map[string]map[string][]byte{})
+	local.remotes[1] = remote
+	stateHash, _ := remote.GetCurrentStateHash()
+
+	if _, err := makeTestStateTransfer(local).syncToState(stateHash, []uint64{1}); err == nil {
+		t.Fatalf("Expected state transfer to fail when the last block does not have the checkpoint state hash")
+	}
+	if local.pending != nil {
+		t.Errorf("Expected the state snapshot to be rolled back")
+	}
+	if len(local.blocks) != 1 {
+		t.Errorf("Expected no block of the replica to be put on the local chain, got %d blocks", len(local.blocks))
+	}
+}
+
+func TestStateTransferPrunedBlocks(t *testing.T) {
+	remoteState := map[string]map[string][]byte{
+		"chaincode1": {"key1": []byte("value1")},
+	}
+	pruned := newMockReplica(5, remoteState)
+	for i := 1; i < 4; i++ {
+		pruned.blocks[i], _ = pruned.blocks[i].Prune()
+	}
+	forged := newMockReplica(5, remoteState)
+	forged.blocks[3], _ = forged.blocks[3].Prune()
+	forged.blocks[3].NonHashData.PrunedBlockHash = []byte("forged")
+	lastPruned := newMockReplica(5, remoteState)
+	lastPruned.blocks[4], _ = lastPruned.blocks[4].Prune()
+
+	local := newMockLedger(1, map[string]map[string][]byte{})
+	local.remotes[0] = forged
+	local.remotes[1] = lastPruned
+	local.remotes[2] = pruned
+	stateHash, _ := pruned.GetCurrentStateHash()
+
+	sts := makeTestStateTransfer(local)
+	if _, err := sts.syncToState(stateHash, []uint64{0, 1}); err == nil {
+		t.Fatalf("Expected state transfer to fail with a forged pruned header or a pruned block at the checkpoint")
+	}
+	if len(local.blocks) != 1 {
+		t.Fatalf("Expected no block of the replicas to be put on the local chain, got %d blocks", len(local.blocks))
+	}
+
+	if _, err := sts.syncToState(stateHash, []uint64{2}); err != nil {
+		t.Fatalf("State transfer failed: %s", err)
+	}
+	if !reflect.DeepEqual(local.blocks[1:], pruned.blocks[1:]) {
+		t.Errorf("Expected the pruned blocks of replica 2, got %v", local.blocks)
+	}
+}
+
+func TestStateTransferRollsBackBlocks(t *testing.T) {
+	localState := map[string]map[string][]byte{
+		"chaincode1": {"key1": []byte("stale")},
+	}
+	remote := newMockReplica(5, map[string]map[string][]byte{
+		"chaincode1": {"key1": []byte("value1")},
+	})
+
+	local := newMockLedger(2, localState)
+	local.remotes[1] = remote
+	local.failPutBlock = 3
+	stateHash, _ := remote.GetCurrentStateHash()
+
+	if _, err := makeTestStateTransfer(local).syncToState(stateHash, []uint64{1}); err == nil {
+		t.Fatalf("Expected state transfer to fail when a block cannot be put")
+	}
+	if len(local.blocks) != 2 {
+		t.Errorf("Expected the blocks put before the failure to be removed, got %d blocks", len(local.blocks))
+	}
+	if !reflect.DeepEqual(local.state, localState) || local.pending != nil {
+		t.Errorf("Expected the local state %v to be kept, got %v", localState, local.state)
+	}
+}
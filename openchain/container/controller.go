@@ -138,7 +138,8 @@ func (vm *dockerVM) stop(ctxt context.Context, id string, timeout uint, dontkill
 
 //constants for supported containers
 const (
-	DOCKER = "Docker"
+	DOCKER  = "Docker"
+	PROCESS = "Process"
 )
 
 type image struct {
@@ -153,7 +154,7 @@ type refCountedLock struct {
 }
 
 //VMController - manages VMs
-//   . abstract construction of different types of VMs (we only care about Docker for now)
+//   . abstract construction of different types of VMs (Docker and local processes)
 //   . manage lifecycle of VM (start with build, start, stop ...
 //     eventually probably need fine grained management)
 type VMController struct {
@@ -179,6 +180,8 @@ func (vmc *VMController) newVM(typ string) vm {
 	switch typ {
 	case DOCKER:
 		v = &dockerVM{}
+	case PROCESS:
+		v = &processVM{}
 	case "":
 		v = &dockerVM{}
 	}
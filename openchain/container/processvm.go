@@ -0,0 +1,279 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package container
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+)
+
+//processVM is a vm. The chaincode is built with the local Go toolchain and runs
+//as a process of the peer host, so no docker daemon is needed. It is identified
+//by the same id as a docker image
+type processVM struct {
+}
+
+//processSpec is saved next to the chaincode binary at build time, it holds
+//what the docker vm keeps in the container config
+type processSpec struct {
+	Args []string
+	Env  []string
+}
+
+//chaincodeProcess is a running chaincode. done is closed when the process exits
+type chaincodeProcess struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+}
+
+//processMap holds the running chaincode processes by id
+type processMap struct {
+	sync.Mutex
+	procs map[string]*chaincodeProcess
+}
+
+var runningProcesses = &processMap{procs: make(map[string]*chaincodeProcess)}
+
+const (
+	processSpecFile = "process.json"
+	processLogFile  = "chaincode.log"
+	goInstallPrefix = "RUN go install "
+)
+
+//getDir returns the directory the chaincode is built in
+func (vm *processVM) getDir(id string) string {
+	root := viper.GetString("vm.process.path")
+	if root == "" {
+		root = filepath.Join(os.TempDir(), "openchain-chaincode")
+	}
+	return filepath.Join(root, strings.Replace(id, ":", "_", -1))
+}
+
+//the reader is the same gzipped tar as for docker. The sources are extracted
+//into a GOPATH and the package installed by the Dockerfile is built
+func (vm *processVM) build(ctxt context.Context, id string, args []string, env []string, attachstdin bool, attachstdout bool, reader io.Reader) error {
+	if len(args) == 0 {
+		return fmt.Errorf("No executable for chaincode %s", id)
+	}
+	dir := vm.getDir(id)
+	gopath := filepath.Join(dir, "gopath")
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("Error cleaning build directory %s: %s", dir, err)
+	}
+	if err := os.MkdirAll(gopath, 0755); err != nil {
+		return fmt.Errorf("Error creating build directory %s: %s", dir, err)
+	}
+
+	pkg, err := extractChaincodePackage(reader, gopath)
+	if err != nil {
+		return err
+	}
+
+	binPath := filepath.Join(dir, filepath.Base(args[0]))
+	cmd := exec.Command("go", "build", "-o", binPath, pkg)
+	cmd.Env = setEnv(setEnv(os.Environ(), "GOPATH", gopath), "GO15VENDOREXPERIMENT", "1")
+	vmLogger.Debug("Building chaincode %s from %s", id, pkg)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("Error building chaincode %s: %s\n%s", id, err, output)
+	}
+
+	spec := processSpec{Args: append([]string{binPath}, args[1:]...), Env: env}
+	specBytes, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, processSpecFile), specBytes, 0644); err != nil {
+		return fmt.Errorf("Error saving chaincode process spec: %s", err)
+	}
+	vmLogger.Debug("Built chaincode %s", binPath)
+	return nil
+}
+
+func (vm *processVM) start(ctxt context.Context, id string, args []string, detach bool, instream io.Reader, outstream io.Writer) error {
+	dir := vm.getDir(id)
+	specBytes, err := ioutil.ReadFile(filepath.Join(dir, processSpecFile))
+	if err != nil {
+		return fmt.Errorf("Chaincode %s has not been built: %s", id, err)
+	}
+	spec := &processSpec{}
+	if err = json.Unmarshal(specBytes, spec); err != nil {
+		return fmt.Errorf("Error reading chaincode process spec: %s", err)
+	}
+
+	runningProcesses.Lock()
+	defer runningProcesses.Unlock()
+	if _, ok := runningProcesses.procs[id]; ok {
+		return fmt.Errorf("Chaincode process %s is already running", id)
+	}
+
+	logFile, err := os.OpenFile(filepath.Join(dir, processLogFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("Error opening chaincode log: %s", err)
+	}
+	cmd := exec.Command(spec.Args[0], spec.Args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), spec.Env...)
+	cmd.Stdin = instream
+	cmd.Stdout = logFile
+	if outstream != nil {
+		cmd.Stdout = io.MultiWriter(logFile, outstream)
+	}
+	cmd.Stderr = logFile
+	if err = cmd.Start(); err != nil {
+		logFile.Close()
+		return fmt.Errorf("Error starting chaincode process %s: %s", id, err)
+	}
+
+	proc := &chaincodeProcess{cmd: cmd, done: make(chan struct{})}
+	runningProcesses.procs[id] = proc
+	//supervise the process so that it can be started again once it exits
+	go func() {
+		err := cmd.Wait()
+		logFile.Close()
+		if err != nil {
+			vmLogger.Info("Chaincode process %s exited: %s", id, err)
+		} else {
+			vmLogger.Debug("Chaincode process %s exited", id)
+		}
+		runningProcesses.Lock()
+		if runningProcesses.procs[id] == proc {
+			delete(runningProcesses.procs, id)
+		}
+		runningProcesses.Unlock()
+		close(proc.done)
+	}()
+	vmLogger.Debug("Started chaincode process %s (pid %d)", id, cmd.Process.Pid)
+	return nil
+}
+
+//stop terminates the process and kills it if it has not exited after timeout
+//seconds. There is no container to remove, the built binary is kept so the
+//chaincode can be started again
+func (vm *processVM) stop(ctxt context.Context, id string, timeout uint, dontkill bool, dontremove bool) error {
+	runningProcesses.Lock()
+	proc, ok := runningProcesses.procs[id]
+	runningProcesses.Unlock()
+	if !ok {
+		vmLogger.Debug("No chaincode process %s to stop", id)
+		return nil
+	}
+
+	if err := proc.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		vmLogger.Debug("Error terminating chaincode process %s (%s)", id, err)
+	}
+	select {
+	case <-proc.done:
+		vmLogger.Debug("Stopped chaincode process %s", id)
+		return nil
+	case <-time.After(time.Duration(timeout) * time.Second):
+	}
+	if dontkill {
+		return fmt.Errorf("Chaincode process %s did not stop within %d seconds", id, timeout)
+	}
+	if err := proc.cmd.Process.Kill(); err != nil {
+		vmLogger.Debug("Error killing chaincode process %s (%s)", id, err)
+	}
+	<-proc.done
+	vmLogger.Debug("Killed chaincode process %s", id)
+	return nil
+}
+
+//extractChaincodePackage writes the sources of the chaincode package under
+//dir and returns the import path of the chaincode taken from the Dockerfile
+func extractChaincodePackage(reader io.Reader, dir string) (string, error) {
+	gr, err := gzip.NewReader(reader)
+	if err != nil {
+		return "", fmt.Errorf("Error reading chaincode package: %s", err)
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+
+	var pkg string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("Error reading chaincode package: %s", err)
+		}
+
+		if header.Name == "Dockerfile" {
+			scanner := bufio.NewScanner(tr)
+			for scanner.Scan() {
+				if line := scanner.Text(); strings.HasPrefix(line, goInstallPrefix) {
+					pkg = strings.Fields(line[len(goInstallPrefix):])[0]
+				}
+			}
+			continue
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		path := filepath.Join(dir, filepath.Clean(header.Name))
+		if !strings.HasPrefix(path, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return "", fmt.Errorf("Invalid path in chaincode package: %s", header.Name)
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", err
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)|0600)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(f, tr)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if pkg == "" {
+		return "", fmt.Errorf("No chaincode to build in the chaincode package")
+	}
+	return pkg, nil
+}
+
+//setEnv sets key to value in env, replacing an existing value
+func setEnv(env []string, key string, value string) []string {
+	result := make([]string, 0, len(env)+1)
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, key+"=") {
+			result = append(result, kv)
+		}
+	}
+	return append(result, key+"="+value)
+}
@@ -0,0 +1,132 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package container
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+)
+
+const testProcessChaincode = `package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+func main() {
+	fmt.Println("started " + os.Getenv("OPENCHAIN_CHAINCODE_ID_NAME"))
+	for {
+		time.Sleep(time.Second)
+	}
+}
+`
+
+func getProcessChaincodeBytes() (io.Reader, error) {
+	inputbuf := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(inputbuf)
+	tw := tar.NewWriter(gw)
+	files := []struct {
+		name     string
+		contents string
+	}{
+		{"src/example.com/processtest/main.go", testProcessChaincode},
+		{"Dockerfile", "from golang:1.5.1\n" + goInstallPrefix + "example.com/processtest && cp openchain.yaml $GOPATH/bin"},
+	}
+	for _, file := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: file.name, Size: int64(len(file.contents)), Mode: 0644}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write([]byte(file.contents)); err != nil {
+			return nil, err
+		}
+	}
+	tw.Close()
+	gw.Close()
+	return inputbuf, nil
+}
+
+func TestProcessVM(t *testing.T) {
+	root, err := ioutil.TempDir("", "processvm")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+	viper.Set("vm.process.path", root)
+
+	var ctxt = context.Background()
+	id := "processtest"
+
+	reader, err := getProcessChaincodeBytes()
+	if err != nil {
+		t.Fatalf("Error creating chaincode package: %s", err)
+	}
+	cir := CreateImageReq{ID: id, Reader: reader, Args: []string{"/go/bin/processtest"}, Env: []string{"OPENCHAIN_CHAINCODE_ID_NAME=" + id}}
+	resp, err := VMCProcess(ctxt, PROCESS, cir)
+	if err != nil || resp.(VMCResp).Err != nil {
+		t.Fatalf("Error building chaincode: %s %v", err, resp)
+	}
+
+	sir := StartImageReq{ID: id, Detach: true}
+	resp, err = VMCProcess(ctxt, PROCESS, sir)
+	if err != nil || resp.(VMCResp).Err != nil {
+		t.Fatalf("Error starting chaincode: %s %v", err, resp)
+	}
+
+	resp, _ = VMCProcess(ctxt, PROCESS, sir)
+	if resp.(VMCResp).Err == nil {
+		t.Errorf("Expected error starting a running chaincode")
+	}
+
+	logPath := filepath.Join(root, id, processLogFile)
+	started := false
+	for i := 0; i < 50 && !started; i++ {
+		output, _ := ioutil.ReadFile(logPath)
+		started = strings.Contains(string(output), "started "+id)
+		time.Sleep(100 * time.Millisecond)
+	}
+	if !started {
+		t.Errorf("Chaincode process did not log its start with the chaincode env")
+	}
+
+	stopr := StopImageReq{ID: id, Timeout: 1}
+	resp, err = VMCProcess(ctxt, PROCESS, stopr)
+	if err != nil || resp.(VMCResp).Err != nil {
+		t.Fatalf("Error stopping chaincode: %s %v", err, resp)
+	}
+	runningProcesses.Lock()
+	_, running := runningProcesses.procs[id]
+	runningProcesses.Unlock()
+	if running {
+		t.Errorf("Expected chaincode process to be stopped")
+	}
+}
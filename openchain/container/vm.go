@@ -124,6 +124,27 @@ func GetChaincodePackageBytes(spec *pb.ChaincodeSpec) ([]byte, error) {
 	return chaincodePkgBytes, nil
 }
 
+// GetChaincodeUpdatePackageBytes creates bytes for docker container generation of the new code of a
+// deployed chaincode. Unlike GetChaincodePackageBytes, the name of the chaincode is kept as the new
+// code takes over the chaincode and its state
+func GetChaincodeUpdatePackageBytes(spec *pb.ChaincodeSpec) ([]byte, error) {
+	if spec == nil || spec.ChaincodeID == nil {
+		return nil, fmt.Errorf("invalid chaincode spec")
+	}
+	if spec.ChaincodeID.Name == "" {
+		return nil, fmt.Errorf("chaincode name not set")
+	}
+
+	//the hashcode generated while packaging the code is discarded. The ctor of an
+	//update is optional but the hashcode needs one
+	pkgSpec := &pb.ChaincodeSpec{Type: spec.Type, ChaincodeID: &pb.ChaincodeID{Path: spec.ChaincodeID.Path}, CtorMsg: spec.CtorMsg}
+	if pkgSpec.CtorMsg == nil || pkgSpec.CtorMsg.Function == "" {
+		pkgSpec.CtorMsg = &pb.ChaincodeInput{Function: "upgrade"}
+	}
+
+	return GetChaincodePackageBytes(pkgSpec)
+}
+
 // Builds the Chaincode image using the supplied Dockerfile package contents
 func (vm *VM) buildChaincodeContainerUsingDockerfilePackageBytes(spec *pb.ChaincodeSpec, code []byte) error {
 	outputbuf := bytes.NewBuffer(nil)
@@ -206,7 +227,7 @@ func writeChaincodePackage(spec *pb.ChaincodeSpec, tw *tar.Writer) error {
 		urlLocation = spec.ChaincodeID.Path
 	}
 
-	newRunLine := fmt.Sprintf("RUN go install %s && cp src/github.com/openblockchain/obc-peer/openchain.yaml $GOPATH/bin", urlLocation)
+	newRunLine := fmt.Sprintf("%s%s && cp src/github.com/openblockchain/obc-peer/openchain.yaml $GOPATH/bin", goInstallPrefix, urlLocation)
 
 	dockerFileContents := fmt.Sprintf("%s\n%s", viper.GetString("chaincode.golang.Dockerfile"), newRunLine)
 	dockerFileSize := int64(len([]byte(dockerFileContents)))
@@ -0,0 +1,139 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package crypto
+
+import (
+	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
+	"sync"
+)
+
+// Private Variables
+
+var (
+	// Map of initialized auditors
+	auditors = make(map[string]Auditor)
+
+	// Sync
+	auditorMutex sync.Mutex
+)
+
+// Public Methods
+
+// RegisterAuditor registers an auditor to the PKI infrastructure
+func RegisterAuditor(name string, pwd []byte, enrollID, enrollPWD string) error {
+	auditorMutex.Lock()
+	defer auditorMutex.Unlock()
+
+	log.Info("Registering auditor [%s] with name [%s]...", enrollID, name)
+
+	if auditors[name] != nil {
+		log.Info("Registering auditor [%s] with name [%s]...done. Already initialized.", enrollID, name)
+
+		return nil
+	}
+
+	auditor := new(auditorImpl)
+	if err := auditor.register(name, pwd, enrollID, enrollPWD); err != nil {
+		if err != utils.ErrAlreadyRegistered && err != utils.ErrAlreadyInitialized {
+			log.Error("Failed registering auditor [%s] with name [%s] [%s].", enrollID, name, err)
+			return err
+		}
+		log.Info("Registering auditor [%s] with name [%s]...done. Already registered or initiliazed.", enrollID, name)
+	}
+	err := auditor.close()
+	if err != nil {
+		// It is not necessary to report this error to the caller
+		log.Warning("Registering auditor [%s] with name [%s]. Failed closing [%s].", enrollID, name, err)
+	}
+
+	log.Info("Registering auditor [%s] with name [%s]...done!", enrollID, name)
+
+	return nil
+}
+
+// InitAuditor initializes an auditor named name with password pwd
+func InitAuditor(name string, pwd []byte) (Auditor, error) {
+	auditorMutex.Lock()
+	defer auditorMutex.Unlock()
+
+	log.Info("Initializing auditor [%s]...", name)
+
+	if auditors[name] != nil {
+		log.Info("Auditor already initiliazied [%s].", name)
+
+		return auditors[name], nil
+	}
+
+	auditor := new(auditorImpl)
+	if err := auditor.init(name, pwd); err != nil {
+		log.Error("Failed auditor initialization [%s]: [%s].", name, err)
+
+		return nil, err
+	}
+
+	auditors[name] = auditor
+	log.Info("Initializing auditor [%s]...done!", name)
+
+	return auditor, nil
+}
+
+// CloseAuditor releases all the resources allocated by auditors
+func CloseAuditor(auditor Auditor) error {
+	auditorMutex.Lock()
+	defer auditorMutex.Unlock()
+
+	return closeAuditorInternal(auditor)
+}
+
+// CloseAllAuditors closes all the auditors initialized so far
+func CloseAllAuditors() (bool, []error) {
+	auditorMutex.Lock()
+	defer auditorMutex.Unlock()
+
+	log.Info("Closing all auditors...")
+
+	errs := make([]error, len(auditors))
+	for _, value := range auditors {
+		err := closeAuditorInternal(value)
+
+		errs = append(errs, err)
+	}
+
+	log.Info("Closing all auditors...done!")
+
+	return len(errs) != 0, errs
+}
+
+// Private Methods
+
+func closeAuditorInternal(auditor Auditor) error {
+	name := auditor.GetName()
+	log.Info("Closing auditor [%s]...", name)
+	if _, ok := auditors[name]; !ok {
+		return utils.ErrInvalidReference
+	}
+	defer delete(auditors, name)
+
+	err := auditors[name].(*auditorImpl).close()
+
+	log.Info("Closing auditor [%s]...done! [%s].", name, utils.ErrToString(err))
+
+	return err
+}
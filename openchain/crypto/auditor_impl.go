@@ -0,0 +1,213 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package crypto
+
+import (
+	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
+	obc "github.com/openblockchain/obc-peer/protos"
+)
+
+type auditorImpl struct {
+	node *nodeImpl
+
+	isInitialized bool
+
+	// TCA audit key
+	tCertAuditKey []byte
+}
+
+// Public methods
+
+func (auditor *auditorImpl) GetName() string {
+	return auditor.node.GetName()
+}
+
+// GetEnrollmentID returns this auditor's enrollment id
+func (auditor *auditorImpl) GetEnrollmentID() string {
+	return auditor.node.enrollID
+}
+
+// DecryptTransaction returns a clone of tx whose payload
+// and chaincodeID are in the clear.
+func (auditor *auditorImpl) DecryptTransaction(tx *obc.Transaction) (*obc.Transaction, error) {
+	if !auditor.isInitialized {
+		return nil, utils.ErrNotInitialized
+	}
+
+	switch tx.ConfidentialityLevel {
+	case obc.ConfidentialityLevel_PUBLIC:
+		return auditor.node.deepCloneTransaction(tx)
+	case obc.ConfidentialityLevel_CONFIDENTIAL:
+		return auditor.node.decryptTx(tx)
+	default:
+		return nil, utils.ErrInvalidConfidentialityLevel
+	}
+}
+
+// GetTransactionEnrollmentID returns the enrollment id
+// of the creator of tx.
+func (auditor *auditorImpl) GetTransactionEnrollmentID(tx *obc.Transaction) (string, error) {
+	if !auditor.isInitialized {
+		return "", utils.ErrNotInitialized
+	}
+
+	if tx.Cert == nil {
+		return "", utils.ErrTransactionCertificate
+	}
+
+	cert, err := utils.DERToX509Certificate(tx.Cert)
+	if err != nil {
+		auditor.node.log.Error("Failed parsing transaction certificate [%s].", err.Error())
+
+		return "", err
+	}
+
+	// Only TCerts carry the enrollment id encrypted under the audit key
+	ct, err := utils.GetExtension(cert, utils.TCertEncEnrollmentID)
+	if err != nil {
+		return "", utils.ErrNotATCert
+	}
+
+	enrollID, err := utils.CBCPKCS7Decrypt(auditor.tCertAuditKey, ct)
+	if err != nil {
+		auditor.node.log.Error("Failed decrypting extension TCERT_ENC_ENROLLMENTID [%s].", err.Error())
+
+		return "", utils.ErrDecrypt
+	}
+
+	return string(enrollID), nil
+}
+
+// GetTransactionsByEnrollmentID returns a decrypted clone of every
+// transaction in blockchain created by enrollID.
+func (auditor *auditorImpl) GetTransactionsByEnrollmentID(blockchain Blockchain, enrollID string) ([]*obc.Transaction, error) {
+	if !auditor.isInitialized {
+		return nil, utils.ErrNotInitialized
+	}
+
+	var txs []*obc.Transaction
+	size := blockchain.GetBlockchainSize()
+	for i := uint64(0); i < size; i++ {
+		block, err := blockchain.GetBlockByNumber(i)
+		if err != nil {
+			auditor.node.log.Error("Failed getting block [%d] [%s].", i, err.Error())
+
+			return nil, err
+		}
+
+		for _, tx := range block.Transactions {
+			creator, err := auditor.GetTransactionEnrollmentID(tx)
+			if err != nil {
+				auditor.node.log.Debug("Skipping transaction [%s] [%s].", tx.Uuid, err.Error())
+
+				continue
+			}
+			if creator != enrollID {
+				continue
+			}
+
+			clone, err := auditor.DecryptTransaction(tx)
+			if err != nil {
+				auditor.node.log.Error("Failed decrypting transaction [%s] [%s].", tx.Uuid, err.Error())
+
+				return nil, err
+			}
+			txs = append(txs, clone)
+		}
+	}
+
+	return txs, nil
+}
+
+// Private methods
+
+func (auditor *auditorImpl) register(id string, pwd []byte, enrollID, enrollPWD string) error {
+	if auditor.isInitialized {
+		auditor.node.log.Error("Registering [%s]...done! Initialization already performed", id)
+
+		return utils.ErrAlreadyInitialized
+	}
+
+	// Register node
+	node := new(nodeImpl)
+	if err := node.register("auditor", id, pwd, enrollID, enrollPWD); err != nil {
+		log.Error("Failed registering [%s] [%s].", enrollID, err.Error())
+		return err
+	}
+
+	auditor.node = node
+
+	return nil
+}
+
+func (auditor *auditorImpl) init(id string, pwd []byte) error {
+	if auditor.isInitialized {
+		auditor.node.log.Error("Already initializaed.")
+
+		return utils.ErrAlreadyInitialized
+	}
+
+	// Init node
+	var node *nodeImpl
+	if auditor.node != nil {
+		node = auditor.node
+	} else {
+		node = new(nodeImpl)
+	}
+	if err := node.init("auditor", id, pwd); err != nil {
+		return err
+	}
+	auditor.node = node
+
+	// Init crypto engine
+	if err := auditor.initCryptoEngine(); err != nil {
+		auditor.node.log.Error("Failed initiliazing crypto engine [%s].", err.Error())
+
+		return err
+	}
+
+	// initialized
+	auditor.isInitialized = true
+
+	auditor.node.log.Info("Initialization...done.")
+
+	return nil
+}
+
+func (auditor *auditorImpl) initCryptoEngine() error {
+	// Load the audit key, retrieve it from the TCA the first time
+	if err := auditor.loadTCertAuditKey(nil); err != nil {
+		return err
+	}
+	if auditor.tCertAuditKey == nil {
+		if err := auditor.retrieveTCertAuditKey(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (auditor *auditorImpl) close() error {
+	if auditor.node != nil {
+		return auditor.node.close()
+	}
+	return nil
+}
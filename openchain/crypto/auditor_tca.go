@@ -0,0 +1,141 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package crypto
+
+import (
+	obcca "github.com/openblockchain/obc-peer/obc-ca/protos"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google/protobuf"
+	"io/ioutil"
+	"time"
+)
+
+func (auditor *auditorImpl) storeTCertAuditKey(pwd []byte) error {
+	err := ioutil.WriteFile(auditor.node.conf.getTCertAuditKeyPath(), utils.AEStoPEM(auditor.tCertAuditKey), 0700)
+	if err != nil {
+		auditor.node.log.Error("Failed storing TCertAuditKey [%s].", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (auditor *auditorImpl) loadTCertAuditKey(pwd []byte) error {
+	// Load TCertAuditKey
+	auditor.node.log.Debug("Loading TCertAuditKey at [%s]...", auditor.node.conf.getTCertAuditKeyPath())
+
+	missing, _ := utils.FilePathMissing(auditor.node.conf.getTCertAuditKeyPath())
+	if missing {
+		auditor.node.log.Debug("Failed loading TCertAuditKey. File is missing.")
+
+		return nil
+	}
+
+	pem, err := ioutil.ReadFile(auditor.node.conf.getTCertAuditKeyPath())
+	if err != nil {
+		auditor.node.log.Error("Failed loading TCertAuditKey [%s].", err.Error())
+
+		return err
+	}
+
+	tCertAuditKey, err := utils.PEMtoAES(pem, pwd)
+	if err != nil {
+		auditor.node.log.Error("Failed parsing TCertAuditKey [%s].", err.Error())
+
+		return err
+	}
+	auditor.tCertAuditKey = tCertAuditKey
+
+	auditor.node.log.Debug("Loading TCertAuditKey...done!")
+
+	return nil
+}
+
+func (auditor *auditorImpl) retrieveTCertAuditKey() error {
+	auditor.node.log.Debug("Retrieving TCertAuditKey from the TCA...")
+
+	key, err := auditor.tcaReadAuditKey()
+	if err != nil {
+		auditor.node.log.Error("Failed contacting TCA [%s].", err.Error())
+
+		return err
+	}
+	auditor.tCertAuditKey = key
+
+	// TODO: handle this situation more carefully
+	if err := auditor.storeTCertAuditKey(nil); err != nil {
+		return err
+	}
+
+	auditor.node.log.Debug("Retrieving TCertAuditKey from the TCA...done!")
+
+	return nil
+}
+
+func (auditor *auditorImpl) tcaReadAuditKey() ([]byte, error) {
+	sockP, err := grpc.Dial(auditor.node.conf.getTCAPAddr(), grpc.WithInsecure())
+	if err != nil {
+		auditor.node.log.Error("Failed tca dial in [%s].", err.Error())
+
+		return nil, err
+	}
+	defer sockP.Close()
+
+	tcaP := obcca.NewTCAPClient(sockP)
+
+	now := time.Now()
+	timestamp := google_protobuf.Timestamp{Seconds: int64(now.Second()), Nanos: int32(now.Nanosecond())}
+	req := &obcca.TCertAuditKeyReq{
+		Ts:  &timestamp,
+		Id:  &obcca.Identity{Id: auditor.node.enrollID},
+		Sig: nil,
+	}
+	rawReq, err := proto.Marshal(req)
+	if err != nil {
+		auditor.node.log.Error("Failed marshaling request [%s].", err.Error())
+		return nil, err
+	}
+
+	// Sign rawReq
+	r, s, err := auditor.node.ecdsaSignWithEnrollmentKey(rawReq)
+	if err != nil {
+		auditor.node.log.Error("Failed creating signature [%s].", err.Error())
+		return nil, err
+	}
+
+	R, _ := r.MarshalText()
+	S, _ := s.MarshalText()
+
+	// Append the signature and send the request
+	req.Sig = &obcca.Signature{Type: obcca.CryptoType_ECDSA, R: R, S: S}
+
+	auditKey, err := tcaP.ReadAuditKey(context.Background(), req)
+	if err != nil {
+		auditor.node.log.Error("Failed requesting tca audit key [%s].", err.Error())
+
+		return nil, err
+	}
+
+	return auditKey.Key, nil
+}
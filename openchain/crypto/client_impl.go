@@ -34,6 +34,9 @@ type clientImpl struct {
 
 	// TCA KDFKey
 	tCertOwnerKDFKey []byte
+
+	// Pool of unused TCerts refilled in background
+	tCertPool *tCertPool
 }
 
 // NewChaincodeDeployTransaction is used to deploy chaincode.
@@ -50,7 +53,49 @@ func (client *clientImpl) NewChaincodeDeployTransaction(chaincodeDeploymentSpec
 		return nil, err
 	}
 
-	if chaincodeDeploymentSpec.ChaincodeSpec.ConfidentialityLevel == obc.ConfidentialityLevel_CONFIDENTIAL {
+	return client.secureTransaction(tx, chaincodeDeploymentSpec.ChaincodeSpec.ConfidentialityLevel)
+}
+
+// NewChaincodeUpdateTransaction is used to replace the code of a deployed chaincode.
+func (client *clientImpl) NewChaincodeUpdateTransaction(chaincodeDeploymentSpec *obc.ChaincodeDeploymentSpec, uuid string) (*obc.Transaction, error) {
+	// Verify that the client is initialized
+	if !client.isInitialized {
+		return nil, utils.ErrNotInitialized
+	}
+
+	// Create a new transaction
+	tx, err := obc.NewChaincodeUpdateTransaction(chaincodeDeploymentSpec, uuid)
+	if err != nil {
+		client.node.log.Error("Failed creating new transaction [%s].", err.Error())
+		return nil, err
+	}
+
+	return client.secureTransaction(tx, chaincodeDeploymentSpec.ChaincodeSpec.ConfidentialityLevel)
+}
+
+// NewChaincodeTerminateTransaction is used to terminate a deployed chaincode.
+func (client *clientImpl) NewChaincodeTerminateTransaction(chaincodeInvocation *obc.ChaincodeInvocationSpec, uuid string) (*obc.Transaction, error) {
+	// Verify that the client is initialized
+	if !client.isInitialized {
+		return nil, utils.ErrNotInitialized
+	}
+
+	// Create a new transaction
+	tx, err := obc.NewChaincodeExecute(chaincodeInvocation, uuid, obc.Transaction_CHAINCODE_TERMINATE)
+	if err != nil {
+		client.node.log.Error("Failed creating new transaction [%s].", err.Error())
+		return nil, err
+	}
+
+	return client.secureTransaction(tx, chaincodeInvocation.ChaincodeSpec.ConfidentialityLevel)
+}
+
+// secureTransaction encrypts, if confidential, and signs a transaction
+// managing the lifecycle of a chaincode, i.e. a deploy, an update or a
+// terminate transaction
+func (client *clientImpl) secureTransaction(tx *obc.Transaction, confidentialityLevel obc.ConfidentialityLevel) (*obc.Transaction, error) {
+	var err error
+	if confidentialityLevel == obc.ConfidentialityLevel_CONFIDENTIAL {
 		// 1. set confidentiality level and nonce
 		tx.ConfidentialityLevel = obc.ConfidentialityLevel_CONFIDENTIAL
 		tx.Nonce, err = utils.GetRandomBytes(utils.NonceSize)
@@ -158,6 +203,15 @@ func (client *clientImpl) NewChaincodeExecute(chaincodeInvocation *obc.Chaincode
 	client.node.log.Debug("Appending certificate [%s].", utils.EncodeBase64(rawTCert))
 	tx.Cert = rawTCert
 
+	// Reveal the requested attributes of the certificate to the chaincode
+	if attributes := chaincodeInvocation.ChaincodeSpec.Attributes; len(attributes) > 0 {
+		tx.AttributeKeys, err = client.getTCertAttributeKeys(rawTCert, attributes)
+		if err != nil {
+			client.node.log.Error("Failed deriving attribute keys [%s].", err.Error())
+			return nil, err
+		}
+	}
+
 	// Sign the transaction and append the signature
 	// 1. Marshall tx to bytes
 	rawTx, err := proto.Marshal(tx)
@@ -230,6 +284,15 @@ func (client *clientImpl) NewChaincodeQuery(chaincodeInvocation *obc.ChaincodeIn
 	client.node.log.Debug("Appending certificate [%s].", utils.EncodeBase64(rawTCert))
 	tx.Cert = rawTCert
 
+	// Reveal the requested attributes of the certificate to the chaincode
+	if attributes := chaincodeInvocation.ChaincodeSpec.Attributes; len(attributes) > 0 {
+		tx.AttributeKeys, err = client.getTCertAttributeKeys(rawTCert, attributes)
+		if err != nil {
+			client.node.log.Error("Failed deriving attribute keys [%s].", err.Error())
+			return nil, err
+		}
+	}
+
 	// Sign the transaction and append the signature
 	// 1. Marshall tx to bytes
 	rawTx, err := proto.Marshal(tx)
@@ -283,6 +346,28 @@ func (client *clientImpl) DecryptQueryResult(queryTx *obc.Transaction, ct []byte
 	return out, nil
 }
 
+// EndorseTransaction approves tx with the enrollment certificate of the client
+func (client *clientImpl) EndorseTransaction(tx *obc.Transaction) (*obc.Endorsement, error) {
+	// Verify that the client is initialized
+	if !client.isInitialized {
+		return nil, utils.ErrNotInitialized
+	}
+
+	msg, err := client.node.getEndorsementMessage(tx)
+	if err != nil {
+		client.node.log.Error("Failed marshaling tx [%s].", err.Error())
+		return nil, err
+	}
+
+	enrollCert, signature, err := client.node.signWithEnrollmentCert(msg)
+	if err != nil {
+		client.node.log.Error("Failed creating endorsement [%s].", err.Error())
+		return nil, err
+	}
+
+	return &obc.Endorsement{Cert: enrollCert.Raw, Signature: signature}, nil
+}
+
 
 func (client *clientImpl) GetName() string {
 	return client.node.GetName()
@@ -326,27 +411,26 @@ func (client *clientImpl) init(id string, pwd []byte) error {
 	}
 	client.node = node
 
-	// Initialize keystore
-	client.node.log.Info("Init keystore...")
-	err := client.initKeyStore()
-	if err != nil {
-		if err != utils.ErrKeyStoreAlreadyInitialized {
-			client.node.log.Error("Keystore already initialized.")
-		} else {
-			client.node.log.Error("Failed initiliazing keystore [%s].", err.Error())
+	// TCert keys are derived from the enrollment key, which must then be available
+	if client.node.enrollPrivKey == nil {
+		client.node.log.Error("Clients require an exportable enrollment key.")
 
-			return err
-		}
+		return utils.ErrKeyNotExportable
 	}
-	client.node.log.Info("Init keystore...done.")
 
 	// Init crypto engine
-	err = client.initCryptoEngine()
+	err := client.initCryptoEngine()
 	if err != nil {
 		client.node.log.Error("Failed initiliazing crypto engine [%s].", err.Error())
 		return err
 	}
 
+	// Start prefetching TCerts
+	client.tCertPool = newTCertPool(client)
+	client.tCertPool.start()
+
+	// Renew the enrollment certificate before it expires
+	client.node.startECertRenewal(client.renewEnrollmentCertificate)
 
 	// initialized
 	client.isInitialized = true
@@ -356,7 +440,22 @@ func (client *clientImpl) init(id string, pwd []byte) error {
 	return nil
 }
 
+// GetTCertPoolMetrics returns the metrics of the TCert pool
+func (client *clientImpl) GetTCertPoolMetrics() TCertPoolMetrics {
+	if client.tCertPool == nil {
+		return TCertPoolMetrics{}
+	}
+	return client.tCertPool.getMetrics()
+}
+
 func (client *clientImpl) close() error {
+	if client.node != nil {
+		client.node.stopECertRenewal()
+	}
+	if client.tCertPool != nil {
+		client.tCertPool.close()
+		client.tCertPool = nil
+	}
 	if client.node != nil {
 		return client.node.close()
 	}
@@ -386,15 +485,15 @@ func (client *clientImpl) checkTransaction(tx *obc.Transaction) error {
 		}
 		// TODO: verify cert
 
-		// 3. Marshall tx without signature
-		signature := tx.Signature
-		tx.Signature = nil
+		// 3. Marshall tx without signature and endorsements
+		signature, endorsements := tx.Signature, tx.Endorsements
+		tx.Signature, tx.Endorsements = nil, nil
 		rawTx, err := proto.Marshal(tx)
 		if err != nil {
 			client.node.log.Error("Failed marshaling tx [%s].", err.Error())
 			return err
 		}
-		tx.Signature = signature
+		tx.Signature, tx.Endorsements = signature, endorsements
 
 		// 2. Verify signature
 		ver, err := client.node.verify(cert.PublicKey, rawTx, tx.Signature)
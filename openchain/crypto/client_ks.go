@@ -20,26 +20,15 @@ under the License.
 package crypto
 
 import (
-	"database/sql"
 	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
+	"time"
 )
 
-func (client *clientImpl) initKeyStore() error {
-	// create tables
-	client.node.ks.log.Debug("Create Table if not exists [TCert] at [%s].", client.node.ks.conf.getKeyStorePath())
-	if _, err := client.node.ks.sqlDB.Exec("CREATE TABLE IF NOT EXISTS TCerts (id INTEGER, cert BLOB, PRIMARY KEY (id))"); err != nil {
-		client.node.ks.log.Debug("Failed creating table [%s].", err.Error())
-		return err
-	}
-
-	return nil
-}
-
-func (ks *keyStore) GetNextTCert(tCertFetcher func(num int) ([][]byte, error)) ([]byte, error) {
+func (ks *keyStore) GetNextTCert(num int, tCertFetcher func(num int) ([][]byte, error)) ([]byte, error) {
 	ks.m.Lock()
 	defer ks.m.Unlock()
 
-	cert, err := ks.selectNextTCert()
+	cert, err := ks.NextTCert()
 	if err != nil {
 		ks.log.Error("Failed selecting next TCert [%s].", err.Error())
 
@@ -52,46 +41,22 @@ func (ks *keyStore) GetNextTCert(tCertFetcher func(num int) ([][]byte, error)) (
 
 		// 1. Fetch
 		ks.log.Debug("Fectch TCerts from TCA...")
-		certs, err := tCertFetcher(10)
+		certs, err := tCertFetcher(num)
 		if err != nil {
 			return nil, err
 		}
 
 		// 2. Store
 		ks.log.Debug("Store them...")
-		tx, err := ks.sqlDB.Begin()
-		if err != nil {
-			ks.log.Error("Failed beginning transaction [%s].", err.Error())
-
-			return nil, err
-		}
-
-		for i, cert := range certs {
-			ks.log.Debug("Insert index [%d]", i)
-
-			//			db.log.Info("Insert key  ", utils.EncodeBase64(keys[i]))
-			ks.log.Debug("Insert cert [%s].", utils.EncodeBase64(cert))
-
-			_, err := tx.Exec("INSERT INTO TCerts (cert) VALUES (?)", cert)
-
-			if err != nil {
-				ks.log.Error("Failed inserting cert [%s].", err.Error())
-				continue
-			}
-		}
-
-		err = tx.Commit()
-		if err != nil {
-			ks.log.Error("Failed committing transaction [%s].", err.Error())
-
-			tx.Rollback()
+		if err := ks.StoreTCerts(certs); err != nil {
+			ks.log.Error("Failed storing TCerts [%s].", err.Error())
 
 			return nil, err
 		}
 
 		ks.log.Debug("Fectch TCerts from TCA...done!")
 
-		cert, err = ks.selectNextTCert()
+		cert, err = ks.NextTCert()
 		if err != nil {
 			ks.log.Error("Failed selecting next TCert after fetching [%s].", err.Error())
 
@@ -100,64 +65,21 @@ func (ks *keyStore) GetNextTCert(tCertFetcher func(num int) ([][]byte, error)) (
 	}
 
 	return cert, nil
-	//	return nil, nil, errors.New("No cert obtained")
-	//	return utils.NewSelfSignedCert()
 }
 
-func (ks *keyStore) selectNextTCert() ([]byte, error) {
-	ks.log.Debug("Select next TCert...")
-
-	// Open transaction
-	tx, err := ks.sqlDB.Begin()
-	if err != nil {
-		ks.log.Error("Failed beginning transaction [%s].", err.Error())
-
-		return nil, err
-	}
-
-	// Get the first row available
-	var id int
-	var cert []byte
-	row := ks.sqlDB.QueryRow("SELECT id, cert FROM TCerts")
-	err = row.Scan(&id, &cert)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	} else if err != nil {
-		ks.log.Error("Error during select [%s].", err.Error())
-
-		return nil, err
-	}
-
-	ks.log.Debug("id [%d]", id)
-	ks.log.Debug("cert [%s].", utils.EncodeBase64(cert))
-
-	// TODO: rather than removing, move the cert to another table
-	// which stores the TCerts used
-
-	// Remove that row
-	ks.log.Debug("Removing row with id [%d]...", id)
-
-	if _, err := tx.Exec("DELETE FROM TCerts WHERE id = ?", id); err != nil {
-		ks.log.Error("Failed removing row [%d] [%s].", id, err.Error())
-
-		tx.Rollback()
-
-		return nil, err
-	}
-
-	ks.log.Debug("Removing row with id [%d]...done", id)
-
-	// Finalize
-	err = tx.Commit()
-	if err != nil {
-		ks.log.Error("Failed commiting [%s].", err.Error())
-		tx.Rollback()
-
-		return nil, err
-	}
+// DeleteExpiredTCerts removes the unused TCerts expired at now and returns
+// how many were removed
+func (ks *keyStore) DeleteExpiredTCerts(now time.Time) (int, error) {
+	ks.m.Lock()
+	defer ks.m.Unlock()
 
-	ks.log.Debug("Select next TCert...done!")
+	return ks.DeleteTCerts(func(rawCert []byte) bool {
+		cert, err := utils.DERToX509Certificate(rawCert)
+		if err != nil {
+			// Left to getNextTCert, which reports it
+			return false
+		}
 
-	return cert, nil
+		return now.After(cert.NotAfter)
+	})
 }
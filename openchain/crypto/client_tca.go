@@ -21,6 +21,7 @@ package crypto
 
 import (
 	obcca "github.com/openblockchain/obc-peer/obc-ca/protos"
+	obc "github.com/openblockchain/obc-peer/protos"
 
 	"bytes"
 	"crypto/ecdsa"
@@ -82,7 +83,7 @@ func (client *clientImpl) loadTCertOwnerKDFKey(pwd []byte) error {
 // corresponding to the tuple (cert, signing key)
 func (client *clientImpl) getNextTCert() ([]byte, error) {
 	client.node.log.Debug("Getting next TCert...")
-	rawCert, err := client.node.ks.GetNextTCert(client.getTCertsFromTCA)
+	rawCert, err := client.tCertPool.getNextTCert()
 	if err != nil {
 		client.node.log.Error("getNextTCert: failed accessing db [%s].", err.Error())
 
@@ -98,39 +99,30 @@ func (client *clientImpl) getNextTCert() ([]byte, error) {
 	return rawCert, nil
 }
 
+// renewEnrollmentCertificate renews the enrollment certificate of the client.
+// TCert keys are derived from the enrollment key, hence the TCerts issued for
+// the previous key are discarded together with the key swap.
+func (client *clientImpl) renewEnrollmentCertificate() error {
+	// Hold off TCA requests until the new key is in place
+	client.tCertPool.fetchM.Lock()
+	defer client.tCertPool.fetchM.Unlock()
+
+	return client.node.renewEnrollmentCertificate(client.tCertPool.drain)
+}
+
 func (client *clientImpl) signWithTCert(tCertDER []byte, msg []byte) ([]byte, error) {
 	// Extract the signing key from the tCert
 
 //	client.node.log.Debug("TCertOwnerKDFKey [%s].", utils.EncodeBase64(client.tCertOwnerKDFKey))
 
-	TCertOwnerEncryptKey := utils.HMACTruncated(client.tCertOwnerKDFKey, []byte{1}, utils.AESKeyLength)
 	ExpansionKey := utils.HMAC(client.tCertOwnerKDFKey, []byte{2})
 
-	tCert, err := utils.DERToX509Certificate(tCertDER)
-	if err != nil {
-		client.node.log.Error("Failed parsing key [%s].", err.Error())
-
-		return nil, err
-	}
-
-	// TODO: retrieve TCertIndex from the ciphertext encrypted under the TCertOwnerEncryptKey
-	ct, err := utils.GetExtension(tCert, utils.TCertEncTCertIndex)
-	if err != nil {
-		client.node.log.Error("Failed getting extension TCERT_ENC_TCERTINDEX [%s].", err.Error())
-
-		return nil, err
-	}
-
-	// Decrypt ct to TCertIndex (TODO: || EnrollPub_Key || EnrollID ?)
-	decryptedTCertIndex, err := utils.CBCPKCS7Decrypt(TCertOwnerEncryptKey, ct)
+	TCertIndex, err := client.getTCertIndex(tCertDER)
 	if err != nil {
-		client.node.log.Error("Failed decrypting extension TCERT_ENC_TCERTINDEX [%s].", err.Error())
-
 		return nil, err
 	}
 
 	// Compute ExpansionValue based on TCertIndex
-	TCertIndex := decryptedTCertIndex
 
 	client.node.log.Debug("TCertIndex [%s].", utils.EncodeBase64(TCertIndex))
 	mac := hmac.New(utils.NewHash, ExpansionKey)
@@ -142,9 +134,10 @@ func (client *clientImpl) signWithTCert(tCertDER []byte, msg []byte) ([]byte, er
 	// using elliptic curve point addition per NIST FIPS PUB 186-4- specified P-384
 
 	// Compute temporary secret key
+	enrollPrivKey := client.node.getEnrollmentPrivKey()
 	tempSK := &ecdsa.PrivateKey{
 		PublicKey: ecdsa.PublicKey{
-			Curve: client.node.enrollPrivKey.Curve,
+			Curve: enrollPrivKey.Curve,
 			X:     new(big.Int),
 			Y:     new(big.Int),
 		},
@@ -153,24 +146,72 @@ func (client *clientImpl) signWithTCert(tCertDER []byte, msg []byte) ([]byte, er
 
 	var k = new(big.Int).SetBytes(ExpansionValue)
 	var one = new(big.Int).SetInt64(1)
-	n := new(big.Int).Sub(client.node.enrollPrivKey.Params().N, one)
+	n := new(big.Int).Sub(enrollPrivKey.Params().N, one)
 	k.Mod(k, n)
 	k.Add(k, one)
 
-	tempSK.D.Add(client.node.enrollPrivKey.D, k)
-	tempSK.D.Mod(tempSK.D, client.node.enrollPrivKey.PublicKey.Params().N)
+	tempSK.D.Add(enrollPrivKey.D, k)
+	tempSK.D.Mod(tempSK.D, enrollPrivKey.PublicKey.Params().N)
 
 	// Compute temporary public key
-	tempX, tempY := client.node.enrollPrivKey.PublicKey.ScalarBaseMult(k.Bytes())
+	tempX, tempY := enrollPrivKey.PublicKey.ScalarBaseMult(k.Bytes())
 	tempSK.PublicKey.X, tempSK.PublicKey.Y =
 		tempSK.PublicKey.Add(
-			client.node.enrollPrivKey.PublicKey.X, client.node.enrollPrivKey.PublicKey.Y,
+			enrollPrivKey.PublicKey.X, enrollPrivKey.PublicKey.Y,
 			tempX, tempY,
 		)
 
 	return client.node.sign(tempSK, msg)
 }
 
+// getTCertIndex decrypts the TCertIndex embedded in the passed transaction certificate
+func (client *clientImpl) getTCertIndex(tCertDER []byte) ([]byte, error) {
+	TCertOwnerEncryptKey := utils.HMACTruncated(client.tCertOwnerKDFKey, []byte{1}, utils.AESKeyLength)
+
+	tCert, err := utils.DERToX509Certificate(tCertDER)
+	if err != nil {
+		client.node.log.Error("Failed parsing key [%s].", err.Error())
+
+		return nil, err
+	}
+
+	ct, err := utils.GetExtension(tCert, utils.TCertEncTCertIndex)
+	if err != nil {
+		client.node.log.Error("Failed getting extension TCERT_ENC_TCERTINDEX [%s].", err.Error())
+
+		return nil, err
+	}
+
+	// Decrypt ct to TCertIndex (TODO: || EnrollPub_Key || EnrollID ?)
+	TCertIndex, err := utils.CBCPKCS7Decrypt(TCertOwnerEncryptKey, ct)
+	if err != nil {
+		client.node.log.Error("Failed decrypting extension TCERT_ENC_TCERTINDEX [%s].", err.Error())
+
+		return nil, err
+	}
+
+	return TCertIndex, nil
+}
+
+// getTCertAttributeKeys derives the keys revealing the named attributes
+// of the passed transaction certificate
+func (client *clientImpl) getTCertAttributeKeys(tCertDER []byte, names []string) ([]*obc.AttributeKey, error) {
+	TCertIndex, err := client.getTCertIndex(tCertDER)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*obc.AttributeKey, len(names))
+	for i, name := range names {
+		keys[i] = &obc.AttributeKey{
+			Name: name,
+			Key:  utils.TCertAttributeKey(client.tCertOwnerKDFKey, TCertIndex, name),
+		}
+	}
+
+	return keys, nil
+}
+
 func (client *clientImpl) getTCertsFromTCA(num int) ([][]byte, error) {
 	client.node.log.Debug("Get [%d] certificates from the TCA...", num)
 
@@ -211,6 +252,7 @@ func (client *clientImpl) getTCertsFromTCA(num int) ([][]byte, error) {
 
 	TCertOwnerEncryptKey := utils.HMACTruncated(TCertOwnerKDFKey, []byte{1}, utils.AESKeyLength)
 	ExpansionKey := utils.HMAC(TCertOwnerKDFKey, []byte{2})
+	enrollPrivKey := client.node.getEnrollmentPrivKey()
 
 	resCert := make([][]byte, num)
 
@@ -271,7 +313,7 @@ func (client *clientImpl) getTCertsFromTCA(num int) ([][]byte, error) {
 		// Compute temporary secret key
 		tempSK := &ecdsa.PrivateKey{
 			PublicKey: ecdsa.PublicKey{
-				Curve: client.node.enrollPrivKey.Curve,
+				Curve: enrollPrivKey.Curve,
 				X:     new(big.Int),
 				Y:     new(big.Int),
 			},
@@ -280,18 +322,18 @@ func (client *clientImpl) getTCertsFromTCA(num int) ([][]byte, error) {
 
 		var k = new(big.Int).SetBytes(ExpansionValue)
 		var one = new(big.Int).SetInt64(1)
-		n := new(big.Int).Sub(client.node.enrollPrivKey.Params().N, one)
+		n := new(big.Int).Sub(enrollPrivKey.Params().N, one)
 		k.Mod(k, n)
 		k.Add(k, one)
 
-		tempSK.D.Add(client.node.enrollPrivKey.D, k)
-		tempSK.D.Mod(tempSK.D, client.node.enrollPrivKey.PublicKey.Params().N)
+		tempSK.D.Add(enrollPrivKey.D, k)
+		tempSK.D.Mod(tempSK.D, enrollPrivKey.PublicKey.Params().N)
 
 		// Compute temporary public key
-		tempX, tempY := client.node.enrollPrivKey.PublicKey.ScalarBaseMult(k.Bytes())
+		tempX, tempY := enrollPrivKey.PublicKey.ScalarBaseMult(k.Bytes())
 		tempSK.PublicKey.X, tempSK.PublicKey.Y =
 			tempSK.PublicKey.Add(
-				client.node.enrollPrivKey.PublicKey.X, client.node.enrollPrivKey.PublicKey.Y,
+				enrollPrivKey.PublicKey.X, enrollPrivKey.PublicKey.Y,
 				tempX, tempY,
 			)
 
@@ -0,0 +1,246 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package crypto
+
+import (
+	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TCertPoolMetrics reports the activity of the TCert pool of a client
+type TCertPoolMetrics struct {
+	// Size is the number of unused TCerts in the pool
+	Size uint64
+
+	// Fetched is the number of TCerts obtained from the TCA
+	Fetched uint64
+
+	// Consumed is the number of TCerts handed out to transactions
+	Consumed uint64
+
+	// Expired is the number of TCerts discarded because expired
+	Expired uint64
+
+	// Refills is the number of background refills completed
+	Refills uint64
+
+	// RefillFailures is the number of background refills failed
+	RefillFailures uint64
+
+	// Misses is the number of times a transaction found the pool empty
+	// and had to wait for the TCA
+	Misses uint64
+}
+
+// tCertPool keeps the number of unused TCerts of a client between the low
+// and the high watermark. Whenever the pool drops below the low watermark,
+// it is refilled up to the high watermark in background.
+type tCertPool struct {
+	client *clientImpl
+
+	low  int
+	high int
+
+	metrics TCertPoolMetrics
+
+	// Serializes the requests to the TCA
+	fetchM sync.Mutex
+
+	refill chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func newTCertPool(client *clientImpl) *tCertPool {
+	low, high := client.node.conf.getTCertPoolWatermarks()
+
+	return &tCertPool{
+		client: client,
+		low:    low,
+		high:   high,
+		refill: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+func (pool *tCertPool) start() {
+	go pool.run()
+
+	// Fill the pool before the first transaction asks for a TCert
+	pool.signalRefill()
+}
+
+func (pool *tCertPool) close() {
+	close(pool.stop)
+	<-pool.done
+}
+
+func (pool *tCertPool) run() {
+	defer close(pool.done)
+
+	for {
+		select {
+		case <-pool.refill:
+			pool.fill()
+		case <-pool.stop:
+			return
+		}
+	}
+}
+
+func (pool *tCertPool) signalRefill() {
+	select {
+	case pool.refill <- struct{}{}:
+	default:
+		// A refill is already pending
+	}
+}
+
+// fill discards the expired TCerts, so that they do not count towards the
+// low watermark, and tops up the pool to the high watermark
+func (pool *tCertPool) fill() {
+	expired, err := pool.client.node.ks.DeleteExpiredTCerts(time.Now())
+	if err != nil {
+		pool.client.node.log.Error("Failed discarding expired TCerts [%s].", err.Error())
+		atomic.AddUint64(&pool.metrics.RefillFailures, 1)
+
+		return
+	}
+	if expired > 0 {
+		pool.client.node.log.Debug("Discarded [%d] expired TCerts.", expired)
+		atomic.AddUint64(&pool.metrics.Expired, uint64(expired))
+	}
+
+	size, err := pool.client.node.ks.CountTCerts()
+	if err != nil {
+		pool.client.node.log.Error("Failed counting TCerts [%s].", err.Error())
+		atomic.AddUint64(&pool.metrics.RefillFailures, 1)
+
+		return
+	}
+	if size >= pool.low {
+		return
+	}
+
+	pool.client.node.log.Debug("Refilling TCert pool from [%d] to [%d]...", size, pool.high)
+
+	certs, err := pool.fetch(pool.high - size)
+	if err != nil {
+		pool.client.node.log.Error("Failed refilling TCert pool [%s].", err.Error())
+		atomic.AddUint64(&pool.metrics.RefillFailures, 1)
+
+		return
+	}
+
+	if err := pool.client.node.ks.StoreTCerts(certs); err != nil {
+		pool.client.node.log.Error("Failed storing TCerts [%s].", err.Error())
+		atomic.AddUint64(&pool.metrics.RefillFailures, 1)
+
+		return
+	}
+	atomic.AddUint64(&pool.metrics.Refills, 1)
+
+	pool.client.node.log.Debug("Refilling TCert pool from [%d] to [%d]...done!", size, pool.high)
+}
+
+func (pool *tCertPool) fetch(num int) ([][]byte, error) {
+	pool.fetchM.Lock()
+	defer pool.fetchM.Unlock()
+
+	certs, err := pool.client.getTCertsFromTCA(num)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddUint64(&pool.metrics.Fetched, uint64(len(certs)))
+
+	return certs, nil
+}
+
+// getNextTCert returns an unused and not expired TCert. It only waits for
+// the TCA if the pool is empty.
+func (pool *tCertPool) getNextTCert() ([]byte, error) {
+	for {
+		size, err := pool.client.node.ks.CountTCerts()
+		if err == nil && size == 0 {
+			atomic.AddUint64(&pool.metrics.Misses, 1)
+		}
+
+		rawCert, err := pool.client.node.ks.GetNextTCert(pool.high, pool.fetch)
+		if err != nil {
+			return nil, err
+		}
+
+		if size <= pool.low {
+			pool.signalRefill()
+		}
+
+		cert, err := utils.DERToX509Certificate(rawCert)
+		if err != nil {
+			pool.client.node.log.Error("Failed parsing TCert [%s].", err.Error())
+
+			return nil, err
+		}
+		if time.Now().After(cert.NotAfter) {
+			pool.client.node.log.Debug("Discarding expired TCert [%s].", utils.EncodeBase64(rawCert))
+			atomic.AddUint64(&pool.metrics.Expired, 1)
+
+			continue
+		}
+
+		atomic.AddUint64(&pool.metrics.Consumed, 1)
+
+		return rawCert, nil
+	}
+}
+
+// drain discards all the unused TCerts
+func (pool *tCertPool) drain() error {
+	for {
+		cert, err := pool.client.node.ks.NextTCert()
+		if err != nil {
+			pool.client.node.log.Error("Failed draining TCert pool [%s].", err.Error())
+
+			return err
+		}
+		if cert == nil {
+			return nil
+		}
+	}
+}
+
+func (pool *tCertPool) getMetrics() TCertPoolMetrics {
+	metrics := TCertPoolMetrics{
+		Fetched:        atomic.LoadUint64(&pool.metrics.Fetched),
+		Consumed:       atomic.LoadUint64(&pool.metrics.Consumed),
+		Expired:        atomic.LoadUint64(&pool.metrics.Expired),
+		Refills:        atomic.LoadUint64(&pool.metrics.Refills),
+		RefillFailures: atomic.LoadUint64(&pool.metrics.RefillFailures),
+		Misses:         atomic.LoadUint64(&pool.metrics.Misses),
+	}
+
+	if size, err := pool.client.node.ks.CountTCerts(); err == nil {
+		metrics.Size = uint64(size)
+	}
+
+	return metrics
+}
@@ -1,6 +1,8 @@
 package crypto
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	obc "github.com/openblockchain/obc-peer/protos"
 )
 
@@ -20,6 +22,12 @@ type Client interface {
 	// NewChaincodeDeployTransaction is used to deploy chaincode.
 	NewChaincodeDeployTransaction(chaincodeDeploymentSpec *obc.ChaincodeDeploymentSpec, uuid string) (*obc.Transaction, error)
 
+	// NewChaincodeUpdateTransaction is used to replace the code of a deployed chaincode.
+	NewChaincodeUpdateTransaction(chaincodeDeploymentSpec *obc.ChaincodeDeploymentSpec, uuid string) (*obc.Transaction, error)
+
+	// NewChaincodeTerminateTransaction is used to terminate a deployed chaincode.
+	NewChaincodeTerminateTransaction(chaincodeInvocation *obc.ChaincodeInvocationSpec, uuid string) (*obc.Transaction, error)
+
 	// NewChaincodeExecute is used to execute chaincode's functions.
 	NewChaincodeExecute(chaincodeInvocation *obc.ChaincodeInvocationSpec, uuid string) (*obc.Transaction, error)
 
@@ -28,6 +36,14 @@ type Client interface {
 
 	// DecryptQueryResult is used to decrypt the result of a query transaction
 	DecryptQueryResult(queryTx *obc.Transaction, result []byte) ([]byte, error)
+
+	// EndorseTransaction approves tx on behalf of the organization of this
+	// client. The endorsement is appended to tx.Endorsements by the submitter.
+	EndorseTransaction(tx *obc.Transaction) (*obc.Endorsement, error)
+
+	// GetTCertPoolMetrics returns the metrics of the pool of TCerts
+	// prefetched from the TCA
+	GetTCertPoolMetrics() TCertPoolMetrics
 }
 
 // Peer is an entity able to verify transactions
@@ -65,6 +81,63 @@ type Peer interface {
 	// the deploy transaction and the execute transaction. Notice that,
 	// executeTx can also correspond to a deploy transaction.
 	GetStateEncryptor(deployTx, executeTx *obc.Transaction) (StateEncryptor, error)
+
+	// GetTLSCertificate returns the TLS certificate, and its private key,
+	// issued to this peer by the TLSCA.
+	GetTLSCertificate() (*tls.Certificate, error)
+
+	// GetTLSCACertPool returns the TLSCA certificates against which
+	// the TLS certificates of remote peers are verified.
+	GetTLSCACertPool() *x509.CertPool
+
+	// SignHello signs hello with this peer's enrollment key, binding it to
+	// this peer's TLS certificate. It returns the enrollment certificate
+	// and the signature.
+	SignHello(hello []byte) ([]byte, []byte, error)
+
+	// VerifyHello checks that the remote peer authenticated by tlsCert holds
+	// the enrollment certificate cert issued to peerID, and that signature
+	// is its signature of hello. If this is the case, VerifyHello returns nil.
+	VerifyHello(peerID string, cert, tlsCert, hello, signature []byte) error
+}
+
+// Auditor is an entity able to decrypt confidential transactions
+// and to link them to the enrollment id of their creator
+type Auditor interface {
+	Entity
+
+	// GetEnrollmentID returns this auditor's enrollment id
+	GetEnrollmentID() string
+
+	// DecryptTransaction returns a clone of tx whose payload
+	// and chaincodeID are in the clear.
+	DecryptTransaction(tx *obc.Transaction) (*obc.Transaction, error)
+
+	// GetTransactionEnrollmentID returns the enrollment id
+	// of the creator of tx.
+	GetTransactionEnrollmentID(tx *obc.Transaction) (string, error)
+
+	// GetTransactionsByEnrollmentID returns a decrypted clone of every
+	// transaction in blockchain created by enrollID.
+	GetTransactionsByEnrollmentID(blockchain Blockchain, enrollID string) ([]*obc.Transaction, error)
+}
+
+// Blockchain gives an Auditor read access to the blocks to review
+type Blockchain interface {
+
+	// GetBlockchainSize returns the number of blocks
+	GetBlockchainSize() uint64
+
+	// GetBlockByNumber returns the block at height blockNumber
+	GetBlockByNumber(blockNumber uint64) (*obc.Block, error)
+}
+
+// ChaincodeDeployments gives a validator access to the deploy transactions
+// carrying the endorsement policies of the chaincodes
+type ChaincodeDeployments interface {
+
+	// GetDeployTransaction returns the transaction that deployed the chaincode named name
+	GetDeployTransaction(name string) (*obc.Transaction, error)
 }
 
 // StateEncryptor is used to encrypt chaincode's state
@@ -23,16 +23,32 @@ import (
 	pb "github.com/openblockchain/obc-peer/protos"
 
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
 	"fmt"
+	"github.com/op/go-logging"
 	"github.com/openblockchain/obc-peer/obc-ca/obcca"
+	capb "github.com/openblockchain/obc-peer/obc-ca/protos"
 	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
 	"github.com/openblockchain/obc-peer/openchain/util"
 	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"io/ioutil"
+	"math/big"
+	"net"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"reflect"
+	"time"
 )
 
 var (
@@ -43,6 +59,8 @@ var (
 	deployer Client
 	invoker  Client
 
+	auditor Auditor
+
 	caAlreadyOn bool
 	eca         *obcca.ECA
 	tca         *obcca.TCA
@@ -78,6 +96,13 @@ func TestMain(m *testing.M) {
 		panic(fmt.Errorf("Failed initializing validators [%s].", err.Error()))
 	}
 
+	// Init auditors
+	err = initAuditors()
+	if err != nil {
+		fmt.Printf("Failed initializing auditors [%s]\n", err.Error())
+		panic(fmt.Errorf("Failed initializing auditors [%s].", err.Error()))
+	}
+
 	ret := m.Run()
 
 	cleanup()
@@ -144,6 +169,138 @@ func TestClientMultiExecuteTransaction(t *testing.T) {
 	}
 }
 
+func TestClientTCertPool(t *testing.T) {
+	pool := invoker.(*clientImpl).tCertPool
+
+	// Add an expired TCert to the pool
+	expired := newExpiredTCert(t)
+	if err := invoker.(*clientImpl).node.ks.StoreTCerts([][]byte{expired}); err != nil {
+		t.Fatalf("Failed storing TCert [%s].", err.Error())
+	}
+
+	before := invoker.GetTCertPoolMetrics()
+	for i := 0; i < 100 && invoker.GetTCertPoolMetrics().Expired == before.Expired; i++ {
+		cert, err := pool.getNextTCert()
+		if err != nil {
+			t.Fatalf("Failed getting next TCert [%s].", err.Error())
+		}
+		if reflect.DeepEqual(cert, expired) {
+			t.Fatalf("Expired TCerts must not be returned")
+		}
+	}
+
+	metrics := invoker.GetTCertPoolMetrics()
+	if metrics.Expired != before.Expired+1 {
+		t.Fatalf("Expired TCert must be discarded")
+	}
+	if metrics.Consumed <= before.Consumed {
+		t.Fatalf("Consumed TCerts must be counted")
+	}
+	if metrics.Fetched < metrics.Consumed+metrics.Expired-1 {
+		t.Fatalf("Fetched TCerts must be counted")
+	}
+
+	// The pool must be refilled in background
+	for i := 0; i < 50 && invoker.GetTCertPoolMetrics().Size < uint64(pool.low); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if invoker.GetTCertPoolMetrics().Size < uint64(pool.low) {
+		t.Fatalf("Pool must be refilled above the low watermark")
+	}
+}
+
+func TestClientTCertPoolFillDiscardsExpired(t *testing.T) {
+	pool := invoker.(*clientImpl).tCertPool
+	ks := invoker.(*clientImpl).node.ks
+
+	// Fill the pool up to the low watermark with expired TCerts only
+	if err := pool.drain(); err != nil {
+		t.Fatalf("Failed draining pool [%s].", err.Error())
+	}
+	for i := 0; i < pool.low; i++ {
+		if err := ks.StoreTCerts([][]byte{newExpiredTCert(t)}); err != nil {
+			t.Fatalf("Failed storing TCert [%s].", err.Error())
+		}
+	}
+
+	before := invoker.GetTCertPoolMetrics()
+	pool.fill()
+	metrics := invoker.GetTCertPoolMetrics()
+
+	if metrics.Expired < before.Expired+uint64(pool.low) {
+		t.Fatalf("Expired TCerts must be discarded by the refill")
+	}
+	if metrics.Size < uint64(pool.high) {
+		t.Fatalf("Pool must be refilled up to the high watermark, was [%d]", metrics.Size)
+	}
+	if n, err := ks.DeleteExpiredTCerts(time.Now()); err != nil || n != 0 {
+		t.Fatalf("Pool must not hold expired TCerts [%d] [%v]", n, err)
+	}
+}
+
+// newExpiredTCert returns a self-signed certificate that expired an hour ago
+func newExpiredTCert(t *testing.T) []byte {
+	key, err := utils.NewECDSAKey()
+	if err != nil {
+		t.Fatalf("Failed generating key [%s].", err.Error())
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-2 * time.Hour),
+		NotAfter:     time.Now().Add(-time.Hour),
+	}
+	expired, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed creating certificate [%s].", err.Error())
+	}
+
+	return expired
+}
+
+func TestClientAttributeKeys(t *testing.T) {
+	uuid, err := util.GenerateUUID()
+	if err != nil {
+		t.Fatalf("Failed generating uuid [%s].", err.Error())
+	}
+	tx, err := invoker.NewChaincodeExecute(
+		&pb.ChaincodeInvocationSpec{
+			ChaincodeSpec: &pb.ChaincodeSpec{
+				Type:        pb.ChaincodeSpec_GOLANG,
+				ChaincodeID: &pb.ChaincodeID{Path: "Contract001"},
+				Attributes:  []string{"role"},
+			},
+		},
+		uuid,
+	)
+	if err != nil {
+		t.Fatalf("Failed creating execute transaction [%s].", err.Error())
+	}
+
+	if len(tx.AttributeKeys) != 1 || tx.AttributeKeys[0].Name != "role" {
+		t.Fatalf("Attribute key of role must be revealed")
+	}
+
+	cert, err := utils.DERToX509Certificate(tx.Cert)
+	if err != nil {
+		t.Fatalf("Failed parsing certificate [%s].", err.Error())
+	}
+	ct, err := utils.GetTCertAttribute(cert, "role")
+	if err != nil {
+		t.Fatalf("Failed getting attribute [%s].", err.Error())
+	}
+	role, err := utils.CBCPKCS7Decrypt(tx.AttributeKeys[0].Key, ct)
+	if err != nil {
+		t.Fatalf("Failed decrypting attribute [%s].", err.Error())
+	}
+	if string(role) != "bank-admin" {
+		t.Fatalf("Attribute role must be bank-admin, was [%s]", string(role))
+	}
+
+	if _, err := utils.GetTCertAttribute(cert, "company"); err != utils.ErrAttributeNotFound {
+		t.Fatalf("Attribute company must not be found")
+	}
+}
+
 func TestPeerID(t *testing.T) {
 	// Verify that any id modification doesn't change
 	id := peer.GetID()
@@ -336,6 +493,51 @@ func TestPeerSignVerify(t *testing.T) {
 	}
 }
 
+func TestPeerHello(t *testing.T) {
+	hello := []byte("Hello World!!!")
+	cert, signature, err := peer.SignHello(hello)
+	if err != nil {
+		t.Fatalf("Failed signing hello [%s].", err.Error())
+	}
+
+	peerTLSCert, err := peer.GetTLSCertificate()
+	if err != nil {
+		t.Fatalf("Failed getting TLS certificate [%s].", err.Error())
+	}
+	validatorTLSCert, err := validator.GetTLSCertificate()
+	if err != nil {
+		t.Fatalf("Failed getting TLS certificate [%s].", err.Error())
+	}
+
+	// The hello is accepted over a connection authenticated by the TLS certificate of the peer
+	err = validator.VerifyHello(peer.GetEnrollmentID(), cert, peerTLSCert.Certificate[0], hello, signature)
+	if err != nil {
+		t.Fatalf("Failed verifying hello [%s].", err.Error())
+	}
+
+	// The peer cannot pose as another one
+	err = validator.VerifyHello(validator.GetEnrollmentID(), cert, peerTLSCert.Certificate[0], hello, signature)
+	if err != utils.ErrPeerIDMismatch {
+		t.Fatalf("Hello claiming another peer id must be rejected [%v].", err)
+	}
+
+	// The hello cannot be replayed over another connection
+	err = validator.VerifyHello(peer.GetEnrollmentID(), cert, validatorTLSCert.Certificate[0], hello, signature)
+	if err != utils.ErrInvalidSignature {
+		t.Fatalf("Hello bound to another TLS certificate must be rejected [%v].", err)
+	}
+
+	// TLS certificates must be issued by the TLSCA
+	selfSignedCert, _, err := utils.NewSelfSignedCert()
+	if err != nil {
+		t.Fatalf("Failed generating self-signed certificate [%s].", err.Error())
+	}
+	err = validator.VerifyHello(peer.GetEnrollmentID(), cert, selfSignedCert, hello, signature)
+	if err == nil {
+		t.Fatalf("Hello over a connection not authenticated by the TLSCA must be rejected.")
+	}
+}
+
 func TestValidatorID(t *testing.T) {
 	// Verify that any id modification doesn't change
 	id := validator.GetID()
@@ -633,6 +835,682 @@ func TestValidatorSignVerify(t *testing.T) {
 	}
 }
 
+func TestValidatorRevokedCertificate(t *testing.T) {
+	tx, err := createPublicExecuteTransaction()
+	if err != nil {
+		t.Fatalf("Failed creating execute transaction [%s].", err.Error())
+	}
+
+	_, err = validator.TransactionPreValidation(tx)
+	if err != nil {
+		t.Fatalf("Error must be nil [%s].", err.Error())
+	}
+
+	// Revoke the transaction certificate through the TCA administrator interface
+	sockA, err := grpc.Dial("localhost"+viper.GetString("ports.tcaA"), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed dialing tca [%s].", err.Error())
+	}
+	defer sockA.Close()
+
+	_, err = capb.NewTCAAClient(sockA).RevokeCertificate(context.Background(), &capb.TCertRevokeReq{
+		Id:     &capb.Identity{Id: "admin"},
+		Cert:   &capb.Cert{Cert: tx.Cert},
+		Reason: capb.RevocationReason_KEY_COMPROMISE,
+	})
+	if err != nil {
+		t.Fatalf("Failed revoking certificate [%s].", err.Error())
+	}
+
+	// Do not wait for the next periodic refresh
+	err = validator.(*validatorImpl).peer.node.retrieveCRLs()
+	if err != nil {
+		t.Fatalf("Failed retrieving certificate revocation lists [%s].", err.Error())
+	}
+
+	_, err = validator.TransactionPreValidation(tx)
+	if err != utils.ErrCertificateRevoked {
+		t.Fatalf("Transaction signed with a revoked certificate must be rejected [%v].", err)
+	}
+}
+
+func TestValidatorRevokedEnrollmentCertificate(t *testing.T) {
+	// A dedicated user, as its enrollment certificate gets revoked
+	conf := utils.NodeConfiguration{Type: "client", Name: "user3"}
+	if err := RegisterClient(conf.Name, nil, conf.GetEnrollmentID(), conf.GetEnrollmentPWD()); err != nil {
+		t.Fatalf("Failed registering client [%s].", err.Error())
+	}
+	client, err := InitClient(conf.Name, nil)
+	if err != nil {
+		t.Fatalf("Failed initializing client [%s].", err.Error())
+	}
+	defer CloseClient(client)
+
+	uuid, err := util.GenerateUUID()
+	if err != nil {
+		t.Fatalf("Failed generating uuid [%s].", err.Error())
+	}
+	tx, err := client.NewChaincodeExecute(
+		&pb.ChaincodeInvocationSpec{
+			ChaincodeSpec: &pb.ChaincodeSpec{
+				Type:        pb.ChaincodeSpec_GOLANG,
+				ChaincodeID: &pb.ChaincodeID{Path: "Contract001"},
+			},
+		},
+		uuid,
+	)
+	if err != nil {
+		t.Fatalf("Failed creating execute transaction [%s].", err.Error())
+	}
+
+	_, err = validator.TransactionPreValidation(tx)
+	if err != nil {
+		t.Fatalf("Error must be nil [%s].", err.Error())
+	}
+
+	// Revoking the enrollment certificate revokes the transaction certificates of its owner
+	sockA, err := grpc.Dial("localhost"+viper.GetString("ports.ecaA"), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed dialing eca [%s].", err.Error())
+	}
+	defer sockA.Close()
+
+	_, err = capb.NewECAAClient(sockA).RevokeCertificate(context.Background(), &capb.ECertRevokeReq{
+		Id:     &capb.Identity{Id: "admin"},
+		Cert:   &capb.Cert{Cert: client.(*clientImpl).node.enrollCert.Raw},
+		Reason: capb.RevocationReason_KEY_COMPROMISE,
+	})
+	if err != nil {
+		t.Fatalf("Failed revoking certificate [%s].", err.Error())
+	}
+
+	err = validator.(*validatorImpl).peer.node.retrieveCRLs()
+	if err != nil {
+		t.Fatalf("Failed retrieving certificate revocation lists [%s].", err.Error())
+	}
+
+	_, err = validator.TransactionPreValidation(tx)
+	if err != utils.ErrCertificateRevoked {
+		t.Fatalf("Transaction signed with a transaction certificate of a revoked user must be rejected [%v].", err)
+	}
+}
+
+func TestValidatorRequiresCRL(t *testing.T) {
+	node := validator.(*validatorImpl).peer.node
+	node.stopCRLRefresh()
+	defer node.startCRLRefresh()
+
+	// The ECA revocation list does not verify against the TCA certificate
+	ecaChain, err := ioutil.ReadFile(node.conf.getECACertsChainPath())
+	if err != nil {
+		t.Fatalf("Failed reading eca certificates chain [%s].", err.Error())
+	}
+	tcaChain, err := ioutil.ReadFile(node.conf.getTCACertsChainPath())
+	if err != nil {
+		t.Fatalf("Failed reading tca certificates chain [%s].", err.Error())
+	}
+	if err := ioutil.WriteFile(node.conf.getECACertsChainPath(), tcaChain, 0700); err != nil {
+		t.Fatalf("Failed writing eca certificates chain [%s].", err.Error())
+	}
+	defer ioutil.WriteFile(node.conf.getECACertsChainPath(), ecaChain, 0700)
+
+	if err := node.startCRLRefresh(); err == nil {
+		t.Fatalf("Validator must not start without a verified certificate revocation list")
+	}
+	if node.crlStop != nil {
+		t.Fatalf("Certificate revocation lists must not be refreshed after a failed start")
+	}
+}
+
+func TestValidatorEndorsementPolicy(t *testing.T) {
+	// Deploy Contract001 requiring 2 of {orgA, orgB, orgC}
+	deployTx, err := pb.NewChaincodeDeployTransaction(
+		&pb.ChaincodeDeploymentSpec{
+			ChaincodeSpec: &pb.ChaincodeSpec{
+				Type:        pb.ChaincodeSpec_GOLANG,
+				ChaincodeID: &pb.ChaincodeID{Path: "Contract001"},
+				EndorsementPolicy: &pb.EndorsementPolicy{
+					Threshold:     2,
+					Organizations: []string{"orgA", "orgB", "orgC"},
+				},
+			},
+		},
+		"Contract001",
+	)
+	if err != nil {
+		t.Fatalf("Failed creating deploy transaction [%s].", err.Error())
+	}
+	SetChaincodeDeployments(testChaincodeDeployments{"": deployTx})
+	defer SetChaincodeDeployments(nil)
+
+	tx, err := createPublicExecuteTransaction()
+	if err != nil {
+		t.Fatalf("Failed creating execute transaction [%s].", err.Error())
+	}
+	if _, err := validator.TransactionPreValidation(tx); err != utils.ErrEndorsementPolicyNotSatisfied {
+		t.Fatalf("Transaction without endorsements must be rejected [%v].", err)
+	}
+
+	// user1 belongs to orgA, user2 to orgB
+	endorsementA, err := deployer.EndorseTransaction(tx)
+	if err != nil {
+		t.Fatalf("Failed endorsing transaction [%s].", err.Error())
+	}
+	endorsementB, err := invoker.EndorseTransaction(tx)
+	if err != nil {
+		t.Fatalf("Failed endorsing transaction [%s].", err.Error())
+	}
+
+	// An organization counts once
+	tx.Endorsements = []*pb.Endorsement{endorsementA, endorsementA}
+	if _, err := validator.TransactionPreValidation(tx); err != utils.ErrEndorsementPolicyNotSatisfied {
+		t.Fatalf("Transaction endorsed by a single organization must be rejected [%v].", err)
+	}
+
+	// Endorsements must match the transaction
+	forged := &pb.Endorsement{Cert: endorsementB.Cert, Signature: endorsementA.Signature}
+	tx.Endorsements = []*pb.Endorsement{endorsementA, forged}
+	if _, err := validator.TransactionPreValidation(tx); err != utils.ErrEndorsementPolicyNotSatisfied {
+		t.Fatalf("Transaction with a forged endorsement must be rejected [%v].", err)
+	}
+
+	tx.Endorsements = []*pb.Endorsement{endorsementA, endorsementB}
+	if _, err := validator.TransactionPreValidation(tx); err != nil {
+		t.Fatalf("Transaction satisfying the endorsement policy must be accepted [%s].", err.Error())
+	}
+}
+
+func TestValidatorUpdatePolicy(t *testing.T) {
+	spec := &pb.ChaincodeDeploymentSpec{
+		ChaincodeSpec: &pb.ChaincodeSpec{
+			Type:        pb.ChaincodeSpec_GOLANG,
+			ChaincodeID: &pb.ChaincodeID{Path: "Contract001"},
+		},
+	}
+	deployTx, err := pb.NewChaincodeDeployTransaction(spec, "Contract001")
+	if err != nil {
+		t.Fatalf("Failed creating deploy transaction [%s].", err.Error())
+	}
+	SetChaincodeDeployments(testChaincodeDeployments{"Contract001": deployTx})
+	defer SetChaincodeDeployments(nil)
+
+	uuid, err := util.GenerateUUID()
+	if err != nil {
+		t.Fatalf("Failed generating uuid [%s].", err.Error())
+	}
+	tx, err := deployer.NewChaincodeUpdateTransaction(
+		&pb.ChaincodeDeploymentSpec{
+			ChaincodeSpec: &pb.ChaincodeSpec{
+				Type:        pb.ChaincodeSpec_GOLANG,
+				ChaincodeID: &pb.ChaincodeID{Path: "Contract002", Name: "Contract001"},
+			},
+		},
+		uuid,
+	)
+	if err != nil {
+		t.Fatalf("Failed creating update transaction [%s].", err.Error())
+	}
+	if _, err := validator.TransactionPreValidation(tx); err != utils.ErrChaincodeNotUpdatable {
+		t.Fatalf("Update of a chaincode deployed without endorsement policy must be rejected [%v].", err)
+	}
+
+	// Deploy Contract001 requiring 2 of {orgA, orgB}
+	spec.ChaincodeSpec.EndorsementPolicy = &pb.EndorsementPolicy{Threshold: 2, Organizations: []string{"orgA", "orgB"}}
+	deployTx, err = pb.NewChaincodeDeployTransaction(spec, "Contract001")
+	if err != nil {
+		t.Fatalf("Failed creating deploy transaction [%s].", err.Error())
+	}
+	SetChaincodeDeployments(testChaincodeDeployments{"Contract001": deployTx})
+
+	if _, err := validator.TransactionPreValidation(tx); err != utils.ErrEndorsementPolicyNotSatisfied {
+		t.Fatalf("Update without endorsements must be rejected [%v].", err)
+	}
+
+	endorsementA, err := deployer.EndorseTransaction(tx)
+	if err != nil {
+		t.Fatalf("Failed endorsing transaction [%s].", err.Error())
+	}
+	endorsementB, err := invoker.EndorseTransaction(tx)
+	if err != nil {
+		t.Fatalf("Failed endorsing transaction [%s].", err.Error())
+	}
+	tx.Endorsements = []*pb.Endorsement{endorsementA, endorsementB}
+	if _, err := validator.TransactionPreValidation(tx); err != nil {
+		t.Fatalf("Update satisfying the endorsement policy must be accepted [%s].", err.Error())
+	}
+}
+
+func TestValidatorTerminatePolicy(t *testing.T) {
+	spec := &pb.ChaincodeDeploymentSpec{
+		ChaincodeSpec: &pb.ChaincodeSpec{
+			Type:        pb.ChaincodeSpec_GOLANG,
+			ChaincodeID: &pb.ChaincodeID{Path: "Contract001"},
+		},
+	}
+	deployTx, err := pb.NewChaincodeDeployTransaction(spec, "Contract001")
+	if err != nil {
+		t.Fatalf("Failed creating deploy transaction [%s].", err.Error())
+	}
+	SetChaincodeDeployments(testChaincodeDeployments{"Contract001": deployTx})
+	defer SetChaincodeDeployments(nil)
+
+	uuid, err := util.GenerateUUID()
+	if err != nil {
+		t.Fatalf("Failed generating uuid [%s].", err.Error())
+	}
+	tx, err := deployer.NewChaincodeTerminateTransaction(
+		&pb.ChaincodeInvocationSpec{
+			ChaincodeSpec: &pb.ChaincodeSpec{
+				Type:        pb.ChaincodeSpec_GOLANG,
+				ChaincodeID: &pb.ChaincodeID{Name: "Contract001"},
+			},
+		},
+		uuid,
+	)
+	if err != nil {
+		t.Fatalf("Failed creating terminate transaction [%s].", err.Error())
+	}
+	if _, err := validator.TransactionPreValidation(tx); err != utils.ErrChaincodeNotTerminable {
+		t.Fatalf("Termination of a chaincode deployed without endorsement policy must be rejected [%v].", err)
+	}
+
+	// Deploy Contract001 requiring 1 of {orgA}
+	spec.ChaincodeSpec.EndorsementPolicy = &pb.EndorsementPolicy{Threshold: 1, Organizations: []string{"orgA"}}
+	deployTx, err = pb.NewChaincodeDeployTransaction(spec, "Contract001")
+	if err != nil {
+		t.Fatalf("Failed creating deploy transaction [%s].", err.Error())
+	}
+	SetChaincodeDeployments(testChaincodeDeployments{"Contract001": deployTx})
+
+	endorsement, err := deployer.EndorseTransaction(tx)
+	if err != nil {
+		t.Fatalf("Failed endorsing transaction [%s].", err.Error())
+	}
+	tx.Endorsements = []*pb.Endorsement{endorsement}
+	if _, err := validator.TransactionPreValidation(tx); err != nil {
+		t.Fatalf("Termination satisfying the endorsement policy must be accepted [%s].", err.Error())
+	}
+}
+
+func TestValidatorECertRenewal(t *testing.T) {
+	node := validator.(*validatorImpl).peer.node
+
+	msg := []byte("Hello World!!!")
+	oldID := validator.GetID()
+	oldSignature, err := validator.Sign(msg)
+	if err != nil {
+		t.Fatalf("Failed generating signature [%s].", err.Error())
+	}
+
+	if err := node.renewEnrollmentCertificate(nil); err != nil {
+		t.Fatalf("Failed renewing enrollment certificate [%s].", err.Error())
+	}
+
+	newID := validator.GetID()
+	if bytes.Equal(oldID, newID) {
+		t.Fatalf("Renewal must change the validator id")
+	}
+	newSignature, err := validator.Sign(msg)
+	if err != nil {
+		t.Fatalf("Failed generating signature [%s].", err.Error())
+	}
+	if err := validator.Verify(newID, newSignature, msg); err != nil {
+		t.Fatalf("Failed verifying signature with the renewed certificate [%s].", err.Error())
+	}
+
+	// The superseded certificate is accepted during the grace period
+	if err := node.retrieveCRLs(); err != nil {
+		t.Fatalf("Failed retrieving certificate revocation lists [%s].", err.Error())
+	}
+	if err := validator.Verify(oldID, oldSignature, msg); err != nil {
+		t.Fatalf("Superseded certificate must be accepted during the grace period [%s].", err.Error())
+	}
+
+	// ...and rejected afterwards
+	grace := viper.GetString("peer.pki.eca.renewal.gracePeriod")
+	viper.Set("peer.pki.eca.renewal.gracePeriod", "0s")
+	defer viper.Set("peer.pki.eca.renewal.gracePeriod", grace)
+
+	if err := node.retrieveCRLs(); err != nil {
+		t.Fatalf("Failed retrieving certificate revocation lists [%s].", err.Error())
+	}
+	if err := validator.Verify(oldID, oldSignature, msg); err != utils.ErrCertificateRevoked {
+		t.Fatalf("Superseded certificate must be rejected after the grace period [%v].", err)
+	}
+	if err := validator.Verify(newID, newSignature, msg); err != nil {
+		t.Fatalf("Failed verifying signature with the renewed certificate [%s].", err.Error())
+	}
+}
+
+// failingKeyStore is a key store which fails storing keys
+type failingKeyStore struct {
+	KeyStore
+}
+
+func (ks *failingKeyStore) StoreKey(alias string, key *ecdsa.PrivateKey) error {
+	return fmt.Errorf("Key store unavailable.")
+}
+
+func TestValidatorECertRenewalRetry(t *testing.T) {
+	node := validator.(*validatorImpl).peer.node
+	oldID := validator.GetID()
+
+	// The ECA supersedes the current certificate, but the node fails storing the new key
+	backend := node.ks.KeyStore
+	node.ks.KeyStore = &failingKeyStore{backend}
+	err := node.renewEnrollmentCertificate(nil)
+	node.ks.KeyStore = backend
+	if err == nil {
+		t.Fatalf("Renewal must fail when the enrollment key cannot be stored")
+	}
+	if !bytes.Equal(oldID, validator.GetID()) {
+		t.Fatalf("Failed renewal must keep the validator id")
+	}
+
+	// Renewing again with the superseded certificate is accepted during the grace period
+	if err := node.renewEnrollmentCertificate(nil); err != nil {
+		t.Fatalf("Failed renewing enrollment certificate with the superseded certificate [%s].", err.Error())
+	}
+	if bytes.Equal(oldID, validator.GetID()) {
+		t.Fatalf("Renewal must change the validator id")
+	}
+}
+
+func TestAuditorConfidentialTransaction(t *testing.T) {
+	tx, err := createConfidentialExecuteTransaction()
+	if err != nil {
+		t.Fatalf("Failed creating execute transaction [%s].", err.Error())
+	}
+
+	enrollID, err := auditor.GetTransactionEnrollmentID(tx)
+	if err != nil {
+		t.Fatalf("Failed getting transaction enrollment id [%s].", err.Error())
+	}
+	if enrollID != invoker.(*clientImpl).node.enrollID {
+		t.Fatalf("Enrollment id must be [%s], was [%s].", invoker.(*clientImpl).node.enrollID, enrollID)
+	}
+
+	res, err := auditor.DecryptTransaction(tx)
+	if err != nil {
+		t.Fatalf("Failed decrypting transaction [%s].", err.Error())
+	}
+	if res.Payload == nil || res.ChaincodeID == nil {
+		t.Fatalf("Payload and chaincodeID must be in the clear")
+	}
+}
+
+func TestAuditorTransactionsByEnrollmentID(t *testing.T) {
+	deployTx, err := createConfidentialDeployTransaction()
+	if err != nil {
+		t.Fatalf("Failed creating deploy transaction [%s].", err.Error())
+	}
+	executeTx, err := createConfidentialExecuteTransaction()
+	if err != nil {
+		t.Fatalf("Failed creating execute transaction [%s].", err.Error())
+	}
+	queryTx, err := createPublicQueryTransaction()
+	if err != nil {
+		t.Fatalf("Failed creating query transaction [%s].", err.Error())
+	}
+
+	blockchain := testBlockchain{
+		&pb.Block{},
+		&pb.Block{Transactions: []*pb.Transaction{deployTx, executeTx}},
+		&pb.Block{Transactions: []*pb.Transaction{queryTx}},
+	}
+
+	txs, err := auditor.GetTransactionsByEnrollmentID(blockchain, invoker.(*clientImpl).node.enrollID)
+	if err != nil {
+		t.Fatalf("Failed listing transactions [%s].", err.Error())
+	}
+	if len(txs) != 2 {
+		t.Fatalf("Expected 2 transactions, got [%d].", len(txs))
+	}
+	if txs[0].Uuid != executeTx.Uuid || txs[1].Uuid != queryTx.Uuid {
+		t.Fatalf("Unexpected transactions [%s] [%s].", txs[0].Uuid, txs[1].Uuid)
+	}
+}
+
+func TestKeyStoreVault(t *testing.T) {
+	conf := &configuration{prefix: "test", name: "vault"}
+	if err := conf.loadConfiguration(); err != nil {
+		t.Fatalf("Failed loading configuration [%s].", err.Error())
+	}
+	defer os.RemoveAll(conf.getKeyStorePath())
+	log := logging.MustGetLogger("CRYPTO.test.vault")
+
+	ks, err := newVaultKeyStore(conf, log, []byte("passphrase"))
+	if err != nil {
+		t.Fatalf("Failed creating vault [%s].", err.Error())
+	}
+	key, err := utils.NewECDSAKey()
+	if err != nil {
+		t.Fatalf("Failed generating key [%s].", err.Error())
+	}
+	if err := ks.StoreKey("key", key); err != nil {
+		t.Fatalf("Failed storing key [%s].", err.Error())
+	}
+	if err := ks.StoreCert("id", []byte("cert")); err != nil {
+		t.Fatalf("Failed storing cert [%s].", err.Error())
+	}
+	if err := ks.StoreTCerts([][]byte{[]byte("tcert")}); err != nil {
+		t.Fatalf("Failed storing tcerts [%s].", err.Error())
+	}
+	ks.Close()
+
+	// The key must not sit on disk in the clear
+	raw, err := ioutil.ReadFile(conf.getKeyStoreVaultPath())
+	if err != nil {
+		t.Fatalf("Failed reading vault [%s].", err.Error())
+	}
+	der, _ := utils.PrivateKeyToDER(key)
+	if bytes.Contains(raw, der) {
+		t.Fatalf("Vault contains the key in the clear")
+	}
+
+	if _, err := newVaultKeyStore(conf, log, []byte("wrong")); err != utils.ErrInvalidPassphrase {
+		t.Fatalf("Opening the vault with a wrong passphrase must fail")
+	}
+
+	ks, err = newVaultKeyStore(conf, log, []byte("passphrase"))
+	if err != nil {
+		t.Fatalf("Failed reopening vault [%s].", err.Error())
+	}
+	defer ks.Close()
+
+	msg := []byte("Hello World")
+	sigma, err := ks.Sign("key", msg)
+	if err != nil {
+		t.Fatalf("Failed signing [%s].", err.Error())
+	}
+	if ok, _ := utils.ECDSAVerify(&key.PublicKey, msg, sigma); !ok {
+		t.Fatalf("Signature must verify")
+	}
+
+	cert, err := ks.GetCert("id")
+	if err != nil || !bytes.Equal(cert, []byte("cert")) {
+		t.Fatalf("Failed getting cert")
+	}
+
+	tCert, err := ks.NextTCert()
+	if err != nil || !bytes.Equal(tCert, []byte("tcert")) {
+		t.Fatalf("Failed getting tcert")
+	}
+	tCert, err = ks.NextTCert()
+	if err != nil || tCert != nil {
+		t.Fatalf("TCert pool must be empty")
+	}
+
+	if err := ks.StoreTCerts([][]byte{[]byte("old"), []byte("new")}); err != nil {
+		t.Fatalf("Failed storing tcerts [%s].", err.Error())
+	}
+	n, err := ks.DeleteTCerts(func(cert []byte) bool { return bytes.Equal(cert, []byte("old")) })
+	if err != nil || n != 1 {
+		t.Fatalf("Failed deleting tcerts [%d] [%v]", n, err)
+	}
+	tCert, err = ks.NextTCert()
+	if err != nil || !bytes.Equal(tCert, []byte("new")) {
+		t.Fatalf("Only matching tcerts must be deleted")
+	}
+}
+
+// testRemoteSigner stands in for a PKCS#11-style remote signer
+type testRemoteSigner struct {
+	m    sync.Mutex
+	keys map[string]*ecdsa.PrivateKey
+}
+
+func (signer *testRemoteSigner) ImportKey(ctx context.Context, req *pb.KeyImportRequest) (*pb.KeyImportResponse, error) {
+	key, err := utils.DERToPrivateKey(req.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signer.m.Lock()
+	signer.keys[req.Alias] = key.(*ecdsa.PrivateKey)
+	signer.m.Unlock()
+
+	return &pb.KeyImportResponse{}, nil
+}
+
+func (signer *testRemoteSigner) Sign(ctx context.Context, req *pb.SignRequest) (*pb.SignResponse, error) {
+	signer.m.Lock()
+	key, ok := signer.keys[req.Alias]
+	signer.m.Unlock()
+	if !ok {
+		return nil, utils.ErrKeyNotFound
+	}
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, req.Digest)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := asn1.Marshal(utils.ECDSASignature{R: r, S: s})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.SignResponse{Signature: raw}, nil
+}
+
+func TestKeyStoreRemoteSigner(t *testing.T) {
+	sock := filepath.Join(os.TempDir(), "obc-test-signer.sock")
+	os.Remove(sock)
+	lis, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("Failed listening [%s].", err.Error())
+	}
+	signer := &testRemoteSigner{keys: make(map[string]*ecdsa.PrivateKey)}
+	srv := grpc.NewServer()
+	pb.RegisterRemoteSignerServer(srv, signer)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	viper.Set("peer.pki.keystore.remote.address", "unix:"+sock)
+
+	testRemoteKeyStore(t)
+}
+
+func TestKeyStoreRemoteSignerTLS(t *testing.T) {
+	key, err := utils.NewECDSAKey()
+	if err != nil {
+		t.Fatalf("Failed generating key [%s].", err.Error())
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "obc-signer"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:              []string{"obc-signer"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed creating certificate [%s].", err.Error())
+	}
+	certFile := filepath.Join(os.TempDir(), "obc-test-signer.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}), 0600); err != nil {
+		t.Fatalf("Failed writing certificate [%s].", err.Error())
+	}
+	defer os.Remove(certFile)
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed listening [%s].", err.Error())
+	}
+	signer := &testRemoteSigner{keys: make(map[string]*ecdsa.PrivateKey)}
+	srv := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&tls.Certificate{Certificate: [][]byte{raw}, PrivateKey: key})))
+	pb.RegisterRemoteSignerServer(srv, signer)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	viper.Set("peer.pki.keystore.remote.address", lis.Addr().String())
+	viper.Set("peer.pki.keystore.remote.tls.rootcert.file", certFile)
+	viper.Set("peer.pki.keystore.remote.tls.serverhostoverride", "obc-signer")
+	defer viper.Set("peer.pki.keystore.remote.tls.rootcert.file", "")
+	defer viper.Set("peer.pki.keystore.remote.tls.serverhostoverride", "")
+
+	testRemoteKeyStore(t)
+}
+
+func TestKeyStoreRemoteSignerRequiresTLS(t *testing.T) {
+	viper.Set("peer.pki.keystore.remote.address", "localhost:0")
+
+	conf := &configuration{prefix: "test", name: "remote"}
+	if err := conf.loadConfiguration(); err != nil {
+		t.Fatalf("Failed loading configuration [%s].", err.Error())
+	}
+	defer os.RemoveAll(conf.getKeyStorePath())
+
+	if _, err := newRemoteKeyStore(conf, logging.MustGetLogger("CRYPTO.test.remote")); err != utils.ErrRemoteSignerTLSRequired {
+		t.Fatalf("Remote signer over TCP must require TLS [%v].", err)
+	}
+}
+
+// testRemoteKeyStore imports a key into the remote signer and signs with it
+func testRemoteKeyStore(t *testing.T) {
+	conf := &configuration{prefix: "test", name: "remote"}
+	if err := conf.loadConfiguration(); err != nil {
+		t.Fatalf("Failed loading configuration [%s].", err.Error())
+	}
+	defer os.RemoveAll(conf.getKeyStorePath())
+	log := logging.MustGetLogger("CRYPTO.test.remote")
+
+	ks, err := newRemoteKeyStore(conf, log)
+	if err != nil {
+		t.Fatalf("Failed connecting to the remote signer [%s].", err.Error())
+	}
+	defer ks.Close()
+
+	key, err := utils.NewECDSAKey()
+	if err != nil {
+		t.Fatalf("Failed generating key [%s].", err.Error())
+	}
+	if err := ks.StoreKey("key", key); err != nil {
+		t.Fatalf("Failed importing key [%s].", err.Error())
+	}
+
+	// The key must live in the signer only
+	if missing, _ := utils.FilePathMissing(filepath.Join(conf.getKeysPath(), "key")); !missing {
+		t.Fatalf("Key must not be stored on disk")
+	}
+	if _, err := ks.GetKey("key"); err != utils.ErrKeyNotExportable {
+		t.Fatalf("Key must not be exportable")
+	}
+
+	msg := []byte("Hello World")
+	sigma, err := ks.Sign("key", msg)
+	if err != nil {
+		t.Fatalf("Failed signing [%s].", err.Error())
+	}
+	if ok, _ := utils.ECDSAVerify(&key.PublicKey, msg, sigma); !ok {
+		t.Fatalf("Signature must verify")
+	}
+}
+
 func setup() {
 	viper.SetConfigName("crypto_test") // name of config file (without extension)
 	viper.AddConfigPath(".")           // path to look for the config file in
@@ -750,6 +1628,39 @@ func initValidators() error {
 	return err
 }
 
+func initAuditors() error {
+	// Register
+	conf := utils.NodeConfiguration{Type: "auditor", Name: "auditor"}
+	err := RegisterAuditor(conf.Name, nil, conf.GetEnrollmentID(), conf.GetEnrollmentPWD())
+	if err != nil {
+		return err
+	}
+
+	// Init
+	auditor, err = InitAuditor(conf.Name, nil)
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+type testBlockchain []*pb.Block
+
+func (blockchain testBlockchain) GetBlockchainSize() uint64 {
+	return uint64(len(blockchain))
+}
+
+func (blockchain testBlockchain) GetBlockByNumber(blockNumber uint64) (*pb.Block, error) {
+	return blockchain[blockNumber], nil
+}
+
+type testChaincodeDeployments map[string]*pb.Transaction
+
+func (deployments testChaincodeDeployments) GetDeployTransaction(name string) (*pb.Transaction, error) {
+	return deployments[name], nil
+}
+
 func createConfidentialDeployTransaction() (*pb.Transaction, error) {
 	uuid, err := util.GenerateUUID()
 	if err != nil {
@@ -873,6 +1784,7 @@ func cleanup() {
 	CloseAllClients()
 	CloseAllPeers()
 	CloseAllValidators()
+	CloseAllAuditors()
 	killCAs()
 	removeFolders()
 	fmt.Println("Cleanup...done!")
@@ -0,0 +1,110 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package crypto
+
+import (
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
+	obc "github.com/openblockchain/obc-peer/protos"
+)
+
+func (node *nodeImpl) decryptTx(tx *obc.Transaction) (*obc.Transaction, error) {
+	if tx.Nonce == nil || len(tx.Nonce) == 0 {
+		return nil, errors.New("Failed decrypting payload. Invalid nonce.")
+	}
+
+	// clone tx
+	clone, err := node.deepCloneTransaction(tx)
+	if err != nil {
+		node.log.Error("Failed deep cloning [%s].", err.Error())
+		return nil, err
+	}
+
+	// Derive root key
+	key := utils.HMAC(node.enrollChainKey, clone.Nonce)
+
+	//	node.log.Info("Deriving from  ", utils.EncodeBase64(node.enrollChainKey))
+	//	node.log.Info("Nonce  ", utils.EncodeBase64(tx.Nonce))
+	//	node.log.Info("Derived key  ", utils.EncodeBase64(key))
+	//	node.log.Info("Encrypted Payload  ", utils.EncodeBase64(tx.EncryptedPayload))
+	//	node.log.Info("Encrypted ChaincodeID  ", utils.EncodeBase64(tx.EncryptedChaincodeID))
+
+	// Decrypt using the derived key
+
+	payloadKey := utils.HMACTruncated(key, []byte{1}, utils.AESKeyLength)
+	encryptedPayload := make([]byte, len(clone.EncryptedPayload))
+	copy(encryptedPayload, clone.EncryptedPayload)
+	payload, err := utils.CBCPKCS7Decrypt(payloadKey, encryptedPayload)
+	if err != nil {
+		node.log.Error("Failed decrypting payload [%s].", err.Error())
+		return nil, err
+	}
+	clone.Payload = payload
+
+	chaincodeIDKey := utils.HMACTruncated(key, []byte{2}, utils.AESKeyLength)
+	encryptedChaincodeID := make([]byte, len(clone.EncryptedChaincodeID))
+	copy(encryptedChaincodeID, clone.EncryptedChaincodeID)
+	rawChaincodeID, err := utils.CBCPKCS7Decrypt(chaincodeIDKey, encryptedChaincodeID)
+
+	chaincodeID := &obc.ChaincodeID{}
+	if err := proto.Unmarshal(rawChaincodeID, chaincodeID); err != nil {
+		node.log.Error("Failed decrypting chaincodeID [%s].", err.Error())
+
+		return nil, err
+	}
+	clone.ChaincodeID = chaincodeID
+
+	return clone, nil
+}
+
+func (node *nodeImpl) deepCloneTransaction(tx *obc.Transaction) (*obc.Transaction, error) {
+	raw, err := proto.Marshal(tx)
+	if err != nil {
+		node.log.Error("Failed cloning transaction [%s].", err.Error())
+
+		return nil, err
+	}
+
+	clone := &obc.Transaction{}
+	err = proto.Unmarshal(raw, clone)
+	if err != nil {
+		node.log.Error("Failed cloning transaction [%s].", err.Error())
+
+		return nil, err
+	}
+
+	return clone, nil
+}
+
+// getEndorsementMessage returns the message signed by the endorsers of tx,
+// that is tx without the submitter's certificate and signature and without
+// the endorsements
+func (node *nodeImpl) getEndorsementMessage(tx *obc.Transaction) ([]byte, error) {
+	clone, err := node.deepCloneTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	clone.Cert = nil
+	clone.Signature = nil
+	clone.Endorsements = nil
+
+	return proto.Marshal(clone)
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/op/go-logging"
 	"github.com/spf13/viper"
 	"path/filepath"
+	"time"
 )
 
 func (node *nodeImpl) initConfiguration(prefix, name string) error {
@@ -45,6 +46,17 @@ type configuration struct {
 	ecaPAddressProperty string
 	tcaPAddressProperty string
 	tlscaPAddressProperty string
+	crlRefreshIntervalProperty string
+	keyStoreBackendProperty string
+	keyStoreVaultPassphraseProperty string
+	remoteSignerAddressProperty string
+	remoteSignerTLSRootCertFileProperty string
+	remoteSignerTLSServerHostOverrideProperty string
+	tCertPoolLowWatermarkProperty string
+	tCertPoolHighWatermarkProperty string
+	eCertRenewBeforeProperty string
+	eCertRenewalCheckIntervalProperty string
+	eCertRenewalGracePeriodProperty string
 }
 
 func (conf *configuration) loadConfiguration() error {
@@ -52,6 +64,17 @@ func (conf *configuration) loadConfiguration() error {
 	conf.ecaPAddressProperty = "peer.pki.eca.paddr"
 	conf.tcaPAddressProperty = "peer.pki.tca.paddr"
 	conf.tlscaPAddressProperty = "peer.pki.tlsca.paddr"
+	conf.crlRefreshIntervalProperty = "peer.pki.crl.refreshInterval"
+	conf.keyStoreBackendProperty = "peer.pki.keystore.backend"
+	conf.keyStoreVaultPassphraseProperty = "peer.pki.keystore.vault.passphrase"
+	conf.remoteSignerAddressProperty = "peer.pki.keystore.remote.address"
+	conf.remoteSignerTLSRootCertFileProperty = "peer.pki.keystore.remote.tls.rootcert.file"
+	conf.remoteSignerTLSServerHostOverrideProperty = "peer.pki.keystore.remote.tls.serverhostoverride"
+	conf.tCertPoolLowWatermarkProperty = "peer.pki.tca.tcertPool.lowWatermark"
+	conf.tCertPoolHighWatermarkProperty = "peer.pki.tca.tcertPool.highWatermark"
+	conf.eCertRenewBeforeProperty = "peer.pki.eca.renewal.before"
+	conf.eCertRenewalCheckIntervalProperty = "peer.pki.eca.renewal.checkInterval"
+	conf.eCertRenewalGracePeriodProperty = "peer.pki.eca.renewal.gracePeriod"
 
 	// Check mandatory fields
 	if err := conf.checkProperty(conf.configurationPathProperty); err != nil {
@@ -98,6 +121,53 @@ func (conf *configuration) getTLSCAPAddr() string {
 	return viper.GetString(conf.tlscaPAddressProperty)
 }
 
+func (conf *configuration) getCRLRefreshInterval() time.Duration {
+	interval := viper.GetDuration(conf.crlRefreshIntervalProperty)
+	if interval <= 0 {
+		return time.Minute
+	}
+	return interval
+}
+
+func (conf *configuration) getECertRenewBefore() time.Duration {
+	before := viper.GetDuration(conf.eCertRenewBeforeProperty)
+	if before <= 0 {
+		return time.Hour * 24 * 7
+	}
+	return before
+}
+
+func (conf *configuration) getECertRenewalCheckInterval() time.Duration {
+	interval := viper.GetDuration(conf.eCertRenewalCheckIntervalProperty)
+	if interval <= 0 {
+		return time.Hour
+	}
+	return interval
+}
+
+func (conf *configuration) getECertRenewalGracePeriod() time.Duration {
+	if !viper.IsSet(conf.eCertRenewalGracePeriodProperty) {
+		return time.Hour * 24
+	}
+	grace := viper.GetDuration(conf.eCertRenewalGracePeriodProperty)
+	if grace < 0 {
+		return 0
+	}
+	return grace
+}
+
+func (conf *configuration) getTCertPoolWatermarks() (int, int) {
+	low := viper.GetInt(conf.tCertPoolLowWatermarkProperty)
+	if low <= 0 {
+		low = 10
+	}
+	high := viper.GetInt(conf.tCertPoolHighWatermarkProperty)
+	if high <= low {
+		high = 5 * low
+	}
+	return low, high
+}
+
 func (conf *configuration) getConfPath() string {
 	return conf.configurationPath
 }
@@ -114,6 +184,34 @@ func (conf *configuration) getKeyStoreFilePath() string {
 	return filepath.Join(conf.getKeyStorePath(), conf.getKeyStoreFilename())
 }
 
+func (conf *configuration) getKeyStoreBackend() string {
+	backend := viper.GetString(conf.keyStoreBackendProperty)
+	if backend == "" {
+		return sqliteKeyStoreBackend
+	}
+	return backend
+}
+
+func (conf *configuration) getKeyStoreVaultPath() string {
+	return filepath.Join(conf.getKeyStorePath(), "vault")
+}
+
+func (conf *configuration) getKeyStoreVaultPassphrase() []byte {
+	return []byte(viper.GetString(conf.keyStoreVaultPassphraseProperty))
+}
+
+func (conf *configuration) getRemoteSignerAddr() string {
+	return viper.GetString(conf.remoteSignerAddressProperty)
+}
+
+func (conf *configuration) getRemoteSignerTLSRootCertFile() string {
+	return viper.GetString(conf.remoteSignerTLSRootCertFileProperty)
+}
+
+func (conf *configuration) getRemoteSignerTLSServerHostOverride() string {
+	return viper.GetString(conf.remoteSignerTLSServerHostOverrideProperty)
+}
+
 func (conf *configuration) getKeysPath() string {
 	return conf.getConfPath()
 }
@@ -134,6 +232,10 @@ func (conf *configuration) getEnrollmentCertFilename() string {
 	return "enrollment.cert"
 }
 
+func (conf *configuration) getRenewedEnrollmentCertPath() string {
+	return conf.getEnrollmentCertPath() + ".renewed"
+}
+
 func (conf *configuration) getEnrollmentIDPath() string {
 	return filepath.Join(conf.getKeysPath(), conf.getEnrollmentIDFilename())
 }
@@ -158,6 +260,14 @@ func (conf *configuration) getECACertsChainFilename() string {
 	return "eca.cert.chain"
 }
 
+func (conf *configuration) getTLSCACertsChainPath() string {
+	return filepath.Join(conf.getKeysPath(), conf.getTLSCACertsChainFilename())
+}
+
+func (conf *configuration) getTLSCACertsChainFilename() string {
+	return "tlsca.cert.chain"
+}
+
 func (conf *configuration) getTLSKeyPath() string {
 	return filepath.Join(conf.getKeysPath(), conf.getTLSKeyFilename())
 }
@@ -197,3 +307,11 @@ func (conf *configuration) getTCertOwnerKDFKeyPath() string {
 func (conf *configuration) getTCertOwnerKDFKeyFilename() string {
 	return "tca.kdf.key"
 }
+
+func (conf *configuration) getTCertAuditKeyPath() string {
+	return filepath.Join(conf.getKeysPath(), conf.getTCertAuditKeyFilename())
+}
+
+func (conf *configuration) getTCertAuditKeyFilename() string {
+	return "tca.audit.key"
+}
@@ -0,0 +1,201 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package crypto
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	obcca "github.com/openblockchain/obc-peer/obc-ca/protos"
+	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
+	"golang.org/x/net/context"
+	"io/ioutil"
+	"time"
+)
+
+// crlReasonCodeOID is the object identifier of the CRL entry reason code extension
+var crlReasonCodeOID = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+// crlCertificateHashOID is the object identifier of the CRL entry extension carrying the hash of
+// the revoked certificate
+var crlCertificateHashOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 12}
+
+func (node *nodeImpl) startCRLRefresh() error {
+	if node.crlStop != nil {
+		return nil
+	}
+
+	// Retrieve the revocation lists once before serving, afterwards refresh them periodically.
+	// Without a verified list revoked certificates would be accepted, so the node does not start.
+	if err := node.retrieveCRLs(); err != nil {
+		node.log.Error("Failed retrieving certificate revocation lists [%s].", err.Error())
+
+		return err
+	}
+
+	stop := make(chan struct{})
+	node.crlStop = stop
+
+	go func() {
+		ticker := time.NewTicker(node.conf.getCRLRefreshInterval())
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := node.retrieveCRLs(); err != nil {
+					node.log.Warning("Failed retrieving certificate revocation lists [%s].", err.Error())
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (node *nodeImpl) stopCRLRefresh() {
+	if node.crlStop != nil {
+		close(node.crlStop)
+		node.crlStop = nil
+	}
+}
+
+func (node *nodeImpl) retrieveCRLs() error {
+	node.log.Debug("Retrieving certificate revocation lists...")
+
+	ecaCRL, err := node.callECAReadCRL(context.Background(), &obcca.CRLReadReq{})
+	if err != nil {
+		return err
+	}
+	tcaCRL, err := node.callTCAReadCRL(context.Background(), &obcca.CRLReadReq{})
+	if err != nil {
+		return err
+	}
+
+	// Revoked certificates are identified by their issuer and their hash, as certificates issued
+	// before the CAs randomized serial numbers all share serial number 1.
+	// Each entry records when the certificate stops being accepted: superseded enrollment
+	// certificates remain valid for a grace period after their renewal.
+	revoked := make(map[string]time.Time)
+	grace := node.conf.getECertRenewalGracePeriod()
+	for _, entry := range []struct {
+		raw       []byte
+		chainPath string
+	}{
+		{ecaCRL.Crl, node.conf.getECACertsChainPath()},
+		{tcaCRL.Crl, node.conf.getTCACertsChainPath()},
+	} {
+		crl, caCert, err := node.verifyCRL(entry.raw, entry.chainPath)
+		if err != nil {
+			node.log.Error("Failed verifying certificate revocation list [%s].", err.Error())
+
+			return err
+		}
+
+		for _, cert := range crl.TBSCertList.RevokedCertificates {
+			hash := getRevokedCertificateHash(cert)
+			if hash == nil {
+				node.log.Error("Failed reading hash of revoked certificate [%s].", cert.SerialNumber.String())
+
+				return errors.New("Revoked certificate without hash.")
+			}
+
+			var notAccepted time.Time
+			if getRevocationReason(cert) == obcca.RevocationReason_SUPERSEDED {
+				notAccepted = cert.RevocationTime.Add(grace)
+			}
+			revoked[revocationKey(caCert.RawSubject, hash)] = notAccepted
+		}
+	}
+
+	node.crlMutex.Lock()
+	node.revokedCerts = revoked
+	node.crlMutex.Unlock()
+
+	node.log.Debug("Retrieving certificate revocation lists...done! [%d] revoked certificates.", len(revoked))
+
+	return nil
+}
+
+func (node *nodeImpl) verifyCRL(raw []byte, caCertPath string) (*pkix.CertificateList, *x509.Certificate, error) {
+	pem, err := ioutil.ReadFile(caCertPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	caCert, err := utils.PEMtoCertificate(pem)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	crl, err := x509.ParseCRL(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := caCert.CheckCRLSignature(crl); err != nil {
+		return nil, nil, err
+	}
+	if crl.HasExpired(time.Now()) {
+		return nil, nil, errors.New("Certificate revocation list expired.")
+	}
+
+	return crl, caCert, nil
+}
+
+func (node *nodeImpl) isCertRevoked(cert *x509.Certificate) bool {
+	node.crlMutex.RLock()
+	defer node.crlMutex.RUnlock()
+
+	notAccepted, ok := node.revokedCerts[revocationKey(cert.RawIssuer, utils.Hash(cert.Raw))]
+
+	return ok && !time.Now().Before(notAccepted)
+}
+
+func revocationKey(issuer, hash []byte) string {
+	return string(issuer) + string(hash)
+}
+
+func getRevokedCertificateHash(cert pkix.RevokedCertificate) []byte {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(crlCertificateHashOID) {
+			var hash []byte
+			if _, err := asn1.Unmarshal(ext.Value, &hash); err == nil {
+				return hash
+			}
+		}
+	}
+
+	return nil
+}
+
+func getRevocationReason(cert pkix.RevokedCertificate) obcca.RevocationReason {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(crlReasonCodeOID) {
+			var reason asn1.Enumerated
+			if _, err := asn1.Unmarshal(ext.Value, &reason); err == nil {
+				return obcca.RevocationReason(reason)
+			}
+		}
+	}
+
+	return obcca.RevocationReason_UNSPECIFIED
+}
@@ -17,9 +17,18 @@ func (node *nodeImpl) initCryptoEngine() error {
 		return err
 	}
 
+	// Load TLSCA certs chain
+	if err := node.loadTLSCACertsChain(); err != nil {
+		return err
+	}
+
+	// Load TLS certificate
+	if err := node.loadTLSCertificate(); err != nil {
+		return err
+	}
+
 	// Load enrollment secret key
-	// TODO: finalize encrypted pem support
-	if err := node.loadEnrollmentKey(nil); err != nil {
+	if err := node.loadEnrollmentKey(); err != nil {
 		return err
 	}
 
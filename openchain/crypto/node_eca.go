@@ -127,6 +127,48 @@ func (node *nodeImpl) callECAReadCertificate(ctx context.Context, in *obcca.ECer
 	return cert, nil
 }
 
+func (node *nodeImpl) callECAReadCRL(ctx context.Context, in *obcca.CRLReadReq, opts ...grpc.CallOption) (*obcca.CRL, error) {
+	sockP, err := grpc.Dial(node.conf.getECAPAddr(), grpc.WithInsecure())
+	if err != nil {
+		node.log.Error("Failed eca dialing in [%s].", err.Error())
+
+		return nil, err
+	}
+	defer sockP.Close()
+
+	ecaP := obcca.NewECAPClient(sockP)
+
+	crl, err := ecaP.ReadCRL(context.Background(), in)
+	if err != nil {
+		node.log.Error("Failed requesting read crl [%s].", err.Error())
+
+		return nil, err
+	}
+
+	return crl, nil
+}
+
+func (node *nodeImpl) callECARenewCertificate(ctx context.Context, in *obcca.ECertRenewReq, opts ...grpc.CallOption) (*obcca.Cert, error) {
+	sockP, err := grpc.Dial(node.conf.getECAPAddr(), grpc.WithInsecure())
+	if err != nil {
+		node.log.Error("Failed eca dialing in [%s].", err.Error())
+
+		return nil, err
+	}
+	defer sockP.Close()
+
+	ecaP := obcca.NewECAPClient(sockP)
+
+	cred, err := ecaP.RenewCertificate(context.Background(), in)
+	if err != nil {
+		node.log.Error("Failed requesting renew certificate [%s].", err.Error())
+
+		return nil, err
+	}
+
+	return cred.Cert, nil
+}
+
 func (node *nodeImpl) getEnrollmentCertificateFromECA(id, pw string) (interface{}, []byte, []byte, error) {
 	priv, err := utils.NewECDSAKey()
 
@@ -21,9 +21,12 @@ package crypto
 
 import (
 	"crypto/ecdsa"
+	"crypto/tls"
 	"crypto/x509"
 	"github.com/op/go-logging"
 	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
+	"sync"
+	"time"
 )
 
 // Public Struct
@@ -46,19 +49,55 @@ type nodeImpl struct {
 	// 48-bytes identifier
 	id []byte
 
-	// Enrollment Certificate and private key
+	// Enrollment Certificate and private key. enrollMutex guards id,
+	// enrollCert, enrollPrivKey and the stored enrollment key, all of
+	// which are replaced when the enrollment certificate is renewed.
 	enrollID      string
+	enrollMutex   sync.RWMutex
 	enrollCert    *x509.Certificate
 	enrollPrivKey *ecdsa.PrivateKey
 
 	// Enrollment Chain
 	enrollChainKey []byte
+
+	// TLS certificate and the TLSCA certs authenticating remote peers
+	tlsCert     *tls.Certificate
+	tlsCertPool *x509.CertPool
+
+	// Certificate revocation lists
+	crlMutex     sync.RWMutex
+	revokedCerts map[string]time.Time
+	crlStop      chan struct{}
+
+	// Enrollment certificate renewal
+	renewalStop chan struct{}
 }
 
 func (node *nodeImpl) GetName() string {
 	return node.conf.name
 }
 
+func (node *nodeImpl) getID() []byte {
+	node.enrollMutex.RLock()
+	defer node.enrollMutex.RUnlock()
+
+	return node.id
+}
+
+func (node *nodeImpl) getEnrollmentCert() *x509.Certificate {
+	node.enrollMutex.RLock()
+	defer node.enrollMutex.RUnlock()
+
+	return node.enrollCert
+}
+
+func (node *nodeImpl) getEnrollmentPrivKey() *ecdsa.PrivateKey {
+	node.enrollMutex.RLock()
+	defer node.enrollMutex.RUnlock()
+
+	return node.enrollPrivKey
+}
+
 func (node *nodeImpl) register(prefix, name string, pwd []byte, enrollID, enrollPWD string) error {
 	if node.isInitialized {
 		node.log.Error("Registering [%s]...done! Initialization already performed", enrollID)
@@ -88,6 +127,12 @@ func (node *nodeImpl) register(prefix, name string, pwd []byte, enrollID, enroll
 		return err
 	}
 
+	if err := node.initKeyStore(pwd); err != nil {
+		node.log.Error("Failed initiliazing keystore [%s].", err.Error())
+
+		return err
+	}
+
 	if err := node.retrieveECACertsChain(enrollID); err != nil {
 		node.log.Error("Failed retrieveing ECA certs chain [%s].", err.Error())
 
@@ -100,6 +145,12 @@ func (node *nodeImpl) register(prefix, name string, pwd []byte, enrollID, enroll
 		return err
 	}
 
+	if err := node.retrieveTLSCACertsChain(enrollID); err != nil {
+		node.log.Error("Failed retrieveing TLSCA certs chain [%s].", err.Error())
+
+		return err
+	}
+
 	if err := node.retrieveEnrollmentData(enrollID, enrollPWD); err != nil {
 		node.log.Error("Failed retrieveing enrollment data [%s].", err.Error())
 
@@ -137,8 +188,7 @@ func (node *nodeImpl) init(prefix, name string, pwd []byte) error {
 
 	// Initialize keystore
 	node.log.Info("Init keystore...")
-	// TODO: password support
-	err := node.initKeyStore()
+	err := node.initKeyStore(pwd)
 	if err != nil {
 		if err != utils.ErrKeyStoreAlreadyInitialized {
 			node.log.Error("Keystore already initialized.")
@@ -21,6 +21,7 @@ package crypto
 
 import (
 	"crypto/ecdsa"
+	"crypto/x509"
 	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
 	"io/ioutil"
 	"os"
@@ -50,13 +51,7 @@ func (node *nodeImpl) retrieveEnrollmentData(userID, pwd string) error {
 	// Store enrollment  key
 	node.log.Debug("Storing enrollment data for user [%s]...", userID)
 
-	rawKey, err := utils.PrivateKeyToPEM(key)
-	if err != nil {
-		node.log.Error("Failed converting enrollment key to PEM [id=%s]: ", userID, err)
-		return err
-	}
-
-	err = ioutil.WriteFile(node.conf.getEnrollmentKeyPath(), rawKey, 0700)
+	err = node.ks.StoreKey(node.conf.getEnrollmentKeyFilename(), key.(*ecdsa.PrivateKey))
 	if err != nil {
 		node.log.Error("Failed storing enrollment key [id=%s]: ", userID, err)
 		return err
@@ -86,28 +81,33 @@ func (node *nodeImpl) retrieveEnrollmentData(userID, pwd string) error {
 	return nil
 }
 
-func (node *nodeImpl) loadEnrollmentKey(pwd []byte) error {
-	node.log.Debug("Loading enrollment key at [%s]...", node.conf.getEnrollmentKeyPath())
+func (node *nodeImpl) loadEnrollmentKey() error {
+	node.log.Debug("Loading enrollment key [%s]...", node.conf.getEnrollmentKeyFilename())
 
-	rawEnrollPrivKey, err := ioutil.ReadFile(node.conf.getEnrollmentKeyPath())
-	if err != nil {
-		node.log.Error("Failed loading enrollment private key [%s].", err.Error())
+	enrollPrivKey, err := node.ks.GetKey(node.conf.getEnrollmentKeyFilename())
+	if err == utils.ErrKeyNotExportable {
+		// The keystore signs on behalf of the node
+		node.log.Debug("Enrollment key not exportable.")
 
-		return err
+		return nil
 	}
-
-	enrollPrivKey, err := utils.PEMtoPrivateKey(rawEnrollPrivKey, pwd)
 	if err != nil {
-		node.log.Error("Failed parsing enrollment private key [%s].", err.Error())
+		node.log.Error("Failed loading enrollment private key [%s].", err.Error())
 
 		return err
 	}
-	node.enrollPrivKey = enrollPrivKey.(*ecdsa.PrivateKey)
+	node.enrollPrivKey = enrollPrivKey
 
 	return nil
 }
 
 func (node *nodeImpl) loadEnrollmentCertificate() error {
+	if err := node.recoverRenewedEnrollmentCertificate(); err != nil {
+		node.log.Error("Failed recovering renewed enrollment certificate [%s].", err.Error())
+
+		return err
+	}
+
 	node.log.Debug("Loading enrollment certificate at [%s]...", node.conf.getEnrollmentCertPath())
 
 	pemEnrollCert, err := ioutil.ReadFile(node.conf.getEnrollmentCertPath())
@@ -125,11 +125,7 @@ func (node *nodeImpl) loadEnrollmentCertificate() error {
 	}
 	node.enrollCert = enrollCert
 
-	pk := node.enrollCert.PublicKey.(*ecdsa.PublicKey)
-	err = utils.VerifySignCapability(node.enrollPrivKey, pk)
-	if err != nil {
-		node.log.Error("Failed checking enrollment certificate against enrollment key [%s].", err.Error())
-
+	if err := node.checkEnrollmentKey(node.enrollCert); err != nil {
 		return err
 	}
 
@@ -140,6 +136,60 @@ func (node *nodeImpl) loadEnrollmentCertificate() error {
 	return nil
 }
 
+// checkEnrollmentKey checks that cert certifies the stored enrollment key
+func (node *nodeImpl) checkEnrollmentKey(cert *x509.Certificate) error {
+	pk, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		node.log.Error("Failed checking enrollment certificate against enrollment key.")
+
+		return utils.ErrInvalidKey
+	}
+	msg := []byte("This is a message to be signed and verified by ECDSA!")
+	sigma, err := node.signWithEnrollmentKey(msg)
+	if err != nil {
+		node.log.Error("Failed checking enrollment certificate against enrollment key [%s].", err.Error())
+
+		return err
+	}
+	ok, err = utils.ECDSAVerify(pk, msg, sigma)
+	if err != nil || !ok {
+		node.log.Error("Failed checking enrollment certificate against enrollment key.")
+
+		return utils.ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// recoverRenewedEnrollmentCertificate completes a renewal interrupted after
+// the enrollment key was replaced. The staged certificate is moved in place
+// if it certifies the stored key and discarded otherwise.
+func (node *nodeImpl) recoverRenewedEnrollmentCertificate() error {
+	path := node.conf.getRenewedEnrollmentCertPath()
+
+	pemCert, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cert, _, err := utils.PEMtoCertificateAndDER(pemCert)
+	if err == nil {
+		err = node.checkEnrollmentKey(cert)
+	}
+	if err != nil {
+		node.log.Warning("Discarding renewed enrollment certificate [%s].", err.Error())
+
+		return os.Remove(path)
+	}
+
+	node.log.Info("Completing renewal of the enrollment certificate.")
+
+	return os.Rename(path, node.conf.getEnrollmentCertPath())
+}
+
 func (node *nodeImpl) loadEnrollmentID() error {
 	node.log.Debug("Loading enrollment id at [%s]...", node.conf.getEnrollmentIDPath())
 
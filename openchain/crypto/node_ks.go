@@ -20,25 +20,63 @@ under the License.
 package crypto
 
 import (
-	"database/sql"
+	"crypto/ecdsa"
 	"github.com/op/go-logging"
 	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
-	"os"
-	"path/filepath"
 	"sync"
-
-	// Required to succefully initialized the driver
-	_ "github.com/mattn/go-sqlite3"
 )
 
+// KeyStore is the storage backend of a node. It holds the private keys and
+// the certificates of the node. Backends are selected by the
+// peer.pki.keystore.backend property.
+type KeyStore interface {
+
+	// StoreKey stores key under alias
+	StoreKey(alias string, key *ecdsa.PrivateKey) error
+
+	// GetKey returns the key stored under alias. Backends keeping the keys
+	// out of the process return utils.ErrKeyNotExportable.
+	GetKey(alias string) (*ecdsa.PrivateKey, error)
+
+	// Sign signs msg with the key stored under alias. The signature is
+	// the ASN.1 encoding of the ECDSA signature of the hash of msg.
+	Sign(alias string, msg []byte) ([]byte, error)
+
+	// StoreCert stores the DER encoded certificate cert under id
+	StoreCert(id string, cert []byte) error
+
+	// GetCert returns the certificate stored under id, nil if missing
+	GetCert(id string) ([]byte, error)
+
+	// StoreTCerts adds certs to the pool of unused transaction certificates
+	StoreTCerts(certs [][]byte) error
+
+	// NextTCert removes and returns an unused transaction certificate,
+	// nil if the pool is empty
+	NextTCert() ([]byte, error)
 
-func (node *nodeImpl) initKeyStore() error {
-	// TODO: move all the ket/certificate store/load to the keyStore struct
+	// CountTCerts returns the number of unused transaction certificates
+	CountTCerts() (int, error)
 
+	// DeleteTCerts removes the unused transaction certificates for which
+	// match returns true, and returns how many were removed
+	DeleteTCerts(match func(cert []byte) bool) (int, error)
+
+	// Close releases the resources held by the backend
+	Close() error
+}
+
+const (
+	sqliteKeyStoreBackend = "sqlite"
+	vaultKeyStoreBackend  = "vault"
+	remoteKeyStoreBackend = "remote"
+)
+
+func (node *nodeImpl) initKeyStore(pwd []byte) error {
 	ks := keyStore{}
 	ks.log = node.log
 	ks.conf = node.conf
-	if err := ks.init(); err != nil {
+	if err := ks.init(pwd); err != nil {
 		return err
 	}
 
@@ -48,10 +86,9 @@ func (node *nodeImpl) initKeyStore() error {
 }
 
 type keyStore struct {
-	isOpen bool
+	KeyStore
 
-	// backend
-	sqlDB *sql.DB
+	isOpen bool
 
 	// Configuration
 	conf *configuration
@@ -63,7 +100,7 @@ type keyStore struct {
 	m sync.Mutex
 }
 
-func (ks *keyStore) init() error {
+func (ks *keyStore) init(pwd []byte) error {
 	ks.m.Lock()
 	defer ks.m.Unlock()
 
@@ -71,22 +108,37 @@ func (ks *keyStore) init() error {
 		return utils.ErrKeyStoreAlreadyInitialized
 	}
 
-	err := ks.createKeyStoreIfKeyStorePathEmpty()
-	if err != nil {
-		return err
-	}
+	var backend KeyStore
+	var err error
+	switch ks.conf.getKeyStoreBackend() {
+	case sqliteKeyStoreBackend:
+		backend, err = newSQLKeyStore(ks.conf, ks.log)
+	case vaultKeyStoreBackend:
+		passphrase := pwd
+		if len(passphrase) == 0 {
+			passphrase = ks.conf.getKeyStoreVaultPassphrase()
+		}
+		backend, err = newVaultKeyStore(ks.conf, ks.log, passphrase)
+	case remoteKeyStoreBackend:
+		backend, err = newRemoteKeyStore(ks.conf, ks.log)
+	default:
+		ks.log.Error("Unknown keystore backend [%s].", ks.conf.getKeyStoreBackend())
 
-	err = ks.openKeyStore()
+		return utils.ErrUnknownKeyStoreBackend
+	}
 	if err != nil {
 		return err
 	}
 
+	ks.KeyStore = backend
+	ks.isOpen = true
+
 	return nil
 }
 
 func (ks *keyStore) close() error {
 	ks.log.Info("Closing keystore...")
-	err := ks.sqlDB.Close()
+	err := ks.Close()
 
 	if err != nil {
 		ks.log.Error("Failed closing keystore [%s].", err.Error())
@@ -97,87 +149,3 @@ func (ks *keyStore) close() error {
 	ks.isOpen = false
 	return err
 }
-
-func (ks *keyStore) createKeyStoreIfKeyStorePathEmpty() error {
-	// Check directory
-	ksPath := ks.conf.getKeyStorePath()
-	missing, err := utils.DirMissingOrEmpty(ksPath)
-	ks.log.Debug("Keystore path [%s] missing [%t]: [%s]", ksPath, missing, utils.ErrToString(err))
-
-	if !missing {
-		// Check file
-		missing, err = utils.FileMissing(ks.conf.getKeyStorePath(), ks.conf.getKeyStoreFilename())
-		ks.log.Debug("Keystore file [%s] missing [%t]:[%s]", ks.conf.getKeyStoreFilePath(), missing, utils.ErrToString(err))
-	}
-
-	if missing {
-		err := ks.createKeyStore()
-		if err != nil {
-			ks.log.Debug("Failed creating db At [%s]: ", ks.conf.getKeyStoreFilePath(), err.Error())
-			return nil
-		}
-	}
-
-	return nil
-}
-
-func (ks *keyStore) createKeyStore() error {
-	dbPath := ks.conf.getKeyStorePath()
-	ks.log.Debug("Creating Keystore at [%s].", dbPath)
-
-	missing, err := utils.FileMissing(dbPath, ks.conf.getKeyStoreFilename())
-	if !missing {
-		ks.log.Debug("Creating Keystore at [%s]. Keystore already there", dbPath)
-		return nil
-	}
-
-	os.MkdirAll(dbPath, 0755)
-
-	ks.log.Debug("Open Keystore at [%s].", dbPath)
-	db, err := sql.Open("sqlite3", filepath.Join(dbPath, ks.conf.getKeyStoreFilename()))
-	if err != nil {
-		return err
-	}
-
-	ks.log.Debug("Ping Keystore at [%s].", dbPath)
-	err = db.Ping()
-	if err != nil {
-		ks.log.Fatal(err)
-	}
-
-	defer db.Close()
-
-	// create tables
-	log.Debug("Create Table if not exists [%s] at [%s].", "Certificates", dbPath)
-	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS Certificates (id VARCHAR, cert BLOB, PRIMARY KEY (id))"); err != nil {
-		log.Debug("Failed creating table [%s].", err.Error())
-		return err
-	}
-
-	ks.log.Debug("Keystore created at [%s].", dbPath)
-	return nil
-}
-
-func (ks *keyStore) deleteKeyStore() error {
-	ks.log.Debug("Removing KeyStore at [%s].", ks.conf.getKeyStorePath())
-
-	return os.RemoveAll(ks.conf.getKeyStorePath())
-}
-
-func (ks *keyStore) openKeyStore() error {
-	if ks.isOpen {
-		return nil
-	}
-	ksPath := ks.conf.getKeyStorePath()
-
-	sqlDB, err := sql.Open("sqlite3", filepath.Join(ksPath, ks.conf.getKeyStoreFilename()))
-
-	if err != nil {
-		ks.log.Error("Error opening keystore%s", err.Error())
-		return err
-	}
-	ks.isOpen = true
-	ks.sqlDB = sqlDB
-
-	return nil
-}
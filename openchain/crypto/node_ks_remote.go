@@ -0,0 +1,129 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"github.com/op/go-logging"
+	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
+	obc "github.com/openblockchain/obc-peer/protos"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"net"
+	"strings"
+	"time"
+)
+
+// remoteKeyStore delegates the keys to a PKCS#11-style remote signer reached
+// over gRPC. Keys are imported once and never read back, so they never sit
+// on the local disk. Certificates are kept in the local sqlite store.
+type remoteKeyStore struct {
+	*sqlKeyStore
+
+	conn   *grpc.ClientConn
+	signer obc.RemoteSignerClient
+}
+
+func newRemoteKeyStore(conf *configuration, log *logging.Logger) (*remoteKeyStore, error) {
+	addr := conf.getRemoteSignerAddr()
+	if addr == "" {
+		log.Error("Missing remote signer address.")
+
+		return nil, utils.ErrInvalidReference
+	}
+
+	conn, err := dialRemoteSigner(addr, conf.getRemoteSignerTLSRootCertFile(), conf.getRemoteSignerTLSServerHostOverride())
+	if err != nil {
+		log.Error("Failed dialing remote signer at [%s]: [%s].", addr, err.Error())
+
+		return nil, err
+	}
+
+	sqlKS, err := newSQLKeyStore(conf, log)
+	if err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	return &remoteKeyStore{sqlKS, conn, obc.NewRemoteSignerClient(conn)}, nil
+}
+
+// dialRemoteSigner connects to addr. Addresses of the form unix:<path>
+// designate a local unix socket. Keys are sent to the signer in the clear,
+// so TCP addresses require TLS, verified against the root certificate in
+// rootCertFile.
+func dialRemoteSigner(addr, rootCertFile, serverName string) (*grpc.ClientConn, error) {
+	opts := []grpc.DialOption{grpc.WithTimeout(time.Second), grpc.WithBlock()}
+	if strings.HasPrefix(addr, "unix:") {
+		opts = append(opts, grpc.WithInsecure(), grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout("unix", strings.TrimPrefix(addr, "unix:"), timeout)
+		}))
+	} else {
+		if rootCertFile == "" {
+			return nil, utils.ErrRemoteSignerTLSRequired
+		}
+
+		creds, err := credentials.NewClientTLSFromFile(rootCertFile, serverName)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(creds))
+	}
+
+	return grpc.Dial(addr, opts...)
+}
+
+func (ks *remoteKeyStore) StoreKey(alias string, key *ecdsa.PrivateKey) error {
+	der, err := utils.PrivateKeyToDER(key)
+	if err != nil {
+		return err
+	}
+
+	if _, err := ks.signer.ImportKey(context.Background(), &obc.KeyImportRequest{Alias: alias, PrivateKey: der}); err != nil {
+		ks.log.Error("Failed importing key [%s] into the remote signer [%s].", alias, err.Error())
+
+		return err
+	}
+
+	return nil
+}
+
+func (ks *remoteKeyStore) GetKey(alias string) (*ecdsa.PrivateKey, error) {
+	return nil, utils.ErrKeyNotExportable
+}
+
+func (ks *remoteKeyStore) Sign(alias string, msg []byte) ([]byte, error) {
+	resp, err := ks.signer.Sign(context.Background(), &obc.SignRequest{Alias: alias, Digest: utils.Hash(msg)})
+	if err != nil {
+		ks.log.Error("Failed signing with the remote signer [%s].", err.Error())
+
+		return nil, err
+	}
+
+	return resp.Signature, nil
+}
+
+func (ks *remoteKeyStore) Close() error {
+	ks.conn.Close()
+
+	return ks.sqlKeyStore.Close()
+}
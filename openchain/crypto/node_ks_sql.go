@@ -0,0 +1,409 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"database/sql"
+	"github.com/op/go-logging"
+	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	// Required to succefully initialized the driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqlKeyStore keeps the certificates in a local sqlite file and the keys as
+// PEM files under the keys path.
+type sqlKeyStore struct {
+	// backend
+	sqlDB *sql.DB
+
+	// Cache of the loaded keys
+	keys  map[string]*ecdsa.PrivateKey
+	keysM sync.Mutex
+
+	// Configuration
+	conf *configuration
+
+	// Logging
+	log *logging.Logger
+}
+
+func newSQLKeyStore(conf *configuration, log *logging.Logger) (*sqlKeyStore, error) {
+	ks := &sqlKeyStore{conf: conf, log: log, keys: make(map[string]*ecdsa.PrivateKey)}
+
+	if err := ks.createKeyStoreIfKeyStorePathEmpty(); err != nil {
+		return nil, err
+	}
+
+	if err := ks.openKeyStore(); err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+func (ks *sqlKeyStore) StoreKey(alias string, key *ecdsa.PrivateKey) error {
+	raw, err := utils.PrivateKeyToPEM(key)
+	if err != nil {
+		ks.log.Error("Failed converting key to PEM [%s]: [%s].", alias, err.Error())
+		return err
+	}
+
+	if err := utils.WriteFileAtomic(filepath.Join(ks.conf.getKeysPath(), alias), raw, 0700); err != nil {
+		ks.log.Error("Failed storing key [%s]: [%s].", alias, err.Error())
+		return err
+	}
+
+	ks.keysM.Lock()
+	ks.keys[alias] = key
+	ks.keysM.Unlock()
+
+	return nil
+}
+
+func (ks *sqlKeyStore) GetKey(alias string) (*ecdsa.PrivateKey, error) {
+	ks.keysM.Lock()
+	defer ks.keysM.Unlock()
+
+	if key, ok := ks.keys[alias]; ok {
+		return key, nil
+	}
+
+	raw, err := ioutil.ReadFile(filepath.Join(ks.conf.getKeysPath(), alias))
+	if err != nil {
+		ks.log.Error("Failed loading key [%s]: [%s].", alias, err.Error())
+		return nil, err
+	}
+
+	key, err := utils.PEMtoPrivateKey(raw, nil)
+	if err != nil {
+		ks.log.Error("Failed parsing key [%s]: [%s].", alias, err.Error())
+		return nil, err
+	}
+
+	ecdsaKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, utils.ErrInvalidKey
+	}
+	ks.keys[alias] = ecdsaKey
+
+	return ecdsaKey, nil
+}
+
+func (ks *sqlKeyStore) Sign(alias string, msg []byte) ([]byte, error) {
+	key, err := ks.GetKey(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.ECDSASign(key, msg)
+}
+
+func (ks *sqlKeyStore) StoreCert(id string, cert []byte) error {
+	ks.log.Debug("Insert id [%s].", id)
+	ks.log.Debug("Insert cert [%s].", utils.EncodeBase64(cert))
+
+	if _, err := ks.sqlDB.Exec("INSERT OR REPLACE INTO Certificates (id, cert) VALUES (?, ?)", id, cert); err != nil {
+		ks.log.Error("Failed inserting cert [%s].", err.Error())
+
+		return err
+	}
+
+	return nil
+}
+
+func (ks *sqlKeyStore) GetCert(id string) ([]byte, error) {
+	var cert []byte
+	row := ks.sqlDB.QueryRow("SELECT cert FROM Certificates where id = ?", id)
+	err := row.Scan(&cert)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		ks.log.Error("Error during select [%s].", err.Error())
+
+		return nil, err
+	}
+
+	return cert, nil
+}
+
+func (ks *sqlKeyStore) StoreTCerts(certs [][]byte) error {
+	tx, err := ks.sqlDB.Begin()
+	if err != nil {
+		ks.log.Error("Failed beginning transaction [%s].", err.Error())
+
+		return err
+	}
+
+	for i, cert := range certs {
+		ks.log.Debug("Insert index [%d]", i)
+		ks.log.Debug("Insert cert [%s].", utils.EncodeBase64(cert))
+
+		_, err := tx.Exec("INSERT INTO TCerts (cert) VALUES (?)", cert)
+
+		if err != nil {
+			ks.log.Error("Failed inserting cert [%s].", err.Error())
+			continue
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ks.log.Error("Failed committing transaction [%s].", err.Error())
+
+		tx.Rollback()
+
+		return err
+	}
+
+	return nil
+}
+
+func (ks *sqlKeyStore) NextTCert() ([]byte, error) {
+	ks.log.Debug("Select next TCert...")
+
+	// Open transaction
+	tx, err := ks.sqlDB.Begin()
+	if err != nil {
+		ks.log.Error("Failed beginning transaction [%s].", err.Error())
+
+		return nil, err
+	}
+
+	// Get the first row available
+	var id int
+	var cert []byte
+	row := tx.QueryRow("SELECT id, cert FROM TCerts")
+	err = row.Scan(&id, &cert)
+
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+
+		return nil, nil
+	} else if err != nil {
+		ks.log.Error("Error during select [%s].", err.Error())
+
+		tx.Rollback()
+
+		return nil, err
+	}
+
+	ks.log.Debug("id [%d]", id)
+	ks.log.Debug("cert [%s].", utils.EncodeBase64(cert))
+
+	// TODO: rather than removing, move the cert to another table
+	// which stores the TCerts used
+
+	// Remove that row
+	ks.log.Debug("Removing row with id [%d]...", id)
+
+	if _, err := tx.Exec("DELETE FROM TCerts WHERE id = ?", id); err != nil {
+		ks.log.Error("Failed removing row [%d] [%s].", id, err.Error())
+
+		tx.Rollback()
+
+		return nil, err
+	}
+
+	ks.log.Debug("Removing row with id [%d]...done", id)
+
+	// Finalize
+	err = tx.Commit()
+	if err != nil {
+		ks.log.Error("Failed commiting [%s].", err.Error())
+		tx.Rollback()
+
+		return nil, err
+	}
+
+	ks.log.Debug("Select next TCert...done!")
+
+	return cert, nil
+}
+
+func (ks *sqlKeyStore) CountTCerts() (int, error) {
+	var count int
+	if err := ks.sqlDB.QueryRow("SELECT COUNT(*) FROM TCerts").Scan(&count); err != nil {
+		ks.log.Error("Failed counting TCerts [%s].", err.Error())
+
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (ks *sqlKeyStore) DeleteTCerts(match func(cert []byte) bool) (int, error) {
+	tx, err := ks.sqlDB.Begin()
+	if err != nil {
+		ks.log.Error("Failed beginning transaction [%s].", err.Error())
+
+		return 0, err
+	}
+
+	rows, err := tx.Query("SELECT id, cert FROM TCerts")
+	if err != nil {
+		ks.log.Error("Error during select [%s].", err.Error())
+
+		tx.Rollback()
+
+		return 0, err
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		var cert []byte
+		if err := rows.Scan(&id, &cert); err != nil {
+			ks.log.Error("Error during scan [%s].", err.Error())
+
+			rows.Close()
+			tx.Rollback()
+
+			return 0, err
+		}
+		if match(cert) {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		ks.log.Error("Error during select [%s].", err.Error())
+
+		tx.Rollback()
+
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if _, err := tx.Exec("DELETE FROM TCerts WHERE id = ?", id); err != nil {
+			ks.log.Error("Failed removing row [%d] [%s].", id, err.Error())
+
+			tx.Rollback()
+
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		ks.log.Error("Failed commiting [%s].", err.Error())
+
+		return 0, err
+	}
+
+	return len(ids), nil
+}
+
+func (ks *sqlKeyStore) Close() error {
+	return ks.sqlDB.Close()
+}
+
+func (ks *sqlKeyStore) createKeyStoreIfKeyStorePathEmpty() error {
+	// Check directory
+	ksPath := ks.conf.getKeyStorePath()
+	missing, err := utils.DirMissingOrEmpty(ksPath)
+	ks.log.Debug("Keystore path [%s] missing [%t]: [%s]", ksPath, missing, utils.ErrToString(err))
+
+	if !missing {
+		// Check file
+		missing, err = utils.FileMissing(ks.conf.getKeyStorePath(), ks.conf.getKeyStoreFilename())
+		ks.log.Debug("Keystore file [%s] missing [%t]:[%s]", ks.conf.getKeyStoreFilePath(), missing, utils.ErrToString(err))
+	}
+
+	if missing {
+		err := ks.createKeyStore()
+		if err != nil {
+			ks.log.Debug("Failed creating db At [%s]: ", ks.conf.getKeyStoreFilePath(), err.Error())
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (ks *sqlKeyStore) createKeyStore() error {
+	dbPath := ks.conf.getKeyStorePath()
+	ks.log.Debug("Creating Keystore at [%s].", dbPath)
+
+	missing, err := utils.FileMissing(dbPath, ks.conf.getKeyStoreFilename())
+	if !missing {
+		ks.log.Debug("Creating Keystore at [%s]. Keystore already there", dbPath)
+		return nil
+	}
+
+	os.MkdirAll(dbPath, 0755)
+
+	ks.log.Debug("Open Keystore at [%s].", dbPath)
+	db, err := sql.Open("sqlite3", filepath.Join(dbPath, ks.conf.getKeyStoreFilename()))
+	if err != nil {
+		return err
+	}
+
+	ks.log.Debug("Ping Keystore at [%s].", dbPath)
+	err = db.Ping()
+	if err != nil {
+		ks.log.Fatal(err)
+	}
+
+	defer db.Close()
+
+	ks.log.Debug("Keystore created at [%s].", dbPath)
+	return nil
+}
+
+func (ks *sqlKeyStore) deleteKeyStore() error {
+	ks.log.Debug("Removing KeyStore at [%s].", ks.conf.getKeyStorePath())
+
+	return os.RemoveAll(ks.conf.getKeyStorePath())
+}
+
+func (ks *sqlKeyStore) openKeyStore() error {
+	ksPath := ks.conf.getKeyStorePath()
+
+	sqlDB, err := sql.Open("sqlite3", filepath.Join(ksPath, ks.conf.getKeyStoreFilename()))
+	if err != nil {
+		ks.log.Error("Error opening keystore%s", err.Error())
+		return err
+	}
+
+	// create tables
+	ks.log.Debug("Create Table if not exists [%s] at [%s].", "Certificates", ksPath)
+	if _, err := sqlDB.Exec("CREATE TABLE IF NOT EXISTS Certificates (id VARCHAR, cert BLOB, PRIMARY KEY (id))"); err != nil {
+		ks.log.Debug("Failed creating table [%s].", err.Error())
+		sqlDB.Close()
+		return err
+	}
+
+	ks.log.Debug("Create Table if not exists [%s] at [%s].", "TCerts", ksPath)
+	if _, err := sqlDB.Exec("CREATE TABLE IF NOT EXISTS TCerts (id INTEGER, cert BLOB, PRIMARY KEY (id))"); err != nil {
+		ks.log.Debug("Failed creating table [%s].", err.Error())
+		sqlDB.Close()
+		return err
+	}
+
+	ks.sqlDB = sqlDB
+
+	return nil
+}
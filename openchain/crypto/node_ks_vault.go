@@ -0,0 +1,328 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/op/go-logging"
+	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+const (
+	vaultSaltSize   = 16
+	vaultIterations = 4096
+)
+
+// vaultKeyStore keeps keys and certificates in a single file encrypted under
+// a key derived from a passphrase. The file is laid out as
+// salt || AES-CBC(content) || HMAC(salt || AES-CBC(content)).
+type vaultKeyStore struct {
+	path string
+
+	salt   []byte
+	encKey []byte
+	macKey []byte
+
+	content vaultContent
+
+	// Cache of the parsed keys
+	keys map[string]*ecdsa.PrivateKey
+
+	// Logging
+	log *logging.Logger
+
+	// Sync
+	m sync.Mutex
+}
+
+type vaultContent struct {
+	Keys   map[string][]byte
+	Certs  map[string][]byte
+	TCerts [][]byte
+}
+
+func newVaultKeyStore(conf *configuration, log *logging.Logger, passphrase []byte) (*vaultKeyStore, error) {
+	if len(passphrase) == 0 {
+		log.Error("Missing passphrase for vault keystore.")
+
+		return nil, utils.ErrInvalidPassphrase
+	}
+
+	ks := &vaultKeyStore{
+		path: conf.getKeyStoreVaultPath(),
+		content: vaultContent{
+			Keys:  make(map[string][]byte),
+			Certs: make(map[string][]byte),
+		},
+		keys: make(map[string]*ecdsa.PrivateKey),
+		log:  log,
+	}
+
+	missing, _ := utils.FilePathMissing(ks.path)
+	if missing {
+		log.Debug("Creating vault at [%s].", ks.path)
+
+		if err := os.MkdirAll(conf.getKeyStorePath(), 0755); err != nil {
+			return nil, err
+		}
+
+		salt, err := utils.GetRandomBytes(vaultSaltSize)
+		if err != nil {
+			return nil, err
+		}
+		ks.deriveKeys(passphrase, salt)
+
+		if err := ks.save(); err != nil {
+			return nil, err
+		}
+
+		return ks, nil
+	}
+
+	if err := ks.load(passphrase); err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+func (ks *vaultKeyStore) deriveKeys(passphrase, salt []byte) {
+	// PBKDF2 with HMAC-SHA3-384, first block only
+	block := make([]byte, 4)
+	binary.BigEndian.PutUint32(block, 1)
+
+	u := utils.HMAC(passphrase, append(append([]byte{}, salt...), block...))
+	master := append([]byte{}, u...)
+	for i := 1; i < vaultIterations; i++ {
+		u = utils.HMAC(passphrase, u)
+		for j := range master {
+			master[j] ^= u[j]
+		}
+	}
+
+	ks.salt = salt
+	ks.encKey = utils.HMACTruncated(master, []byte{1}, utils.AESKeyLength)
+	ks.macKey = utils.HMAC(master, []byte{2})
+}
+
+func (ks *vaultKeyStore) load(passphrase []byte) error {
+	raw, err := ioutil.ReadFile(ks.path)
+	if err != nil {
+		ks.log.Error("Failed reading vault [%s].", err.Error())
+
+		return err
+	}
+
+	macSize := utils.NewHash().Size()
+	if len(raw) < vaultSaltSize+macSize {
+		return utils.ErrInvalidPassphrase
+	}
+
+	ks.deriveKeys(passphrase, raw[:vaultSaltSize])
+
+	body, tag := raw[:len(raw)-macSize], raw[len(raw)-macSize:]
+	if !hmac.Equal(tag, utils.HMAC(ks.macKey, body)) {
+		ks.log.Error("Failed authenticating vault. Wrong passphrase or corrupted file.")
+
+		return utils.ErrInvalidPassphrase
+	}
+
+	pt, err := utils.CBCPKCS7Decrypt(ks.encKey, body[vaultSaltSize:])
+	if err != nil {
+		ks.log.Error("Failed decrypting vault [%s].", err.Error())
+
+		return utils.ErrDecrypt
+	}
+
+	if err := json.Unmarshal(pt, &ks.content); err != nil {
+		ks.log.Error("Failed parsing vault [%s].", err.Error())
+
+		return err
+	}
+
+	return nil
+}
+
+// save writes the vault to a temporary file first and then renames it,
+// so that a crash never leaves a truncated vault behind.
+func (ks *vaultKeyStore) save() error {
+	pt, err := json.Marshal(&ks.content)
+	if err != nil {
+		return err
+	}
+
+	ct, err := utils.CBCPKCS7Encrypt(ks.encKey, pt)
+	if err != nil {
+		ks.log.Error("Failed encrypting vault [%s].", err.Error())
+
+		return utils.ErrEncrypt
+	}
+
+	body := append(append([]byte{}, ks.salt...), ct...)
+	raw := append(body, utils.HMAC(ks.macKey, body)...)
+
+	tmp := ks.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, raw, 0600); err != nil {
+		ks.log.Error("Failed writing vault [%s].", err.Error())
+
+		return err
+	}
+
+	return os.Rename(tmp, ks.path)
+}
+
+func (ks *vaultKeyStore) StoreKey(alias string, key *ecdsa.PrivateKey) error {
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	der, err := utils.PrivateKeyToDER(key)
+	if err != nil {
+		return err
+	}
+
+	ks.content.Keys[alias] = der
+	ks.keys[alias] = key
+
+	return ks.save()
+}
+
+func (ks *vaultKeyStore) GetKey(alias string) (*ecdsa.PrivateKey, error) {
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	return ks.getKey(alias)
+}
+
+func (ks *vaultKeyStore) getKey(alias string) (*ecdsa.PrivateKey, error) {
+	if key, ok := ks.keys[alias]; ok {
+		return key, nil
+	}
+
+	der, ok := ks.content.Keys[alias]
+	if !ok {
+		return nil, utils.ErrKeyNotFound
+	}
+
+	key, err := utils.DERToPrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	ecdsaKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, utils.ErrInvalidKey
+	}
+	ks.keys[alias] = ecdsaKey
+
+	return ecdsaKey, nil
+}
+
+func (ks *vaultKeyStore) Sign(alias string, msg []byte) ([]byte, error) {
+	key, err := ks.GetKey(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.ECDSASign(key, msg)
+}
+
+func (ks *vaultKeyStore) StoreCert(id string, cert []byte) error {
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	ks.content.Certs[id] = cert
+
+	return ks.save()
+}
+
+func (ks *vaultKeyStore) GetCert(id string) ([]byte, error) {
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	return ks.content.Certs[id], nil
+}
+
+func (ks *vaultKeyStore) StoreTCerts(certs [][]byte) error {
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	ks.content.TCerts = append(ks.content.TCerts, certs...)
+
+	return ks.save()
+}
+
+func (ks *vaultKeyStore) NextTCert() ([]byte, error) {
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	if len(ks.content.TCerts) == 0 {
+		return nil, nil
+	}
+
+	cert := ks.content.TCerts[0]
+	ks.content.TCerts = ks.content.TCerts[1:]
+
+	if err := ks.save(); err != nil {
+		return nil, err
+	}
+
+	return cert, nil
+}
+
+func (ks *vaultKeyStore) CountTCerts() (int, error) {
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	return len(ks.content.TCerts), nil
+}
+
+func (ks *vaultKeyStore) DeleteTCerts(match func(cert []byte) bool) (int, error) {
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	var kept [][]byte
+	for _, cert := range ks.content.TCerts {
+		if !match(cert) {
+			kept = append(kept, cert)
+		}
+	}
+
+	deleted := len(ks.content.TCerts) - len(kept)
+	if deleted == 0 {
+		return 0, nil
+	}
+	ks.content.TCerts = kept
+
+	if err := ks.save(); err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+func (ks *vaultKeyStore) Close() error {
+	return nil
+}
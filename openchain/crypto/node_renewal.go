@@ -0,0 +1,185 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"github.com/golang/protobuf/proto"
+	obcca "github.com/openblockchain/obc-peer/obc-ca/protos"
+	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
+	"golang.org/x/net/context"
+	protobuf "google/protobuf"
+	"os"
+	"time"
+)
+
+// startECertRenewal periodically checks the expiration of the enrollment
+// certificate and invokes renew when it is due for renewal
+func (node *nodeImpl) startECertRenewal(renew func() error) {
+	if node.renewalStop != nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	node.renewalStop = stop
+
+	go func() {
+		ticker := time.NewTicker(node.conf.getECertRenewalCheckInterval())
+		defer ticker.Stop()
+
+		for {
+			if node.isECertRenewalDue() {
+				if err := renew(); err != nil {
+					node.log.Warning("Failed renewing enrollment certificate [%s].", err.Error())
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+func (node *nodeImpl) stopECertRenewal() {
+	if node.renewalStop != nil {
+		close(node.renewalStop)
+		node.renewalStop = nil
+	}
+}
+
+func (node *nodeImpl) isECertRenewalDue() bool {
+	return time.Now().After(node.getEnrollmentCert().NotAfter.Add(-node.conf.getECertRenewBefore()))
+}
+
+// renewEnrollmentCertificate binds a fresh enrollment key to the enrollment ID
+// of this node. The ECA revokes the previous certificate as superseded.
+// If the new key or certificate cannot be stored, the node keeps the
+// previous ones, which the ECA still accepts for a renewal during its
+// grace period.
+// The new key and certificate replace the current ones under enrollMutex;
+// onRenewed, if not nil, runs under the same lock to discard material bound
+// to the previous key.
+func (node *nodeImpl) renewEnrollmentCertificate(onRenewed func() error) error {
+	node.log.Info("Renewing enrollment certificate...")
+
+	priv, err := utils.NewECDSAKey()
+	if err != nil {
+		node.log.Error("Failed generating key [%s].", err.Error())
+
+		return err
+	}
+
+	// Prepare the request
+	pubraw, _ := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	req := &obcca.ECertRenewReq{
+		Ts:   &protobuf.Timestamp{Seconds: time.Now().Unix(), Nanos: 0},
+		Id:   &obcca.Identity{Id: node.enrollID},
+		Cert: &obcca.Cert{Cert: node.getEnrollmentCert().Raw},
+		Pub:  &obcca.PublicKey{Type: obcca.CryptoType_ECDSA, Key: pubraw},
+	}
+
+	// Prove possession of the new key
+	rawReq, _ := proto.Marshal(req)
+	r, s, err := ecdsa.Sign(rand.Reader, priv, utils.Hash(rawReq))
+	if err != nil {
+		node.log.Error("Failed signing request [%s].", err.Error())
+
+		return err
+	}
+	R, _ := r.MarshalText()
+	S, _ := s.MarshalText()
+	req.Pop = &obcca.Signature{Type: obcca.CryptoType_ECDSA, R: R, S: S}
+
+	// Authenticate with the current key
+	rawReq, _ = proto.Marshal(req)
+	r, s, err = node.ecdsaSignWithEnrollmentKey(rawReq)
+	if err != nil {
+		node.log.Error("Failed signing request [%s].", err.Error())
+
+		return err
+	}
+	R, _ = r.MarshalText()
+	S, _ = s.MarshalText()
+	req.Sig = &obcca.Signature{Type: obcca.CryptoType_ECDSA, R: R, S: S}
+
+	pbCert, err := node.callECARenewCertificate(context.Background(), req)
+	if err != nil {
+		node.log.Error("Failed requesting enrollment certificate renewal [%s].", err.Error())
+
+		return err
+	}
+
+	enrollCert, err := utils.DERToX509Certificate(pbCert.Cert)
+	if err != nil {
+		node.log.Error("Failed parsing enrollment certificate [%s].", err.Error())
+
+		return err
+	}
+	pub, ok := enrollCert.PublicKey.(*ecdsa.PublicKey)
+	if !ok || pub.X.Cmp(priv.X) != 0 || pub.Y.Cmp(priv.Y) != 0 {
+		node.log.Error("Enrollment certificate does not match the new key.")
+
+		return utils.ErrInvalidKey
+	}
+
+	node.enrollMutex.Lock()
+	defer node.enrollMutex.Unlock()
+
+	// Store the new enrollment key and certificate. The certificate is staged
+	// first so that a crash after replacing the key can be recovered from.
+	if err := utils.WriteFileAtomic(node.conf.getRenewedEnrollmentCertPath(), utils.DERCertToPEM(pbCert.Cert), 0700); err != nil {
+		node.log.Error("Failed storing enrollment certificate [%s].", err.Error())
+
+		return err
+	}
+	if err := node.ks.StoreKey(node.conf.getEnrollmentKeyFilename(), priv); err != nil {
+		node.log.Error("Failed storing enrollment key [%s].", err.Error())
+
+		os.Remove(node.conf.getRenewedEnrollmentCertPath())
+		return err
+	}
+	if err := os.Rename(node.conf.getRenewedEnrollmentCertPath(), node.conf.getEnrollmentCertPath()); err != nil {
+		node.log.Error("Failed storing enrollment certificate [%s].", err.Error())
+
+		return err
+	}
+
+	node.enrollCert = enrollCert
+	if node.enrollPrivKey != nil {
+		node.enrollPrivKey = priv
+	}
+	node.id = utils.Hash(pbCert.Cert)
+	node.log.Debug("Setting id to [%s].", utils.EncodeBase64(node.id))
+
+	if onRenewed != nil {
+		if err := onRenewed(); err != nil {
+			return err
+		}
+	}
+
+	node.log.Info("Renewing enrollment certificate...done! Valid until [%s].", enrollCert.NotAfter.String())
+
+	return nil
+}
@@ -20,6 +20,8 @@ under the License.
 package crypto
 
 import (
+	"crypto/x509"
+	"encoding/asn1"
 	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
 	"math/big"
 )
@@ -29,9 +31,36 @@ func (node *nodeImpl) sign(signKey interface{}, msg []byte) ([]byte, error) {
 	return utils.ECDSASign(signKey, msg)
 }
 
+func (node *nodeImpl) signWithEnrollmentKey(msg []byte) ([]byte, error) {
+	_, signature, err := node.signWithEnrollmentCert(msg)
+	return signature, err
+}
+
+// signWithEnrollmentCert signs msg with the enrollment key and returns the
+// enrollment certificate matching that key, even across a renewal
+func (node *nodeImpl) signWithEnrollmentCert(msg []byte) (*x509.Certificate, []byte, error) {
+	node.enrollMutex.RLock()
+	defer node.enrollMutex.RUnlock()
+
+	node.log.Debug("Signing message with enrollment key [%s].", utils.EncodeBase64(msg))
+	signature, err := node.ks.Sign(node.conf.getEnrollmentKeyFilename(), msg)
+
+	return node.enrollCert, signature, err
+}
+
 func (node *nodeImpl) ecdsaSignWithEnrollmentKey(msg []byte) (*big.Int, *big.Int, error) {
 	node.log.Debug("Signing message direct [%s].", utils.EncodeBase64(msg))
-	return utils.ECDSASignDirect(node.enrollPrivKey, msg)
+	sigma, err := node.signWithEnrollmentKey(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signature := new(utils.ECDSASignature)
+	if _, err := asn1.Unmarshal(sigma, signature); err != nil {
+		return nil, nil, err
+	}
+
+	return signature.R, signature.S, nil
 }
 
 func (node *nodeImpl) verify(verKey interface{}, msg, signature []byte) (bool, error) {
@@ -103,6 +103,27 @@ func (node *nodeImpl) callTCAReadCertificate(ctx context.Context, in *obcca.TCer
 	return cert, nil
 }
 
+func (node *nodeImpl) callTCAReadCRL(ctx context.Context, in *obcca.CRLReadReq, opts ...grpc.CallOption) (*obcca.CRL, error) {
+	sockP, err := grpc.Dial(node.conf.getTCAPAddr(), grpc.WithInsecure())
+	if err != nil {
+		node.log.Error("Failed tca dial in [%s].", err.Error())
+
+		return nil, err
+	}
+	defer sockP.Close()
+
+	tcaP := obcca.NewTCAPClient(sockP)
+
+	crl, err := tcaP.ReadCRL(context.Background(), in)
+	if err != nil {
+		node.log.Error("Failed requesting tca read crl [%s].", err.Error())
+
+		return nil, err
+	}
+
+	return crl, nil
+}
+
 func (node *nodeImpl) getTCACertificate() ([]byte, error) {
 	// Prepare the request
 	now := time.Now()
@@ -0,0 +1,134 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package crypto
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
+)
+
+func (node *nodeImpl) loadTLSCertificate() error {
+	node.log.Debug("Loading TLS certificate at [%s]...", node.conf.getTLSCertPath())
+
+	tlsCert, err := tls.LoadX509KeyPair(node.conf.getTLSCertPath(), node.conf.getTLSKeyPath())
+	if err != nil {
+		node.log.Error("Failed loading TLS certificate [%s].", err.Error())
+
+		return err
+	}
+	node.tlsCert = &tlsCert
+
+	return nil
+}
+
+func (node *nodeImpl) getTLSCertificate() (*tls.Certificate, error) {
+	if node.tlsCert == nil {
+		return nil, utils.ErrTLSCertificateMissing
+	}
+
+	return node.tlsCert, nil
+}
+
+// getHelloMessage binds hello to the TLS certificate of the connection it
+// is sent over, so that a signed hello cannot be replayed on another one
+func (node *nodeImpl) getHelloMessage(hello, tlsCert []byte) []byte {
+	return append(append([]byte{}, hello...), tlsCert...)
+}
+
+// signHello signs hello with the enrollment key and returns the enrollment
+// certificate together with the signature
+func (node *nodeImpl) signHello(hello []byte) ([]byte, []byte, error) {
+	if node.tlsCert == nil {
+		return nil, nil, utils.ErrTLSCertificateMissing
+	}
+
+	enrollCert, signature, err := node.signWithEnrollmentCert(node.getHelloMessage(hello, node.tlsCert.Certificate[0]))
+	if err != nil {
+		node.log.Error("Failed signing hello [%s].", err.Error())
+
+		return nil, nil, err
+	}
+
+	return enrollCert.Raw, signature, nil
+}
+
+// verifyHello checks that the remote peer authenticated by tlsCert holds
+// the enrollment certificate cert issued to peerID, and that signature is
+// its signature of hello
+func (node *nodeImpl) verifyHello(peerID string, cert, tlsCert, hello, signature []byte) error {
+	if tlsCert == nil {
+		return utils.ErrTLSCertificateMissing
+	}
+
+	// The TLS certificate must have been issued by the TLSCA
+	x509TLSCert, err := utils.DERToX509Certificate(tlsCert)
+	if err != nil {
+		node.log.Error("Failed parsing TLS certificate [%s].", err.Error())
+
+		return err
+	}
+	if _, err := x509TLSCert.Verify(x509.VerifyOptions{Roots: node.tlsCertPool}); err != nil {
+		node.log.Error("Failed verifying TLS certificate [%s].", err.Error())
+
+		return err
+	}
+
+	// The enrollment certificate must have been issued by the ECA to peerID
+	x509Cert, err := utils.DERToX509Certificate(cert)
+	if err != nil {
+		node.log.Error("Failed parsing enrollment certificate [%s].", err.Error())
+
+		return err
+	}
+	if _, err := x509Cert.Verify(x509.VerifyOptions{Roots: node.rootsCertPool}); err != nil {
+		node.log.Error("Failed verifying enrollment certificate [%s].", err.Error())
+
+		return err
+	}
+	if node.isCertRevoked(x509Cert) {
+		node.log.Error("Failed enrollment certificate revoked [%s].", x509Cert.SerialNumber.String())
+
+		return utils.ErrCertificateRevoked
+	}
+	enrollID, err := utils.GetECertEnrollmentID(x509Cert)
+	if err != nil {
+		node.log.Error("Failed getting enrollment id [%s].", err.Error())
+
+		return err
+	}
+	if enrollID != peerID {
+		node.log.Error("Failed peer [%s] claims to be [%s].", enrollID, peerID)
+
+		return utils.ErrPeerIDMismatch
+	}
+
+	ok, err := node.verify(x509Cert.PublicKey, node.getHelloMessage(hello, tlsCert), signature)
+	if err != nil {
+		node.log.Error("Failed verifying hello signature [%s].", err.Error())
+
+		return err
+	}
+	if !ok {
+		return utils.ErrInvalidSignature
+	}
+
+	return nil
+}
@@ -32,8 +32,85 @@ import (
 	"crypto/x509"
 	"time"
 	"github.com/openblockchain/obc-peer/openchain/util"
+	"errors"
+	"io/ioutil"
 )
 
+func (node *nodeImpl) retrieveTLSCACertsChain(userID string) error {
+	// Retrieve TLSCA certificate and verify it
+	tlscaCertRaw, err := node.getTLSCACertificate()
+	if err != nil {
+		node.log.Error("Failed getting TLSCA certificate [%s].", err.Error())
+
+		return err
+	}
+	node.log.Debug("TLSCA certificate [%s].", utils.EncodeBase64(tlscaCertRaw))
+
+	// TODO: Test TLSCA cert againt root CA
+	_, err = utils.DERToX509Certificate(tlscaCertRaw)
+	if err != nil {
+		node.log.Error("Failed parsing TLSCA certificate [%s].", err.Error())
+
+		return err
+	}
+
+	// Store TLSCA cert
+	node.log.Debug("Storing TLSCA certificate for [%s]...", userID)
+
+	err = ioutil.WriteFile(node.conf.getTLSCACertsChainPath(), utils.DERCertToPEM(tlscaCertRaw), 0700)
+	if err != nil {
+		node.log.Error("Failed storing tlsca certificate [%s].", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (node *nodeImpl) loadTLSCACertsChain() error {
+	// Nodes registered before the TLSCA chain was kept locally fetch it now
+	if missing, _ := utils.FileMissing(node.conf.getKeysPath(), node.conf.getTLSCACertsChainFilename()); missing {
+		if err := node.retrieveTLSCACertsChain(node.conf.name); err != nil {
+			return err
+		}
+	}
+
+	node.log.Debug("Loading TLSCA certificates chain at [%s]...", node.conf.getTLSCACertsChainPath())
+
+	chain, err := ioutil.ReadFile(node.conf.getTLSCACertsChainPath())
+	if err != nil {
+		node.log.Error("Failed loading TLSCA certificates chain [%s].", err.Error())
+
+		return err
+	}
+
+	// TLS certificates are kept apart from the enrollment and transaction
+	// certificates so that they cannot be passed off as one another
+	node.tlsCertPool = x509.NewCertPool()
+	ok := node.tlsCertPool.AppendCertsFromPEM(chain)
+	if !ok {
+		node.log.Error("Failed appending TLSCA certificates chain.")
+
+		return errors.New("Failed appending TLSCA certificates chain.")
+	}
+
+	return nil
+}
+
+func (node *nodeImpl) getTLSCACertificate() ([]byte, error) {
+	// Prepare the request
+	req := &obcca.TLSCertReadReq{&obcca.Identity{Id: "tlsca-root"}, nil}
+	pbCert, err := node.callTLSCAReadCertificate(context.Background(), req)
+	if err != nil {
+		node.log.Error("Failed requesting tls certificate [%s].", err.Error())
+
+		return nil, err
+	}
+
+	// TODO Verify pbCert.Cert
+
+	return pbCert.Cert, nil
+}
+
 func (node *nodeImpl) getTLSCertificateFromTLSCA(id, affiliation string) (interface{}, []byte, error) {
 	node.log.Info("getTLSCertificate...")
 	
@@ -111,4 +188,25 @@ func (node *nodeImpl) callTLSCACreateCertificate(ctx context.Context, in *obcca.
 	}
 
 	return cert, nil
-}
\ No newline at end of file
+}
+
+func (node *nodeImpl) callTLSCAReadCertificate(ctx context.Context, in *obcca.TLSCertReadReq, opts ...grpc.CallOption) (*obcca.Cert, error) {
+	sockP, err := grpc.Dial(node.conf.getTLSCAPAddr(), grpc.WithInsecure())
+	if err != nil {
+		node.log.Error("Failed tlsca dialing in [%s].", err.Error())
+
+		return nil, err
+	}
+	defer sockP.Close()
+
+	tlscaP := obcca.NewTLSCAPClient(sockP)
+
+	cert, err := tlscaP.ReadCertificate(context.Background(), in)
+	if err != nil {
+		node.log.Error("Failed requesting read certificate [%s].", err.Error())
+
+		return nil, err
+	}
+
+	return cert, nil
+}
@@ -20,6 +20,8 @@ under the License.
 package crypto
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"github.com/golang/protobuf/proto"
 	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
 	obc "github.com/openblockchain/obc-peer/protos"
@@ -40,8 +42,9 @@ func (peer *peerImpl) GetName() string {
 // GetID returns this peer's identifier
 func (peer *peerImpl) GetID() []byte {
 	// Clone id to avoid exposure of internal data structure
-	clone := make([]byte, len(peer.node.id))
-	copy(clone, peer.node.id)
+	id := peer.node.getID()
+	clone := make([]byte, len(id))
+	copy(clone, id)
 
 	return clone
 }
@@ -70,17 +73,21 @@ func (peer *peerImpl) TransactionPreValidation(tx *obc.Transaction) (*obc.Transa
 			peer.node.log.Error("TransactionPreExecution: failed unmarshalling cert [%s] [%s].", err.Error())
 			return tx, err
 		}
-		// TODO: verify cert
+		// 2. Check that the cert has not been revoked
+		if peer.node.isCertRevoked(cert) {
+			peer.node.log.Error("TransactionPreExecution: certificate revoked [%s].", cert.SerialNumber.String())
+			return tx, utils.ErrCertificateRevoked
+		}
 
-		// 3. Marshall tx without signature
-		signature := tx.Signature
-		tx.Signature = nil
+		// 3. Marshall tx without signature and endorsements, which are signed on their own
+		signature, endorsements := tx.Signature, tx.Endorsements
+		tx.Signature, tx.Endorsements = nil, nil
 		rawTx, err := proto.Marshal(tx)
 		if err != nil {
 			peer.node.log.Error("TransactionPreExecution: failed marshaling tx [%s] [%s].", err.Error())
 			return tx, err
 		}
-		tx.Signature = signature
+		tx.Signature, tx.Endorsements = signature, endorsements
 
 		// 2. Verify signature
 		ok, err := peer.node.verify(cert.PublicKey, rawTx, tx.Signature)
@@ -130,6 +137,44 @@ func (peer *peerImpl) GetStateEncryptor(deployTx, invokeTx *obc.Transaction) (St
 	return nil, utils.ErrNotImplemented
 }
 
+// GetTLSCertificate returns the TLS certificate, and its private key,
+// issued to this peer by the TLSCA.
+func (peer *peerImpl) GetTLSCertificate() (*tls.Certificate, error) {
+	if !peer.isInitialized {
+		return nil, utils.ErrNotInitialized
+	}
+
+	return peer.node.getTLSCertificate()
+}
+
+// GetTLSCACertPool returns the TLSCA certificates against which
+// the TLS certificates of remote peers are verified.
+func (peer *peerImpl) GetTLSCACertPool() *x509.CertPool {
+	return peer.node.tlsCertPool
+}
+
+// SignHello signs hello with this peer's enrollment key, binding it to
+// this peer's TLS certificate. It returns the enrollment certificate
+// and the signature.
+func (peer *peerImpl) SignHello(hello []byte) ([]byte, []byte, error) {
+	if !peer.isInitialized {
+		return nil, nil, utils.ErrNotInitialized
+	}
+
+	return peer.node.signHello(hello)
+}
+
+// VerifyHello checks that the remote peer authenticated by tlsCert holds
+// the enrollment certificate cert issued to peerID, and that signature
+// is its signature of hello. If this is the case, VerifyHello returns nil.
+func (peer *peerImpl) VerifyHello(peerID string, cert, tlsCert, hello, signature []byte) error {
+	if !peer.isInitialized {
+		return utils.ErrNotInitialized
+	}
+
+	return peer.node.verifyHello(peerID, cert, tlsCert, hello, signature)
+}
+
 // Private methods
 
 func (peer *peerImpl) register(prefix, name string, pwd []byte, enrollID, enrollPWD string) error {
@@ -170,6 +215,11 @@ func (peer *peerImpl) init(prefix, id string, pwd []byte) error {
 	}
 	peer.node = node
 
+	// Renew the enrollment certificate before it expires
+	peer.node.startECertRenewal(func() error {
+		return peer.node.renewEnrollmentCertificate(nil)
+	})
+
 	// initialized
 	peer.isInitialized = true
 
@@ -179,6 +229,8 @@ func (peer *peerImpl) init(prefix, id string, pwd []byte) error {
 
 func (peer *peerImpl) close() error {
 	if peer.node != nil {
+		peer.node.stopECertRenewal()
+
 		return peer.node.close()
 	}
 	return nil
@@ -34,8 +34,27 @@ import (
 var (
 	// TCertEncTCertIndex oid for TCertIndex
 	TCertEncTCertIndex = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7}
+
+	// TCertEncEnrollmentID oid for the enrollment id encrypted under the audit key
+	TCertEncEnrollmentID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 8}
+
+	// TCertEncAttributesBase oid prefix for the encrypted attributes
+	TCertEncAttributesBase = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 9}
+
+	// ECertAffiliation oid for the organization of the ECert owner
+	ECertAffiliation = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 10}
+
+	// ECertEnrollmentID oid for the enrollment id of the ECert owner
+	ECertEnrollmentID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 11}
 )
 
+// TCertAttribute is an attribute embedded in a TCert. Value is encrypted
+// under the key returned by TCertAttributeKey.
+type TCertAttribute struct {
+	Name  string
+	Value []byte
+}
+
 // DERToX509Certificate converts der to x509
 func DERToX509Certificate(asn1Data []byte) (*x509.Certificate, error) {
 	return x509.ParseCertificate(asn1Data)
@@ -101,6 +120,57 @@ func GetExtension(cert *x509.Certificate, oid asn1.ObjectIdentifier) ([]byte, er
 	return nil, errors.New("Failed retrieving extension.")
 }
 
+// GetTCertAttribute returns the encrypted value of the named attribute of a TCert
+func GetTCertAttribute(cert *x509.Certificate, name string) ([]byte, error) {
+	prefix := len(TCertEncAttributesBase)
+
+	for _, ext := range cert.Extensions {
+		if len(ext.Id) != prefix+1 || !IntArrayEquals(ext.Id[:prefix], TCertEncAttributesBase) {
+			continue
+		}
+
+		var attr TCertAttribute
+		if _, err := asn1.Unmarshal(ext.Value, &attr); err != nil {
+			return nil, err
+		}
+		if attr.Name == name {
+			return attr.Value, nil
+		}
+	}
+
+	return nil, ErrAttributeNotFound
+}
+
+// GetECertAffiliation returns the organization the ECert cert belongs to
+func GetECertAffiliation(cert *x509.Certificate) (string, error) {
+	for _, ext := range cert.Extensions {
+		if IntArrayEquals(ext.Id, ECertAffiliation) {
+			return string(ext.Value), nil
+		}
+	}
+
+	return "", ErrAffiliationNotFound
+}
+
+// GetECertEnrollmentID returns the enrollment id the ECert cert was issued to
+func GetECertEnrollmentID(cert *x509.Certificate) (string, error) {
+	for _, ext := range cert.Extensions {
+		if IntArrayEquals(ext.Id, ECertEnrollmentID) {
+			return string(ext.Value), nil
+		}
+	}
+
+	return "", ErrEnrollmentIDNotFound
+}
+
+// TCertAttributeKey derives the key encrypting the named attribute of the TCert
+// having index tCertIndex
+func TCertAttributeKey(tCertOwnerKDFKey, tCertIndex []byte, name string) []byte {
+	preKey := HMAC(tCertOwnerKDFKey, []byte{3})
+
+	return HMACTruncated(preKey, append(append([]byte{}, tCertIndex...), name...), AESKeyLength)
+}
+
 // NewSelfSignedCert create a self signed certificate
 func NewSelfSignedCert() ([]byte, interface{}, error) {
 	privKey, err := NewECDSAKey()
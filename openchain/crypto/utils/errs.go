@@ -50,6 +50,54 @@ var (
 
 	// ErrInvalidConfidentialityLevel Invalid confidentiality level
 	ErrInvalidConfidentialityLevel = errors.New("Invalid confidentiality level")
+
+	// ErrCertificateRevoked Certificate revoked
+	ErrCertificateRevoked          = errors.New("Certificate revoked.")
+
+	// ErrNotATCert Not a transaction certificate
+	ErrNotATCert                   = errors.New("Not a transaction certificate.")
+
+	// ErrAttributeNotFound Attribute not found
+	ErrAttributeNotFound           = errors.New("Attribute not found.")
+
+	// ErrUnknownKeyStoreBackend Unknown keystore backend
+	ErrUnknownKeyStoreBackend      = errors.New("Unknown keystore backend.")
+
+	// ErrKeyNotExportable Key not exportable
+	ErrKeyNotExportable            = errors.New("Key not exportable.")
+
+	// ErrRemoteSignerTLSRequired Remote signer reached over TCP without TLS
+	ErrRemoteSignerTLSRequired     = errors.New("Remote signer over TCP requires TLS.")
+
+	// ErrKeyNotFound Key not found
+	ErrKeyNotFound                 = errors.New("Key not found.")
+
+	// ErrInvalidKey Invalid key
+	ErrInvalidKey                  = errors.New("Invalid key.")
+
+	// ErrInvalidPassphrase Invalid passphrase
+	ErrInvalidPassphrase           = errors.New("Invalid passphrase or corrupted keystore.")
+
+	// ErrAffiliationNotFound Affiliation not found
+	ErrAffiliationNotFound         = errors.New("Affiliation not found.")
+
+	// ErrEndorsementPolicyNotSatisfied Endorsement policy not satisfied
+	ErrEndorsementPolicyNotSatisfied = errors.New("Endorsement policy not satisfied.")
+
+	// ErrEnrollmentIDNotFound Enrollment id not found
+	ErrEnrollmentIDNotFound        = errors.New("Enrollment id not found.")
+
+	// ErrPeerIDMismatch Peer id does not match the enrollment certificate
+	ErrPeerIDMismatch              = errors.New("Peer id does not match the enrollment certificate.")
+
+	// ErrTLSCertificateMissing Missing TLS certificate
+	ErrTLSCertificateMissing       = errors.New("Missing TLS certificate.")
+
+	// ErrChaincodeNotUpdatable Chaincode deployed without endorsement policy
+	ErrChaincodeNotUpdatable       = errors.New("Chaincode not updatable: deployed without endorsement policy.")
+
+	// ErrChaincodeNotTerminable Chaincode deployed without endorsement policy
+	ErrChaincodeNotTerminable      = errors.New("Chaincode not terminable: deployed without endorsement policy.")
 )
 
 
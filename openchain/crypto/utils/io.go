@@ -92,6 +92,30 @@ func FilePathMissing(path string) (bool, error) {
 	return false, nil
 }
 
+// WriteFileAtomic writes data to a temporary file and renames it to
+// filename, so that a crash leaves either the old or the new content
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	tmp := filename + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, filename)
+}
+
 // DecodeBase64 decodes from Base64
 func DecodeBase64(in string) ([]byte, error) {
 	return base64.StdEncoding.DecodeString(in)
@@ -32,10 +32,30 @@ var (
 
 	// Sync
 	mutex sync.Mutex
+
+	// Source of the endorsement policies
+	deployments      ChaincodeDeployments
+	deploymentsMutex sync.RWMutex
 )
 
 // Public Methods
 
+// SetChaincodeDeployments sets where validators look up the endorsement
+// policies of the chaincodes. Policies are not enforced until it is set.
+func SetChaincodeDeployments(d ChaincodeDeployments) {
+	deploymentsMutex.Lock()
+	defer deploymentsMutex.Unlock()
+
+	deployments = d
+}
+
+func getChaincodeDeployments() ChaincodeDeployments {
+	deploymentsMutex.RLock()
+	defer deploymentsMutex.RUnlock()
+
+	return deployments
+}
+
 // RegisterValidator registers a client to the PKI infrastructure
 func RegisterValidator(name string, pwd []byte, enrollID, enrollPWD string) error {
 	mutex.Lock()
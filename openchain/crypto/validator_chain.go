@@ -23,81 +23,10 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"encoding/binary"
-	"errors"
-	"github.com/golang/protobuf/proto"
 	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
-	obc "github.com/openblockchain/obc-peer/protos"
 	"github.com/op/go-logging"
 )
 
-func (validator *validatorImpl) decryptTx(tx *obc.Transaction) (*obc.Transaction, error) {
-	if tx.Nonce == nil || len(tx.Nonce) == 0 {
-		return nil, errors.New("Failed decrypting payload. Invalid nonce.")
-	}
-
-	// clone tx
-	clone, err := validator.deepCloneTransaction(tx)
-	if err != nil {
-		validator.peer.node.log.Error("Failed deep cloning [%s].", err.Error())
-		return nil, err
-	}
-
-	// Derive root key
-	key := utils.HMAC(validator.peer.node.enrollChainKey, clone.Nonce)
-
-	//	validator.peer.node.log.Info("Deriving from  ", utils.EncodeBase64(validator.peer.node.enrollChainKey))
-	//	validator.peer.node.log.Info("Nonce  ", utils.EncodeBase64(tx.Nonce))
-	//	validator.peer.node.log.Info("Derived key  ", utils.EncodeBase64(key))
-	//	validator.peer.node.log.Info("Encrypted Payload  ", utils.EncodeBase64(tx.EncryptedPayload))
-	//	validator.peer.node.log.Info("Encrypted ChaincodeID  ", utils.EncodeBase64(tx.EncryptedChaincodeID))
-
-	// Decrypt using the derived key
-
-	payloadKey := utils.HMACTruncated(key, []byte{1}, utils.AESKeyLength)
-	encryptedPayload := make([]byte, len(clone.EncryptedPayload))
-	copy(encryptedPayload, clone.EncryptedPayload)
-	payload, err := utils.CBCPKCS7Decrypt(payloadKey, encryptedPayload)
-	if err != nil {
-		validator.peer.node.log.Error("Failed decrypting payload [%s].", err.Error())
-		return nil, err
-	}
-	clone.Payload = payload
-
-	chaincodeIDKey := utils.HMACTruncated(key, []byte{2}, utils.AESKeyLength)
-	encryptedChaincodeID := make([]byte, len(clone.EncryptedChaincodeID))
-	copy(encryptedChaincodeID, clone.EncryptedChaincodeID)
-	rawChaincodeID, err := utils.CBCPKCS7Decrypt(chaincodeIDKey, encryptedChaincodeID)
-
-	chaincodeID := &obc.ChaincodeID{}
-	if err := proto.Unmarshal(rawChaincodeID, chaincodeID); err != nil {
-		validator.peer.node.log.Error("Failed decrypting chaincodeID [%s].", err.Error())
-
-		return nil, err
-	}
-	clone.ChaincodeID = chaincodeID
-
-	return clone, nil
-}
-
-func (validator *validatorImpl) deepCloneTransaction(tx *obc.Transaction) (*obc.Transaction, error) {
-	raw, err := proto.Marshal(tx)
-	if err != nil {
-		validator.peer.node.log.Error("Failed cloning transaction [%s].", err.Error())
-
-		return nil, err
-	}
-
-	clone := &obc.Transaction{}
-	err = proto.Unmarshal(raw, clone)
-	if err != nil {
-		validator.peer.node.log.Error("Failed cloning transaction [%s].", err.Error())
-
-		return nil, err
-	}
-
-	return clone, nil
-}
-
 type stateEncryptorImpl struct {
 	log           *logging.Logger
 
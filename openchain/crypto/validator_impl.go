@@ -21,6 +21,7 @@ package crypto
 
 import (
 	"crypto/ecdsa"
+	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
@@ -60,7 +61,17 @@ func (validator *validatorImpl) TransactionPreValidation(tx *obc.Transaction) (*
 		return nil, utils.ErrNotInitialized
 	}
 
-	return validator.peer.TransactionPreValidation(tx)
+	tx, err := validator.peer.TransactionPreValidation(tx)
+	if err != nil {
+		return tx, err
+	}
+
+	// Enforce the endorsement policy of the chaincode before execution
+	if err := validator.checkEndorsementPolicy(tx); err != nil {
+		return tx, err
+	}
+
+	return tx, nil
 }
 
 // TransactionPreValidation verifies that the transaction is
@@ -80,7 +91,7 @@ func (validator *validatorImpl) TransactionPreExecution(tx *obc.Transaction) (*o
 		validator.peer.node.log.Debug("Deep cloning.")
 
 		// Nothing to do here. Clone tx.
-		clone, err := validator.deepCloneTransaction(tx)
+		clone, err := validator.peer.node.deepCloneTransaction(tx)
 		if err != nil {
 			validator.peer.node.log.Error("Failed deep cloning [%s].", err.Error())
 			return nil, err
@@ -91,7 +102,7 @@ func (validator *validatorImpl) TransactionPreExecution(tx *obc.Transaction) (*o
 		validator.peer.node.log.Debug("Clone and Decrypt.")
 
 		// Clone the transaction and decrypt it
-		newTx, err := validator.decryptTx(tx)
+		newTx, err := validator.peer.node.decryptTx(tx)
 		if err != nil {
 			validator.peer.node.log.Error("Failed decrypting [%s].", err.Error())
 
@@ -122,6 +133,13 @@ func (validator *validatorImpl) Verify(vkID, signature, message []byte) error {
 		validator.peer.node.log.Error("Failed getting enrollment cert ", utils.EncodeBase64(vkID), err)
 	}
 
+	// Superseded certificates are accepted during the renewal grace period only
+	if validator.peer.node.isCertRevoked(cert) {
+		validator.peer.node.log.Error("Failed certificate revoked for ", utils.EncodeBase64(vkID))
+
+		return utils.ErrCertificateRevoked
+	}
+
 	vk := cert.PublicKey.(*ecdsa.PublicKey)
 
 	ok, err := validator.verify(vk, message, signature)
@@ -138,6 +156,32 @@ func (validator *validatorImpl) Verify(vkID, signature, message []byte) error {
 	return nil
 }
 
+// GetTLSCertificate returns the TLS certificate, and its private key,
+// issued to this validator by the TLSCA.
+func (validator *validatorImpl) GetTLSCertificate() (*tls.Certificate, error) {
+	return validator.peer.GetTLSCertificate()
+}
+
+// GetTLSCACertPool returns the TLSCA certificates against which
+// the TLS certificates of remote peers are verified.
+func (validator *validatorImpl) GetTLSCACertPool() *x509.CertPool {
+	return validator.peer.GetTLSCACertPool()
+}
+
+// SignHello signs hello with this validator's enrollment key, binding it to
+// this validator's TLS certificate. It returns the enrollment certificate
+// and the signature.
+func (validator *validatorImpl) SignHello(hello []byte) ([]byte, []byte, error) {
+	return validator.peer.SignHello(hello)
+}
+
+// VerifyHello checks that the remote peer authenticated by tlsCert holds
+// the enrollment certificate cert issued to peerID, and that signature
+// is its signature of hello. If this is the case, VerifyHello returns nil.
+func (validator *validatorImpl) VerifyHello(peerID string, cert, tlsCert, hello, signature []byte) error {
+	return validator.peer.VerifyHello(peerID, cert, tlsCert, hello, signature)
+}
+
 func (validator *validatorImpl) GetStateEncryptor(deployTx, executeTx *obc.Transaction) (StateEncryptor, error) {
 	// Check nonce
 	if deployTx.Nonce == nil || len(deployTx.Nonce) == 0 {
@@ -236,27 +280,18 @@ func (validator *validatorImpl) init(name string, pwd []byte) error {
 	}
 	validator.peer = peer
 
-	// Initialize keystore
-	validator.peer.node.log.Info("Init keystore...")
-	err := validator.initKeyStore()
-	if err != nil {
-		if err != utils.ErrKeyStoreAlreadyInitialized {
-			validator.peer.node.log.Error("Keystore already initialized.")
-		} else {
-			validator.peer.node.log.Error("Failed initiliazing keystore [%s].", err.Error())
-
-			return err
-		}
-	}
-	validator.peer.node.log.Info("Init keystore...done.")
-
 	// Init crypto engine
-	err = validator.initCryptoEngine()
+	err := validator.initCryptoEngine()
 	if err != nil {
 		validator.peer.node.log.Error("Failed initiliazing crypto engine [%s].", err.Error())
 		return err
 	}
 
+	// Start refreshing the certificate revocation lists
+	if err := validator.peer.node.startCRLRefresh(); err != nil {
+		return err
+	}
+
 	// initialized
 	validator.isInitialized = true
 
@@ -272,6 +307,8 @@ func (validator *validatorImpl) initCryptoEngine() error {
 
 func (validator *validatorImpl) close() error {
 	if validator.peer != nil {
+		validator.peer.node.stopCRLRefresh()
+
 		return validator.peer.close()
 	}
 
@@ -20,28 +20,16 @@ under the License.
 package crypto
 
 import (
-	"database/sql"
 	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
 )
 
-func (validator *validatorImpl) initKeyStore() error {
-	// create tables
-	validator.peer.node.log.Debug("Create Table if not exists", "Certificates", validator.peer.node.conf.getKeysPath())
-	if _, err := validator.peer.node.ks.sqlDB.Exec("CREATE TABLE IF NOT EXISTS Certificates (id VARCHAR, cert BLOB, PRIMARY KEY (id))"); err != nil {
-		validator.peer.node.log.Debug("Failed creating table [%s].", err.Error())
-		return err
-	}
-
-	return nil
-}
-
 func (ks *keyStore) GetEnrollmentCert(id []byte, certFetcher func(id []byte) ([]byte, error)) ([]byte, error) {
 	ks.m.Lock()
 	defer ks.m.Unlock()
 
 	sid := utils.EncodeBase64(id)
 
-	cert, err := ks.selectEnrollmentCert(sid)
+	cert, err := ks.GetCert(sid)
 	if err != nil {
 		ks.log.Error("Failed selecting enrollment cert [%s].", err.Error())
 
@@ -61,67 +49,14 @@ func (ks *keyStore) GetEnrollmentCert(id []byte, certFetcher func(id []byte) ([]
 
 		// 2. Store
 		ks.log.Debug("Store certificate...")
-		tx, err := ks.sqlDB.Begin()
-		if err != nil {
-			ks.log.Error("Failed beginning transaction [%s].", err.Error())
-
-			return nil, err
-		}
-
-		ks.log.Debug("Insert id [%s].", sid)
-		ks.log.Debug("Insert cert [%s].", utils.EncodeBase64(cert))
-
-		_, err = tx.Exec("INSERT INTO Certificates (id, cert) VALUES (?, ?)", sid, cert)
-
-		if err != nil {
-			ks.log.Error("Failed inserting cert [%s].", err.Error())
-
-			tx.Rollback()
-
-			return nil, err
-		}
-
-		err = tx.Commit()
-		if err != nil {
-			ks.log.Error("Failed committing transaction [%s].", err.Error())
-
-			tx.Rollback()
+		if err := ks.StoreCert(sid, cert); err != nil {
+			ks.log.Error("Failed storing enrollment cert [%s].", err.Error())
 
 			return nil, err
 		}
 
 		ks.log.Debug("Fectch Enrollment Certificate from ECA...done!")
-
-		cert, err = ks.selectEnrollmentCert(sid)
-		if err != nil {
-			ks.log.Error("Failed selecting next TCert after fetching [%s].", err.Error())
-
-			return nil, err
-		}
-	}
-
-	return cert, nil
-}
-
-func (ks *keyStore) selectEnrollmentCert(id string) ([]byte, error) {
-	ks.log.Debug("Select Enrollment TCert...")
-
-	// Get the first row available
-	var cert []byte
-	row := ks.sqlDB.QueryRow("SELECT cert FROM Certificates where id = ?", id)
-	err := row.Scan(&cert)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	} else if err != nil {
-		ks.log.Error("Error during select [%s].", err.Error())
-
-		return nil, err
 	}
 
-	ks.log.Debug("Cert [%s].", utils.EncodeBase64(cert))
-
-	ks.log.Debug("Select Enrollment Cert...done!")
-
 	return cert, nil
 }
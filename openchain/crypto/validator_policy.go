@@ -0,0 +1,163 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package crypto
+
+import (
+	"crypto/x509"
+	"github.com/golang/protobuf/proto"
+	"github.com/openblockchain/obc-peer/openchain/crypto/utils"
+	obc "github.com/openblockchain/obc-peer/protos"
+)
+
+// checkEndorsementPolicy verifies that the endorsements of tx satisfy the
+// endorsement policy set when the chaincode was deployed. The same policy
+// authorizes the updates and the termination of the chaincode, which can be
+// neither updated nor terminated when deployed without policy
+func (validator *validatorImpl) checkEndorsementPolicy(tx *obc.Transaction) error {
+	switch tx.Type {
+	case obc.Transaction_CHAINCODE_EXECUTE, obc.Transaction_CHAINCODE_UPDATE, obc.Transaction_CHAINCODE_TERMINATE:
+	default:
+		return nil
+	}
+
+	deployments := getChaincodeDeployments()
+	if deployments == nil {
+		validator.peer.node.log.Debug("No chaincode deployments available. Endorsement policies not enforced.")
+
+		return nil
+	}
+
+	policy, err := validator.getEndorsementPolicy(deployments, tx)
+	if err != nil {
+		validator.peer.node.log.Error("Failed getting endorsement policy [%s].", err.Error())
+
+		return err
+	}
+	if policy == nil || policy.Threshold == 0 {
+		switch tx.Type {
+		case obc.Transaction_CHAINCODE_UPDATE:
+			validator.peer.node.log.Error("Failed updating chaincode [%s].", utils.ErrChaincodeNotUpdatable.Error())
+
+			return utils.ErrChaincodeNotUpdatable
+		case obc.Transaction_CHAINCODE_TERMINATE:
+			validator.peer.node.log.Error("Failed terminating chaincode [%s].", utils.ErrChaincodeNotTerminable.Error())
+
+			return utils.ErrChaincodeNotTerminable
+		}
+		return nil
+	}
+
+	msg, err := validator.peer.node.getEndorsementMessage(tx)
+	if err != nil {
+		validator.peer.node.log.Error("Failed marshaling tx [%s].", err.Error())
+
+		return err
+	}
+
+	// Each organization counts once, no matter how many of its members endorsed tx
+	endorsed := make(map[string]bool)
+	for _, endorsement := range tx.Endorsements {
+		org, err := validator.verifyEndorsement(endorsement, msg)
+		if err != nil {
+			validator.peer.node.log.Warning("Discarding endorsement [%s].", err.Error())
+
+			continue
+		}
+		endorsed[org] = true
+	}
+
+	var count uint32
+	for _, org := range policy.Organizations {
+		if endorsed[org] {
+			count++
+		}
+	}
+	if count < policy.Threshold {
+		validator.peer.node.log.Error("Endorsement policy not satisfied: [%d] of [%d] required endorsements.", count, policy.Threshold)
+
+		return utils.ErrEndorsementPolicyNotSatisfied
+	}
+
+	return nil
+}
+
+func (validator *validatorImpl) getEndorsementPolicy(deployments ChaincodeDeployments, tx *obc.Transaction) (*obc.EndorsementPolicy, error) {
+	var err error
+	if tx.ConfidentialityLevel == obc.ConfidentialityLevel_CONFIDENTIAL {
+		if tx, err = validator.peer.node.decryptTx(tx); err != nil {
+			return nil, err
+		}
+	}
+	if tx.ChaincodeID == nil {
+		return nil, utils.ErrInvalidReference
+	}
+
+	deployTx, err := deployments.GetDeployTransaction(tx.ChaincodeID.Name)
+	if err != nil {
+		return nil, err
+	}
+	if deployTx == nil {
+		return nil, utils.ErrInvalidReference
+	}
+	if deployTx.ConfidentialityLevel == obc.ConfidentialityLevel_CONFIDENTIAL {
+		if deployTx, err = validator.peer.node.decryptTx(deployTx); err != nil {
+			return nil, err
+		}
+	}
+
+	spec := &obc.ChaincodeDeploymentSpec{}
+	if err := proto.Unmarshal(deployTx.Payload, spec); err != nil {
+		return nil, err
+	}
+
+	return spec.GetChaincodeSpec().GetEndorsementPolicy(), nil
+}
+
+// verifyEndorsement checks endorsement against msg and returns the
+// organization of the endorser
+func (validator *validatorImpl) verifyEndorsement(endorsement *obc.Endorsement, msg []byte) (string, error) {
+	cert, err := utils.DERToX509Certificate(endorsement.Cert)
+	if err != nil {
+		return "", err
+	}
+
+	// The endorser must be enrolled
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: validator.peer.node.rootsCertPool}); err != nil {
+		return "", err
+	}
+	if validator.peer.node.isCertRevoked(cert) {
+		return "", utils.ErrCertificateRevoked
+	}
+
+	org, err := utils.GetECertAffiliation(cert)
+	if err != nil {
+		return "", err
+	}
+
+	ok, err := validator.verify(cert.PublicKey, msg, endorsement.Signature)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", utils.ErrInvalidSignature
+	}
+
+	return org, nil
+}
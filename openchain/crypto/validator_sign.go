@@ -32,7 +32,7 @@ func (validator *validatorImpl) sign(signKey interface{}, msg []byte) ([]byte, e
 }
 
 func (validator *validatorImpl) signWithEnrollmentKey(msg []byte) ([]byte, error) {
-	sigma, err := utils.ECDSASign(validator.peer.node.enrollPrivKey, msg)
+	sigma, err := validator.peer.node.signWithEnrollmentKey(msg)
 
 	validator.peer.node.log.Debug("Signing message [%s], sigma [%s].", utils.EncodeBase64(msg), utils.EncodeBase64(sigma))
 
@@ -47,6 +47,7 @@ type OpenchainDB struct {
 	StateCF      *gorocksdb.ColumnFamilyHandle
 	StateDeltaCF *gorocksdb.ColumnFamilyHandle
 	IndexesCF    *gorocksdb.ColumnFamilyHandle
+	readOnly     bool
 }
 
 var openchainDB *OpenchainDB
@@ -204,7 +205,29 @@ func openDB() (*OpenchainDB, error) {
 		return nil, err
 	}
 	isOpen = true
-	return &OpenchainDB{db, cfHandlers[1], cfHandlers[2], cfHandlers[3], cfHandlers[4]}, nil
+	return &OpenchainDB{DB: db, BlockchainCF: cfHandlers[1], StateCF: cfHandlers[2],
+		StateDeltaCF: cfHandlers[3], IndexesCF: cfHandlers[4]}, nil
+}
+
+// OpenDBReadOnly opens the existing openchain db at dbPath, for instance a copy of the
+// db of another peer, in read only mode. The db of this peer is not affected
+func OpenDBReadOnly(dbPath string) (*OpenchainDB, error) {
+	missing, err := dirMissingOrEmpty(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	if missing {
+		return nil, fmt.Errorf("db dir [%s] is missing or empty", dbPath)
+	}
+	opts := gorocksdb.NewDefaultOptions()
+	db, cfHandlers, err := gorocksdb.OpenDbForReadOnlyColumnFamilies(opts, dbPath,
+		[]string{"default", blockchainCF, stateCF, stateDeltaCF, indexesCF},
+		[]*gorocksdb.Options{opts, opts, opts, opts, opts}, false)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenchainDB{DB: db, BlockchainCF: cfHandlers[1], StateCF: cfHandlers[2],
+		StateDeltaCF: cfHandlers[3], IndexesCF: cfHandlers[4], readOnly: true}, nil
 }
 
 // CloseDB releases all column family handles and closes rocksdb
@@ -213,7 +236,9 @@ func (openchainDB *OpenchainDB) CloseDB() {
 	openchainDB.StateCF.Destroy()
 	openchainDB.StateDeltaCF.Destroy()
 	openchainDB.DB.Close()
-	isOpen = false
+	if !openchainDB.readOnly {
+		isOpen = false
+	}
 }
 
 // DeleteState delets ALL state keys/values from the DB. This is generally
@@ -51,6 +51,33 @@ func TestOpenDB_DirEmpty(t *testing.T) {
 	performBasicReadWrite(t)
 }
 
+func TestOpenDBReadOnly(t *testing.T) {
+	createTestDB()
+	defer deleteTestDB()
+	performBasicReadWrite(t)
+
+	readOnlyDB, err := OpenDBReadOnly(getDBPath())
+	if err != nil {
+		t.Fatalf("Failed to open DB read only: %s", err)
+	}
+	value, err := readOnlyDB.GetFromBlockchainCF([]byte("dummyKey"))
+	if err != nil {
+		t.Fatalf("read error = [%s]", err)
+	}
+	if !bytes.Equal(value, []byte("dummyValue")) {
+		t.Fatal("read error. Bytes not equal")
+	}
+	readOnlyDB.CloseDB()
+	if !isOpen {
+		t.Fatal("Closing a read only DB should not close the DB of the peer")
+	}
+
+	_, err = OpenDBReadOnly(viper.GetString("peer.fileSystemPath") + "/missing")
+	if err == nil {
+		t.Fatal("Opening a missing DB read only should throw error")
+	}
+}
+
 // db helper functions
 func createTestDBPath() {
 	dbPath := viper.GetString("peer.fileSystemPath")
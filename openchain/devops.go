@@ -32,6 +32,7 @@ import (
 	"github.com/openblockchain/obc-peer/openchain/chaincode"
 	"github.com/openblockchain/obc-peer/openchain/container"
 	"github.com/openblockchain/obc-peer/openchain/crypto"
+	"github.com/openblockchain/obc-peer/openchain/ledger"
 	"github.com/openblockchain/obc-peer/openchain/peer"
 	"github.com/openblockchain/obc-peer/openchain/util"
 	pb "github.com/openblockchain/obc-peer/protos"
@@ -88,7 +89,7 @@ func (*Devops) Build(context context.Context, spec *pb.ChaincodeSpec) (*pb.Chain
 }
 
 // get chaincode bytes
-func (*Devops) getChaincodeBytes(context context.Context, spec *pb.ChaincodeSpec) (*pb.ChaincodeDeploymentSpec, error) {
+func (*Devops) getChaincodeBytes(context context.Context, spec *pb.ChaincodeSpec, update bool) (*pb.ChaincodeDeploymentSpec, error) {
 	mode := viper.GetString("chaincode.mode")
 	var codePackageBytes []byte
 	if mode != chaincode.DevModeUserRunsChaincode {
@@ -98,7 +99,11 @@ func (*Devops) getChaincodeBytes(context context.Context, spec *pb.ChaincodeSpec
 			return nil, err
 		}
 
-		codePackageBytes, err = container.GetChaincodePackageBytes(spec)
+		if update {
+			codePackageBytes, err = container.GetChaincodeUpdatePackageBytes(spec)
+		} else {
+			codePackageBytes, err = container.GetChaincodePackageBytes(spec)
+		}
 		if err != nil {
 			err = fmt.Errorf("Error getting chaincode package bytes: %s", err)
 			devopsLogger.Error(fmt.Sprintf("%s", err))
@@ -111,8 +116,21 @@ func (*Devops) getChaincodeBytes(context context.Context, spec *pb.ChaincodeSpec
 
 // Deploy deploys the supplied chaincode image to the validators through a transaction
 func (d *Devops) Deploy(ctx context.Context, spec *pb.ChaincodeSpec) (*pb.ChaincodeDeploymentSpec, error) {
+	return d.deployOrUpdate(ctx, spec, false)
+}
+
+// Update replaces the code of the deployed chaincode named in the supplied spec through a transaction.
+// The chaincode keeps its state
+func (d *Devops) Update(ctx context.Context, spec *pb.ChaincodeSpec) (*pb.ChaincodeDeploymentSpec, error) {
+	if spec.ChaincodeID == nil || spec.ChaincodeID.Name == "" {
+		return nil, fmt.Errorf("name not given for update")
+	}
+	return d.deployOrUpdate(ctx, spec, true)
+}
+
+func (d *Devops) deployOrUpdate(ctx context.Context, spec *pb.ChaincodeSpec, update bool) (*pb.ChaincodeDeploymentSpec, error) {
 	// get the deployment spec
-	chaincodeDeploymentSpec, err := d.getChaincodeBytes(ctx, spec)
+	chaincodeDeploymentSpec, err := d.getChaincodeBytes(ctx, spec, update)
 
 	if err != nil {
 		devopsLogger.Error(fmt.Sprintf("Error deploying chaincode spec: %v\n\n error: %s", spec, err))
@@ -121,7 +139,14 @@ func (d *Devops) Deploy(ctx context.Context, spec *pb.ChaincodeSpec) (*pb.Chainc
 
 	// Now create the Transactions message and send to Peer.
 
+	// The deploy transaction is named after the chaincode, updates get their own uuid
 	transID := chaincodeDeploymentSpec.ChaincodeSpec.ChaincodeID.Name
+	if update {
+		if transID, err = util.GenerateUUID(); err != nil {
+			devopsLogger.Error(fmt.Sprintf("Error generating UUID: %s", err))
+			return nil, err
+		}
+	}
 
 	var tx *pb.Transaction
 	var sec crypto.Client
@@ -143,7 +168,11 @@ func (d *Devops) Deploy(ctx context.Context, spec *pb.ChaincodeSpec) (*pb.Chainc
 		if devopsLogger.IsEnabledFor(logging.DEBUG) {
 			devopsLogger.Debug("Creating secure transaction %s", transID)
 		}
-		tx, err = sec.NewChaincodeDeployTransaction(chaincodeDeploymentSpec, transID)
+		if update {
+			tx, err = sec.NewChaincodeUpdateTransaction(chaincodeDeploymentSpec, transID)
+		} else {
+			tx, err = sec.NewChaincodeDeployTransaction(chaincodeDeploymentSpec, transID)
+		}
 		if nil != err {
 			return nil, err
 		}
@@ -151,7 +180,11 @@ func (d *Devops) Deploy(ctx context.Context, spec *pb.ChaincodeSpec) (*pb.Chainc
 		if devopsLogger.IsEnabledFor(logging.DEBUG) {
 			devopsLogger.Debug("Creating deployment transaction (%s)", transID)
 		}
-		tx, err = pb.NewChaincodeDeployTransaction(chaincodeDeploymentSpec, transID)
+		if update {
+			tx, err = pb.NewChaincodeUpdateTransaction(chaincodeDeploymentSpec, transID)
+		} else {
+			tx, err = pb.NewChaincodeDeployTransaction(chaincodeDeploymentSpec, transID)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("Error deploying chaincode: %s ", err)
 		}
@@ -192,6 +225,15 @@ func (d *Devops) invokeOrQuery(ctx context.Context, chaincodeInvocationSpec *pb.
 	resp := d.coord.ExecuteTransaction(transaction)
 	if resp.Status == pb.Response_FAILURE {
 		err = fmt.Errorf(string(resp.Msg))
+	} else if invoke {
+		// Report the transaction as pending until it is committed. Should it already
+		// have been committed, the receipt from the blockchain takes precedence
+		ledger, ledgerErr := ledger.GetLedger()
+		if ledgerErr != nil {
+			devopsLogger.Error(fmt.Sprintf("Error getting ledger to mark transaction %s pending: %s", transaction.Uuid, ledgerErr))
+		} else {
+			ledger.MarkTransactionPending(transaction.Uuid)
+		}
 	}
 
 	return resp, err
@@ -252,6 +294,86 @@ func (d *Devops) Query(ctx context.Context, chaincodeInvocationSpec *pb.Chaincod
 	return d.invokeOrQuery(ctx, chaincodeInvocationSpec, false)
 }
 
+// Terminate terminates the chaincode named in the supplied spec through a transaction. The chaincode
+// can no longer be invoked or queried but its state remains readable
+func (d *Devops) Terminate(ctx context.Context, spec *pb.ChaincodeSpec) (*pb.Response, error) {
+	if spec.ChaincodeID == nil || spec.ChaincodeID.Name == "" {
+		return nil, fmt.Errorf("name not given for terminate")
+	}
+
+	uuid, err := util.GenerateUUID()
+	if err != nil {
+		devopsLogger.Error(fmt.Sprintf("Error generating UUID: %s", err))
+		return nil, err
+	}
+	chaincodeInvocationSpec := &pb.ChaincodeInvocationSpec{ChaincodeSpec: spec}
+
+	var tx *pb.Transaction
+	if viper.GetBool("security.enabled") {
+		if devopsLogger.IsEnabledFor(logging.DEBUG) {
+			devopsLogger.Debug("Initializing secure devops using context %s", spec.SecureContext)
+		}
+		sec, err := crypto.InitClient(spec.SecureContext, nil)
+		defer crypto.CloseClient(sec)
+
+		// remove the security context since we are no longer need it down stream
+		spec.SecureContext = ""
+
+		if nil != err {
+			return nil, err
+		}
+		if devopsLogger.IsEnabledFor(logging.DEBUG) {
+			devopsLogger.Debug("Creating secure termination transaction %s", uuid)
+		}
+		if tx, err = sec.NewChaincodeTerminateTransaction(chaincodeInvocationSpec, uuid); nil != err {
+			return nil, err
+		}
+	} else {
+		if devopsLogger.IsEnabledFor(logging.DEBUG) {
+			devopsLogger.Debug("Creating termination transaction (%s)", uuid)
+		}
+		if tx, err = pb.NewChaincodeExecute(chaincodeInvocationSpec, uuid, pb.Transaction_CHAINCODE_TERMINATE); nil != err {
+			return nil, err
+		}
+	}
+
+	if devopsLogger.IsEnabledFor(logging.DEBUG) {
+		devopsLogger.Debug("Sending termination transaction (%s) to validator", tx.Uuid)
+	}
+	resp := d.coord.ExecuteTransaction(tx)
+	if resp.Status == pb.Response_FAILURE {
+		err = fmt.Errorf(string(resp.Msg))
+	}
+
+	return resp, err
+}
+
+// GetChaincodes lists the chaincodes deployed on the network as recorded in the chaincode registry,
+// or only the chaincode named by the supplied ID if set. ErrNotFound is returned if no chaincode
+// with that name was deployed
+func (d *Devops) GetChaincodes(ctx context.Context, chaincodeID *pb.ChaincodeID) (*pb.ChaincodeRegistry, error) {
+	ledger, err := ledger.GetLedger()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting ledger: %s", err)
+	}
+	if chaincodeID == nil || chaincodeID.Name == "" {
+		registry, err := chaincode.GetChaincodeRegistry(ledger)
+		if err != nil {
+			return nil, fmt.Errorf("Error retrieving chaincode registry: %s", err)
+		}
+		return registry, nil
+	}
+
+	entry, err := chaincode.GetChaincodeRegistryEntry(ledger, chaincodeID.Name)
+	if err != nil {
+		return nil, fmt.Errorf("Error retrieving chaincode %s from registry: %s", chaincodeID.Name, err)
+	}
+	if entry == nil {
+		return nil, ErrNotFound
+	}
+	return &pb.ChaincodeRegistry{Entries: []*pb.ChaincodeRegistryEntry{entry}}, nil
+}
+
 // CheckSpec to see if chaincode resides within current package capture for language.
 func CheckSpec(spec *pb.ChaincodeSpec) error {
 	// Don't allow nil value
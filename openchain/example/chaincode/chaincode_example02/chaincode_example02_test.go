@@ -0,0 +1,59 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/openblockchain/obc-peer/openchain/chaincode/shim/shimtest"
+)
+
+func checkQuery(t *testing.T, stub *shimtest.MockStub, name string, expected string) {
+	res, err := stub.MockQuery("query", []string{name})
+	if err != nil {
+		t.Fatalf("Failed querying %s: %s", name, err)
+	}
+	if string(res) != expected {
+		t.Fatalf("Expected %s to be %s, got %s", name, expected, string(res))
+	}
+}
+
+func TestExample02(t *testing.T) {
+	stub := shimtest.NewMockStub("ex02", new(SimpleChaincode))
+
+	if _, err := stub.MockRun("1", "init", []string{"a", "100", "b", "200"}); err != nil {
+		t.Fatalf("Failed init: %s", err)
+	}
+	checkQuery(t, stub, "a", "100")
+	checkQuery(t, stub, "b", "200")
+
+	if _, err := stub.MockRun("2", "invoke", []string{"a", "b", "10"}); err != nil {
+		t.Fatalf("Failed invoke: %s", err)
+	}
+	checkQuery(t, stub, "a", "90")
+	checkQuery(t, stub, "b", "210")
+
+	if _, err := stub.MockRun("3", "delete", []string{"a"}); err != nil {
+		t.Fatalf("Failed delete: %s", err)
+	}
+	if _, err := stub.MockQuery("query", []string{"a"}); err == nil {
+		t.Fatalf("Expected query of deleted entity to fail")
+	}
+}
@@ -22,11 +22,13 @@ package ledger
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"strconv"
 
 	"github.com/openblockchain/obc-peer/openchain/db"
 	"github.com/openblockchain/obc-peer/openchain/util"
 	"github.com/openblockchain/obc-peer/protos"
+	"github.com/spf13/viper"
 	"github.com/tecbot/gorocksdb"
 	"golang.org/x/net/context"
 )
@@ -38,12 +40,19 @@ type blockchain struct {
 	previousBlockHash  []byte
 	indexer            blockchainIndexer
 	lastProcessedBlock *lastProcessedBlock
+	// pruneKeepBlocks is the number of most recent blocks that keep their
+	// transactions. Zero means that pruning is disabled.
+	pruneKeepBlocks uint64
+	// prunedCount is the number of blocks, starting at block 0, whose
+	// transactions have been pruned.
+	prunedCount uint64
 }
 
 type lastProcessedBlock struct {
 	block       *protos.Block
 	blockNumber uint64
 	blockHash   []byte
+	prunedCount uint64
 }
 
 var indexBlockDataSynchronously = true
@@ -53,8 +62,18 @@ func newBlockchain() (*blockchain, error) {
 	if err != nil {
 		return nil, err
 	}
-	blockchain := &blockchain{0, nil, nil, nil}
-	blockchain.size = size
+	prunedCount, err := fetchPrunedBlockCountFromDB()
+	if err != nil {
+		return nil, err
+	}
+	blockchain := &blockchain{size: size, prunedCount: prunedCount}
+	if viper.GetBool("ledger.blockchain.pruning.enabled") {
+		keepBlocks := viper.GetInt("ledger.blockchain.pruning.keepBlocks")
+		if keepBlocks < 1 {
+			return nil, fmt.Errorf("Number of blocks to keep when pruning must be greater than 0. Current value is %d.", keepBlocks)
+		}
+		blockchain.pruneKeepBlocks = uint64(keepBlocks)
+	}
 	if size > 0 {
 		previousBlock, err := fetchBlockFromDB(size - 1)
 		if err != nil {
@@ -120,8 +139,27 @@ func (blockchain *blockchain) getTransactionByUUID(txUUID string) (*protos.Trans
 	if err != nil {
 		return nil, err
 	}
-	transaction := block.GetTransactions()[txIndex]
-	return transaction, nil
+	return getTransactionFromBlock(block, txIndex)
+}
+
+// getTransactionReceipt get the receipt of a transaction identified by uuid. The indexes are
+// consulted first, so this waits for the asynchronous indexer to catch up with the chain
+func (blockchain *blockchain) getTransactionReceipt(txUUID string) (*protos.TransactionReceipt, error) {
+	blockNumber, txIndex, err := blockchain.indexer.fetchTransactionIndexByUUID(txUUID)
+	if err != nil {
+		return nil, err
+	}
+	txError, err := fetchTransactionErrorByUUIDFromDB(txUUID)
+	if err != nil {
+		return nil, err
+	}
+	receipt := &protos.TransactionReceipt{Uuid: txUUID, Status: protos.TransactionReceipt_COMMITTED,
+		BlockNumber: blockNumber, TxIndex: txIndex}
+	if txError != "" {
+		receipt.Status = protos.TransactionReceipt_FAILED
+		receipt.Error = txError
+	}
+	return receipt, nil
 }
 
 // getTransactions get all transactions in a block identified by block number
@@ -148,7 +186,7 @@ func (blockchain *blockchain) getTransaction(blockNumber uint64, txIndex uint64)
 	if err != nil {
 		return nil, err
 	}
-	return block.GetTransactions()[txIndex], nil
+	return getTransactionFromBlock(block, txIndex)
 }
 
 // getTransactionByBlockHash get a transaction identified by blockhash and index within the block
@@ -157,7 +195,7 @@ func (blockchain *blockchain) getTransactionByBlockHash(blockHash []byte, txInde
 	if err != nil {
 		return nil, err
 	}
-	return block.GetTransactions()[txIndex], nil
+	return getTransactionFromBlock(block, txIndex)
 }
 
 func (blockchain *blockchain) getBlockchainInfo() (*protos.BlockchainInfo, error) {
@@ -206,7 +244,11 @@ func (blockchain *blockchain) addPersistenceChangesForNewBlock(ctx context.Conte
 	if blockchain.indexer.isSynchronous() {
 		blockchain.indexer.createIndexesSync(block, blockNumber, blockHash, writeBatch)
 	}
-	blockchain.lastProcessedBlock = &lastProcessedBlock{block, blockNumber, blockHash}
+	prunedCount, err := blockchain.addPersistenceChangesForPruning(blockNumber+1, writeBatch)
+	if err != nil {
+		return 0, err
+	}
+	blockchain.lastProcessedBlock = &lastProcessedBlock{block, blockNumber, blockHash, prunedCount}
 	return blockNumber, nil
 }
 
@@ -214,6 +256,7 @@ func (blockchain *blockchain) blockPersistenceStatus(success bool) {
 	if success {
 		blockchain.size++
 		blockchain.previousBlockHash = blockchain.lastProcessedBlock.blockHash
+		blockchain.prunedCount = blockchain.lastProcessedBlock.prunedCount
 		if !blockchain.indexer.isSynchronous() {
 			blockchain.indexer.createIndexesAsync(blockchain.lastProcessedBlock.block,
 				blockchain.lastProcessedBlock.blockNumber, blockchain.lastProcessedBlock.blockHash)
@@ -223,19 +266,31 @@ func (blockchain *blockchain) blockPersistenceStatus(success bool) {
 }
 
 func (blockchain *blockchain) persistRawBlock(block *protos.Block, blockNumber uint64) error {
+	size := blockchain.getSize()
+	// Need to check as we suport out of order blocks in cases such as block/state synchronization. This is
+	// really blockchain height, not size.
+	if size < blockNumber+1 {
+		size = blockNumber + 1
+	}
+
+	// Blocks that are already older than the pruning window, which happens when
+	// a new peer bootstraps from a state snapshot, are stored pruned right away
+	if blockNumber < blockchain.getPrunedBlockCount(size) {
+		prunedBlock, err := block.Prune()
+		if err != nil {
+			return err
+		}
+		block = prunedBlock
+	}
+
 	blockBytes, blockBytesErr := block.Bytes()
 	if blockBytesErr != nil {
 		return blockBytesErr
 	}
 	writeBatch := gorocksdb.NewWriteBatch()
 	writeBatch.PutCF(db.GetDBHandle().BlockchainCF, encodeBlockNumberDBKey(blockNumber), blockBytes)
-
-	// Need to check as we suport out of order blocks in cases such as block/state synchronization. This is
-	// really blockchain height, not size.
-	if blockchain.getSize() < blockNumber+1 {
-		sizeBytes := encodeUint64(blockNumber + 1)
-		writeBatch.PutCF(db.GetDBHandle().BlockchainCF, blockCountKey, sizeBytes)
-		blockchain.size = blockNumber + 1
+	if blockchain.getSize() < size {
+		writeBatch.PutCF(db.GetDBHandle().BlockchainCF, blockCountKey, encodeUint64(size))
 	}
 	blockHash, err := block.GetHash()
 	if err != nil {
@@ -246,11 +301,126 @@ func (blockchain *blockchain) persistRawBlock(block *protos.Block, blockNumber u
 		blockchain.indexer.createIndexesSync(block, blockNumber, blockHash, writeBatch)
 	}
 
+	prunedCount, err := blockchain.addPersistenceChangesForPruning(size, writeBatch)
+	if err != nil {
+		return err
+	}
+
 	opt := gorocksdb.NewDefaultWriteOptions()
 	err = db.GetDBHandle().DB.Write(opt, writeBatch)
 	if err != nil {
 		return err
 	}
+	blockchain.size = size
+	blockchain.prunedCount = prunedCount
+	return nil
+}
+
+// truncate removes the blocks from blockNumber on along with their indexes, so
+// that the blockchain has blockNumber blocks. It undoes persistRawBlock for
+// blocks that turn out to be invalid once they are on the chain.
+func (blockchain *blockchain) truncate(blockNumber uint64) error {
+	size := blockchain.getSize()
+	if blockNumber >= size {
+		return nil
+	}
+	writeBatch := gorocksdb.NewWriteBatch()
+	for i := blockNumber; i < size; i++ {
+		block, err := fetchBlockFromDB(i)
+		if err != nil {
+			return err
+		}
+		if block == nil {
+			continue
+		}
+		blockHash, err := block.GetHash()
+		if err != nil {
+			return err
+		}
+		removeIndexDataForPruning(block, i, writeBatch)
+		writeBatch.DeleteCF(db.GetDBHandle().IndexesCF, encodeBlockHashKey(blockHash))
+		writeBatch.DeleteCF(db.GetDBHandle().BlockchainCF, encodeBlockNumberDBKey(i))
+	}
+	writeBatch.PutCF(db.GetDBHandle().BlockchainCF, blockCountKey, encodeUint64(blockNumber))
+	prunedCount := blockchain.prunedCount
+	if prunedCount > blockNumber {
+		prunedCount = blockNumber
+		writeBatch.PutCF(db.GetDBHandle().BlockchainCF, prunedBlockCountKey, encodeUint64(prunedCount))
+	}
+	var previousBlockHash []byte
+	if blockNumber > 0 {
+		previousBlock, err := fetchBlockFromDB(blockNumber - 1)
+		if err != nil {
+			return err
+		}
+		if previousBlock != nil {
+			if previousBlockHash, err = previousBlock.GetHash(); err != nil {
+				return err
+			}
+		}
+	}
+
+	opt := gorocksdb.NewDefaultWriteOptions()
+	err := db.GetDBHandle().DB.Write(opt, writeBatch)
+	if err != nil {
+		return err
+	}
+	blockchain.size = blockNumber
+	blockchain.previousBlockHash = previousBlockHash
+	blockchain.prunedCount = prunedCount
+	return nil
+}
+
+// getPrunedBlockCount returns the number of blocks, starting at block 0, that
+// are pruned in a blockchain of the given size
+func (blockchain *blockchain) getPrunedBlockCount(size uint64) uint64 {
+	if blockchain.pruneKeepBlocks == 0 || size <= blockchain.pruneKeepBlocks {
+		return 0
+	}
+	return size - blockchain.pruneKeepBlocks
+}
+
+// addPersistenceChangesForPruning adds to the writeBatch the changes needed to
+// prune the transactions of all the blocks that have fallen out of the pruning
+// window of a blockchain of the given size. It returns the resulting count of
+// pruned blocks, which must only be applied once the writeBatch is committed.
+func (blockchain *blockchain) addPersistenceChangesForPruning(size uint64, writeBatch *gorocksdb.WriteBatch) (uint64, error) {
+	prunedCount := blockchain.getPrunedBlockCount(size)
+	if prunedCount <= blockchain.prunedCount {
+		return blockchain.prunedCount, nil
+	}
+	for blockNumber := blockchain.prunedCount; blockNumber < prunedCount; blockNumber++ {
+		err := pruneBlock(blockNumber, writeBatch)
+		if err != nil {
+			return 0, err
+		}
+	}
+	writeBatch.PutCF(db.GetDBHandle().BlockchainCF, prunedBlockCountKey, encodeUint64(prunedCount))
+	return prunedCount, nil
+}
+
+// pruneBlock replaces the block stored in the db by its pruned version and
+// removes the indexes of its transactions. Missing blocks, which are expected
+// during block/state synchronization, and already pruned blocks are skipped.
+func pruneBlock(blockNumber uint64, writeBatch *gorocksdb.WriteBatch) error {
+	block, err := fetchBlockFromDB(blockNumber)
+	if err != nil {
+		return err
+	}
+	if block == nil || block.IsPruned() {
+		return nil
+	}
+	ledgerLogger.Debug("Pruning transactions of block number [%d]", blockNumber)
+	removeIndexDataForPruning(block, blockNumber, writeBatch)
+	prunedBlock, err := block.Prune()
+	if err != nil {
+		return err
+	}
+	blockBytes, err := prunedBlock.Bytes()
+	if err != nil {
+		return err
+	}
+	writeBatch.PutCF(db.GetDBHandle().BlockchainCF, encodeBlockNumberDBKey(blockNumber), blockBytes)
 	return nil
 }
 
@@ -282,7 +452,11 @@ func (blockchain *blockchain) persistRawBlock(block *protos.Block, blockNumber u
 // }
 
 func fetchBlockFromDB(blockNumber uint64) (*protos.Block, error) {
-	blockBytes, err := db.GetDBHandle().GetFromBlockchainCF(encodeBlockNumberDBKey(blockNumber))
+	return fetchBlockFromOpenchainDB(db.GetDBHandle(), blockNumber)
+}
+
+func fetchBlockFromOpenchainDB(openchainDB *db.OpenchainDB, blockNumber uint64) (*protos.Block, error) {
+	blockBytes, err := openchainDB.GetFromBlockchainCF(encodeBlockNumberDBKey(blockNumber))
 	if err != nil {
 		return nil, err
 	}
@@ -297,11 +471,36 @@ func fetchTransactionFromDB(blockNum uint64, txIndex uint64) (*protos.Transactio
 	if err != nil {
 		return nil, err
 	}
-	return block.GetTransactions()[txIndex], nil
+	return getTransactionFromBlock(block, txIndex)
+}
+
+// getTransactionFromBlock returns ErrResourceNotFound if the block does not
+// hold the transaction, for instance because it has been pruned
+func getTransactionFromBlock(block *protos.Block, txIndex uint64) (*protos.Transaction, error) {
+	transactions := block.GetTransactions()
+	if txIndex >= uint64(len(transactions)) {
+		return nil, ErrResourceNotFound
+	}
+	return transactions[txIndex], nil
 }
 
 func fetchBlockchainSizeFromDB() (uint64, error) {
-	bytes, err := db.GetDBHandle().GetFromBlockchainCF(blockCountKey)
+	return fetchBlockchainSizeFromOpenchainDB(db.GetDBHandle())
+}
+
+func fetchBlockchainSizeFromOpenchainDB(openchainDB *db.OpenchainDB) (uint64, error) {
+	bytes, err := openchainDB.GetFromBlockchainCF(blockCountKey)
+	if err != nil {
+		return 0, err
+	}
+	if bytes == nil {
+		return 0, nil
+	}
+	return decodeToUint64(bytes), nil
+}
+
+func fetchPrunedBlockCountFromDB() (uint64, error) {
+	bytes, err := db.GetDBHandle().GetFromBlockchainCF(prunedBlockCountKey)
 	if err != nil {
 		return 0, err
 	}
@@ -324,6 +523,7 @@ func fetchBlockchainSizeFromSnapshot(snapshot *gorocksdb.Snapshot) (uint64, erro
 }
 
 var blockCountKey = []byte("blockCount")
+var prunedBlockCountKey = []byte("prunedBlockCount")
 
 func encodeBlockNumberDBKey(blockNumber uint64) []byte {
 	return encodeUint64(blockNumber)
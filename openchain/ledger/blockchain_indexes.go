@@ -33,6 +33,7 @@ var indexLogger = logging.MustGetLogger("indexes")
 var prefixBlockHashKey = byte(1)
 var prefixTxUUIDKey = byte(2)
 var prefixAddressBlockNumCompositeKey = byte(3)
+var prefixTxErrorKey = byte(4)
 
 type blockchainIndexer interface {
 	isSynchronous() bool
@@ -112,9 +113,36 @@ func addIndexDataForPersistence(block *protos.Block, blockNumber uint64, blockHa
 	for address, txsIndexes := range addressToTxIndexesMap {
 		writeBatch.PutCF(cf, encodeAddressBlockNumCompositeKey(address, blockNumber), encodeListTxIndexes(txsIndexes))
 	}
+
+	// add TxUUID -> error, for the failed transactions
+	for _, txResult := range block.GetNonHashData().GetTransactionResults() {
+		if txResult.Error != "" {
+			writeBatch.PutCF(cf, encodeTxErrorKey(txResult.Uuid), []byte(txResult.Error))
+		}
+	}
 	return nil
 }
 
+// removeIndexDataForPruning removes the indexes of the transactions of a block
+// that is being pruned. The blockhash -> blockNumber index is kept, as the
+// header of a pruned block stays in the blockchain.
+func removeIndexDataForPruning(block *protos.Block, blockNumber uint64, writeBatch *gorocksdb.WriteBatch) {
+	cf := db.GetDBHandle().IndexesCF
+	addresses := make(map[string]bool)
+	for _, tx := range block.GetTransactions() {
+		writeBatch.DeleteCF(cf, encodeTxUUIDKey(tx.Uuid))
+		addresses[getTxExecutingAddress(tx)] = true
+	}
+	for _, txResult := range block.GetNonHashData().GetTransactionResults() {
+		if txResult.Error != "" {
+			writeBatch.DeleteCF(cf, encodeTxErrorKey(txResult.Uuid))
+		}
+	}
+	for address := range addresses {
+		writeBatch.DeleteCF(cf, encodeAddressBlockNumCompositeKey(address, blockNumber))
+	}
+}
+
 func fetchBlockNumberByBlockHashFromDB(blockHash []byte) (uint64, error) {
 	blockNumberBytes, err := db.GetDBHandle().GetFromIndexesCF(encodeBlockHashKey(blockHash))
 	if err != nil {
@@ -135,6 +163,14 @@ func fetchTransactionIndexByUUIDFromDB(txUUID string) (uint64, uint64, error) {
 	return decodeBlockNumTxIndex(blockNumTxIndexBytes)
 }
 
+func fetchTransactionErrorByUUIDFromDB(txUUID string) (string, error) {
+	txErrorBytes, err := db.GetDBHandle().GetFromIndexesCF(encodeTxErrorKey(txUUID))
+	if err != nil {
+		return "", err
+	}
+	return string(txErrorBytes), nil
+}
+
 func getTxExecutingAddress(tx *protos.Transaction) string {
 	// TODO Fetch address form tx
 	return "address1"
@@ -187,6 +223,11 @@ func encodeTxUUIDKey(txUUID string) []byte {
 	return prependKeyPrefix(prefixTxUUIDKey, []byte(txUUID))
 }
 
+// encode TxErrorKey
+func encodeTxErrorKey(txUUID string) []byte {
+	return prependKeyPrefix(prefixTxErrorKey, []byte(txUUID))
+}
+
 func encodeAddressBlockNumCompositeKey(address string, blockNumber uint64) []byte {
 	b := proto.NewBuffer([]byte{prefixAddressBlockNumCompositeKey})
 	b.EncodeRawBytes([]byte(address))
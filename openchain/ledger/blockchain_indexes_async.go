@@ -112,7 +112,9 @@ func (indexer *blockchainIndexerAsync) createIndexesAsync(block *protos.Block, b
 	return nil
 }
 
-// createIndexes adds entries into db for creating indexes on various atributes
+// createIndexes adds entries into db for creating indexes on various atributes. The entries are
+// the same as those of the synchronous indexer, the failed transactions included, so transaction
+// receipts need nothing more than fetchTransactionIndexByUUID waiting for the block to be indexed.
 func (indexer *blockchainIndexerAsync) createIndexesInternal(block *protos.Block, blockNumber uint64, blockHash []byte) error {
 	openchainDB := db.GetDBHandle()
 	writeBatch := gorocksdb.NewWriteBatch()
@@ -22,6 +22,7 @@ package ledger
 import (
 	"errors"
 	"github.com/openblockchain/obc-peer/openchain/ledger/testutil"
+	"github.com/openblockchain/obc-peer/protos"
 	"testing"
 	"time"
 )
@@ -68,3 +69,26 @@ func TestIndexesAsync_ClientWaitScenario(t *testing.T) {
 	block := testBlockchainWrapper.getBlockByHash(blockHash)
 	testutil.AssertEquals(t, block, blocks[0])
 }
+
+func TestIndexesAsync_TransactionReceipt(t *testing.T) {
+	testDBWrapper.CreateFreshDB(t)
+	testBlockchainWrapper := newTestBlockchainWrapper(t)
+	chain := testBlockchainWrapper.blockchain
+	if chain.indexer.isSynchronous() {
+		t.Skip("Skipping because blockchain is configured to index block data synchronously")
+	}
+	tx1, uuid1 := buildTestTx()
+	tx2, uuid2 := buildTestTx()
+	block := protos.NewBlock([]*protos.Transaction{tx1, tx2})
+	block.NonHashData = &protos.NonHashData{TransactionResults: []*protos.TransactionResult{&protos.TransactionResult{Uuid: uuid2, Error: "Error in chaincode"}}}
+	testBlockchainWrapper.addNewBlock(block, []byte("stateHash"))
+
+	// the receipts wait for the indexer to index the block
+	receipt, err := chain.getTransactionReceipt(uuid1)
+	testutil.AssertNoError(t, err, "Error while getting receipt of committed transaction")
+	testutil.AssertEquals(t, receipt.Status, protos.TransactionReceipt_COMMITTED)
+	receipt, err = chain.getTransactionReceipt(uuid2)
+	testutil.AssertNoError(t, err, "Error while getting receipt of failed transaction")
+	testutil.AssertEquals(t, receipt, &protos.TransactionReceipt{Uuid: uuid2, Status: protos.TransactionReceipt_FAILED,
+		BlockNumber: 0, TxIndex: 1, Error: "Error in chaincode"})
+}
@@ -111,3 +111,76 @@ func TestBlockchainBlockLedgerCommitTimestamp(t *testing.T) {
 		t.Fatal("Expected block time to be after start time")
 	}
 }
+
+func TestBlockChainPruning(t *testing.T) {
+	testDBWrapper.CreateFreshDB(t)
+	blockchainTestWrapper := newTestBlockchainWrapper(t)
+	blockchain := blockchainTestWrapper.blockchain
+	blockchain.pruneKeepBlocks = 2
+
+	var blockHashes [][]byte
+	var txUUIDs []string
+	for i := 0; i < 5; i++ {
+		tx, uuid := buildTestTx()
+		block := protos.NewBlock([]*protos.Transaction{tx})
+		blockchainTestWrapper.addNewBlock(block, []byte("stateHash"))
+		blockHash, _ := block.GetHash()
+		blockHashes = append(blockHashes, blockHash)
+		txUUIDs = append(txUUIDs, uuid)
+	}
+	testutil.AssertEquals(t, blockchain.getSize(), uint64(5))
+	testutil.AssertEquals(t, blockchain.prunedCount, uint64(3))
+
+	for i := uint64(0); i < 5; i++ {
+		block := blockchainTestWrapper.getBlock(i)
+		blockHash, _ := block.GetHash()
+		testutil.AssertEquals(t, blockHash, blockHashes[i])
+		testutil.AssertEquals(t, blockchainTestWrapper.getBlockByHash(blockHashes[i]).GetStateHash(), []byte("stateHash"))
+		if i > 0 {
+			testutil.AssertEquals(t, block.PreviousBlockHash, blockHashes[i-1])
+		}
+		if i < 3 {
+			testutil.AssertEquals(t, block.IsPruned(), true)
+			testutil.AssertEquals(t, len(block.GetTransactions()), 0)
+			_, err := blockchain.getTransactionByUUID(txUUIDs[i])
+			testutil.AssertEquals(t, err, ErrResourceNotFound)
+			_, err = blockchain.getTransaction(i, 0)
+			testutil.AssertEquals(t, err, ErrResourceNotFound)
+		} else {
+			testutil.AssertEquals(t, block.IsPruned(), false)
+			testutil.AssertEquals(t, blockchainTestWrapper.getTransactionByUUID(txUUIDs[i]).Uuid, txUUIDs[i])
+		}
+	}
+
+	// The count of pruned blocks survives a restart
+	blockchain, err := newBlockchain()
+	testutil.AssertNoError(t, err, "Error while getting handle to chain")
+	testutil.AssertEquals(t, blockchain.prunedCount, uint64(3))
+	testutil.AssertEquals(t, blockchain.previousBlockHash, blockHashes[4])
+}
+
+func TestBlockChainPruningRawBlocks(t *testing.T) {
+	testDBWrapper.CreateFreshDB(t)
+	blockchainTestWrapper := newTestBlockchainWrapper(t)
+	blockchain := blockchainTestWrapper.blockchain
+	blockchain.pruneKeepBlocks = 2
+
+	// Blocks are received out of order, as during block/state synchronization
+	var blocks []*protos.Block
+	for i := 0; i < 4; i++ {
+		blocks = append(blocks, buildTestBlock())
+	}
+	for _, i := range []uint64{3, 0, 2, 1} {
+		err := blockchain.persistRawBlock(blocks[i], i)
+		testutil.AssertNoError(t, err, "Error while putting raw block")
+	}
+	testutil.AssertEquals(t, blockchain.getSize(), uint64(4))
+	testutil.AssertEquals(t, blockchain.prunedCount, uint64(2))
+	for i := uint64(0); i < 4; i++ {
+		block := blockchainTestWrapper.getBlock(i)
+		testutil.AssertEquals(t, block.IsPruned(), i < 2)
+		blockHash, _ := block.GetHash()
+		expectedBlockHash, _ := blocks[i].GetHash()
+		testutil.AssertEquals(t, blockHash, expectedBlockHash)
+	}
+}
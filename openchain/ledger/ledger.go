@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/op/go-logging"
 	"github.com/openblockchain/obc-peer/events/producer"
@@ -47,11 +48,28 @@ var (
 	ErrResourceNotFound = errors.New("ledger: resource not found")
 )
 
+// pendingTxExpiry is how long a transaction submitted through this peer is reported
+// as pending. Transactions dropped or rejected before reaching a block are never
+// committed, their receipts are forgotten once they expire.
+var pendingTxExpiry = 10 * time.Minute
+
+type pendingTx struct {
+	uuid        string
+	submittedAt time.Time
+}
+
 // Ledger - the struct for openchain ledger
 type Ledger struct {
 	blockchain *blockchain
 	state      *state.State
 	currentID  interface{}
+	txResults  []*protos.TransactionResult
+
+	// transactions submitted through this peer that are not committed yet, by
+	// submission time. The queue keeps them in submission order for expiry.
+	pendingTxs      map[string]time.Time
+	pendingTxsQueue []pendingTx
+	pendingTxsLock  sync.Mutex
 }
 
 var ledger *Ledger
@@ -73,7 +91,7 @@ func newLedger() (*Ledger, error) {
 	}
 
 	state := state.NewState()
-	return &Ledger{blockchain, state, nil}, nil
+	return &Ledger{blockchain: blockchain, state: state, pendingTxs: make(map[string]time.Time)}, nil
 }
 
 /////////////////// Transaction-batch related methods ///////////////////////////////
@@ -128,6 +146,9 @@ func (ledger *Ledger) CommitTxBatch(id interface{}, transactions []*protos.Trans
 
 	writeBatch := gorocksdb.NewWriteBatch()
 	block := protos.NewBlock(transactions)
+	if len(ledger.txResults) > 0 {
+		block.NonHashData = &protos.NonHashData{TransactionResults: ledger.txResults}
+	}
 	newBlockNumber, err := ledger.blockchain.addPersistenceChangesForNewBlock(context.TODO(), block, stateHash, writeBatch)
 	if err != nil {
 		success = false
@@ -140,7 +161,13 @@ func (ledger *Ledger) CommitTxBatch(id interface{}, transactions []*protos.Trans
 		success = false
 		return dbErr
 	}
+	ledger.removePendingTransactions(transactions)
 	producer.Send(producer.CreateBlockEvent(block))
+	for _, txResult := range ledger.txResults {
+		if txResult.ChaincodeEvent != nil {
+			producer.Send(producer.CreateChaincodeEvent(txResult.ChaincodeEvent))
+		}
+	}
 	return nil
 }
 
@@ -167,6 +194,19 @@ func (ledger *Ledger) TxFinished(txUUID string, txSuccessful bool) {
 	ledger.state.TxFinish(txUUID, txSuccessful)
 }
 
+// SetChaincodeEvent - Records the event set by the chaincode for a successful transaction
+// of the current transaction-batch. The event is stored with the results of the block and
+// sent to the event hub when the transaction-batch is committed
+func (ledger *Ledger) SetChaincodeEvent(event *protos.ChaincodeEvent) {
+	ledger.txResults = append(ledger.txResults, &protos.TransactionResult{Uuid: event.TxUuid, ChaincodeEvent: event})
+}
+
+// SetTxError - Records why a transaction of the current transaction-batch failed. The error
+// is stored with the results of the block and is reported by the receipt of the transaction
+func (ledger *Ledger) SetTxError(txUUID string, txErr error) {
+	ledger.txResults = append(ledger.txResults, &protos.TransactionResult{Uuid: txUUID, Error: txErr.Error()})
+}
+
 /////////////////// world-state related methods /////////////////////////////////////
 /////////////////////////////////////////////////////////////////////////////////////
 
@@ -190,6 +230,87 @@ func (ledger *Ledger) GetState(chaincodeID string, key string, committed bool) (
 	return ledger.state.Get(chaincodeID, key, committed)
 }
 
+// GetStateWithProof returns the committed value of the key for the chaincodeID along with a proof
+// that the key-value belongs to the state recorded in the StateHash of the last block of the chain.
+// The proof can be checked without a peer with statemgmt.VerifyStateProof. A nil value and a nil
+// proof are returned if the key does not exist.
+func (ledger *Ledger) GetStateWithProof(chaincodeID string, key string) ([]byte, *statemgmt.StateProof, error) {
+	return ledger.state.GetWithProof(chaincodeID, key)
+}
+
+// GetStateValueWithProof returns the committed value of the key for the chaincodeID along with
+// its proof, the number of the last block and the StateHash of that block, in the wire format
+// served by the API. The proof is checked against the block before it is returned, so a block
+// committed in between makes it start over. ErrResourceNotFound is returned if the key does not exist.
+func (ledger *Ledger) GetStateValueWithProof(chaincodeID string, key string) (*protos.StateValueWithProof, error) {
+	const maxAttempts = 3
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		value, proof, err := ledger.GetStateWithProof(chaincodeID, key)
+		if err != nil {
+			return nil, err
+		}
+		size := ledger.GetBlockchainSize()
+		if value == nil || size == 0 {
+			return nil, ErrResourceNotFound
+		}
+		block, err := ledger.GetBlockByNumber(size - 1)
+		if err != nil {
+			return nil, err
+		}
+		if err := statemgmt.VerifyStateProof(block.StateHash, chaincodeID, key, value, proof); err != nil {
+			ledgerLogger.Debug("Proof of key [%s] of chaincode [%s] does not match block [%d], retrying: %s", key, chaincodeID, size-1, err)
+			continue
+		}
+		return &protos.StateValueWithProof{
+			Value:       value,
+			BlockNumber: size - 1,
+			StateHash:   block.StateHash,
+			Nodes:       proof.ToProtoNodes(),
+		}, nil
+	}
+	return nil, fmt.Errorf("State changed while proving key [%s] of chaincode [%s]", key, chaincodeID)
+}
+
+// GetStateAsOf returns the value of the key for the chaincodeID as it was right after the block
+// blockNumber was committed. The value is derived from the committed state by walking backwards through
+// the state deltas retained in the db (see 'ledger.state.deltaHistorySize'). A nil value is returned if
+// the key did not exist at that block. ErrOutOfBounds is returned if the block is beyond the blockchain
+// and ErrResourceNotFound is returned if a state delta needed to reach back to the block is no longer retained.
+func (ledger *Ledger) GetStateAsOf(chaincodeID string, key string, blockNumber uint64) ([]byte, error) {
+	size := ledger.GetBlockchainSize()
+	if blockNumber >= size {
+		return nil, ErrOutOfBounds
+	}
+	value, err := ledger.state.Get(chaincodeID, key, true)
+	if err != nil {
+		return nil, err
+	}
+	for deltaBlockNumber := size - 1; deltaBlockNumber > blockNumber; deltaBlockNumber-- {
+		stateDelta, err := ledger.state.FetchStateDeltaFromDB(deltaBlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if stateDelta == nil {
+			ledgerLogger.Debug("State delta for block [%d] is not available", deltaBlockNumber)
+			return nil, ErrResourceNotFound
+		}
+		updatedValue := stateDelta.Get(chaincodeID, key)
+		if updatedValue != nil {
+			value = updatedValue.GetPreviousValue()
+		}
+	}
+	return value, nil
+}
+
+// GetStateRangeScanIterator returns an iterator to get all the keys (and values) of the chaincodeID
+// between startKey and endKey (both inclusive), in lexical order of the keys. An empty startKey (or endKey)
+// leaves that end of the range open. If committed is false, this also includes the changes made in memory
+// by the ongoing transaction-batch. If committed is true, only the state in the db is considered.
+// The caller must call Close on the iterator once done.
+func (ledger *Ledger) GetStateRangeScanIterator(chaincodeID string, startKey string, endKey string, committed bool) (statemgmt.RangeScanIterator, error) {
+	return ledger.state.GetRangeScanIterator(chaincodeID, startKey, endKey, committed)
+}
+
 // SetState sets state to given value for chaincodeID and key. Does not immideatly writes to DB
 func (ledger *Ledger) SetState(chaincodeID string, key string, value []byte) error {
 	return ledger.state.Set(chaincodeID, key, value)
@@ -250,6 +371,30 @@ func (ledger *Ledger) ApplyStateDelta(id interface{}, delta *statemgmt.StateDelt
 	return nil
 }
 
+// ApplyStateSnapshot applies a snapshot of the state of another peer, given
+// as a state delta setting each of its key-values, in place of the current
+// state. Keys of the current state which are missing from the snapshot are
+// deleted. Like ledger.ApplyStateDelta this is an in memory change only, the
+// current state is only replaced once ledger.CommitStateDelta is called and
+// is left untouched by ledger.RollbackStateDelta.
+func (ledger *Ledger) ApplyStateSnapshot(id interface{}, snapshot *statemgmt.StateDelta) error {
+	stateSnapshot, err := ledger.GetStateSnapshot()
+	if err != nil {
+		return err
+	}
+	defer stateSnapshot.Release()
+	delta := statemgmt.NewStateDelta()
+	delta.ApplyChanges(snapshot)
+	for stateSnapshot.Next() {
+		compositeKey, value := stateSnapshot.GetRawKeyValue()
+		chaincodeID, key := statemgmt.DecodeCompositeKey(compositeKey)
+		if !snapshot.IsUpdatedValueSet(chaincodeID, key) {
+			delta.Delete(chaincodeID, key, value)
+		}
+	}
+	return ledger.ApplyStateDelta(id, delta)
+}
+
 // CommitStateDelta will commit the state delta passed to ledger.ApplyStateDelta
 // to the DB
 func (ledger *Ledger) CommitStateDelta(id interface{}) error {
@@ -302,11 +447,71 @@ func (ledger *Ledger) GetBlockchainSize() uint64 {
 	return ledger.blockchain.getSize()
 }
 
+// GetPrunedBlockCount returns the number of blocks, starting at block 0, that
+// only keep their headers once the blockchain reaches the given size. A new
+// peer bootstrapping from a state snapshot only needs the headers of these
+// blocks.
+func (ledger *Ledger) GetPrunedBlockCount(blockchainSize uint64) uint64 {
+	return ledger.blockchain.getPrunedBlockCount(blockchainSize)
+}
+
 // GetTransactionByUUID return transaction by it's uuid
 func (ledger *Ledger) GetTransactionByUUID(txUUID string) (*protos.Transaction, error) {
 	return ledger.blockchain.getTransactionByUUID(txUUID)
 }
 
+// MarkTransactionPending records that a transaction has been submitted through this peer.
+// Its receipt reports it as pending until a block containing it is put on the chain, or
+// until the transaction expires
+func (ledger *Ledger) MarkTransactionPending(txUUID string) {
+	ledger.pendingTxsLock.Lock()
+	defer ledger.pendingTxsLock.Unlock()
+	now := time.Now()
+	ledger.expirePendingTransactions(now)
+	ledger.pendingTxs[txUUID] = now
+	ledger.pendingTxsQueue = append(ledger.pendingTxsQueue, pendingTx{txUUID, now})
+}
+
+// GetTransactionReceipt returns the receipt of a transaction. A transaction on the chain is
+// reported as committed or failed, a transaction submitted through this peer that is not on the
+// chain yet is reported as pending. ErrResourceNotFound is returned for any other transaction
+func (ledger *Ledger) GetTransactionReceipt(txUUID string) (*protos.TransactionReceipt, error) {
+	receipt, err := ledger.blockchain.getTransactionReceipt(txUUID)
+	if err != ErrResourceNotFound {
+		return receipt, err
+	}
+	ledger.pendingTxsLock.Lock()
+	defer ledger.pendingTxsLock.Unlock()
+	ledger.expirePendingTransactions(time.Now())
+	if _, ok := ledger.pendingTxs[txUUID]; ok {
+		return &protos.TransactionReceipt{Uuid: txUUID, Status: protos.TransactionReceipt_PENDING}, nil
+	}
+	return nil, ErrResourceNotFound
+}
+
+func (ledger *Ledger) removePendingTransactions(transactions []*protos.Transaction) {
+	ledger.pendingTxsLock.Lock()
+	defer ledger.pendingTxsLock.Unlock()
+	for _, tx := range transactions {
+		delete(ledger.pendingTxs, tx.Uuid)
+	}
+	ledger.expirePendingTransactions(time.Now())
+}
+
+// expirePendingTransactions forgets the transactions submitted before now - pendingTxExpiry.
+// It must be called with pendingTxsLock held.
+func (ledger *Ledger) expirePendingTransactions(now time.Time) {
+	expiry := now.Add(-pendingTxExpiry)
+	for len(ledger.pendingTxsQueue) > 0 && !ledger.pendingTxsQueue[0].submittedAt.After(expiry) {
+		tx := ledger.pendingTxsQueue[0]
+		// the transaction may have been committed, or submitted again since
+		if submittedAt, ok := ledger.pendingTxs[tx.uuid]; ok && submittedAt.Equal(tx.submittedAt) {
+			delete(ledger.pendingTxs, tx.uuid)
+		}
+		ledger.pendingTxsQueue = ledger.pendingTxsQueue[1:]
+	}
+}
+
 // PutRawBlock puts a raw block on the chain. This function should only be
 // used for synchronization between peers.
 func (ledger *Ledger) PutRawBlock(block *protos.Block, blockNumber uint64) error {
@@ -314,10 +519,18 @@ func (ledger *Ledger) PutRawBlock(block *protos.Block, blockNumber uint64) error
 	if err != nil {
 		return err
 	}
+	ledger.removePendingTransactions(block.GetTransactions())
 	producer.Send(producer.CreateBlockEvent(block))
 	return nil
 }
 
+// TruncateBlockchain removes the blocks from blockNumber on, so that the blockchain
+// has blockNumber blocks. This function should only be used to undo PutRawBlock for
+// blocks received during synchronization that fail verification.
+func (ledger *Ledger) TruncateBlockchain(blockNumber uint64) error {
+	return ledger.blockchain.truncate(blockNumber)
+}
+
 // VerifyChain will verify the integrety of the blockchain. This is accomplished
 // by ensuring that the previous block hash stored in each block matches
 // the actual hash of the previous block in the chain. The return value is the
@@ -325,6 +538,8 @@ func (ledger *Ledger) PutRawBlock(block *protos.Block, blockNumber uint64) error
 // For example, if VerifyChain(0, 99) is called and prevous hash values stored
 // in blocks 8, 32, and 42 do not match the actual hashes of respective previous
 // block 42 would be the return value from this function.
+// Pruned blocks are verified against the next block that is not pruned, so
+// the return value may be above highBlock if highBlock is pruned.
 // highBlock is the high block in the chain to include in verofication. If you
 // wish to verify the entire chain, use ledger.GetBlockchainSize() - 1.
 // lowBlock is the low block in the chain to include in verification. If
@@ -337,6 +552,27 @@ func (ledger *Ledger) VerifyChain(highBlock, lowBlock uint64) (uint64, error) {
 		return lowBlock, ErrOutOfBounds
 	}
 
+	// The hash recorded in a pruned block can not be computed again from its
+	// header, it is only verified by the previous block hash of the next block.
+	// Verification starts at the first block from highBlock on that is not
+	// pruned, so that the hashes of the pruned blocks are anchored on it.
+	for {
+		block, err := ledger.GetBlockByNumber(highBlock)
+		if err != nil {
+			return highBlock, fmt.Errorf("Error fetching block %d.", highBlock)
+		}
+		if block == nil {
+			return highBlock, fmt.Errorf("Block %d is nil.", highBlock)
+		}
+		if !block.IsPruned() {
+			break
+		}
+		if highBlock+1 >= ledger.GetBlockchainSize() {
+			return highBlock, fmt.Errorf("Block %d is pruned and there is no block after it to verify its hash.", highBlock)
+		}
+		highBlock++
+	}
+
 	for i := highBlock; i > lowBlock; i-- {
 		currentBlock, err := ledger.GetBlockByNumber(i)
 		if err != nil {
@@ -382,5 +618,6 @@ func (ledger *Ledger) checkValidIDCommitORRollback(id interface{}) error {
 func (ledger *Ledger) resetForNextTxGroup(txCommited bool) {
 	ledgerLogger.Debug("resetting ledger state for next transaction batch")
 	ledger.currentID = nil
+	ledger.txResults = nil
 	ledger.state.ClearInMemoryChanges(txCommited)
 }
@@ -0,0 +1,403 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+	"github.com/openblockchain/obc-peer/protos"
+)
+
+// An archive starts with archiveMagic, the archive version and the number of blocks. It is
+// followed by records, each made of a record type and a length-prefixed payload: the blocks
+// in order, the state deltas that are kept, the state snapshot in chunks and finally the end
+// record, whose payload is the SHA-256 checksum of every byte of the archive before it
+const archiveMagic = "OBCLEDGERARCHIVE"
+const archiveVersion = uint64(1)
+
+const (
+	archiveRecordBlock      = uint64(1) // block number, block bytes
+	archiveRecordStateDelta = uint64(2) // block number, state delta bytes
+	archiveRecordStateChunk = uint64(3) // state delta bytes holding key-values of the snapshot
+	archiveRecordEnd        = uint64(4) // checksum
+)
+
+// number of key-values of the state snapshot per archive record
+const archiveStateChunkSize = 1000
+
+// id of the state deltas applied while importing the state of an archive
+const archiveImportID = "importArchive"
+
+// guards against allocating the length read from a corrupted archive
+const archiveMaxRecordLength = 1 << 30
+
+// ExportArchive writes the blocks, the state deltas that are kept and a snapshot of the state
+// of the ledger to w, in a versioned and checksummed format that ImportArchive reads
+func (ledger *Ledger) ExportArchive(w io.Writer) error {
+	snapshot, err := ledger.GetStateSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Release()
+	size := snapshot.GetBlockNumber()
+
+	archiveWriter := newArchiveWriter(w)
+	archiveWriter.writeHeader(size)
+	for blockNumber := uint64(0); blockNumber < size; blockNumber++ {
+		block, err := fetchBlockFromDB(blockNumber)
+		if err != nil {
+			return err
+		}
+		if block == nil {
+			return fmt.Errorf("Block %d is nil.", blockNumber)
+		}
+		blockBytes, err := block.Bytes()
+		if err != nil {
+			return err
+		}
+		archiveWriter.writeRecord(archiveRecordBlock, blockNumber, blockBytes)
+	}
+	for blockNumber := uint64(0); blockNumber < size; blockNumber++ {
+		stateDelta, err := ledger.state.FetchStateDeltaFromDB(blockNumber)
+		if err != nil {
+			return err
+		}
+		if stateDelta != nil {
+			archiveWriter.writeRecord(archiveRecordStateDelta, blockNumber, stateDelta.Marshal())
+		}
+	}
+	chunk := statemgmt.NewStateDelta()
+	keys := 0
+	for snapshot.Next() {
+		compositeKey, value := snapshot.GetRawKeyValue()
+		chaincodeID, key := statemgmt.DecodeCompositeKey(compositeKey)
+		chunk.Set(chaincodeID, key, value, nil)
+		keys++
+		if keys == archiveStateChunkSize {
+			archiveWriter.writeRecord(archiveRecordStateChunk, 0, chunk.Marshal())
+			chunk = statemgmt.NewStateDelta()
+			keys = 0
+		}
+	}
+	if keys > 0 {
+		archiveWriter.writeRecord(archiveRecordStateChunk, 0, chunk.Marshal())
+	}
+	return archiveWriter.writeEnd()
+}
+
+// VerifyArchive checks that r holds a complete archive of a supported version whose
+// checksum matches. The links between its blocks are verified by ImportArchive
+func VerifyArchive(r io.Reader) error {
+	_, err := readArchive(r, nil)
+	return err
+}
+
+// ImportArchive puts the blocks, the state deltas and the state of an archive written by
+// ExportArchive into the ledger, which must be empty. The archive is verified first. The
+// state is imported next and its hash is checked against the last block, the state is
+// deleted again if it does not match. The blocks are put next and the chain is checked
+// with VerifyChain, the blocks and the state are deleted again if it is broken. The state
+// deltas are only put into the ledger once the chain is verified
+func (ledger *Ledger) ImportArchive(r io.ReadSeeker) error {
+	if ledger.GetBlockchainSize() != 0 {
+		return fmt.Errorf("The ledger must be empty to import an archive, it has %d blocks", ledger.GetBlockchainSize())
+	}
+	lastBlock, err := readArchive(r, nil)
+	if err != nil {
+		return err
+	}
+	if lastBlock == nil {
+		return nil
+	}
+
+	if _, err := r.Seek(0, 0); err != nil {
+		return err
+	}
+	err = ledger.importArchiveState(r, lastBlock)
+	if err == nil {
+		err = ledger.importArchiveBlocks(r)
+	}
+	if err != nil {
+		if truncateErr := ledger.TruncateBlockchain(0); truncateErr != nil {
+			ledgerLogger.Error("Error deleting the blocks of a rejected archive: %s", truncateErr)
+		}
+		if deleteErr := ledger.DeleteALLStateKeysAndValues(); deleteErr != nil {
+			ledgerLogger.Error("Error deleting the state of a rejected archive: %s", deleteErr)
+		}
+		return err
+	}
+
+	if _, err := r.Seek(0, 0); err != nil {
+		return err
+	}
+	_, err = readArchive(r, func(recordType uint64, blockNumber uint64, size uint64, payload []byte) error {
+		if recordType != archiveRecordStateDelta {
+			return nil
+		}
+		stateDelta := statemgmt.NewStateDelta()
+		stateDelta.Unmarshal(payload)
+		return ledger.state.PutStateDelta(blockNumber, size-1, stateDelta)
+	})
+	return err
+}
+
+// importArchiveBlocks puts the blocks of the archive into the ledger and verifies the
+// resulting chain
+func (ledger *Ledger) importArchiveBlocks(r io.ReadSeeker) error {
+	if _, err := r.Seek(0, 0); err != nil {
+		return err
+	}
+	_, err := readArchive(r, func(recordType uint64, blockNumber uint64, size uint64, payload []byte) error {
+		if recordType != archiveRecordBlock {
+			return nil
+		}
+		block, err := protos.UnmarshallBlock(payload)
+		if err != nil {
+			return err
+		}
+		return ledger.PutRawBlock(block, blockNumber)
+	})
+	if err != nil {
+		return err
+	}
+	size := ledger.GetBlockchainSize()
+	if size < 2 {
+		return nil
+	}
+	badBlock, err := ledger.VerifyChain(size-1, 0)
+	if err != nil {
+		return err
+	}
+	if badBlock != 0 {
+		return fmt.Errorf("Invalid archive: the previous block hash of block %d does not match", badBlock)
+	}
+	return nil
+}
+
+// importArchiveState puts the state snapshot of the archive into the ledger and checks
+// its hash against the state hash of the last block of the archive
+func (ledger *Ledger) importArchiveState(r io.Reader, lastBlock *protos.Block) error {
+	_, err := readArchive(r, func(recordType uint64, blockNumber uint64, size uint64, payload []byte) error {
+		if recordType != archiveRecordStateChunk {
+			return nil
+		}
+		chunk := statemgmt.NewStateDelta()
+		chunk.Unmarshal(payload)
+		if err := ledger.ApplyStateDelta(archiveImportID, chunk); err != nil {
+			return err
+		}
+		return ledger.CommitStateDelta(archiveImportID)
+	})
+	if err != nil {
+		return err
+	}
+	stateHash, err := ledger.GetTempStateHash()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(stateHash, lastBlock.StateHash) {
+		return fmt.Errorf("The state hash of the archive %x does not match the state hash of its last block %x",
+			stateHash, lastBlock.StateHash)
+	}
+	return nil
+}
+
+// archiveVisitor is called by readArchive for every block, state delta and state chunk
+// record, size is the number of blocks in the archive
+type archiveVisitor func(recordType uint64, blockNumber uint64, size uint64, payload []byte) error
+
+// readArchive reads and verifies the archive, calling visit for its records if visit is
+// not nil. It returns the last block of the archive, nil if the archive has no blocks
+func readArchive(r io.Reader, visit archiveVisitor) (*protos.Block, error) {
+	archiveReader := newArchiveReader(r)
+	size, err := archiveReader.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	nextBlockNumber := uint64(0)
+	var lastBlock *protos.Block
+	for {
+		recordType, blockNumber, payload, err := archiveReader.readRecord()
+		if err != nil {
+			return nil, err
+		}
+		switch recordType {
+		case archiveRecordBlock:
+			if blockNumber != nextBlockNumber || blockNumber >= size {
+				return nil, fmt.Errorf("Invalid archive: unexpected block %d", blockNumber)
+			}
+			nextBlockNumber++
+			block, err := protos.UnmarshallBlock(payload)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid archive: block %d: %s", blockNumber, err)
+			}
+			lastBlock = block
+		case archiveRecordStateDelta:
+			if blockNumber >= size {
+				return nil, fmt.Errorf("Invalid archive: unexpected state delta of block %d", blockNumber)
+			}
+		case archiveRecordStateChunk:
+		case archiveRecordEnd:
+			if nextBlockNumber != size {
+				return nil, fmt.Errorf("Invalid archive: %d blocks out of %d", nextBlockNumber, size)
+			}
+			if !bytes.Equal(payload, archiveReader.checksum) {
+				return nil, fmt.Errorf("Invalid archive: checksum mismatch")
+			}
+			return lastBlock, nil
+		default:
+			return nil, fmt.Errorf("Invalid archive: unknown record type %d", recordType)
+		}
+		if visit != nil {
+			if err := visit(recordType, blockNumber, size, payload); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+type archiveWriter struct {
+	w    *bufio.Writer
+	hash hash.Hash
+	err  error
+}
+
+func newArchiveWriter(w io.Writer) *archiveWriter {
+	return &archiveWriter{w: bufio.NewWriter(w), hash: sha256.New()}
+}
+
+func (archiveWriter *archiveWriter) write(b []byte) {
+	if archiveWriter.err != nil {
+		return
+	}
+	archiveWriter.hash.Write(b)
+	_, archiveWriter.err = archiveWriter.w.Write(b)
+}
+
+func (archiveWriter *archiveWriter) writeUvarint(number uint64) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	archiveWriter.write(buf[:binary.PutUvarint(buf, number)])
+}
+
+func (archiveWriter *archiveWriter) writeHeader(size uint64) {
+	archiveWriter.write([]byte(archiveMagic))
+	archiveWriter.writeUvarint(archiveVersion)
+	archiveWriter.writeUvarint(size)
+}
+
+func (archiveWriter *archiveWriter) writeRecord(recordType uint64, blockNumber uint64, payload []byte) {
+	archiveWriter.writeUvarint(recordType)
+	archiveWriter.writeUvarint(blockNumber)
+	archiveWriter.writeUvarint(uint64(len(payload)))
+	archiveWriter.write(payload)
+}
+
+func (archiveWriter *archiveWriter) writeEnd() error {
+	archiveWriter.writeRecord(archiveRecordEnd, 0, archiveWriter.hash.Sum(nil))
+	if archiveWriter.err != nil {
+		return archiveWriter.err
+	}
+	return archiveWriter.w.Flush()
+}
+
+type archiveReader struct {
+	r    *bufio.Reader
+	hash hash.Hash
+	// checksum of the archive up to the last record read
+	checksum []byte
+}
+
+func newArchiveReader(r io.Reader) *archiveReader {
+	return &archiveReader{r: bufio.NewReader(r), hash: sha256.New()}
+}
+
+func (archiveReader *archiveReader) ReadByte() (byte, error) {
+	b, err := archiveReader.r.ReadByte()
+	if err == nil {
+		archiveReader.hash.Write([]byte{b})
+	}
+	return b, err
+}
+
+func (archiveReader *archiveReader) readUvarint() (uint64, error) {
+	number, err := binary.ReadUvarint(archiveReader)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid archive: %s", err)
+	}
+	return number, nil
+}
+
+func (archiveReader *archiveReader) readBytes(length uint64) ([]byte, error) {
+	b := make([]byte, length)
+	if _, err := io.ReadFull(archiveReader.r, b); err != nil {
+		return nil, fmt.Errorf("Invalid archive: %s", err)
+	}
+	archiveReader.hash.Write(b)
+	return b, nil
+}
+
+func (archiveReader *archiveReader) readHeader() (uint64, error) {
+	magic, err := archiveReader.readBytes(uint64(len(archiveMagic)))
+	if err != nil {
+		return 0, err
+	}
+	if string(magic) != archiveMagic {
+		return 0, fmt.Errorf("Invalid archive: not a ledger archive")
+	}
+	version, err := archiveReader.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+	if version != archiveVersion {
+		return 0, fmt.Errorf("Invalid archive: unsupported version %d", version)
+	}
+	return archiveReader.readUvarint()
+}
+
+func (archiveReader *archiveReader) readRecord() (uint64, uint64, []byte, error) {
+	archiveReader.checksum = archiveReader.hash.Sum(nil)
+	recordType, err := archiveReader.readUvarint()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	blockNumber, err := archiveReader.readUvarint()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	length, err := archiveReader.readUvarint()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if length > archiveMaxRecordLength {
+		return 0, 0, nil, fmt.Errorf("Invalid archive: record of %d bytes", length)
+	}
+	payload, err := archiveReader.readBytes(length)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return recordType, blockNumber, payload, nil
+}
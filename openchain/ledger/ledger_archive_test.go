@@ -0,0 +1,187 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package ledger
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+	"github.com/openblockchain/obc-peer/openchain/ledger/testutil"
+	"github.com/openblockchain/obc-peer/protos"
+)
+
+func TestLedgerArchiveExportImport(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+
+	ledger.BeginTxBatch(0)
+	ledger.TxBegin("txUuid1")
+	ledger.SetState("chaincode1", "key1", []byte("value1"))
+	ledger.SetState("chaincode2", "key2", []byte("value2"))
+	ledger.TxFinished("txUuid1", true)
+	transaction, _ := buildTestTx()
+	ledger.CommitTxBatch(0, []*protos.Transaction{transaction}, []byte("proof"))
+
+	ledger.BeginTxBatch(1)
+	ledger.TxBegin("txUuid2")
+	ledger.SetState("chaincode1", "key1", []byte("value1B"))
+	ledger.DeleteState("chaincode2", "key2")
+	ledger.TxFinished("txUuid2", true)
+	transaction, _ = buildTestTx()
+	ledger.CommitTxBatch(1, []*protos.Transaction{transaction}, []byte("proof"))
+
+	block0 := ledgerTestWrapper.GetBlockByNumber(0)
+	block1 := ledgerTestWrapper.GetBlockByNumber(1)
+	stateDelta1 := ledgerTestWrapper.GetStateDelta(1)
+
+	var archive bytes.Buffer
+	testutil.AssertNoError(t, ledger.ExportArchive(&archive), "Error while exporting archive")
+	testutil.AssertNoError(t, VerifyArchive(bytes.NewReader(archive.Bytes())), "Error while verifying archive")
+
+	// Import into an empty ledger
+	ledgerTestWrapper = createFreshDBAndTestLedgerWrapper(t)
+	ledger = ledgerTestWrapper.ledger
+	testutil.AssertNoError(t, ledger.ImportArchive(bytes.NewReader(archive.Bytes())), "Error while importing archive")
+	testutil.AssertEquals(t, ledger.GetBlockchainSize(), uint64(2))
+	testutil.AssertEquals(t, ledgerTestWrapper.GetBlockByNumber(0), block0)
+	testutil.AssertEquals(t, ledgerTestWrapper.GetBlockByNumber(1), block1)
+	testutil.AssertEquals(t, ledgerTestWrapper.GetStateDelta(1), stateDelta1)
+	testutil.AssertEquals(t, ledgerTestWrapper.GetState("chaincode1", "key1", true), []byte("value1B"))
+	testutil.AssertNil(t, ledgerTestWrapper.GetState("chaincode2", "key2", true))
+
+	// A ledger that is not empty cannot import
+	testutil.AssertError(t, ledger.ImportArchive(bytes.NewReader(archive.Bytes())), "Expected an error importing into a ledger that is not empty")
+}
+
+func TestLedgerArchiveCorrupted(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+
+	ledger.BeginTxBatch(0)
+	ledger.TxBegin("txUuid1")
+	ledger.SetState("chaincode1", "key1", []byte("value1"))
+	ledger.TxFinished("txUuid1", true)
+	transaction, _ := buildTestTx()
+	ledger.CommitTxBatch(0, []*protos.Transaction{transaction}, []byte("proof"))
+
+	var archive bytes.Buffer
+	testutil.AssertNoError(t, ledger.ExportArchive(&archive), "Error while exporting archive")
+
+	corrupted := append([]byte{}, archive.Bytes()...)
+	corrupted[len(archiveMagic)+10] ^= 0xff
+	testutil.AssertError(t, VerifyArchive(bytes.NewReader(corrupted)), "Expected an error verifying a corrupted archive")
+
+	truncated := archive.Bytes()[:archive.Len()-1]
+	testutil.AssertError(t, VerifyArchive(bytes.NewReader(truncated)), "Expected an error verifying a truncated archive")
+
+	// Nothing is imported from a corrupted archive
+	ledgerTestWrapper = createFreshDBAndTestLedgerWrapper(t)
+	ledger = ledgerTestWrapper.ledger
+	testutil.AssertError(t, ledger.ImportArchive(bytes.NewReader(corrupted)), "Expected an error importing a corrupted archive")
+	testutil.AssertEquals(t, ledger.GetBlockchainSize(), uint64(0))
+}
+
+func TestLedgerArchiveRejected(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+
+	ledger.BeginTxBatch(0)
+	ledger.TxBegin("txUuid1")
+	ledger.SetState("chaincode1", "key1", []byte("value1"))
+	ledger.TxFinished("txUuid1", true)
+	transaction, _ := buildTestTx()
+	ledger.CommitTxBatch(0, []*protos.Transaction{transaction}, []byte("proof"))
+
+	ledger.BeginTxBatch(1)
+	ledger.TxBegin("txUuid2")
+	ledger.SetState("chaincode1", "key2", []byte("value2"))
+	ledger.TxFinished("txUuid2", true)
+	transaction, _ = buildTestTx()
+	ledger.CommitTxBatch(1, []*protos.Transaction{transaction}, []byte("proof"))
+
+	var archive bytes.Buffer
+	testutil.AssertNoError(t, ledger.ExportArchive(&archive), "Error while exporting archive")
+
+	// An archive whose state does not match the state hash of its last block
+	tamperedState := rewriteTestArchive(t, archive.Bytes(), func(recordType uint64, blockNumber uint64, payload []byte) []byte {
+		if recordType != archiveRecordStateChunk {
+			return payload
+		}
+		chunk := statemgmt.NewStateDelta()
+		chunk.Unmarshal(payload)
+		chunk.Set("chaincode1", "key1", []byte("tampered"), nil)
+		return chunk.Marshal()
+	})
+	testutil.AssertNoError(t, VerifyArchive(bytes.NewReader(tamperedState)), "Error while verifying archive")
+	ledgerTestWrapper = createFreshDBAndTestLedgerWrapper(t)
+	ledger = ledgerTestWrapper.ledger
+	testutil.AssertError(t, ledger.ImportArchive(bytes.NewReader(tamperedState)), "Expected an error importing an archive with a tampered state")
+	testutil.AssertEquals(t, ledger.GetBlockchainSize(), uint64(0))
+	testutil.AssertNil(t, ledgerTestWrapper.GetState("chaincode1", "key1", true))
+	testutil.AssertNil(t, ledgerTestWrapper.GetState("chaincode1", "key2", true))
+
+	// An archive whose blocks are not linked
+	brokenLink := rewriteTestArchive(t, archive.Bytes(), func(recordType uint64, blockNumber uint64, payload []byte) []byte {
+		if recordType != archiveRecordBlock || blockNumber != 1 {
+			return payload
+		}
+		block, err := protos.UnmarshallBlock(payload)
+		testutil.AssertNoError(t, err, "Error while unmarshalling block")
+		block.PreviousBlockHash = []byte("wrongHash")
+		blockBytes, err := block.Bytes()
+		testutil.AssertNoError(t, err, "Error while marshalling block")
+		return blockBytes
+	})
+	testutil.AssertNoError(t, VerifyArchive(bytes.NewReader(brokenLink)), "Error while verifying archive")
+	testutil.AssertError(t, ledger.ImportArchive(bytes.NewReader(brokenLink)), "Expected an error importing an archive with a broken link")
+	testutil.AssertEquals(t, ledger.GetBlockchainSize(), uint64(0))
+	block, err := fetchBlockFromDB(0)
+	testutil.AssertNoError(t, err, "Error while fetching block")
+	testutil.AssertNil(t, block)
+	testutil.AssertNil(t, ledgerTestWrapper.GetState("chaincode1", "key1", true))
+
+	// The ledger is still empty and imports a valid archive
+	testutil.AssertNoError(t, ledger.ImportArchive(bytes.NewReader(archive.Bytes())), "Error while importing archive")
+	testutil.AssertEquals(t, ledger.GetBlockchainSize(), uint64(2))
+	testutil.AssertEquals(t, ledgerTestWrapper.GetState("chaincode1", "key1", true), []byte("value1"))
+	testutil.AssertEquals(t, ledgerTestWrapper.GetState("chaincode1", "key2", true), []byte("value2"))
+}
+
+// rewriteTestArchive writes the records of archive to a new archive with a valid
+// checksum, replacing each payload with the one returned by change
+func rewriteTestArchive(t *testing.T, archive []byte, change func(recordType uint64, blockNumber uint64, payload []byte) []byte) []byte {
+	archiveReader := newArchiveReader(bytes.NewReader(archive))
+	size, err := archiveReader.readHeader()
+	testutil.AssertNoError(t, err, "Error while reading archive header")
+	var rewritten bytes.Buffer
+	archiveWriter := newArchiveWriter(&rewritten)
+	archiveWriter.writeHeader(size)
+	for {
+		recordType, blockNumber, payload, err := archiveReader.readRecord()
+		testutil.AssertNoError(t, err, "Error while reading archive record")
+		if recordType == archiveRecordEnd {
+			break
+		}
+		archiveWriter.writeRecord(recordType, blockNumber, change(recordType, blockNumber, payload))
+	}
+	testutil.AssertNoError(t, archiveWriter.writeEnd(), "Error while writing archive")
+	return rewritten.Bytes()
+}
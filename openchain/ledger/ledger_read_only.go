@@ -0,0 +1,73 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package ledger
+
+import (
+	"github.com/openblockchain/obc-peer/openchain/db"
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt/state"
+	"github.com/openblockchain/obc-peer/protos"
+)
+
+// ReadOnlyLedger gives read access to the blocks and the state deltas stored in the db of
+// another peer, for instance a copy of the db taken for an audit. The ledger of this peer
+// is not affected
+type ReadOnlyLedger struct {
+	openchainDB *db.OpenchainDB
+}
+
+// OpenReadOnlyLedger opens the db at dbPath in read only mode. Close must be called once
+// you are done
+func OpenReadOnlyLedger(dbPath string) (*ReadOnlyLedger, error) {
+	openchainDB, err := db.OpenDBReadOnly(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return &ReadOnlyLedger{openchainDB}, nil
+}
+
+// GetBlockchainSize returns number of blocks in the blockchain
+func (ledger *ReadOnlyLedger) GetBlockchainSize() (uint64, error) {
+	return fetchBlockchainSizeFromOpenchainDB(ledger.openchainDB)
+}
+
+// GetBlockByNumber return block given the number of the block on blockchain.
+// Lowest block on chain is block number zero
+func (ledger *ReadOnlyLedger) GetBlockByNumber(blockNumber uint64) (*protos.Block, error) {
+	size, err := ledger.GetBlockchainSize()
+	if err != nil {
+		return nil, err
+	}
+	if blockNumber >= size {
+		return nil, ErrOutOfBounds
+	}
+	return fetchBlockFromOpenchainDB(ledger.openchainDB, blockNumber)
+}
+
+// GetStateDelta returns the state delta for a specific block. Nil is returned if the
+// state delta of the block is no longer kept in the db
+func (ledger *ReadOnlyLedger) GetStateDelta(blockNumber uint64) (*statemgmt.StateDelta, error) {
+	return state.FetchStateDeltaFromOpenchainDB(ledger.openchainDB, blockNumber)
+}
+
+// Close closes the db
+func (ledger *ReadOnlyLedger) Close() {
+	ledger.openchainDB.CloseDB()
+}
@@ -21,12 +21,16 @@ package ledger
 
 import (
 	"bytes"
+	"errors"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
 	"github.com/openblockchain/obc-peer/openchain/ledger/testutil"
 	"github.com/openblockchain/obc-peer/protos"
+	"github.com/spf13/viper"
 )
 
 func TestLedgerCommit(t *testing.T) {
@@ -407,6 +411,72 @@ func TestVerifyChain(t *testing.T) {
 	testutil.AssertError(t, err, "Expected error as high block is out of bounds")
 }
 
+func TestVerifyChainPruned(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+	ledger.blockchain.pruneKeepBlocks = 2
+
+	for i := 0; i < 5; i++ {
+		ledger.BeginTxBatch(i)
+		ledger.TxBegin("txUuid" + strconv.Itoa(i))
+		ledger.SetState("chaincode"+strconv.Itoa(i), "key"+strconv.Itoa(i), []byte("value"+strconv.Itoa(i)))
+		ledger.TxFinished("txUuid"+strconv.Itoa(i), true)
+		transaction, _ := buildTestTx()
+		ledger.CommitTxBatch(i, []*protos.Transaction{transaction}, []byte("proof"))
+	}
+
+	testutil.AssertEquals(t, ledgerTestWrapper.GetBlockByNumber(2).IsPruned(), true)
+	testutil.AssertEquals(t, ledgerTestWrapper.GetBlockByNumber(3).IsPruned(), false)
+	testutil.AssertEquals(t, ledgerTestWrapper.VerifyChain(4, 0), uint64(0))
+	testutil.AssertEquals(t, ledgerTestWrapper.VerifyChain(2, 0), uint64(0))
+
+	// The hash recorded in a pruned block is checked against the next kept block
+	forgedBlock := ledgerTestWrapper.GetBlockByNumber(2)
+	forgedBlock.StateHash = []byte("forgedStateHash")
+	forgedBlock.NonHashData.PrunedBlockHash = []byte("forgedBlockHash")
+	ledgerTestWrapper.PutRawBlock(forgedBlock, 2)
+	testutil.AssertEquals(t, ledgerTestWrapper.VerifyChain(2, 0), uint64(3))
+	testutil.AssertEquals(t, ledgerTestWrapper.VerifyChain(4, 0), uint64(3))
+}
+
+func TestBootstrapPrunedBlocks(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+
+	for i := 0; i < 5; i++ {
+		ledger.BeginTxBatch(i)
+		ledger.TxBegin("txUuid" + strconv.Itoa(i))
+		ledger.SetState("chaincode"+strconv.Itoa(i), "key"+strconv.Itoa(i), []byte("value"+strconv.Itoa(i)))
+		ledger.TxFinished("txUuid"+strconv.Itoa(i), true)
+		transaction, _ := buildTestTx()
+		ledger.CommitTxBatch(i, []*protos.Transaction{transaction}, []byte("proof"))
+	}
+	var blocks []*protos.Block
+	for i := uint64(0); i < 5; i++ {
+		blocks = append(blocks, ledgerTestWrapper.GetBlockByNumber(i))
+	}
+
+	// A new peer only receives the headers of the blocks it prunes
+	ledgerTestWrapper = createFreshDBAndTestLedgerWrapper(t)
+	ledger = ledgerTestWrapper.ledger
+	ledger.blockchain.pruneKeepBlocks = 2
+	pruneBelow := ledger.GetPrunedBlockCount(5)
+	testutil.AssertEquals(t, pruneBelow, uint64(3))
+	for i := uint64(0); i < 5; i++ {
+		block := blocks[i]
+		if i < pruneBelow {
+			prunedBlock, err := block.Prune()
+			testutil.AssertNoError(t, err, "Error while pruning block")
+			block = prunedBlock
+		}
+		ledgerTestWrapper.PutRawBlock(block, i)
+	}
+	testutil.AssertEquals(t, ledger.GetBlockchainSize(), uint64(5))
+	testutil.AssertEquals(t, ledgerTestWrapper.GetBlockByNumber(2).IsPruned(), true)
+	testutil.AssertEquals(t, ledgerTestWrapper.GetBlockByNumber(3), blocks[3])
+	testutil.AssertEquals(t, ledgerTestWrapper.VerifyChain(4, 0), uint64(0))
+}
+
 func TestBlockNumberOutOfBoundsError(t *testing.T) {
 	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
 	ledger := ledgerTestWrapper.ledger
@@ -702,3 +772,240 @@ func TestGetTransactionByUUID(t *testing.T) {
 	testutil.AssertEquals(t, err, ErrResourceNotFound)
 	testutil.AssertNil(t, ledgerTransaction)
 }
+
+func TestGetStateAsOf(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+
+	// Block 0
+	ledger.BeginTxBatch(0)
+	ledger.TxBegin("txUuid1")
+	ledger.SetState("chaincode1", "key1", []byte("value1A"))
+	ledger.SetState("chaincode2", "key2", []byte("value2A"))
+	ledger.TxFinished("txUuid1", true)
+	transaction, _ := buildTestTx()
+	ledger.CommitTxBatch(0, []*protos.Transaction{transaction}, []byte("proof"))
+
+	// Block 1
+	ledger.BeginTxBatch(1)
+	ledger.TxBegin("txUuid1")
+	ledger.SetState("chaincode1", "key1", []byte("value1B"))
+	ledger.DeleteState("chaincode2", "key2")
+	ledger.SetState("chaincode3", "key3", []byte("value3B"))
+	ledger.TxFinished("txUuid1", true)
+	transaction, _ = buildTestTx()
+	ledger.CommitTxBatch(1, []*protos.Transaction{transaction}, []byte("proof"))
+
+	// Block 2
+	ledger.BeginTxBatch(2)
+	ledger.TxBegin("txUuid1")
+	ledger.SetState("chaincode1", "key1", []byte("value1C"))
+	ledger.SetState("chaincode2", "key2", []byte("value2C"))
+	ledger.TxFinished("txUuid1", true)
+	transaction, _ = buildTestTx()
+	ledger.CommitTxBatch(2, []*protos.Transaction{transaction}, []byte("proof"))
+
+	testutil.AssertEquals(t, ledgerTestWrapper.GetStateAsOf("chaincode1", "key1", 0), []byte("value1A"))
+	testutil.AssertEquals(t, ledgerTestWrapper.GetStateAsOf("chaincode2", "key2", 0), []byte("value2A"))
+	testutil.AssertNil(t, ledgerTestWrapper.GetStateAsOf("chaincode3", "key3", 0))
+
+	testutil.AssertEquals(t, ledgerTestWrapper.GetStateAsOf("chaincode1", "key1", 1), []byte("value1B"))
+	testutil.AssertNil(t, ledgerTestWrapper.GetStateAsOf("chaincode2", "key2", 1))
+	testutil.AssertEquals(t, ledgerTestWrapper.GetStateAsOf("chaincode3", "key3", 1), []byte("value3B"))
+
+	testutil.AssertEquals(t, ledgerTestWrapper.GetStateAsOf("chaincode1", "key1", 2), []byte("value1C"))
+	testutil.AssertEquals(t, ledgerTestWrapper.GetStateAsOf("chaincode2", "key2", 2), []byte("value2C"))
+	testutil.AssertEquals(t, ledgerTestWrapper.GetStateAsOf("chaincode3", "key3", 2), []byte("value3B"))
+
+	_, err := ledger.GetStateAsOf("chaincode1", "key1", 3)
+	testutil.AssertEquals(t, err, ErrOutOfBounds)
+}
+
+func TestGetStateWithProof(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+
+	ledger.BeginTxBatch(0)
+	ledger.TxBegin("txUuid1")
+	ledger.SetState("chaincode1", "key1", []byte("value1"))
+	ledger.SetState("chaincode2", "key2", []byte("value2"))
+	ledger.TxFinished("txUuid1", true)
+	transaction, _ := buildTestTx()
+	ledger.CommitTxBatch(0, []*protos.Transaction{transaction}, []byte("proof"))
+
+	value, proof, err := ledger.GetStateWithProof("chaincode1", "key1")
+	testutil.AssertNoError(t, err, "Error while getting state with proof")
+	testutil.AssertEquals(t, value, []byte("value1"))
+
+	// The proof is checked against the block header only, after a round trip through bytes
+	blockHeader := ledgerTestWrapper.GetBlockByNumber(0)
+	receivedProof := &statemgmt.StateProof{}
+	testutil.AssertNoError(t, receivedProof.Unmarshal(proof.Marshal()), "Error while unmarshalling proof")
+	testutil.AssertNoError(t, statemgmt.VerifyStateProof(blockHeader.StateHash, "chaincode1", "key1", value, receivedProof),
+		"Expected a valid proof")
+	testutil.AssertError(t, statemgmt.VerifyStateProof(blockHeader.StateHash, "chaincode1", "key1", []byte("value2"), receivedProof),
+		"Expected an invalid proof")
+
+	value, proof, err = ledger.GetStateWithProof("chaincode3", "key3")
+	testutil.AssertNoError(t, err, "Error while getting state with proof")
+	testutil.AssertNil(t, value)
+	testutil.AssertNil(t, proof)
+}
+
+func TestGetStateValueWithProof(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+
+	for i := 0; i < 2; i++ {
+		ledger.BeginTxBatch(i)
+		ledger.TxBegin("txUuid")
+		ledger.SetState("chaincode1", "key1", []byte("value"+strconv.Itoa(i)))
+		ledger.SetState("chaincode2", "key2", []byte("value2"))
+		ledger.TxFinished("txUuid", true)
+		transaction, _ := buildTestTx()
+		ledger.CommitTxBatch(i, []*protos.Transaction{transaction}, []byte("proof"))
+	}
+
+	stateValue, err := ledger.GetStateValueWithProof("chaincode1", "key1")
+	testutil.AssertNoError(t, err, "Error while getting state value with proof")
+	testutil.AssertEquals(t, stateValue.Value, []byte("value1"))
+	testutil.AssertEquals(t, stateValue.BlockNumber, uint64(1))
+	testutil.AssertEquals(t, stateValue.StateHash, ledgerTestWrapper.GetBlockByNumber(1).StateHash)
+
+	// The proof is checked against the block header only, after a round trip through the wire format
+	b, err := proto.Marshal(stateValue)
+	testutil.AssertNoError(t, err, "Error while marshalling state value with proof")
+	receivedValue := &protos.StateValueWithProof{}
+	testutil.AssertNoError(t, proto.Unmarshal(b, receivedValue), "Error while unmarshalling state value with proof")
+	receivedProof := statemgmt.StateProofFromProtoNodes(receivedValue.Nodes)
+	testutil.AssertNoError(t, statemgmt.VerifyStateProof(ledgerTestWrapper.GetBlockByNumber(1).StateHash, "chaincode1", "key1", receivedValue.Value, receivedProof),
+		"Expected a valid proof")
+	testutil.AssertError(t, statemgmt.VerifyStateProof(ledgerTestWrapper.GetBlockByNumber(0).StateHash, "chaincode1", "key1", receivedValue.Value, receivedProof),
+		"Expected an invalid proof against an older block")
+
+	_, err = ledger.GetStateValueWithProof("chaincode3", "key3")
+	testutil.AssertEquals(t, err, ErrResourceNotFound)
+}
+
+func TestChaincodeEventsInNonHashData(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+
+	// Block 0
+	ledger.BeginTxBatch(0)
+	ledger.TxBegin("txUuid1")
+	ledger.SetState("chaincode1", "key1", []byte("value1A"))
+	event := &protos.ChaincodeEvent{ChaincodeID: "chaincode1", TxUuid: "txUuid1", EventName: "event1", Payload: []byte("payload1")}
+	ledger.SetChaincodeEvent(event)
+	ledger.TxFinished("txUuid1", true)
+	transaction, _ := buildTestTx()
+	ledger.CommitTxBatch(0, []*protos.Transaction{transaction}, []byte("proof"))
+
+	block := ledgerTestWrapper.GetBlockByNumber(0)
+	testutil.AssertEquals(t, len(block.NonHashData.TransactionResults), 1)
+	testutil.AssertEquals(t, block.NonHashData.TransactionResults[0].Uuid, "txUuid1")
+	testutil.AssertEquals(t, block.NonHashData.TransactionResults[0].ChaincodeEvent, event)
+
+	// Block 1 is rolled back, its event must not be recorded with block 2
+	ledger.BeginTxBatch(1)
+	ledger.TxBegin("txUuid2")
+	ledger.SetChaincodeEvent(&protos.ChaincodeEvent{ChaincodeID: "chaincode1", TxUuid: "txUuid2", EventName: "event2"})
+	ledger.TxFinished("txUuid2", true)
+	ledger.RollbackTxBatch(1)
+
+	ledger.BeginTxBatch(2)
+	ledger.TxBegin("txUuid3")
+	ledger.SetState("chaincode1", "key1", []byte("value1B"))
+	ledger.TxFinished("txUuid3", true)
+	transaction, _ = buildTestTx()
+	ledger.CommitTxBatch(2, []*protos.Transaction{transaction}, []byte("proof"))
+
+	block = ledgerTestWrapper.GetBlockByNumber(1)
+	testutil.AssertEquals(t, len(block.NonHashData.TransactionResults), 0)
+}
+
+func TestGetTransactionReceipt(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+
+	tx1, uuid1 := buildTestTx()
+	tx2, uuid2 := buildTestTx()
+	ledger.MarkTransactionPending(uuid1)
+	ledger.MarkTransactionPending(uuid2)
+
+	receipt, err := ledger.GetTransactionReceipt(uuid1)
+	testutil.AssertNoError(t, err, "Error while getting receipt of pending transaction")
+	testutil.AssertEquals(t, receipt.Status, protos.TransactionReceipt_PENDING)
+
+	ledger.BeginTxBatch(0)
+	ledger.TxBegin(uuid1)
+	ledger.SetState("chaincode1", "key1", []byte("value1"))
+	ledger.TxFinished(uuid1, true)
+	ledger.TxBegin(uuid2)
+	ledger.TxFinished(uuid2, false)
+	ledger.SetTxError(uuid2, errors.New("Error in chaincode"))
+	ledger.CommitTxBatch(0, []*protos.Transaction{tx1, tx2}, []byte("proof"))
+
+	receipt, err = ledger.GetTransactionReceipt(uuid1)
+	testutil.AssertNoError(t, err, "Error while getting receipt of committed transaction")
+	testutil.AssertEquals(t, receipt, &protos.TransactionReceipt{Uuid: uuid1, Status: protos.TransactionReceipt_COMMITTED,
+		BlockNumber: 0, TxIndex: 0})
+
+	receipt, err = ledger.GetTransactionReceipt(uuid2)
+	testutil.AssertNoError(t, err, "Error while getting receipt of failed transaction")
+	testutil.AssertEquals(t, receipt, &protos.TransactionReceipt{Uuid: uuid2, Status: protos.TransactionReceipt_FAILED,
+		BlockNumber: 0, TxIndex: 1, Error: "Error in chaincode"})
+
+	_, err = ledger.GetTransactionReceipt("unknownUuid")
+	testutil.AssertEquals(t, err, ErrResourceNotFound)
+}
+
+func TestGetTransactionReceiptExpiry(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+	defer func(expiry time.Duration) { pendingTxExpiry = expiry }(pendingTxExpiry)
+	pendingTxExpiry = 100 * time.Millisecond
+
+	// a transaction dropped before reaching a block is no longer pending once expired
+	_, uuid1 := buildTestTx()
+	ledger.MarkTransactionPending(uuid1)
+	time.Sleep(200 * time.Millisecond)
+	_, uuid2 := buildTestTx()
+	ledger.MarkTransactionPending(uuid2)
+
+	_, err := ledger.GetTransactionReceipt(uuid1)
+	testutil.AssertEquals(t, err, ErrResourceNotFound)
+	receipt, err := ledger.GetTransactionReceipt(uuid2)
+	testutil.AssertNoError(t, err, "Error while getting receipt of pending transaction")
+	testutil.AssertEquals(t, receipt.Status, protos.TransactionReceipt_PENDING)
+	testutil.AssertEquals(t, len(ledger.pendingTxs), 1)
+}
+
+func TestReadOnlyLedger(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+
+	ledger.BeginTxBatch(0)
+	ledger.TxBegin("txUuid1")
+	ledger.SetState("chaincode1", "key1", []byte("value1"))
+	ledger.TxFinished("txUuid1", true)
+	transaction, _ := buildTestTx()
+	ledger.CommitTxBatch(0, []*protos.Transaction{transaction}, []byte("proof"))
+
+	readOnlyLedger, err := OpenReadOnlyLedger(viper.GetString("peer.fileSystemPath") + "/db")
+	testutil.AssertNoError(t, err, "Error while opening read only ledger")
+	defer readOnlyLedger.Close()
+
+	size, err := readOnlyLedger.GetBlockchainSize()
+	testutil.AssertNoError(t, err, "Error while getting blockchain size")
+	testutil.AssertEquals(t, size, uint64(1))
+	block, err := readOnlyLedger.GetBlockByNumber(0)
+	testutil.AssertNoError(t, err, "Error while getting block")
+	testutil.AssertEquals(t, block, ledgerTestWrapper.GetBlockByNumber(0))
+	_, err = readOnlyLedger.GetBlockByNumber(1)
+	testutil.AssertEquals(t, err, ErrOutOfBounds)
+
+	stateDelta, err := readOnlyLedger.GetStateDelta(0)
+	testutil.AssertNoError(t, err, "Error while getting state delta")
+	testutil.AssertEquals(t, stateDelta.Get("chaincode1", "key1").GetValue(), []byte("value1"))
+}
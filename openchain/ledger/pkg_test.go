@@ -190,6 +190,12 @@ func (ledgerTestWrapper *ledgerTestWrapper) GetStateDelta(blockNumber uint64) *s
 	return delta
 }
 
+func (ledgerTestWrapper *ledgerTestWrapper) GetStateAsOf(chaincodeID string, key string, blockNumber uint64) []byte {
+	value, err := ledgerTestWrapper.ledger.GetStateAsOf(chaincodeID, key, blockNumber)
+	testutil.AssertNoError(ledgerTestWrapper.t, err, "error while getting historical state from ledger")
+	return value
+}
+
 func (ledgerTestWrapper *ledgerTestWrapper) GetTempStateHash() []byte {
 	hash, err := ledgerTestWrapper.ledger.GetTempStateHash()
 	testutil.AssertNoError(ledgerTestWrapper.t, err, "error while getting state hash from ledger")
@@ -0,0 +1,65 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package buckettree
+
+import (
+	"github.com/openblockchain/obc-peer/openchain/db"
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+	"github.com/tecbot/gorocksdb"
+)
+
+// The data nodes are spread over the buckets by the hash of their keys. To scan a range of keys
+// in key order, the persisted state also keeps an index of the composite keys. The index is stored
+// under a prefix that no bucket key can take: bucket keys are byte 0 followed by the varint encoded
+// level, which could only start with 0xFF in a tree of more than 127 levels. The prefix alone marks
+// that the index has been built.
+var keyIndexPrefix = []byte{0x00, 0xFF}
+
+func getKeyIndexEncodedBytes(compositeKey []byte) []byte {
+	encodedBytes := append([]byte{}, keyIndexPrefix...)
+	return append(encodedBytes, compositeKey...)
+}
+
+// buildKeyIndex indexes the keys of the state persisted before the index was introduced
+func buildKeyIndex() error {
+	openchainDB := db.GetDBHandle()
+	marker, err := openchainDB.GetFromStateCF(keyIndexPrefix)
+	if err != nil {
+		return err
+	}
+	if marker != nil {
+		return nil
+	}
+
+	logger.Info("Building the key index of the state")
+	writeBatch := gorocksdb.NewWriteBatch()
+	defer writeBatch.Destroy()
+	itr := openchainDB.GetStateCFIterator()
+	defer itr.Close()
+	// data keys start with the bucket number (starting from 1), bucket keys start with byte 0
+	for itr.Seek([]byte{0x01}); itr.Valid(); itr.Next() {
+		dataKey := newDataKeyFromEncodedBytes(statemgmt.Copy(itr.Key().Data()))
+		writeBatch.PutCF(openchainDB.StateCF, getKeyIndexEncodedBytes(dataKey.compositeKey), []byte{})
+	}
+	writeBatch.PutCF(openchainDB.StateCF, keyIndexPrefix, []byte{1})
+	opt := gorocksdb.NewDefaultWriteOptions()
+	defer opt.Destroy()
+	return openchainDB.DB.Write(opt, writeBatch)
+}
@@ -0,0 +1,91 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package buckettree
+
+import (
+	"bytes"
+
+	"github.com/openblockchain/obc-peer/openchain/db"
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+	"github.com/tecbot/gorocksdb"
+)
+
+// RangeScanIterator implements the interface 'statemgmt.RangeScanIterator'
+type RangeScanIterator struct {
+	dbItr           *gorocksdb.Iterator
+	chaincodeID     string
+	keyIndexPrefix  []byte
+	endKey          string
+	currentKey      string
+	currentDataNode *dataNode
+	started         bool
+	done            bool
+}
+
+// newRangeScanIterator iterates over the keys of the chaincodeID that fall in the range [startKey, endKey].
+// Data nodes are distributed over the buckets by the hash of the key, so the iterator walks the key index
+// in key order from startKey and fetches the data node of each key on the way.
+func newRangeScanIterator(chaincodeID string, startKey string, endKey string) (*RangeScanIterator, error) {
+	dbItr := db.GetDBHandle().GetStateCFIterator()
+	dbItr.Seek(getKeyIndexEncodedBytes(statemgmt.ConstructCompositeKey(chaincodeID, startKey)))
+	prefix := getKeyIndexEncodedBytes(statemgmt.ConstructCompositeKey(chaincodeID, ""))
+	return &RangeScanIterator{dbItr: dbItr, chaincodeID: chaincodeID, keyIndexPrefix: prefix, endKey: endKey}, nil
+}
+
+// Next - see interface 'statemgmt.RangeScanIterator' for details
+func (itr *RangeScanIterator) Next() bool {
+	for !itr.done {
+		if itr.started {
+			itr.dbItr.Next()
+		}
+		itr.started = true
+		if !itr.dbItr.Valid() || !bytes.HasPrefix(itr.dbItr.Key().Data(), itr.keyIndexPrefix) {
+			break
+		}
+		key := string(itr.dbItr.Key().Data()[len(itr.keyIndexPrefix):])
+		if itr.endKey != "" && key > itr.endKey {
+			break
+		}
+		dataNode, err := fetchDataNodeFromDB(newDataKey(itr.chaincodeID, key))
+		if err != nil {
+			logger.Error("Error while fetching data node of key [%s] for range scan: %s", key, err)
+			break
+		}
+		if dataNode == nil {
+			continue
+		}
+		itr.currentKey = key
+		itr.currentDataNode = dataNode
+		return true
+	}
+	itr.done = true
+	itr.currentDataNode = nil
+	return false
+}
+
+// GetKeyValue - see interface 'statemgmt.RangeScanIterator' for details
+func (itr *RangeScanIterator) GetKeyValue() (string, []byte) {
+	return itr.currentKey, itr.currentDataNode.getValue()
+}
+
+// Close - see interface 'statemgmt.RangeScanIterator' for details
+func (itr *RangeScanIterator) Close() {
+	itr.dbItr.Close()
+}
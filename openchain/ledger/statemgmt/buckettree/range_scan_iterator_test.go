@@ -0,0 +1,135 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package buckettree
+
+import (
+	"testing"
+
+	"github.com/openblockchain/obc-peer/openchain/db"
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+	"github.com/openblockchain/obc-peer/openchain/ledger/testutil"
+	"github.com/tecbot/gorocksdb"
+)
+
+func TestRangeScanIterator(t *testing.T) {
+	testDBWrapper.CreateFreshDB(t)
+	stateImplTestWrapper := newStateImplTestWrapper(t)
+	stateDelta := statemgmt.NewStateDelta()
+
+	// insert keys
+	stateDelta.Set("chaincodeID1", "key1", []byte("value1"), nil)
+	stateDelta.Set("chaincodeID2", "key4", []byte("value4"), nil)
+	stateDelta.Set("chaincodeID2", "key2", []byte("value2"), nil)
+	stateDelta.Set("chaincodeID2", "key5", []byte("value5"), nil)
+	stateDelta.Set("chaincodeID2", "key3", []byte("value3"), nil)
+	stateDelta.Set("chaincodeID2", "key1", []byte("value1"), nil)
+	stateDelta.Set("chaincodeID3", "key1", []byte("value1"), nil)
+	stateImplTestWrapper.prepareWorkingSet(stateDelta)
+	stateImplTestWrapper.persistChangesAndResetInMemoryChanges()
+
+	// test range scan for chaincodeID2
+	itr, err := stateImplTestWrapper.stateImpl.GetRangeScanIterator("chaincodeID2", "key2", "key4")
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertRangeScanIteratorResults(t, itr, []string{"key2", "key3", "key4"})
+
+	// test range scan with open ends
+	itr, err = stateImplTestWrapper.stateImpl.GetRangeScanIterator("chaincodeID2", "", "key2")
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertRangeScanIteratorResults(t, itr, []string{"key1", "key2"})
+
+	itr, err = stateImplTestWrapper.stateImpl.GetRangeScanIterator("chaincodeID2", "key4", "")
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertRangeScanIteratorResults(t, itr, []string{"key4", "key5"})
+
+	itr, err = stateImplTestWrapper.stateImpl.GetRangeScanIterator("chaincodeID2", "", "")
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertRangeScanIteratorResults(t, itr, []string{"key1", "key2", "key3", "key4", "key5"})
+
+	// test range scan for a chaincodeID that does not exist
+	itr, err = stateImplTestWrapper.stateImpl.GetRangeScanIterator("chaincodeID4", "", "")
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertRangeScanIteratorResults(t, itr, []string{})
+}
+
+func TestRangeScanIteratorDeletedKeys(t *testing.T) {
+	testDBWrapper.CreateFreshDB(t)
+	stateImplTestWrapper := newStateImplTestWrapper(t)
+	stateDelta := statemgmt.NewStateDelta()
+	stateDelta.Set("chaincodeID1", "key1", []byte("value1"), nil)
+	stateDelta.Set("chaincodeID1", "key2", []byte("value2"), nil)
+	stateDelta.Set("chaincodeID1", "key3", []byte("value3"), nil)
+	stateImplTestWrapper.prepareWorkingSet(stateDelta)
+	stateImplTestWrapper.persistChangesAndResetInMemoryChanges()
+
+	stateDelta = statemgmt.NewStateDelta()
+	stateDelta.Delete("chaincodeID1", "key2", nil)
+	stateImplTestWrapper.prepareWorkingSet(stateDelta)
+	stateImplTestWrapper.persistChangesAndResetInMemoryChanges()
+
+	itr, err := stateImplTestWrapper.stateImpl.GetRangeScanIterator("chaincodeID1", "", "")
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertRangeScanIteratorResults(t, itr, []string{"key1", "key3"})
+}
+
+func TestRangeScanIteratorBuildsKeyIndex(t *testing.T) {
+	testDBWrapper.CreateFreshDB(t)
+	stateImplTestWrapper := newStateImplTestWrapper(t)
+	stateDelta := statemgmt.NewStateDelta()
+	stateDelta.Set("chaincodeID1", "key1", []byte("value1"), nil)
+	stateDelta.Set("chaincodeID1", "key2", []byte("value2"), nil)
+	stateDelta.Set("chaincodeID2", "key1", []byte("value1"), nil)
+	stateImplTestWrapper.prepareWorkingSet(stateDelta)
+	stateImplTestWrapper.persistChangesAndResetInMemoryChanges()
+
+	// drop the key index, as in a state persisted before the index was introduced
+	openchainDB := db.GetDBHandle()
+	writeBatch := gorocksdb.NewWriteBatch()
+	defer writeBatch.Destroy()
+	writeBatch.DeleteCF(openchainDB.StateCF, keyIndexPrefix)
+	for _, compositeKey := range [][]byte{statemgmt.ConstructCompositeKey("chaincodeID1", "key1"),
+		statemgmt.ConstructCompositeKey("chaincodeID1", "key2"), statemgmt.ConstructCompositeKey("chaincodeID2", "key1")} {
+		writeBatch.DeleteCF(openchainDB.StateCF, getKeyIndexEncodedBytes(compositeKey))
+	}
+	opt := gorocksdb.NewDefaultWriteOptions()
+	defer opt.Destroy()
+	testutil.AssertNoError(t, openchainDB.DB.Write(opt, writeBatch), "Error while dropping key index")
+
+	itr, err := stateImplTestWrapper.stateImpl.GetRangeScanIterator("chaincodeID1", "", "")
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertRangeScanIteratorResults(t, itr, []string{})
+
+	// the index is built again when the state is initialized
+	stateImplTestWrapper.constructNewStateImpl()
+	itr, err = stateImplTestWrapper.stateImpl.GetRangeScanIterator("chaincodeID1", "", "")
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertRangeScanIteratorResults(t, itr, []string{"key1", "key2"})
+}
+
+func assertRangeScanIteratorResults(t *testing.T, itr statemgmt.RangeScanIterator, expectedKeys []string) {
+	defer itr.Close()
+	keys := []string{}
+	for itr.Next() {
+		key, value := itr.GetKeyValue()
+		t.Logf("key=[%s], value=[%s]", key, string(value))
+		testutil.AssertEquals(t, string(value), "value"+key[len("key"):])
+		keys = append(keys, key)
+	}
+	testutil.AssertEquals(t, keys, expectedKeys)
+}
@@ -21,6 +21,7 @@ package buckettree
 
 import (
 	"bytes"
+	"fmt"
 
 	"github.com/op/go-logging"
 	"github.com/openblockchain/obc-peer/openchain/db"
@@ -49,6 +50,9 @@ func NewStateImpl() *StateImpl {
 // Initialize - method implementation for interface 'statemgmt.HashableState'
 func (stateImpl *StateImpl) Initialize() error {
 	logger.Info("Initializing bucket tree state implemetation with configurations %+v", conf)
+	if err := buildKeyIndex(); err != nil {
+		return err
+	}
 	rootBucketNode, err := fetchBucketNodeFromDB(constructRootBucketKey())
 	if err != nil {
 		return err
@@ -79,6 +83,55 @@ func (stateImpl *StateImpl) Get(chaincodeID string, key string) ([]byte, error)
 	return dataNode.value, nil
 }
 
+// GetWithProof - method implementation for interface 'statemgmt.HashableState'
+func (stateImpl *StateImpl) GetWithProof(chaincodeID string, key string) ([]byte, *statemgmt.StateProof, error) {
+	dataKey := newDataKey(chaincodeID, key)
+	dataNode, err := fetchDataNodeFromDB(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if dataNode == nil {
+		return nil, nil, nil
+	}
+
+	// The lowest-level bucket is proved by all of its data nodes
+	bucketKey := dataKey.getBucketKey()
+	dataNodes, err := fetchDataNodesFromDBFor(bucketKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof := &statemgmt.StateProof{}
+	proofNode := statemgmt.NewStateProofNode()
+	for _, node := range dataNodes {
+		if util.NotNil(node.value) {
+			proofNode.AddKeyValue(node.getCompositeKey(), node.getValue())
+		}
+	}
+	proof.Nodes = append(proof.Nodes, proofNode)
+
+	// and every bucket up to the root by the crypto-hashes of the sibling buckets
+	for bucketKey.level > 0 {
+		parentBucketKey := bucketKey.getParentKey()
+		parentBucketNode, err := fetchBucketNodeFromDB(parentBucketKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		if parentBucketNode == nil {
+			return nil, nil, fmt.Errorf("Bucket [%s] is missing from DB", parentBucketKey)
+		}
+		proofNode := statemgmt.NewStateProofNode()
+		proofNode.ChildIndex = parentBucketKey.getChildIndex(bucketKey)
+		for i, childCryptoHash := range parentBucketNode.childrenCryptoHash {
+			if i != proofNode.ChildIndex && util.NotNil(childCryptoHash) {
+				proofNode.ChildrenCryptoHashes[i] = childCryptoHash
+			}
+		}
+		proof.Nodes = append(proof.Nodes, proofNode)
+		bucketKey = parentBucketKey
+	}
+	return dataNode.value, proof, nil
+}
+
 // PrepareWorkingSet - method implementation for interface 'statemgmt.HashableState'
 func (stateImpl *StateImpl) PrepareWorkingSet(stateDelta *statemgmt.StateDelta) error {
 	logger.Debug("Enter - PrepareWorkingSet()")
@@ -248,10 +301,13 @@ func (stateImpl *StateImpl) addDataNodeChangesForPersistence(writeBatch *gorocks
 	for _, affectedBucket := range affectedBuckets {
 		dataNodes := stateImpl.dataNodesDelta.getSortedDataNodesFor(affectedBucket)
 		for _, dataNode := range dataNodes {
+			keyIndexBytes := getKeyIndexEncodedBytes(dataNode.dataKey.compositeKey)
 			if util.IsNil(dataNode.value) {
 				writeBatch.DeleteCF(openchainDB.StateCF, dataNode.dataKey.getEncodedBytes())
+				writeBatch.DeleteCF(openchainDB.StateCF, keyIndexBytes)
 			} else {
 				writeBatch.PutCF(openchainDB.StateCF, dataNode.dataKey.getEncodedBytes(), dataNode.value)
+				writeBatch.PutCF(openchainDB.StateCF, keyIndexBytes, []byte{})
 			}
 		}
 	}
@@ -283,3 +339,8 @@ func (stateImpl *StateImpl) PerfHintKeyChanged(chaincodeID string, key string) {
 func (stateImpl *StateImpl) GetStateSnapshotIterator(snapshot *gorocksdb.Snapshot) (statemgmt.StateSnapshotIterator, error) {
 	return newStateSnapshotIterator(snapshot)
 }
+
+// GetRangeScanIterator - method implementation for interface 'statemgmt.HashableState'
+func (stateImpl *StateImpl) GetRangeScanIterator(chaincodeID string, startKey string, endKey string) (statemgmt.RangeScanIterator, error) {
+	return newRangeScanIterator(chaincodeID, startKey, endKey)
+}
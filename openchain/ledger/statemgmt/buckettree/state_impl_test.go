@@ -371,3 +371,35 @@ func TestStateImpl_DB_Changes(t *testing.T) {
 	bucketNodeFromDB, _ = fetchBucketNodeFromDB(newBucketKey(2, 3))
 	testutil.AssertNil(t, bucketNodeFromDB)
 }
+
+func TestStateImpl_GetWithProof(t *testing.T) {
+	// number of buckets at each level 26,9,3,1
+	testHasher, stateImplTestWrapper, stateDelta := createFreshDBAndInitTestStateImplWithCustomHasher(t, 26, 3)
+	testHasher.populate("chaincodeID1", "key1", 0)
+	testHasher.populate("chaincodeID2", "key2", 0)
+	testHasher.populate("chaincodeID3", "key3", 4)
+	testHasher.populate("chaincodeID4", "key4", 25)
+	testHasher.populate("chaincodeID10", "key10", 24)
+
+	stateDelta.Set("chaincodeID1", "key1", []byte("value1"), nil)
+	stateDelta.Set("chaincodeID2", "key2", []byte("value2"), nil)
+	stateDelta.Set("chaincodeID3", "key3", []byte("value3"), nil)
+	stateDelta.Set("chaincodeID4", "key4", []byte("value4"), nil)
+	rootHash := stateImplTestWrapper.prepareWorkingSetAndComputeCryptoHash(stateDelta)
+	stateImplTestWrapper.persistChangesAndResetInMemoryChanges()
+
+	for _, kv := range [][]string{{"chaincodeID1", "key1", "value1"}, {"chaincodeID2", "key2", "value2"},
+		{"chaincodeID3", "key3", "value3"}, {"chaincodeID4", "key4", "value4"}} {
+		value, proof, err := stateImplTestWrapper.stateImpl.GetWithProof(kv[0], kv[1])
+		testutil.AssertNoError(t, err, "Error while getting value with proof")
+		testutil.AssertEquals(t, value, []byte(kv[2]))
+		testutil.AssertEquals(t, len(proof.Nodes), conf.getLowestLevel()+1)
+		testutil.AssertNoError(t, statemgmt.VerifyStateProof(rootHash, kv[0], kv[1], value, proof), "Expected a valid proof")
+		testutil.AssertError(t, statemgmt.VerifyStateProof(rootHash, kv[0], kv[1], []byte("otherValue"), proof), "Expected an invalid proof")
+	}
+
+	value, proof, err := stateImplTestWrapper.stateImpl.GetWithProof("chaincodeID10", "key10")
+	testutil.AssertNoError(t, err, "Error while getting value with proof")
+	testutil.AssertNil(t, value)
+	testutil.AssertNil(t, proof)
+}
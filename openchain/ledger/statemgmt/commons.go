@@ -45,6 +45,18 @@ func DecodeCompositeKey(compositeKey []byte) (string, string) {
 	return string(split[0]), string(split[1])
 }
 
+// IsKeyInRange returns true if key falls in the range [startKey, endKey]. An empty startKey
+// (or endKey) leaves that end of the range open.
+func IsKeyInRange(key string, startKey string, endKey string) bool {
+	if startKey != "" && key < startKey {
+		return false
+	}
+	if endKey != "" && key > endKey {
+		return false
+	}
+	return true
+}
+
 // Copy returns a copy of given bytes
 func Copy(src []byte) []byte {
 	dest := make([]byte, len(src))
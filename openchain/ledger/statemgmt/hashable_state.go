@@ -34,6 +34,10 @@ type HashableState interface {
 	// Get get the value from DB
 	Get(chaincodeID string, key string) ([]byte, error)
 
+	// GetWithProof get the value from DB along with a proof that the key-value belongs to the state
+	// persisted in DB (see VerifyStateProof). A nil value and a nil proof are returned if the key does not exist
+	GetWithProof(chaincodeID string, key string) ([]byte, *StateProof, error)
+
 	// PrepareWorkingSet passes a stateDelta that captures the changes that needs to be applied to the state
 	PrepareWorkingSet(stateDelta *StateDelta) error
 
@@ -57,6 +61,12 @@ type HashableState interface {
 	// key-values or remove some data from particular key-values.
 	GetStateSnapshotIterator(snapshot *gorocksdb.Snapshot) (StateSnapshotIterator, error)
 
+	// GetRangeScanIterator state implementation to provide an iterator over the committed key-values
+	// of the given chaincodeID such that a returned key is lexically greater than or equal to startKey
+	// and less than or equal to endKey. An empty startKey (or endKey) leaves that end of the range open.
+	// The key-values are returned in lexical order of the keys.
+	GetRangeScanIterator(chaincodeID string, startKey string, endKey string) (RangeScanIterator, error)
+
 	// PerfHintKeyChanged state implementation may be provided with some hints before (e.g., during tx execution)
 	// the StateDelta is prepared and passed in PrepareWorkingSet method.
 	// A state implementation may use this hint for prefetching relevant data so as if this could improve
@@ -77,3 +87,18 @@ type StateSnapshotIterator interface {
 	// Close release resources occupied by the iterator
 	Close()
 }
+
+// RangeScanIterator An interface that is to be implemented by the return value of
+// GetRangeScanIterator method in the implementation of HashableState interface
+type RangeScanIterator interface {
+
+	// Move to next key-value. Returns true if next key-value exists
+	Next() bool
+
+	// GetKeyValue returns the key (without the chaincodeID) and the value at the current position.
+	// A nil value means that the key has been deleted (see StateDeltaIterator)
+	GetKeyValue() (string, []byte)
+
+	// Close release resources occupied by the iterator
+	Close()
+}
@@ -0,0 +1,97 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package state
+
+import (
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+)
+
+// CompositeRangeScanIterator - an implementation of interface 'statemgmt.RangeScanIterator'
+// This merges more than one underlying iterators, each of which returns keys in lexical order,
+// into a single iterator that also returns keys in lexical order.
+// If the same key is returned by more than one underlying iterator, the value is taken from the
+// iterator that appears first in the list. A nil value (a key deleted in a state delta) hides the key.
+type CompositeRangeScanIterator struct {
+	itrs         []statemgmt.RangeScanIterator
+	available    []bool
+	currentKey   string
+	currentValue []byte
+}
+
+func newCompositeRangeScanIterator(itrs ...statemgmt.RangeScanIterator) *CompositeRangeScanIterator {
+	compositeItr := &CompositeRangeScanIterator{itrs: itrs, available: make([]bool, len(itrs))}
+	for i, itr := range itrs {
+		compositeItr.available[i] = itr.Next()
+	}
+	return compositeItr
+}
+
+// Next - see interface 'statemgmt.RangeScanIterator' for details
+func (compositeItr *CompositeRangeScanIterator) Next() bool {
+	for {
+		selected := -1
+		var selectedKey string
+		for i, itr := range compositeItr.itrs {
+			if !compositeItr.available[i] {
+				continue
+			}
+			key, _ := itr.GetKeyValue()
+			if selected == -1 || key < selectedKey {
+				selected = i
+				selectedKey = key
+			}
+		}
+		if selected == -1 {
+			compositeItr.currentKey = ""
+			compositeItr.currentValue = nil
+			return false
+		}
+		_, selectedValue := compositeItr.itrs[selected].GetKeyValue()
+
+		// move past the selected key in all the iterators
+		for i, itr := range compositeItr.itrs {
+			if !compositeItr.available[i] {
+				continue
+			}
+			if key, _ := itr.GetKeyValue(); key == selectedKey {
+				compositeItr.available[i] = itr.Next()
+			}
+		}
+
+		if selectedValue != nil {
+			compositeItr.currentKey = selectedKey
+			compositeItr.currentValue = selectedValue
+			return true
+		}
+		logger.Debug("Skipping key [%s] as it is deleted", selectedKey)
+	}
+}
+
+// GetKeyValue - see interface 'statemgmt.RangeScanIterator' for details
+func (compositeItr *CompositeRangeScanIterator) GetKeyValue() (string, []byte) {
+	return compositeItr.currentKey, compositeItr.currentValue
+}
+
+// Close - see interface 'statemgmt.RangeScanIterator' for details
+func (compositeItr *CompositeRangeScanIterator) Close() {
+	for _, itr := range compositeItr.itrs {
+		itr.Close()
+	}
+}
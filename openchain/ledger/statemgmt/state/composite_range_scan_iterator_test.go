@@ -0,0 +1,85 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package state
+
+import (
+	"testing"
+
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+	"github.com/openblockchain/obc-peer/openchain/ledger/testutil"
+)
+
+func TestCompositeRangeScanIterator(t *testing.T) {
+	stateTestWrapper, state := createFreshDBAndConstructState(t)
+
+	// commit initial test state to db
+	state.TxBegin("txUuid")
+	state.Set("chaincode1", "key1", []byte("value1"))
+	state.Set("chaincode1", "key2", []byte("value2"))
+	state.Set("chaincode1", "key3", []byte("value3"))
+	state.Set("chaincode1", "key4", []byte("value4"))
+	state.Set("chaincode1", "key5", []byte("value5"))
+	state.Set("chaincode2", "key1", []byte("value1"))
+	state.TxFinish("txUuid", true)
+	stateTestWrapper.persistAndClearInMemoryChanges(0)
+
+	// make changes in the tx-batch
+	state.TxBegin("txUuid1")
+	state.Set("chaincode1", "key2", []byte("value2_batch"))
+	state.Delete("chaincode1", "key3")
+	state.Set("chaincode1", "key6", []byte("value6_batch"))
+	state.TxFinish("txUuid1", true)
+
+	// make changes in the current tx
+	state.TxBegin("txUuid2")
+	state.Set("chaincode1", "key3", []byte("value3_tx"))
+	state.Delete("chaincode1", "key4")
+	state.Set("chaincode1", "key7", []byte("value7_tx"))
+
+	itr, err := state.GetRangeScanIterator("chaincode1", "key2", "key6", false)
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertIteratorResults(t, itr, []string{"key2", "key3", "key5", "key6"},
+		[][]byte{[]byte("value2_batch"), []byte("value3_tx"), []byte("value5"), []byte("value6_batch")})
+
+	itr, err = state.GetRangeScanIterator("chaincode1", "", "", false)
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertIteratorResults(t, itr, []string{"key1", "key2", "key3", "key5", "key6", "key7"},
+		[][]byte{[]byte("value1"), []byte("value2_batch"), []byte("value3_tx"), []byte("value5"), []byte("value6_batch"), []byte("value7_tx")})
+
+	// committed state only
+	itr, err = state.GetRangeScanIterator("chaincode1", "key2", "key6", true)
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertIteratorResults(t, itr, []string{"key2", "key3", "key4", "key5"},
+		[][]byte{[]byte("value2"), []byte("value3"), []byte("value4"), []byte("value5")})
+	state.TxFinish("txUuid2", true)
+}
+
+func assertIteratorResults(t *testing.T, itr statemgmt.RangeScanIterator, expectedKeys []string, expectedValues [][]byte) {
+	defer itr.Close()
+	keys := []string{}
+	values := [][]byte{}
+	for itr.Next() {
+		key, value := itr.GetKeyValue()
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	testutil.AssertEquals(t, keys, expectedKeys)
+	testutil.AssertEquals(t, values, expectedValues)
+}
@@ -128,6 +128,30 @@ func (state *State) Get(chaincodeID string, key string, committed bool) ([]byte,
 	return state.stateImpl.Get(chaincodeID, key)
 }
 
+// GetWithProof returns the committed state for chaincodeID and key along with a proof that the
+// key-value belongs to the committed state. A nil value and a nil proof are returned if the key does not exist
+func (state *State) GetWithProof(chaincodeID string, key string) ([]byte, *statemgmt.StateProof, error) {
+	return state.stateImpl.GetWithProof(chaincodeID, key)
+}
+
+// GetRangeScanIterator returns an iterator to get all the keys (and values) of the chaincodeID in the
+// range [startKey, endKey], in lexical order of the keys. If committed is false, the changes made by the
+// current tx and the current tx-batch are merged with the state in the db. If committed is true, only
+// the state in the db is considered.
+func (state *State) GetRangeScanIterator(chaincodeID string, startKey string, endKey string, committed bool) (statemgmt.RangeScanIterator, error) {
+	stateImplItr, err := state.stateImpl.GetRangeScanIterator(chaincodeID, startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	if committed {
+		return stateImplItr, nil
+	}
+	return newCompositeRangeScanIterator(
+		statemgmt.NewStateDeltaRangeScanIterator(state.currentTxStateDelta, chaincodeID, startKey, endKey),
+		statemgmt.NewStateDeltaRangeScanIterator(state.stateDelta, chaincodeID, startKey, endKey),
+		stateImplItr), nil
+}
+
 // Set sets state to given value for chaincodeID and key. Does not immideatly writes to DB
 func (state *State) Set(chaincodeID string, key string, value []byte) error {
 	logger.Debug("set() chaincodeID=[%s], key=[%s], value=[%#v]", chaincodeID, key, value)
@@ -218,7 +242,13 @@ func (state *State) GetSnapshot(blockNumber uint64, dbSnapshot *gorocksdb.Snapsh
 
 // FetchStateDeltaFromDB fetches the StateDelta corrsponding to given blockNumber
 func (state *State) FetchStateDeltaFromDB(blockNumber uint64) (*statemgmt.StateDelta, error) {
-	stateDeltaBytes, err := db.GetDBHandle().GetFromStateDeltaCF(encodeStateDeltaKey(blockNumber))
+	return FetchStateDeltaFromOpenchainDB(db.GetDBHandle(), blockNumber)
+}
+
+// FetchStateDeltaFromOpenchainDB fetches the StateDelta corrsponding to given blockNumber
+// from the given db, which may be the db of another peer opened with db.OpenDBReadOnly
+func FetchStateDeltaFromOpenchainDB(openchainDB *db.OpenchainDB, blockNumber uint64) (*statemgmt.StateDelta, error) {
+	stateDeltaBytes, err := openchainDB.GetFromStateDeltaCF(encodeStateDeltaKey(blockNumber))
 	if err != nil {
 		return nil, err
 	}
@@ -254,6 +284,21 @@ func (state *State) AddChangesForPersistence(blockNumber uint64, writeBatch *gor
 	logger.Debug("state.addChangesForPersistence()...finished")
 }
 
+// PutStateDelta stores the stateDelta corresponding to given blockNumber, unless the state
+// deltas kept for the blocks up to lastBlockNumber do not include it. This is to be used
+// when importing the history of a blockchain
+func (state *State) PutStateDelta(blockNumber uint64, lastBlockNumber uint64, stateDelta *statemgmt.StateDelta) error {
+	if lastBlockNumber-blockNumber >= state.historyStateDeltaSize {
+		logger.Debug("Not putting state-delta corresponding to block number[%d]. It is older than historyStateDeltaSize [%d]",
+			blockNumber, state.historyStateDeltaSize)
+		return nil
+	}
+	writeBatch := gorocksdb.NewWriteBatch()
+	writeBatch.PutCF(db.GetDBHandle().StateDeltaCF, encodeStateDeltaKey(blockNumber), stateDelta.Marshal())
+	opt := gorocksdb.NewDefaultWriteOptions()
+	return db.GetDBHandle().DB.Write(opt, writeBatch)
+}
+
 // ApplyStateDelta applies already prepared stateDelta to the existing state.
 // This is an in memory change only. state.CommitStateDelta must be used to
 // commit the state to the DB. This method is to be used in state transfer.
@@ -140,6 +140,54 @@ func (stateDelta *StateDelta) GetUpdates(chaincodeID string) map[string]*Updated
 	return chaincodeStateDelta.updatedKVs
 }
 
+// StateDeltaDiff holds the updates of a key that differ between two state deltas.
+// The update of a delta that does not update the key is nil
+type StateDeltaDiff struct {
+	ChaincodeID       string
+	Key               string
+	UpdatedValue      *UpdatedValue
+	OtherUpdatedValue *UpdatedValue
+}
+
+// Diff returns the keys whose updates differ between the delta and anotherStateDelta,
+// in lexicographical sorted order of chaincodeIDs and keys. Previous values are not compared
+func (stateDelta *StateDelta) Diff(anotherStateDelta *StateDelta) []*StateDeltaDiff {
+	var diffs []*StateDeltaDiff
+	for _, chaincodeID := range sortedUnion(stateDelta.GetUpdatedChaincodeIds(false), anotherStateDelta.GetUpdatedChaincodeIds(false)) {
+		var keys, otherKeys []string
+		if chaincodeStateDelta, ok := stateDelta.chaincodeStateDeltas[chaincodeID]; ok {
+			keys = chaincodeStateDelta.getSortedKeys()
+		}
+		if chaincodeStateDelta, ok := anotherStateDelta.chaincodeStateDeltas[chaincodeID]; ok {
+			otherKeys = chaincodeStateDelta.getSortedKeys()
+		}
+		for _, key := range sortedUnion(keys, otherKeys) {
+			updatedValue := stateDelta.Get(chaincodeID, key)
+			otherUpdatedValue := anotherStateDelta.Get(chaincodeID, key)
+			if !updatedValue.equals(otherUpdatedValue) {
+				diffs = append(diffs, &StateDeltaDiff{chaincodeID, key, updatedValue, otherUpdatedValue})
+			}
+		}
+	}
+	return diffs
+}
+
+func sortedUnion(a []string, b []string) []string {
+	set := make(map[string]bool)
+	for _, s := range a {
+		set[s] = true
+	}
+	for _, s := range b {
+		set[s] = true
+	}
+	union := make([]string, 0, len(set))
+	for s := range set {
+		union = append(union, s)
+	}
+	sort.Strings(union)
+	return union
+}
+
 func (stateDelta *StateDelta) getOrCreateChaincodeStateDelta(chaincodeID string) *chaincodeStateDelta {
 	chaincodeStateDelta, ok := stateDelta.chaincodeStateDeltas[chaincodeID]
 	if !ok {
@@ -246,6 +294,14 @@ func (updatedValue *UpdatedValue) GetPreviousValue() []byte {
 	return updatedValue.previousValue
 }
 
+func (updatedValue *UpdatedValue) equals(anotherUpdatedValue *UpdatedValue) bool {
+	if updatedValue == nil || anotherUpdatedValue == nil {
+		return updatedValue == anotherUpdatedValue
+	}
+	return updatedValue.IsDelete() == anotherUpdatedValue.IsDelete() &&
+		bytes.Equal(updatedValue.value, anotherUpdatedValue.value)
+}
+
 // marshalling / Unmarshalling code
 // We need to revisit the following when we define proto messages
 // for state related structures for transporting. May be we can
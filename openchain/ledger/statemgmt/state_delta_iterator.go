@@ -0,0 +1,72 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package statemgmt
+
+import (
+	"sort"
+)
+
+// StateDeltaIterator implements the interface 'RangeScanIterator' over the changes
+// held in a StateDelta for a single chaincode. Deleted keys are returned with a nil value
+// so that the caller can hide the corresponding keys present in the db.
+type StateDeltaIterator struct {
+	keys         []string
+	values       [][]byte
+	currentIndex int
+}
+
+// NewStateDeltaRangeScanIterator returns an iterator over the keys of the given chaincodeID in the stateDelta
+// that fall in the range [startKey, endKey]. An empty startKey (or endKey) leaves that end of the range open.
+// The iterator works on a copy of the changes so it is not affected by further updates to the stateDelta.
+func NewStateDeltaRangeScanIterator(stateDelta *StateDelta, chaincodeID string, startKey string, endKey string) *StateDeltaIterator {
+	itr := &StateDeltaIterator{currentIndex: -1}
+	updates := stateDelta.GetUpdates(chaincodeID)
+	for key := range updates {
+		if IsKeyInRange(key, startKey, endKey) {
+			itr.keys = append(itr.keys, key)
+		}
+	}
+	sort.Strings(itr.keys)
+	for _, key := range itr.keys {
+		itr.values = append(itr.values, updates[key].GetValue())
+	}
+	return itr
+}
+
+// Next - see interface 'RangeScanIterator' for details
+func (itr *StateDeltaIterator) Next() bool {
+	if itr.currentIndex+1 >= len(itr.keys) {
+		itr.currentIndex = len(itr.keys)
+		return false
+	}
+	itr.currentIndex++
+	return true
+}
+
+// GetKeyValue - see interface 'RangeScanIterator' for details
+func (itr *StateDeltaIterator) GetKeyValue() (string, []byte) {
+	return itr.keys[itr.currentIndex], itr.values[itr.currentIndex]
+}
+
+// Close - see interface 'RangeScanIterator' for details
+func (itr *StateDeltaIterator) Close() {
+	itr.keys = nil
+	itr.values = nil
+}
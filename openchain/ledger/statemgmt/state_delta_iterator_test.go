@@ -0,0 +1,61 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package statemgmt
+
+import (
+	"testing"
+
+	"github.com/openblockchain/obc-peer/openchain/ledger/testutil"
+)
+
+func TestStateDeltaRangeScanIterator(t *testing.T) {
+	stateDelta := NewStateDelta()
+	stateDelta.Set("chaincodeID1", "key3", []byte("value3"), nil)
+	stateDelta.Set("chaincodeID1", "key1", []byte("value1"), nil)
+	stateDelta.Delete("chaincodeID1", "key2", nil)
+	stateDelta.Set("chaincodeID1", "key4", []byte("value4"), nil)
+	stateDelta.Set("chaincodeID2", "key2", []byte("value2"), nil)
+
+	itr := NewStateDeltaRangeScanIterator(stateDelta, "chaincodeID1", "key2", "")
+	// changes made after creating the iterator are not visible
+	stateDelta.Set("chaincodeID1", "key5", []byte("value5"), nil)
+
+	testutil.AssertEquals(t, itr.Next(), true)
+	key, value := itr.GetKeyValue()
+	testutil.AssertEquals(t, key, "key2")
+	testutil.AssertNil(t, value)
+
+	testutil.AssertEquals(t, itr.Next(), true)
+	key, value = itr.GetKeyValue()
+	testutil.AssertEquals(t, key, "key3")
+	testutil.AssertEquals(t, value, []byte("value3"))
+
+	testutil.AssertEquals(t, itr.Next(), true)
+	key, value = itr.GetKeyValue()
+	testutil.AssertEquals(t, key, "key4")
+	testutil.AssertEquals(t, value, []byte("value4"))
+
+	testutil.AssertEquals(t, itr.Next(), false)
+	testutil.AssertEquals(t, itr.Next(), false)
+	itr.Close()
+
+	itr = NewStateDeltaRangeScanIterator(stateDelta, "chaincodeID3", "", "")
+	testutil.AssertEquals(t, itr.Next(), false)
+}
@@ -53,3 +53,29 @@ func TestStateDeltaCryptoHash(t *testing.T) {
 	stateDelta.Delete("chaincodeID2", "key1", nil)
 	testutil.AssertEquals(t, stateDelta.ComputeCryptoHash(), testutil.ComputeCryptoHash([]byte("chaincodeID1key1value1key2value2chaincodeID2key1key2value2")))
 }
+
+func TestStateDeltaDiff(t *testing.T) {
+	stateDelta := NewStateDelta()
+	stateDelta.Set("chaincodeID1", "key1", []byte("value1"), nil)
+	stateDelta.Set("chaincodeID1", "key2", []byte("value2"), nil)
+	stateDelta.Delete("chaincodeID2", "key1", []byte("value1"))
+
+	anotherStateDelta := NewStateDelta()
+	anotherStateDelta.Set("chaincodeID1", "key1", []byte("value1"), []byte("previousValue"))
+	anotherStateDelta.Set("chaincodeID1", "key2", []byte("value2_other"), nil)
+	anotherStateDelta.Delete("chaincodeID2", "key1", []byte("value1"))
+	anotherStateDelta.Set("chaincodeID3", "key1", []byte("value1"), nil)
+
+	testutil.AssertEquals(t, len(stateDelta.Diff(stateDelta)), 0)
+
+	diffs := stateDelta.Diff(anotherStateDelta)
+	testutil.AssertEquals(t, len(diffs), 2)
+	testutil.AssertEquals(t, diffs[0].ChaincodeID, "chaincodeID1")
+	testutil.AssertEquals(t, diffs[0].Key, "key2")
+	testutil.AssertEquals(t, diffs[0].UpdatedValue.GetValue(), []byte("value2"))
+	testutil.AssertEquals(t, diffs[0].OtherUpdatedValue.GetValue(), []byte("value2_other"))
+	testutil.AssertEquals(t, diffs[1].ChaincodeID, "chaincodeID3")
+	testutil.AssertEquals(t, diffs[1].Key, "key1")
+	testutil.AssertNil(t, diffs[1].UpdatedValue)
+	testutil.AssertEquals(t, diffs[1].OtherUpdatedValue.GetValue(), []byte("value1"))
+}
@@ -0,0 +1,250 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package statemgmt
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/openblockchain/obc-peer/openchain/util"
+	"github.com/openblockchain/obc-peer/protos"
+)
+
+// StateProof proves that a key-value belongs to the state whose crypto-hash is
+// recorded in Block.StateHash. It holds the nodes on the path from the node that
+// contains the key-value up to the root of the state implementation (bucket-tree or trie).
+// Both implementations compute the crypto-hash of a node the same way: the key-values
+// of the node followed by the crypto-hashes of its children in the order of their index.
+// A node without key-values and with a single child propagates the crypto-hash of the
+// child up, an empty node has a nil crypto-hash.
+type StateProof struct {
+	Nodes []*StateProofNode
+}
+
+// StateProofNode is a node on the path of a proof. The crypto-hash of the child that
+// lies on the path (ChildIndex) is not included in ChildrenCryptoHashes, the verifier
+// computes it from the previous node of the proof. ChildIndex is ignored for the first node.
+type StateProofNode struct {
+	KeyValues            []*StateProofKeyValue
+	ChildrenCryptoHashes map[int][]byte
+	ChildIndex           int
+}
+
+// StateProofKeyValue is a key-value held by a node of a proof. Key is the composite key
+// (see ConstructCompositeKey) of the key-value.
+type StateProofKeyValue struct {
+	Key   []byte
+	Value []byte
+}
+
+// NewStateProofNode constructs a new StateProofNode
+func NewStateProofNode() *StateProofNode {
+	return &StateProofNode{ChildrenCryptoHashes: make(map[int][]byte)}
+}
+
+// AddKeyValue appends a key-value to the node. Key-values have to be added in the order
+// in which the state implementation includes them in the crypto-hash of the node.
+func (node *StateProofNode) AddKeyValue(key []byte, value []byte) {
+	node.KeyValues = append(node.KeyValues, &StateProofKeyValue{key, value})
+}
+
+func (node *StateProofNode) computeCryptoHash(childCryptoHash []byte) []byte {
+	var cryptoHashContent []byte
+	for _, keyValue := range node.KeyValues {
+		cryptoHashContent = append(cryptoHashContent, keyValue.Key...)
+		cryptoHashContent = append(cryptoHashContent, keyValue.Value...)
+	}
+
+	childrenCryptoHashes := make(map[int][]byte)
+	for index, cryptoHash := range node.ChildrenCryptoHashes {
+		if len(cryptoHash) != 0 {
+			childrenCryptoHashes[index] = cryptoHash
+		}
+	}
+	if childCryptoHash != nil {
+		childrenCryptoHashes[node.ChildIndex] = childCryptoHash
+	}
+	for _, index := range sortedIndexes(childrenCryptoHashes) {
+		cryptoHashContent = append(cryptoHashContent, childrenCryptoHashes[index]...)
+	}
+
+	if len(cryptoHashContent) == 0 {
+		return nil
+	}
+	if len(node.KeyValues) == 0 && len(childrenCryptoHashes) == 1 {
+		return cryptoHashContent
+	}
+	return util.ComputeCryptoHash(cryptoHashContent)
+}
+
+// VerifyStateProof checks that the value of the key for the chaincodeID belongs to the
+// state with the given crypto-hash, typically the StateHash of a block. This needs neither
+// the ledger nor the db, so a client can run it against a block header it trusts.
+// A nil error is returned if the proof is valid.
+func VerifyStateProof(stateHash []byte, chaincodeID string, key string, value []byte, proof *StateProof) error {
+	if proof == nil || len(proof.Nodes) == 0 {
+		return fmt.Errorf("Empty proof for key [%s] of chaincode [%s]", key, chaincodeID)
+	}
+	if len(value) == 0 {
+		return fmt.Errorf("Only the presence of a key can be proved, no value given for key [%s] of chaincode [%s]", key, chaincodeID)
+	}
+
+	compositeKey := ConstructCompositeKey(chaincodeID, key)
+	found := false
+	for _, keyValue := range proof.Nodes[0].KeyValues {
+		if bytes.Equal(keyValue.Key, compositeKey) {
+			if !bytes.Equal(keyValue.Value, value) {
+				return fmt.Errorf("Proof holds a different value for key [%s] of chaincode [%s]", key, chaincodeID)
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Proof does not hold key [%s] of chaincode [%s]", key, chaincodeID)
+	}
+
+	var cryptoHash []byte
+	for _, node := range proof.Nodes {
+		cryptoHash = node.computeCryptoHash(cryptoHash)
+	}
+	if !bytes.Equal(cryptoHash, stateHash) {
+		return fmt.Errorf("Proof leads to state hash [%x] instead of [%x]", cryptoHash, stateHash)
+	}
+	return nil
+}
+
+// Marshal serializes the StateProof
+func (proof *StateProof) Marshal() []byte {
+	buffer := proto.NewBuffer([]byte{})
+	buffer.EncodeVarint(uint64(len(proof.Nodes)))
+	for _, node := range proof.Nodes {
+		buffer.EncodeVarint(uint64(len(node.KeyValues)))
+		for _, keyValue := range node.KeyValues {
+			buffer.EncodeRawBytes(keyValue.Key)
+			buffer.EncodeRawBytes(keyValue.Value)
+		}
+		buffer.EncodeVarint(uint64(len(node.ChildrenCryptoHashes)))
+		for _, index := range sortedIndexes(node.ChildrenCryptoHashes) {
+			buffer.EncodeVarint(uint64(index))
+			buffer.EncodeRawBytes(node.ChildrenCryptoHashes[index])
+		}
+		buffer.EncodeVarint(uint64(node.ChildIndex))
+	}
+	return buffer.Bytes()
+}
+
+// Unmarshal deserializes the StateProof. Unlike a StateDelta, a proof usually comes
+// from an untrusted source, so malformed bytes are reported as an error.
+func (proof *StateProof) Unmarshal(b []byte) error {
+	buffer := proto.NewBuffer(b)
+	numNodes, err := buffer.DecodeVarint()
+	if err != nil {
+		return fmt.Errorf("Error unmarshalling state proof: %s", err)
+	}
+	proof.Nodes = nil
+	for i := uint64(0); i < numNodes; i++ {
+		node := NewStateProofNode()
+		numKeyValues, err := buffer.DecodeVarint()
+		if err != nil {
+			return fmt.Errorf("Error unmarshalling state proof: %s", err)
+		}
+		for j := uint64(0); j < numKeyValues; j++ {
+			key, err := buffer.DecodeRawBytes(true)
+			if err != nil {
+				return fmt.Errorf("Error unmarshalling state proof: %s", err)
+			}
+			value, err := buffer.DecodeRawBytes(true)
+			if err != nil {
+				return fmt.Errorf("Error unmarshalling state proof: %s", err)
+			}
+			node.AddKeyValue(key, value)
+		}
+		numChildren, err := buffer.DecodeVarint()
+		if err != nil {
+			return fmt.Errorf("Error unmarshalling state proof: %s", err)
+		}
+		for j := uint64(0); j < numChildren; j++ {
+			index, err := buffer.DecodeVarint()
+			if err != nil {
+				return fmt.Errorf("Error unmarshalling state proof: %s", err)
+			}
+			cryptoHash, err := buffer.DecodeRawBytes(true)
+			if err != nil {
+				return fmt.Errorf("Error unmarshalling state proof: %s", err)
+			}
+			node.ChildrenCryptoHashes[int(index)] = cryptoHash
+		}
+		childIndex, err := buffer.DecodeVarint()
+		if err != nil {
+			return fmt.Errorf("Error unmarshalling state proof: %s", err)
+		}
+		node.ChildIndex = int(childIndex)
+		proof.Nodes = append(proof.Nodes, node)
+	}
+	return nil
+}
+
+// ToProtoNodes converts the StateProof to the nodes of a protos.StateValueWithProof,
+// the wire format of proofs served by the API
+func (proof *StateProof) ToProtoNodes() []*protos.StateProofNode {
+	nodes := make([]*protos.StateProofNode, 0, len(proof.Nodes))
+	for _, node := range proof.Nodes {
+		protoNode := &protos.StateProofNode{ChildIndex: uint32(node.ChildIndex)}
+		for _, keyValue := range node.KeyValues {
+			protoNode.KeyValues = append(protoNode.KeyValues, &protos.StateProofKeyValue{Key: keyValue.Key, Value: keyValue.Value})
+		}
+		for _, index := range sortedIndexes(node.ChildrenCryptoHashes) {
+			protoNode.ChildrenCryptoHashes = append(protoNode.ChildrenCryptoHashes,
+				&protos.StateProofChildHash{Index: uint32(index), CryptoHash: node.ChildrenCryptoHashes[index]})
+		}
+		nodes = append(nodes, protoNode)
+	}
+	return nodes
+}
+
+// StateProofFromProtoNodes converts the nodes of a protos.StateValueWithProof back to a
+// StateProof that can be checked with VerifyStateProof
+func StateProofFromProtoNodes(protoNodes []*protos.StateProofNode) *StateProof {
+	proof := &StateProof{}
+	for _, protoNode := range protoNodes {
+		node := NewStateProofNode()
+		for _, keyValue := range protoNode.GetKeyValues() {
+			node.AddKeyValue(keyValue.Key, keyValue.Value)
+		}
+		for _, childHash := range protoNode.GetChildrenCryptoHashes() {
+			node.ChildrenCryptoHashes[int(childHash.Index)] = childHash.CryptoHash
+		}
+		node.ChildIndex = int(protoNode.ChildIndex)
+		proof.Nodes = append(proof.Nodes, node)
+	}
+	return proof
+}
+
+func sortedIndexes(m map[int][]byte) []int {
+	indexes := make([]int, 0, len(m))
+	for index := range m {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
@@ -0,0 +1,92 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package statemgmt
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/openblockchain/obc-peer/openchain/ledger/testutil"
+	"github.com/openblockchain/obc-peer/protos"
+)
+
+// buildTestStateProof builds the proof for chaincodeID1/key1 in a bucket-tree with
+// buckets 26,9,3,1 at each level. The lowest-level bucket 1 holds key1, key2, and key3
+// and the lowest-level bucket 4 holds key4.
+func buildTestStateProof() (*StateProof, []byte) {
+	hashBucket3_1 := testutil.ComputeCryptoHash(ConstructCompositeKey("chaincodeID1", "key1"), []byte("value1"),
+		ConstructCompositeKey("chaincodeID2", "key2"), []byte("value2"),
+		ConstructCompositeKey("chaincodeID3", "key3"), []byte("value3"))
+	hashBucket3_4 := testutil.ComputeCryptoHash(ConstructCompositeKey("chaincodeID4", "key4"), []byte("value4"))
+	rootHash := testutil.ComputeCryptoHash(hashBucket3_1, hashBucket3_4)
+
+	proof := &StateProof{}
+	leafNode := NewStateProofNode()
+	leafNode.AddKeyValue(ConstructCompositeKey("chaincodeID1", "key1"), []byte("value1"))
+	leafNode.AddKeyValue(ConstructCompositeKey("chaincodeID2", "key2"), []byte("value2"))
+	leafNode.AddKeyValue(ConstructCompositeKey("chaincodeID3", "key3"), []byte("value3"))
+	proof.Nodes = append(proof.Nodes, leafNode)
+	proof.Nodes = append(proof.Nodes, NewStateProofNode())
+	proof.Nodes = append(proof.Nodes, NewStateProofNode())
+	rootNode := NewStateProofNode()
+	rootNode.ChildrenCryptoHashes[1] = hashBucket3_4
+	proof.Nodes = append(proof.Nodes, rootNode)
+	return proof, rootHash
+}
+
+func TestStateProofVerify(t *testing.T) {
+	proof, rootHash := buildTestStateProof()
+	testutil.AssertNoError(t, VerifyStateProof(rootHash, "chaincodeID1", "key1", []byte("value1"), proof), "Expected a valid proof")
+	testutil.AssertNoError(t, VerifyStateProof(rootHash, "chaincodeID3", "key3", []byte("value3"), proof), "Expected a valid proof")
+
+	testutil.AssertError(t, VerifyStateProof(rootHash, "chaincodeID1", "key1", []byte("value2"), proof), "Expected an error for a wrong value")
+	testutil.AssertError(t, VerifyStateProof(rootHash, "chaincodeID4", "key4", []byte("value4"), proof), "Expected an error for a key not in the proof")
+	testutil.AssertError(t, VerifyStateProof([]byte("otherHash"), "chaincodeID1", "key1", []byte("value1"), proof), "Expected an error for a wrong state hash")
+	testutil.AssertError(t, VerifyStateProof(rootHash, "chaincodeID1", "key1", nil, proof), "Expected an error for a nil value")
+	testutil.AssertError(t, VerifyStateProof(rootHash, "chaincodeID1", "key1", []byte("value1"), nil), "Expected an error for a nil proof")
+
+	// Tamper with a key-value that is not proved
+	proof.Nodes[0].KeyValues[1].Value = []byte("value2_tampered")
+	testutil.AssertError(t, VerifyStateProof(rootHash, "chaincodeID1", "key1", []byte("value1"), proof), "Expected an error for a tampered proof")
+}
+
+func TestStateProofMarshalling(t *testing.T) {
+	proof, rootHash := buildTestStateProof()
+	proof1 := &StateProof{}
+	err := proof1.Unmarshal(proof.Marshal())
+	testutil.AssertNoError(t, err, "Error while unmarshalling state proof")
+	testutil.AssertEquals(t, proof1, proof)
+	testutil.AssertNoError(t, VerifyStateProof(rootHash, "chaincodeID1", "key1", []byte("value1"), proof1), "Expected a valid proof")
+
+	err = proof1.Unmarshal([]byte{5, 1})
+	testutil.AssertError(t, err, "Expected an error for malformed bytes")
+}
+
+func TestStateProofProtoConversion(t *testing.T) {
+	proof, rootHash := buildTestStateProof()
+	b, err := proto.Marshal(&protos.StateValueWithProof{Value: []byte("value1"), StateHash: rootHash, Nodes: proof.ToProtoNodes()})
+	testutil.AssertNoError(t, err, "Error while marshalling state value with proof")
+
+	stateValue := &protos.StateValueWithProof{}
+	testutil.AssertNoError(t, proto.Unmarshal(b, stateValue), "Error while unmarshalling state value with proof")
+	proof1 := StateProofFromProtoNodes(stateValue.Nodes)
+	testutil.AssertEquals(t, proof1, proof)
+	testutil.AssertNoError(t, VerifyStateProof(stateValue.StateHash, "chaincodeID1", "key1", stateValue.Value, proof1), "Expected a valid proof")
+}
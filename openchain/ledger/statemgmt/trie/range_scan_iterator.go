@@ -0,0 +1,82 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package trie
+
+import (
+	"bytes"
+
+	"github.com/openblockchain/obc-peer/openchain/db"
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+	"github.com/openblockchain/obc-peer/openchain/ledger/util"
+	"github.com/tecbot/gorocksdb"
+)
+
+// RangeScanIterator implements the interface 'statemgmt.RangeScanIterator'
+type RangeScanIterator struct {
+	dbItr           *gorocksdb.Iterator
+	chaincodePrefix []byte
+	endKey          string
+	currentKey      string
+	currentValue    []byte
+}
+
+// newRangeScanIterator relies on the trie keys being stored in the lexical order of the composite keys,
+// so the db iterator can be positioned directly at the beginning of the range
+func newRangeScanIterator(chaincodeID string, startKey string, endKey string) (*RangeScanIterator, error) {
+	dbItr := db.GetDBHandle().GetStateCFIterator()
+	dbItr.Seek(newTrieKey(chaincodeID, startKey).getEncodedBytes())
+	chaincodePrefix := statemgmt.ConstructCompositeKey(chaincodeID, "")
+	return &RangeScanIterator{dbItr, chaincodePrefix, endKey, "", nil}, nil
+}
+
+// Next - see interface 'statemgmt.RangeScanIterator' for details
+func (itr *RangeScanIterator) Next() bool {
+	for ; itr.dbItr.Valid(); itr.dbItr.Next() {
+		compositeKey := trieKeyEncoderImpl.decodeTrieKeyBytes(statemgmt.Copy(itr.dbItr.Key().Data()))
+		if !bytes.HasPrefix(compositeKey, itr.chaincodePrefix) {
+			break
+		}
+		key := string(compositeKey[len(itr.chaincodePrefix):])
+		if itr.endKey != "" && key > itr.endKey {
+			break
+		}
+		// intermediate trie nodes do not carry a value
+		value := unmarshalTrieNodeValue(itr.dbItr.Value().Data())
+		if util.NotNil(value) {
+			itr.currentKey = key
+			itr.currentValue = value
+			itr.dbItr.Next()
+			return true
+		}
+	}
+	itr.currentKey = ""
+	itr.currentValue = nil
+	return false
+}
+
+// GetKeyValue - see interface 'statemgmt.RangeScanIterator' for details
+func (itr *RangeScanIterator) GetKeyValue() (string, []byte) {
+	return itr.currentKey, itr.currentValue
+}
+
+// Close - see interface 'statemgmt.RangeScanIterator' for details
+func (itr *RangeScanIterator) Close() {
+	itr.dbItr.Close()
+}
@@ -0,0 +1,80 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package trie
+
+import (
+	"testing"
+
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+	"github.com/openblockchain/obc-peer/openchain/ledger/testutil"
+)
+
+func TestRangeScanIterator(t *testing.T) {
+	testDBWrapper.CreateFreshDB(t)
+	stateTrieTestWrapper := newStateTrieTestWrapper(t)
+	stateTrie := stateTrieTestWrapper.stateTrie
+	stateDelta := statemgmt.NewStateDelta()
+
+	// insert keys
+	stateDelta.Set("chaincodeID1", "key1", []byte("value1"), nil)
+	stateDelta.Set("chaincodeID2", "key4", []byte("value4"), nil)
+	stateDelta.Set("chaincodeID2", "key2", []byte("value2"), nil)
+	stateDelta.Set("chaincodeID2", "key5", []byte("value5"), nil)
+	stateDelta.Set("chaincodeID2", "key3", []byte("value3"), nil)
+	stateDelta.Set("chaincodeID2", "key1", []byte("value1"), nil)
+	stateDelta.Set("chaincodeID3", "key1", []byte("value1"), nil)
+	stateTrie.PrepareWorkingSet(stateDelta)
+	stateTrieTestWrapper.PersistChangesAndResetInMemoryChanges()
+
+	// test range scan for chaincodeID2
+	itr, err := stateTrie.GetRangeScanIterator("chaincodeID2", "key2", "key4")
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertRangeScanIteratorResults(t, itr, []string{"key2", "key3", "key4"})
+
+	// test range scan with open ends
+	itr, err = stateTrie.GetRangeScanIterator("chaincodeID2", "", "key2")
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertRangeScanIteratorResults(t, itr, []string{"key1", "key2"})
+
+	itr, err = stateTrie.GetRangeScanIterator("chaincodeID2", "key4", "")
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertRangeScanIteratorResults(t, itr, []string{"key4", "key5"})
+
+	itr, err = stateTrie.GetRangeScanIterator("chaincodeID2", "", "")
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertRangeScanIteratorResults(t, itr, []string{"key1", "key2", "key3", "key4", "key5"})
+
+	// test range scan for a chaincodeID that does not exist
+	itr, err = stateTrie.GetRangeScanIterator("chaincodeID4", "", "")
+	testutil.AssertNoError(t, err, "Error while getting range scan iterator")
+	assertRangeScanIteratorResults(t, itr, []string{})
+}
+
+func assertRangeScanIteratorResults(t *testing.T, itr statemgmt.RangeScanIterator, expectedKeys []string) {
+	defer itr.Close()
+	keys := []string{}
+	for itr.Next() {
+		key, value := itr.GetKeyValue()
+		t.Logf("key=[%s], value=[%s]", key, string(value))
+		testutil.AssertEquals(t, string(value), "value"+key[len("key"):])
+		keys = append(keys, key)
+	}
+	testutil.AssertEquals(t, keys, expectedKeys)
+}
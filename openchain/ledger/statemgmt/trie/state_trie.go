@@ -64,6 +64,52 @@ func (stateTrie *StateTrie) Get(chaincodeID string, key string) ([]byte, error)
 	return trieNode.value, nil
 }
 
+// GetWithProof - method implementation for interface 'statemgmt.HashableState'
+func (stateTrie *StateTrie) GetWithProof(chaincodeID string, key string) ([]byte, *statemgmt.StateProof, error) {
+	trieNode, err := fetchTrieNodeFromDB(newTrieKey(chaincodeID, key))
+	if err != nil {
+		return nil, nil, err
+	}
+	if trieNode == nil || !trieNode.containsValue() {
+		return nil, nil, nil
+	}
+	value := trieNode.value
+
+	// Walk up from the node of the key to the root, leaving out the crypto-hash of
+	// the child on the path at each level
+	proof := &statemgmt.StateProof{}
+	childIndex := -1
+	for {
+		proofNode := statemgmt.NewStateProofNode()
+		if trieNode.containsValue() {
+			proofNode.AddKeyValue(trieNode.trieKey.getEncodedBytes(), trieNode.value)
+		}
+		for index, childCryptoHash := range trieNode.childrenCryptoHashes {
+			if index != childIndex {
+				proofNode.ChildrenCryptoHashes[index] = childCryptoHash
+			}
+		}
+		if childIndex >= 0 {
+			proofNode.ChildIndex = childIndex
+		}
+		proof.Nodes = append(proof.Nodes, proofNode)
+		if trieNode.isRootNode() {
+			break
+		}
+
+		childIndex = trieNode.getIndexInParent()
+		parentTrieKey := trieNode.getParentTrieKey()
+		trieNode, err = fetchTrieNodeFromDB(parentTrieKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		if trieNode == nil {
+			return nil, nil, fmt.Errorf("Trie node [%x] is missing from DB", parentTrieKey.getEncodedBytes())
+		}
+	}
+	return value, proof, nil
+}
+
 func (stateTrie *StateTrie) PrepareWorkingSet(stateDelta *statemgmt.StateDelta) error {
 	stateTrie.trieDelta = newTrieDelta(stateDelta)
 	stateTrie.recomputeCryptoHash = true
@@ -175,3 +221,8 @@ func (stateTrie *StateTrie) PerfHintKeyChanged(chaincodeID string, key string) {
 func (stateTrie *StateTrie) GetStateSnapshotIterator(snapshot *gorocksdb.Snapshot) (statemgmt.StateSnapshotIterator, error) {
 	return newStateSnapshotIterator(snapshot)
 }
+
+// GetRangeScanIterator - method implementation for interface 'statemgmt.HashableState'
+func (stateTrie *StateTrie) GetRangeScanIterator(chaincodeID string, startKey string, endKey string) (statemgmt.RangeScanIterator, error) {
+	return newRangeScanIterator(chaincodeID, startKey, endKey)
+}
@@ -237,3 +237,33 @@ func TestStateTrie_ComputeHash_WithDB_Staggered_Keys(t *testing.T) {
 	rootHash5 := stateTrieTestWrapper.PrepareWorkingSetAndComputeCryptoHash(stateDelta)
 	testutil.AssertEquals(t, rootHash5, expectedHash_k)
 }
+
+func TestStateTrie_GetWithProof(t *testing.T) {
+	testDBWrapper.CreateFreshDB(t)
+	stateTrie := NewStateTrie()
+	stateTrieTestWrapper := &stateTrieTestWrapper{stateTrie, t}
+	stateDelta := statemgmt.NewStateDelta()
+	// key1 is a prefix of key10, so the node of key1 holds a value and a child
+	stateDelta.Set("chaincodeID1", "key1", []byte("value1"), nil)
+	stateDelta.Set("chaincodeID1", "key10", []byte("value10"), nil)
+	stateDelta.Set("chaincodeID1", "key2", []byte("value2"), nil)
+	stateDelta.Set("chaincodeID2", "key3", []byte("value3"), nil)
+	rootHash := stateTrieTestWrapper.PrepareWorkingSetAndComputeCryptoHash(stateDelta)
+	stateTrieTestWrapper.PersistChangesAndResetInMemoryChanges()
+
+	for _, kv := range [][]string{{"chaincodeID1", "key1", "value1"}, {"chaincodeID1", "key10", "value10"},
+		{"chaincodeID1", "key2", "value2"}, {"chaincodeID2", "key3", "value3"}} {
+		value, proof, err := stateTrie.GetWithProof(kv[0], kv[1])
+		testutil.AssertNoError(t, err, "Error while getting value with proof")
+		testutil.AssertEquals(t, value, []byte(kv[2]))
+		testutil.AssertEquals(t, len(proof.Nodes), len(statemgmt.ConstructCompositeKey(kv[0], kv[1]))+1)
+		testutil.AssertNoError(t, statemgmt.VerifyStateProof(rootHash, kv[0], kv[1], value, proof), "Expected a valid proof")
+		testutil.AssertError(t, statemgmt.VerifyStateProof(rootHash, kv[0], kv[1], []byte("otherValue"), proof), "Expected an invalid proof")
+	}
+
+	// An intermediate node without a value can not be proved
+	value, proof, err := stateTrie.GetWithProof("chaincodeID1", "key")
+	testutil.AssertNoError(t, err, "Error while getting value with proof")
+	testutil.AssertNil(t, value)
+	testutil.AssertNil(t, proof)
+}
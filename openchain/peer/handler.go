@@ -27,7 +27,10 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/looplab/fsm"
 	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
 
+	"github.com/openblockchain/obc-peer/openchain/crypto"
 	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
 	pb "github.com/openblockchain/obc-peer/protos"
 )
@@ -171,6 +174,12 @@ func (d *Handler) beforeHello(e *fsm.Event) {
 		e.Cancel(fmt.Errorf("Error unmarshalling HelloMessage: %s", err))
 		return
 	}
+	if secHelper := d.Coordinator.GetSecHelper(); isPeerAuthenticationEnabled(secHelper) {
+		if err := verifyHelloMessage(secHelper, helloMessage, getRemoteTLSCertificate(d.ChatStream)); err != nil {
+			e.Cancel(fmt.Errorf("Error authenticating HelloMessage from %s: %s", helloMessage.PeerEndpoint, err))
+			return
+		}
+	}
 	// Store the PeerEndpoint
 	d.ToPeerEndpoint = helloMessage.PeerEndpoint
 	peerLogger.Debug("Received %s from endpoint=%s", e.Event, helloMessage)
@@ -433,6 +442,13 @@ func (d *Handler) sendBlocks(syncBlockRange *pb.SyncBlockRange) {
 			peerLogger.Error(fmt.Sprintf("Error sending blockNum %d: %s", currBlockNum, err))
 			break
 		}
+		if currBlockNum < syncBlockRange.PruneBelow {
+			block, err = block.Prune()
+			if err != nil {
+				peerLogger.Error(fmt.Sprintf("Error pruning blockNum %d: %s", currBlockNum, err))
+				break
+			}
+		}
 		// Encode a SyncBlocks into the payload
 		syncBlocks := &pb.SyncBlocks{Range: &pb.SyncBlockRange{Start: currBlockNum, End: currBlockNum}, Blocks: []*pb.Block{block}}
 		syncBlocksBytes, err := proto.Marshal(syncBlocks)
@@ -580,3 +596,39 @@ func (d *Handler) sendStateSnapshot(syncStateSnapshotRequest *pb.SyncStateSnapsh
 	}
 
 }
+
+// verifyHelloMessage checks that the sender of helloMessage, authenticated by tlsCert, holds the
+// enrollment certificate of the PeerID it claims
+func verifyHelloMessage(secHelper crypto.Peer, helloMessage *pb.HelloMessage, tlsCert []byte) error {
+	if helloMessage.PeerEndpoint == nil || helloMessage.PeerEndpoint.ID == nil {
+		return fmt.Errorf("Missing PeerEndpoint")
+	}
+	cert, signature := helloMessage.Cert, helloMessage.Signature
+	helloMessage.Cert, helloMessage.Signature = nil, nil
+	data, err := proto.Marshal(helloMessage)
+	helloMessage.Cert, helloMessage.Signature = cert, signature
+	if err != nil {
+		return fmt.Errorf("Error marshalling HelloMessage: %s", err)
+	}
+	return secHelper.VerifyHello(helloMessage.PeerEndpoint.ID.Name, cert, tlsCert, data, signature)
+}
+
+// getRemoteTLSCertificate returns the certificate the remote end of stream presented during the
+// TLS handshake, or nil if it did not present one
+func getRemoteTLSCertificate(stream ChatStream) []byte {
+	s, ok := stream.(interface {
+		Context() context.Context
+	})
+	if !ok {
+		return nil
+	}
+	authInfo, ok := credentials.FromContext(s.Context())
+	if !ok {
+		return nil
+	}
+	tlsInfo, ok := authInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return nil
+	}
+	return tlsInfo.State.PeerCertificates[0].Raw
+}
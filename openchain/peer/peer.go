@@ -20,6 +20,7 @@ under the License.
 package peer
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -57,6 +58,17 @@ type BlocksRetriever interface {
 	GetBlocks(*pb.SyncBlockRange) (<-chan *pb.SyncBlocks, error)
 }
 
+// StateRetriever interface for retrieving the state snapshot of a remote peer.
+type StateRetriever interface {
+	GetStateSnapshot() (<-chan *pb.SyncStateSnapshot, error)
+}
+
+// RemoteLedger interface for retrieving the blocks and state of a remote peer.
+type RemoteLedger interface {
+	BlocksRetriever
+	StateRetriever
+}
+
 // BlockChainAccessor interface for retreiving blocks by block number
 type BlockChainAccessor interface {
 	GetBlockByNumber(blockNumber uint64) (*pb.Block, error)
@@ -69,7 +81,7 @@ type StateAccessor interface {
 
 // MessageHandler standard interface for handling Openchain messages.
 type MessageHandler interface {
-	BlocksRetriever
+	RemoteLedger
 	HandleMessage(msg *pb.OpenchainMessage) error
 	SendMessage(msg *pb.OpenchainMessage) error
 	To() (pb.PeerEndpoint, error)
@@ -88,6 +100,7 @@ type MessageHandlerCoordinator interface {
 	GetPeers() (*pb.PeersMessage, error)
 	PeersDiscovered(*pb.PeersMessage) error
 	ExecuteTransaction(transaction *pb.Transaction) *pb.Response
+	GetRemoteLedger(receiverAddress string) (RemoteLedger, error)
 }
 
 // ChatStream interface supported by stream between Peers
@@ -155,32 +168,57 @@ func GetPeerEndpoint() (*pb.PeerEndpoint, error) {
 	} else {
 		peerType = pb.PeerEndpoint_NON_VALIDATOR
 	}
-	return &pb.PeerEndpoint{ID: &pb.PeerID{Name: viper.GetString("peer.id")}, Address: peerAddress, Type: peerType}, nil
+	return &pb.PeerEndpoint{ID: &pb.PeerID{Name: getPeerID()}, Address: peerAddress, Type: peerType}, nil
+}
+
+// getPeerID returns the name this peer is known by. With security enabled
+// this is the enrollment ID, which remote peers check against the enrollment
+// certificate presented in the hello handshake.
+func getPeerID() string {
+	if viper.GetBool("security.enabled") {
+		return viper.GetString("security.enrollID")
+	}
+	return viper.GetString("peer.id")
 }
 
 // NewPeerClientConnectionWithAddress Returns a new grpc.ClientConn to the configured local PEER.
 func NewPeerClientConnectionWithAddress(peerAddress string) (*grpc.ClientConn, error) {
+	return newPeerClientConnectionWithAddress(peerAddress, nil)
+}
+
+// newPeerClientConnectionWithAddress returns a new grpc.ClientConn to the PEER at peerAddress.
+// If secHelper is not nil the connection is authenticated with the TLS certificate issued by the TLSCA.
+func newPeerClientConnectionWithAddress(peerAddress string, secHelper crypto.Peer) (*grpc.ClientConn, error) {
 	var opts []grpc.DialOption
 	if viper.GetBool("peer.tls.enabled") {
-		var sn string
-		if viper.GetString("peer.tls.server-host-override") != "" {
-			sn = viper.GetString("peer.tls.server-host-override")
-		}
 		var creds credentials.TransportAuthenticator
-		if viper.GetString("peer.tls.cert.file") != "" {
+		if secHelper != nil {
 			var err error
-			creds, err = credentials.NewClientTLSFromFile(viper.GetString("peer.tls.cert.file"), sn)
+			creds, err = newClientTLSCredentials(secHelper)
 			if err != nil {
-				grpclog.Fatalf("Failed to create TLS credentials %v", err)
+				return nil, fmt.Errorf("Failed to create TLS credentials %v", err)
 			}
 		} else {
-			creds = credentials.NewClientTLSFromCert(nil, sn)
+			var sn string
+			if viper.GetString("peer.tls.server-host-override") != "" {
+				sn = viper.GetString("peer.tls.server-host-override")
+			}
+			if viper.GetString("peer.tls.cert.file") != "" {
+				var err error
+				creds, err = credentials.NewClientTLSFromFile(viper.GetString("peer.tls.cert.file"), sn)
+				if err != nil {
+					grpclog.Fatalf("Failed to create TLS credentials %v", err)
+				}
+			} else {
+				creds = credentials.NewClientTLSFromCert(nil, sn)
+			}
 		}
 		opts = append(opts, grpc.WithTransportCredentials(creds))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
 	}
 	opts = append(opts, grpc.WithTimeout(defaultTimeout))
 	opts = append(opts, grpc.WithBlock())
-	opts = append(opts, grpc.WithInsecure())
 	conn, err := grpc.Dial(peerAddress, opts...)
 	if err != nil {
 		return nil, err
@@ -188,11 +226,69 @@ func NewPeerClientConnectionWithAddress(peerAddress string) (*grpc.ClientConn, e
 	return conn, err
 }
 
+// peerTLSServerName is the server name peers ask for when they connect to each other.
+// The peer server only presents its TLSCA certificate to clients asking for this name.
+const peerTLSServerName = "peer.tlsca"
+
+// newClientTLSCredentials returns the credentials a peer connects to other peers with.
+// TLSCA certificates do not name the host they are issued to, so the certificate of the
+// remote peer is not checked here but in the hello handshake, where the remote peer has
+// to prove it holds the enrollment certificate of the PeerID it claims.
+func newClientTLSCredentials(secHelper crypto.Peer) (credentials.TransportAuthenticator, error) {
+	cert, err := secHelper.GetTLSCertificate()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates:       []tls.Certificate{*cert},
+		ServerName:         peerTLSServerName,
+		InsecureSkipVerify: true,
+	}), nil
+}
+
+// NewServerTLSCredentials returns the credentials the peer server listens with. If secHelper
+// is not nil the server presents the TLS certificate issued by the TLSCA to the peers, and
+// verifies the TLSCA certificates of the connecting peers. The local clients, such as the CLI
+// and the REST server, only trust peer.tls.cert.file, so the server keeps presenting that
+// certificate to them. They are accepted without a certificate, but cannot complete the hello
+// handshake with the peer.
+func NewServerTLSCredentials(secHelper crypto.Peer) (credentials.TransportAuthenticator, error) {
+	if secHelper == nil {
+		return credentials.NewServerTLSFromFile(viper.GetString("peer.tls.cert.file"), viper.GetString("peer.tls.key.file"))
+	}
+	tlscaCert, err := secHelper.GetTLSCertificate()
+	if err != nil {
+		return nil, err
+	}
+	fileCert, err := tls.LoadX509KeyPair(viper.GetString("peer.tls.cert.file"), viper.GetString("peer.tls.key.file"))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load the certificate for local clients: %s", err)
+	}
+	return credentials.NewTLS(&tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if hello.ServerName == peerTLSServerName {
+				return tlscaCert, nil
+			}
+			return &fileCert, nil
+		},
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  secHelper.GetTLSCACertPool(),
+	}), nil
+}
+
 type ledgerWrapper struct {
 	sync.RWMutex
 	ledger *ledger.Ledger
 }
 
+// GetDeployTransaction returns the transaction that deployed the chaincode named name.
+// Deploy transactions are identified by the name of the chaincode they deploy.
+func (lw *ledgerWrapper) GetDeployTransaction(name string) (*pb.Transaction, error) {
+	lw.RLock()
+	defer lw.RUnlock()
+	return lw.ledger.GetTransactionByUUID(name)
+}
+
 type handlerMap struct {
 	sync.RWMutex
 	m map[string]MessageHandler
@@ -248,6 +344,10 @@ func NewPeerWithHandler(handlerFact func(MessageHandlerCoordinator, ChatStream,
 		return nil, fmt.Errorf("Error constructing NewPeerWithHandler: %s", err)
 	}
 	peer.ledgerWrapper = &ledgerWrapper{ledger: ledgerPtr}
+	if peer.secHelper != nil && viper.GetBool("peer.validator.enabled") {
+		// Validators enforce the endorsement policies set at deploy time
+		crypto.SetChaincodeDeployments(peer.ledgerWrapper)
+	}
 	go peer.chatWithPeer(viper.GetString("peer.discovery.rootnode"))
 	return peer, nil
 }
@@ -354,9 +454,25 @@ func (p *PeerImpl) Broadcast(msg *pb.OpenchainMessage) []error {
 	return errorsFromHandlers
 }
 
+// GetRemoteLedger returns the RemoteLedger of the currently registered PeerEndpoint with the supplied address.
+func (p *PeerImpl) GetRemoteLedger(receiverAddress string) (RemoteLedger, error) {
+	p.handlerMap.RLock()
+	defer p.handlerMap.RUnlock()
+	for _, msgHandler := range p.handlerMap.m {
+		toPeerEndpoint, err := msgHandler.To()
+		if err != nil {
+			continue
+		}
+		if toPeerEndpoint.Address == receiverAddress {
+			return msgHandler, nil
+		}
+	}
+	return nil, fmt.Errorf("Remote ledger not found for PeerEndpoint with address: %s", receiverAddress)
+}
+
 // SendTransactionsToPeer current temporary mechanism of forwarding transactions to the configured Validator.
 func (p *PeerImpl) SendTransactionsToPeer(peerAddress string, transaction *pb.Transaction) *pb.Response {
-	conn, err := NewPeerClientConnectionWithAddress(peerAddress)
+	conn, err := newPeerClientConnectionWithAddress(peerAddress, p.secHelper)
 	if err != nil {
 		return &pb.Response{Status: pb.Response_FAILURE, Msg: []byte(fmt.Sprintf("Error sending transactions to peer address=%s:  %s", peerAddress, err))}
 	}
@@ -398,10 +514,10 @@ func (p *PeerImpl) SendTransactionsToPeer(peerAddress string, transaction *pb.Tr
 					return
 				}
 				var ttyp pb.OpenchainMessage_Type
-				if transaction.Type == pb.Transaction_CHAINCODE_EXECUTE || transaction.Type == pb.Transaction_CHAINCODE_NEW {
-					ttyp = pb.OpenchainMessage_CHAIN_TRANSACTION
-				} else {
+				if transaction.Type == pb.Transaction_CHAINCODE_QUERY {
 					ttyp = pb.OpenchainMessage_CHAIN_QUERY
+				} else {
+					ttyp = pb.OpenchainMessage_CHAIN_TRANSACTION
 				}
 
 				msg := &pb.OpenchainMessage{Type: ttyp, Payload: payload, Timestamp: util.CreateUtcTimestamp()}
@@ -426,8 +542,8 @@ func (p *PeerImpl) SendTransactionsToPeer(peerAddress string, transaction *pb.Tr
 }
 
 // SendTransactionsToPeer current temporary mechanism of forwarding transactions to the configured Validator.
-func sendTransactionsToThisPeer(peerAddress string, transaction *pb.Transaction) *pb.Response {
-	conn, err := NewPeerClientConnectionWithAddress(peerAddress)
+func (p *PeerImpl) sendTransactionsToThisPeer(peerAddress string, transaction *pb.Transaction) *pb.Response {
+	conn, err := newPeerClientConnectionWithAddress(peerAddress, p.secHelper)
 	if err != nil {
 		return &pb.Response{Status: pb.Response_FAILURE, Msg: []byte(fmt.Sprintf("Error sending transactions to peer address=%s:  %s", peerAddress, err))}
 	}
@@ -443,10 +559,10 @@ func sendTransactionsToThisPeer(peerAddress string, transaction *pb.Transaction)
 		return &pb.Response{Status: pb.Response_FAILURE, Msg: []byte(fmt.Sprintf("Error sending transaction to local peer: %s", err))}
 	}
 	var ttyp pb.OpenchainMessage_Type
-	if transaction.Type == pb.Transaction_CHAINCODE_EXECUTE || transaction.Type == pb.Transaction_CHAINCODE_NEW {
-		ttyp = pb.OpenchainMessage_CHAIN_TRANSACTION
-	} else {
+	if transaction.Type == pb.Transaction_CHAINCODE_QUERY {
 		ttyp = pb.OpenchainMessage_CHAIN_QUERY
+	} else {
+		ttyp = pb.OpenchainMessage_CHAIN_TRANSACTION
 	}
 
 	msg := &pb.OpenchainMessage{Type: ttyp, Payload: data, Timestamp: util.CreateUtcTimestamp()}
@@ -495,7 +611,7 @@ func (p *PeerImpl) chatWithPeer(peerAddress string) error {
 	for {
 		time.Sleep(1 * time.Second)
 		peerLogger.Debug("Initiating Chat with peer address: %s", peerAddress)
-		conn, err := NewPeerClientConnectionWithAddress(peerAddress)
+		conn, err := newPeerClientConnectionWithAddress(peerAddress, p.secHelper)
 		if err != nil {
 			e := fmt.Errorf("Error creating connection to peer address=%s:  %s", peerAddress, err)
 			peerLogger.Error(e.Error())
@@ -510,7 +626,7 @@ func (p *PeerImpl) chatWithPeer(peerAddress string) error {
 			continue
 		}
 		peerLogger.Debug("Established Chat with peer address: %s", peerAddress)
-		p.handleChat(ctx, stream, true)
+		p.handleChat(stream.Context(), stream, true)
 		stream.CloseSend()
 	}
 }
@@ -559,7 +675,7 @@ func (p *PeerImpl) ExecuteTransaction(transaction *pb.Transaction) *pb.Response
 	peerAddress := getValidatorStreamAddress()
 	var response *pb.Response
 	if viper.GetBool("peer.validator.enabled") { // send gRPC request to yourself
-		response = sendTransactionsToThisPeer(peerAddress, transaction)
+		response = p.sendTransactionsToThisPeer(peerAddress, transaction)
 
 	} else {
 		response = p.SendTransactionsToPeer(peerAddress, transaction)
@@ -581,7 +697,25 @@ func (p *PeerImpl) newHelloMessage() (*pb.HelloMessage, error) {
 	p.ledgerWrapper.RLock()
 	defer p.ledgerWrapper.RUnlock()
 	size := p.ledgerWrapper.ledger.GetBlockchainSize()
-	return &pb.HelloMessage{PeerEndpoint: endpoint, BlockNumber: size}, nil
+	helloMessage := &pb.HelloMessage{PeerEndpoint: endpoint, BlockNumber: size}
+	if isPeerAuthenticationEnabled(p.secHelper) {
+		data, err := proto.Marshal(helloMessage)
+		if err != nil {
+			return nil, fmt.Errorf("Error marshalling HelloMessage: %s", err)
+		}
+		helloMessage.Cert, helloMessage.Signature, err = p.secHelper.SignHello(data)
+		if err != nil {
+			return nil, fmt.Errorf("Error signing HelloMessage: %s", err)
+		}
+	}
+	return helloMessage, nil
+}
+
+// isPeerAuthenticationEnabled returns true if peers have to authenticate each other in the
+// hello handshake. This requires both security, for the enrollment certificates, and TLS,
+// for the TLSCA certificates the hello is bound to.
+func isPeerAuthenticationEnabled(secHelper crypto.Peer) bool {
+	return secHelper != nil && viper.GetBool("peer.tls.enabled")
 }
 
 // GetBlockByNumber return a block by block number
@@ -0,0 +1,124 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package replay
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/op/go-logging"
+	"golang.org/x/net/context"
+
+	"github.com/openblockchain/obc-peer/openchain/chaincode"
+	"github.com/openblockchain/obc-peer/openchain/crypto"
+	"github.com/openblockchain/obc-peer/openchain/ledger"
+	"github.com/openblockchain/obc-peer/openchain/ledger/statemgmt"
+)
+
+var logger = logging.MustGetLogger("replay")
+
+// Divergence describes the first block whose state hash differs from the state hash
+// obtained by replaying the blockchain
+type Divergence struct {
+	BlockNumber       uint64
+	StateHash         []byte
+	ReplayedStateHash []byte
+
+	// The state deltas are nil if the db no longer keeps them, see ledger.state.deltaHistorySize
+	StateDelta         *statemgmt.StateDelta
+	ReplayedStateDelta *statemgmt.StateDelta
+}
+
+// Diff returns the keys whose updates differ between the recorded and the replayed
+// state deltas. Nil is returned if either state delta is not available
+func (divergence *Divergence) Diff() []*statemgmt.StateDeltaDiff {
+	if divergence.StateDelta == nil || divergence.ReplayedStateDelta == nil {
+		return nil
+	}
+	return divergence.StateDelta.Diff(divergence.ReplayedStateDelta)
+}
+
+// Replay re-executes the transactions of every block of source, starting with the
+// genesis block, against the ledger of this peer and compares the resulting state hash
+// with the state hash recorded in the block. The ledger of this peer must be empty and
+// the chaincode support of the default chain must be registered. The first divergence
+// is returned, nil is returned if every state hash matches
+func Replay(source *ledger.ReadOnlyLedger, secHelper crypto.Peer) (*Divergence, error) {
+	target, err := ledger.GetLedger()
+	if err != nil {
+		return nil, err
+	}
+	if target.GetBlockchainSize() != 0 {
+		return nil, fmt.Errorf("The ledger must be empty to replay a blockchain, it has %d blocks", target.GetBlockchainSize())
+	}
+	size, err := source.GetBlockchainSize()
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Replaying %d blocks", size)
+
+	for blockNumber := uint64(0); blockNumber < size; blockNumber++ {
+		block, err := source.GetBlockByNumber(blockNumber)
+		if err != nil {
+			return nil, fmt.Errorf("Error fetching block %d: %s", blockNumber, err)
+		}
+		if block.IsPruned() {
+			return nil, fmt.Errorf("Block %d has been pruned, its transactions cannot be replayed", blockNumber)
+		}
+
+		if err := target.BeginTxBatch(blockNumber); err != nil {
+			return nil, err
+		}
+		stateHash, errs := chaincode.ExecuteTransactions(context.Background(), chaincode.DefaultChain, block.Transactions, secHelper)
+		if hashErr := errs[len(errs)-1]; hashErr != nil {
+			target.RollbackTxBatch(blockNumber)
+			return nil, fmt.Errorf("Error computing the state hash of block %d: %s", blockNumber, hashErr)
+		}
+		for i, txErr := range errs[:len(errs)-1] {
+			if txErr != nil {
+				logger.Debug("Transaction %s of block %d failed: %s", block.Transactions[i].Uuid, blockNumber, txErr)
+			}
+		}
+		if err := target.CommitTxBatch(blockNumber, block.Transactions, block.ConsensusMetadata); err != nil {
+			return nil, err
+		}
+
+		if !bytes.Equal(stateHash, block.StateHash) {
+			logger.Info("State hash of block %d diverges", blockNumber)
+			return newDivergence(source, target, blockNumber, block.StateHash, stateHash)
+		}
+		logger.Debug("State hash of block %d matches", blockNumber)
+	}
+	return nil, nil
+}
+
+func newDivergence(source *ledger.ReadOnlyLedger, target *ledger.Ledger, blockNumber uint64,
+	stateHash []byte, replayedStateHash []byte) (*Divergence, error) {
+	stateDelta, err := source.GetStateDelta(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	replayedStateDelta, err := target.GetStateDelta(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &Divergence{BlockNumber: blockNumber, StateHash: stateHash, ReplayedStateHash: replayedStateHash,
+		StateDelta: stateDelta, ReplayedStateDelta: replayedStateDelta}, nil
+}
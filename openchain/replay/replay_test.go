@@ -0,0 +1,108 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package replay
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/openblockchain/obc-peer/openchain/chaincode"
+	"github.com/openblockchain/obc-peer/openchain/ledger"
+	"github.com/openblockchain/obc-peer/openchain/ledger/testutil"
+	"github.com/openblockchain/obc-peer/openchain/util"
+	"github.com/openblockchain/obc-peer/protos"
+	"github.com/spf13/viper"
+)
+
+func TestMain(m *testing.M) {
+	testutil.SetupTestConfig()
+	getPeerEndpoint := func() (*protos.PeerEndpoint, error) {
+		return &protos.PeerEndpoint{ID: &protos.PeerID{Name: "testpeer"}, Address: "0.0.0.0:40303"}, nil
+	}
+	// the transactions of the test do not run any chaincode
+	chaincode.NewChaincodeSupport(chaincode.DefaultChain, getPeerEndpoint, true, time.Second)
+	os.Exit(m.Run())
+}
+
+func buildTestTx() (*protos.Transaction, string) {
+	uuid, _ := util.GenerateUUID()
+	return protos.NewTransaction(protos.ChaincodeID{Path: "testUrl"}, uuid, "anyfunction", []string{"param1, param2"}), uuid
+}
+
+func TestReplayDivergence(t *testing.T) {
+	sourcePath := viper.GetString("peer.fileSystemPath") + "/source"
+	targetPath := viper.GetString("peer.fileSystemPath") + "/target"
+	defer viper.Set("peer.fileSystemPath", viper.GetString("peer.fileSystemPath"))
+
+	// The transactions of the source ledger do not change the state when executed. The
+	// state delta recorded for block 1 has been tampered with to set a key
+	viper.Set("peer.fileSystemPath", sourcePath)
+	source := ledger.InitTestLedger(t)
+	tx1, uuid1 := buildTestTx()
+	source.BeginTxBatch(0)
+	source.TxBegin(uuid1)
+	source.TxFinished(uuid1, true)
+	err := source.CommitTxBatch(0, []*protos.Transaction{tx1}, nil)
+	testutil.AssertNoError(t, err, "Error while committing block 0")
+	tx2, uuid2 := buildTestTx()
+	source.BeginTxBatch(1)
+	source.TxBegin(uuid2)
+	source.SetState("chaincode1", "key1", []byte("value1"))
+	source.TxFinished(uuid2, true)
+	err = source.CommitTxBatch(1, []*protos.Transaction{tx2}, nil)
+	testutil.AssertNoError(t, err, "Error while committing block 1")
+	block1, err := source.GetBlockByNumber(1)
+	testutil.AssertNoError(t, err, "Error while getting block 1")
+
+	// Replay against an empty ledger
+	viper.Set("peer.fileSystemPath", targetPath)
+	ledger.InitTestLedger(t)
+	readOnlyLedger, err := ledger.OpenReadOnlyLedger(sourcePath + "/db")
+	testutil.AssertNoError(t, err, "Error while opening source ledger")
+	defer readOnlyLedger.Close()
+
+	divergence, err := Replay(readOnlyLedger, nil)
+	testutil.AssertNoError(t, err, "Error while replaying")
+	testutil.AssertNotNil(t, divergence)
+	testutil.AssertEquals(t, divergence.BlockNumber, uint64(1))
+	testutil.AssertEquals(t, divergence.StateHash, block1.StateHash)
+	testutil.AssertNotEquals(t, divergence.ReplayedStateHash, block1.StateHash)
+
+	diffs := divergence.Diff()
+	testutil.AssertEquals(t, len(diffs), 1)
+	testutil.AssertEquals(t, diffs[0].ChaincodeID, "chaincode1")
+	testutil.AssertEquals(t, diffs[0].Key, "key1")
+	testutil.AssertEquals(t, diffs[0].UpdatedValue.GetValue(), []byte("value1"))
+	testutil.AssertNil(t, diffs[0].OtherUpdatedValue)
+}
+
+func TestReplayNonEmptyLedger(t *testing.T) {
+	target := ledger.InitTestLedger(t)
+	tx, uuid := buildTestTx()
+	target.BeginTxBatch(0)
+	target.TxBegin(uuid)
+	target.TxFinished(uuid, true)
+	err := target.CommitTxBatch(0, []*protos.Transaction{tx}, nil)
+	testutil.AssertNoError(t, err, "Error while committing block 0")
+
+	_, err = Replay(nil, nil)
+	testutil.AssertError(t, err, "Replay against a non empty ledger should fail")
+}
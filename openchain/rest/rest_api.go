@@ -373,6 +373,164 @@ func (s *ServerOpenchainREST) GetTransactionByUUID(rw web.ResponseWriter, req *w
 	}
 }
 
+// GetTransactionReceipt returns whether the transaction matching the specified
+// UUID is pending, was committed, or failed
+func (s *ServerOpenchainREST) GetTransactionReceipt(rw web.ResponseWriter, req *web.Request) {
+	// Parse out the transaction UUID
+	txUUID := req.PathParams["uuid"]
+
+	// Retrieve the receipt of the transaction matching the UUID
+	receipt, err := s.server.GetTransactionReceipt(context.Background(), &pb.TransactionReceiptRequest{Uuid: txUUID})
+
+	// Check for Error
+	if err != nil {
+		switch err {
+		case oc.ErrNotFound:
+			rw.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(rw, "{\"Error\": \"Transaction %s is not found.\"}", txUUID)
+		default:
+			rw.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(rw, "{\"Error\": \"Error retrieving receipt of transaction %s: %s.\"}", txUUID, err)
+			logger.Error(fmt.Sprintf("{\"Error\": \"Error retrieving receipt of transaction %s: %s.\"}", txUUID, err))
+		}
+	} else {
+		// Return the receipt
+		rw.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(rw)
+		encoder.Encode(receipt)
+	}
+}
+
+// GetState returns the value of a key within the state of the specified
+// Chaincode. If the "block" query parameter is supplied, the value of the key
+// as it was right after that block was committed is returned instead.
+func (s *ServerOpenchainREST) GetState(rw web.ResponseWriter, req *web.Request) {
+	// Parse out the chaincode ID and key
+	chaincodeID := req.PathParams["chaincodeID"]
+	key := req.PathParams["key"]
+
+	// If no block number is supplied, retrieve the current state
+	blockParam := req.URL.Query().Get("block")
+	if blockParam == "" {
+		value, err := s.server.GetState(context.Background(), chaincodeID, key)
+		if err != nil {
+			rw.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(rw, "{\"Error\": \"Error retrieving state for key %s: %s\"}", key, err)
+			logger.Error(fmt.Sprintf("{\"Error\": \"Error retrieving state for key %s: %s\"}", key, err))
+
+			return
+		}
+
+		rw.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(rw)
+		encoder.Encode(&pb.StateValue{Value: value})
+
+		return
+	}
+
+	// Check for proper block number syntax
+	blockNumber, err := strconv.ParseUint(blockParam, 10, 64)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(rw, "{\"Error\": \"Block number must be an integer (uint64).\"}")
+
+		return
+	}
+
+	// Retrieve the state as of the block
+	stateValue, err := s.server.GetStateAsOf(context.Background(), &pb.StateAsOfRequest{ChaincodeID: chaincodeID, Key: key, BlockNumber: blockNumber})
+	if err != nil {
+		switch err {
+		case oc.ErrNotFound:
+			rw.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(rw, "{\"Error\": \"State as of block %d is not available.\"}", blockNumber)
+		default:
+			rw.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(rw, "{\"Error\": \"%s\"}", err)
+			logger.Error(fmt.Sprintf("{\"Error\": \"%s\"}", err))
+		}
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(rw)
+	encoder.Encode(stateValue)
+}
+
+// GetStateWithProof returns the committed value of a key within the state of
+// the specified Chaincode along with a proof that it belongs to the state
+// recorded in the last block
+func (s *ServerOpenchainREST) GetStateWithProof(rw web.ResponseWriter, req *web.Request) {
+	// Parse out the chaincode ID and key
+	chaincodeID := req.PathParams["chaincodeID"]
+	key := req.PathParams["key"]
+
+	// Retrieve the value and its proof
+	stateValue, err := s.server.GetStateWithProof(context.Background(), &pb.StateProofRequest{ChaincodeID: chaincodeID, Key: key})
+
+	// Check for Error
+	if err != nil {
+		switch err {
+		case oc.ErrNotFound:
+			rw.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(rw, "{\"Error\": \"Key %s of chaincode %s is not found.\"}", key, chaincodeID)
+		default:
+			rw.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(rw, "{\"Error\": \"Error retrieving proof of key %s: %s.\"}", key, err)
+			logger.Error(fmt.Sprintf("{\"Error\": \"Error retrieving proof of key %s: %s.\"}", key, err))
+		}
+	} else {
+		// Return the value and its proof
+		rw.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(rw)
+		encoder.Encode(stateValue)
+	}
+}
+
+// GetChaincodes returns the entries of the chaincode registry, which records
+// every chaincode deployed on the network.
+func (s *ServerOpenchainREST) GetChaincodes(rw web.ResponseWriter, req *web.Request) {
+	registry, err := s.devops.GetChaincodes(context.Background(), &pb.ChaincodeID{})
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(rw, "{\"Error\": \"Error retrieving chaincode registry: %s.\"}", err)
+		logger.Error(fmt.Sprintf("{\"Error\": \"Error retrieving chaincode registry: %s.\"}", err))
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(rw)
+	encoder.Encode(registry)
+}
+
+// GetChaincode returns the entry of the chaincode registry matching the
+// specified chaincode name.
+func (s *ServerOpenchainREST) GetChaincode(rw web.ResponseWriter, req *web.Request) {
+	// Parse out the chaincode name
+	name := req.PathParams["name"]
+
+	registry, err := s.devops.GetChaincodes(context.Background(), &pb.ChaincodeID{Name: name})
+	if err != nil {
+		switch err {
+		case oc.ErrNotFound:
+			rw.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(rw, "{\"Error\": \"Chaincode %s is not found.\"}", name)
+		default:
+			rw.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(rw, "{\"Error\": \"Error retrieving chaincode %s: %s.\"}", name, err)
+			logger.Error(fmt.Sprintf("{\"Error\": \"Error retrieving chaincode %s: %s.\"}", name, err))
+		}
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(rw)
+	encoder.Encode(registry.Entries[0])
+}
+
 // Deploy first builds the chaincode package and subsequently deploys it to the
 // blockchain.
 func (s *ServerOpenchainREST) Deploy(rw web.ResponseWriter, req *web.Request) {
@@ -826,6 +984,15 @@ func StartOpenchainRESTServer(server *oc.ServerOpenchain, devops *oc.Devops) {
 
 	router.Get("/transactions/:uuid", (*ServerOpenchainREST).GetTransactionByUUID)
 
+	router.Get("/transactions/:uuid/receipt", (*ServerOpenchainREST).GetTransactionReceipt)
+
+	router.Get("/state/:chaincodeID/:key", (*ServerOpenchainREST).GetState)
+
+	router.Get("/state/:chaincodeID/:key/proof", (*ServerOpenchainREST).GetStateWithProof)
+
+	router.Get("/chaincodes", (*ServerOpenchainREST).GetChaincodes)
+	router.Get("/chaincodes/:name", (*ServerOpenchainREST).GetChaincode)
+
 	// Add not found page
 	router.NotFound((*ServerOpenchainREST).NotFound)
 
@@ -11,31 +11,55 @@ It is generated from these files:
 	devops.proto
 	events.proto
 	openchain.proto
+	remote_signer.proto
 	server_admin.proto
 
 It has these top-level messages:
 	BlockchainInfo
 	BlockNumber
 	BlockCount
+	StateAsOfRequest
+	StateValue
+	TransactionReceiptRequest
+	StateProofRequest
+	StateValueWithProof
+	StateProofNode
+	StateProofKeyValue
+	StateProofChildHash
 	ChaincodeID
 	ChaincodeInput
+	EndorsementPolicy
 	ChaincodeSpec
 	ChaincodeDeploymentSpec
 	ChaincodeInvocationSpec
 	ChaincodeIdentifier
 	ChaincodeRequestContext
 	ChaincodeExecutionContext
+	ChaincodeEvent
+	AttributeKey
+	ChaincodeSecurityContext
 	ChaincodeMessage
 	PutStateInfo
+	RangeQueryState
+	RangeQueryStateNext
+	RangeQueryStateClose
+	RangeQueryStateKeyValue
+	RangeQueryStateResponse
+	ChaincodeVersion
+	ChaincodeRegistryEntry
+	ChaincodeRegistry
 	Secret
 	BuildResult
+	ChaincodeReg
 	Interest
 	Register
 	Generic
 	OpenchainEvent
+	Endorsement
 	Transaction
 	TransactionBlock
 	TransactionResult
+	TransactionReceipt
 	Block
 	NonHashData
 	PeerAddress
@@ -52,6 +76,10 @@ It has these top-level messages:
 	SyncStateSnapshot
 	SyncStateDeltasRequest
 	SyncStateDeltas
+	KeyImportRequest
+	KeyImportResponse
+	SignRequest
+	SignResponse
 	ServerStatus
 */
 package protos
@@ -101,6 +129,117 @@ func (m *BlockCount) Reset()         { *m = BlockCount{} }
 func (m *BlockCount) String() string { return proto.CompactTextString(m) }
 func (*BlockCount) ProtoMessage()    {}
 
+// Specifies the chaincode state key and the block number at which its value
+// is to be returned.
+type StateAsOfRequest struct {
+	ChaincodeID string `protobuf:"bytes,1,opt,name=chaincodeID" json:"chaincodeID,omitempty"`
+	Key         string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	BlockNumber uint64 `protobuf:"varint,3,opt,name=blockNumber" json:"blockNumber,omitempty"`
+}
+
+func (m *StateAsOfRequest) Reset()         { *m = StateAsOfRequest{} }
+func (m *StateAsOfRequest) String() string { return proto.CompactTextString(m) }
+func (*StateAsOfRequest) ProtoMessage()    {}
+
+// Contains the value of a chaincode state key. An empty value means the key
+// did not exist.
+type StateValue struct {
+	Value []byte `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *StateValue) Reset()         { *m = StateValue{} }
+func (m *StateValue) String() string { return proto.CompactTextString(m) }
+func (*StateValue) ProtoMessage()    {}
+
+// Specifies the transaction whose receipt is to be returned.
+type TransactionReceiptRequest struct {
+	Uuid string `protobuf:"bytes,1,opt,name=uuid" json:"uuid,omitempty"`
+}
+
+func (m *TransactionReceiptRequest) Reset()         { *m = TransactionReceiptRequest{} }
+func (m *TransactionReceiptRequest) String() string { return proto.CompactTextString(m) }
+func (*TransactionReceiptRequest) ProtoMessage()    {}
+
+// Specifies the chaincode state key whose committed value is to be returned
+// with a proof.
+type StateProofRequest struct {
+	ChaincodeID string `protobuf:"bytes,1,opt,name=chaincodeID" json:"chaincodeID,omitempty"`
+	Key         string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+}
+
+func (m *StateProofRequest) Reset()         { *m = StateProofRequest{} }
+func (m *StateProofRequest) String() string { return proto.CompactTextString(m) }
+func (*StateProofRequest) ProtoMessage()    {}
+
+// Contains the committed value of a chaincode state key and the proof that it
+// belongs to the state recorded in the stateHash of block blockNumber. The
+// proof holds the nodes on the path from the node containing the key up to the
+// root of the state, and can be checked without a peer against a trusted block.
+type StateValueWithProof struct {
+	Value       []byte            `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	BlockNumber uint64            `protobuf:"varint,2,opt,name=blockNumber" json:"blockNumber,omitempty"`
+	StateHash   []byte            `protobuf:"bytes,3,opt,name=stateHash,proto3" json:"stateHash,omitempty"`
+	Nodes       []*StateProofNode `protobuf:"bytes,4,rep,name=nodes" json:"nodes,omitempty"`
+}
+
+func (m *StateValueWithProof) Reset()         { *m = StateValueWithProof{} }
+func (m *StateValueWithProof) String() string { return proto.CompactTextString(m) }
+func (*StateValueWithProof) ProtoMessage()    {}
+
+func (m *StateValueWithProof) GetNodes() []*StateProofNode {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+// A node on the path of a state proof. The crypto-hash of the child at
+// childIndex, which lies on the path, is computed from the previous node.
+type StateProofNode struct {
+	KeyValues            []*StateProofKeyValue  `protobuf:"bytes,1,rep,name=keyValues" json:"keyValues,omitempty"`
+	ChildrenCryptoHashes []*StateProofChildHash `protobuf:"bytes,2,rep,name=childrenCryptoHashes" json:"childrenCryptoHashes,omitempty"`
+	ChildIndex           uint32                 `protobuf:"varint,3,opt,name=childIndex" json:"childIndex,omitempty"`
+}
+
+func (m *StateProofNode) Reset()         { *m = StateProofNode{} }
+func (m *StateProofNode) String() string { return proto.CompactTextString(m) }
+func (*StateProofNode) ProtoMessage()    {}
+
+func (m *StateProofNode) GetKeyValues() []*StateProofKeyValue {
+	if m != nil {
+		return m.KeyValues
+	}
+	return nil
+}
+
+func (m *StateProofNode) GetChildrenCryptoHashes() []*StateProofChildHash {
+	if m != nil {
+		return m.ChildrenCryptoHashes
+	}
+	return nil
+}
+
+// A key-value held by a node of a state proof. The key is the composite key
+// of the chaincode ID and the chaincode state key.
+type StateProofKeyValue struct {
+	Key   []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *StateProofKeyValue) Reset()         { *m = StateProofKeyValue{} }
+func (m *StateProofKeyValue) String() string { return proto.CompactTextString(m) }
+func (*StateProofKeyValue) ProtoMessage()    {}
+
+// The crypto-hash of the child at index of a node of a state proof.
+type StateProofChildHash struct {
+	Index      uint32 `protobuf:"varint,1,opt,name=index" json:"index,omitempty"`
+	CryptoHash []byte `protobuf:"bytes,2,opt,name=cryptoHash,proto3" json:"cryptoHash,omitempty"`
+}
+
+func (m *StateProofChildHash) Reset()         { *m = StateProofChildHash{} }
+func (m *StateProofChildHash) String() string { return proto.CompactTextString(m) }
+func (*StateProofChildHash) ProtoMessage()    {}
+
 // Reference imports to suppress errors if they are not otherwise used.
 var _ context.Context
 var _ grpc.ClientConn
@@ -117,6 +256,15 @@ type OpenchainClient interface {
 	// GetBlockCount returns the current number of blocks in the blockchain data
 	// structure.
 	GetBlockCount(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*BlockCount, error)
+	// GetStateAsOf returns the value of a chaincode state key as it was right
+	// after the specified block was committed.
+	GetStateAsOf(ctx context.Context, in *StateAsOfRequest, opts ...grpc.CallOption) (*StateValue, error)
+	// GetTransactionReceipt returns whether a transaction is pending, was
+	// committed, or failed, along with its position in the blockchain.
+	GetTransactionReceipt(ctx context.Context, in *TransactionReceiptRequest, opts ...grpc.CallOption) (*TransactionReceipt, error)
+	// GetStateWithProof returns the committed value of a chaincode state key
+	// along with a proof that it belongs to the state recorded in a block.
+	GetStateWithProof(ctx context.Context, in *StateProofRequest, opts ...grpc.CallOption) (*StateValueWithProof, error)
 }
 
 type openchainClient struct {
@@ -154,6 +302,33 @@ func (c *openchainClient) GetBlockCount(ctx context.Context, in *google_protobuf
 	return out, nil
 }
 
+func (c *openchainClient) GetStateAsOf(ctx context.Context, in *StateAsOfRequest, opts ...grpc.CallOption) (*StateValue, error) {
+	out := new(StateValue)
+	err := grpc.Invoke(ctx, "/protos.Openchain/GetStateAsOf", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *openchainClient) GetTransactionReceipt(ctx context.Context, in *TransactionReceiptRequest, opts ...grpc.CallOption) (*TransactionReceipt, error) {
+	out := new(TransactionReceipt)
+	err := grpc.Invoke(ctx, "/protos.Openchain/GetTransactionReceipt", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *openchainClient) GetStateWithProof(ctx context.Context, in *StateProofRequest, opts ...grpc.CallOption) (*StateValueWithProof, error) {
+	out := new(StateValueWithProof)
+	err := grpc.Invoke(ctx, "/protos.Openchain/GetStateWithProof", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Openchain service
 
 type OpenchainServer interface {
@@ -166,6 +341,15 @@ type OpenchainServer interface {
 	// GetBlockCount returns the current number of blocks in the blockchain data
 	// structure.
 	GetBlockCount(context.Context, *google_protobuf1.Empty) (*BlockCount, error)
+	// GetStateAsOf returns the value of a chaincode state key as it was right
+	// after the specified block was committed.
+	GetStateAsOf(context.Context, *StateAsOfRequest) (*StateValue, error)
+	// GetTransactionReceipt returns whether a transaction is pending, was
+	// committed, or failed, along with its position in the blockchain.
+	GetTransactionReceipt(context.Context, *TransactionReceiptRequest) (*TransactionReceipt, error)
+	// GetStateWithProof returns the committed value of a chaincode state key
+	// along with a proof that it belongs to the state recorded in a block.
+	GetStateWithProof(context.Context, *StateProofRequest) (*StateValueWithProof, error)
 }
 
 func RegisterOpenchainServer(s *grpc.Server, srv OpenchainServer) {
@@ -208,6 +392,42 @@ func _Openchain_GetBlockCount_Handler(srv interface{}, ctx context.Context, dec
 	return out, nil
 }
 
+func _Openchain_GetStateAsOf_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(StateAsOfRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(OpenchainServer).GetStateAsOf(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _Openchain_GetTransactionReceipt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(TransactionReceiptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(OpenchainServer).GetTransactionReceipt(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _Openchain_GetStateWithProof_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(StateProofRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(OpenchainServer).GetStateWithProof(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _Openchain_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.Openchain",
 	HandlerType: (*OpenchainServer)(nil),
@@ -224,6 +444,18 @@ var _Openchain_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetBlockCount",
 			Handler:    _Openchain_GetBlockCount_Handler,
 		},
+		{
+			MethodName: "GetStateAsOf",
+			Handler:    _Openchain_GetStateAsOf_Handler,
+		},
+		{
+			MethodName: "GetTransactionReceipt",
+			Handler:    _Openchain_GetTransactionReceipt_Handler,
+		},
+		{
+			MethodName: "GetStateWithProof",
+			Handler:    _Openchain_GetStateWithProof_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{},
 }
@@ -60,8 +60,12 @@ func NewBlock(transactions []*Transaction) *Block {
 	return block
 }
 
-// GetHash returns the hash of this block.
+// GetHash returns the hash of this block. The hash of a pruned block can not
+// be computed anymore, the hash recorded when the block was pruned is returned.
 func (block *Block) GetHash() ([]byte, error) {
+	if block.IsPruned() {
+		return block.NonHashData.PrunedBlockHash, nil
+	}
 
 	// copy the block and remove the non-hash data
 	blockBytes, err := block.Bytes()
@@ -83,6 +87,35 @@ func (block *Block) GetHash() ([]byte, error) {
 	return hash, nil
 }
 
+// Prune returns a copy of this block without its transactions and their
+// results. Only the header of the block and its hash are kept, which is enough
+// to verify the chain.
+func (block *Block) Prune() (*Block, error) {
+	if block.IsPruned() {
+		return block, nil
+	}
+	hash, err := block.GetHash()
+	if err != nil {
+		return nil, err
+	}
+	prunedBlock := &Block{
+		Timestamp:         block.Timestamp,
+		StateHash:         block.StateHash,
+		PreviousBlockHash: block.PreviousBlockHash,
+		ConsensusMetadata: block.ConsensusMetadata,
+		NonHashData: &NonHashData{
+			LocalLedgerCommitTimestamp: block.GetNonHashData().GetLocalLedgerCommitTimestamp(),
+			PrunedBlockHash:            hash,
+		},
+	}
+	return prunedBlock, nil
+}
+
+// IsPruned returns true if the transactions of this block have been pruned.
+func (block *Block) IsPruned() bool {
+	return block.NonHashData != nil && block.NonHashData.PrunedBlockHash != nil
+}
+
 // GetStateHash returns the stateHash stored in this block. The stateHash
 // is the value returned by state.GetHash() after running all transactions in
 // the block.
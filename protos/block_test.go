@@ -87,3 +87,49 @@ func TestBlockNonHashData(t *testing.T) {
 		t.Fatalf("Expected time2 and block2 times to be equal, but there were not")
 	}
 }
+
+func TestBlockPrune(t *testing.T) {
+	transaction := &Transaction{Type: 2, ChaincodeID: &ChaincodeID{Path: "contract_001"}, Uuid: "001"}
+	block := NewBlock([]*Transaction{transaction})
+	block.NonHashData = &NonHashData{LocalLedgerCommitTimestamp: util.CreateUtcTimestamp()}
+	hash, err := block.GetHash()
+	if err != nil {
+		t.Fatalf("Error generating block hash: %s", err)
+	}
+	if block.IsPruned() {
+		t.Fatalf("Expected block not to be pruned")
+	}
+
+	prunedBlock, err := block.Prune()
+	if err != nil {
+		t.Fatalf("Error pruning block: %s", err)
+	}
+	if !prunedBlock.IsPruned() {
+		t.Fatalf("Expected block to be pruned")
+	}
+	if len(prunedBlock.Transactions) != 0 {
+		t.Fatalf("Expected no transactions in pruned block, but there were %d", len(prunedBlock.Transactions))
+	}
+	if len(block.Transactions) != 1 {
+		t.Fatalf("Expected the original block to be left untouched")
+	}
+	prunedHash, err := prunedBlock.GetHash()
+	if err != nil {
+		t.Fatalf("Error generating pruned block hash: %s", err)
+	}
+	if bytes.Compare(hash, prunedHash) != 0 {
+		t.Fatalf("Expected pruned block hash to be equal to the original block hash")
+	}
+
+	data, err := proto.Marshal(prunedBlock)
+	if err != nil {
+		t.Fatalf("Error marshalling pruned block: %s", err)
+	}
+	unmarshalledBlock, err := UnmarshallBlock(data)
+	if err != nil {
+		t.Fatalf("Error unmarshalling pruned block: %s", err)
+	}
+	if !unmarshalledBlock.IsPruned() {
+		t.Fatalf("Expected unmarshalled block to be pruned")
+	}
+}
@@ -66,23 +66,26 @@ func (x ChaincodeSpec_Type) String() string {
 type ChaincodeMessage_Type int32
 
 const (
-	ChaincodeMessage_UNDEFINED        ChaincodeMessage_Type = 0
-	ChaincodeMessage_REGISTER         ChaincodeMessage_Type = 1
-	ChaincodeMessage_REGISTERED       ChaincodeMessage_Type = 2
-	ChaincodeMessage_INIT             ChaincodeMessage_Type = 3
-	ChaincodeMessage_READY            ChaincodeMessage_Type = 4
-	ChaincodeMessage_TRANSACTION      ChaincodeMessage_Type = 5
-	ChaincodeMessage_COMPLETED        ChaincodeMessage_Type = 6
-	ChaincodeMessage_ERROR            ChaincodeMessage_Type = 7
-	ChaincodeMessage_GET_STATE        ChaincodeMessage_Type = 8
-	ChaincodeMessage_PUT_STATE        ChaincodeMessage_Type = 9
-	ChaincodeMessage_DEL_STATE        ChaincodeMessage_Type = 10
-	ChaincodeMessage_INVOKE_CHAINCODE ChaincodeMessage_Type = 11
-	ChaincodeMessage_INVOKE_QUERY     ChaincodeMessage_Type = 12
-	ChaincodeMessage_RESPONSE         ChaincodeMessage_Type = 13
-	ChaincodeMessage_QUERY            ChaincodeMessage_Type = 14
-	ChaincodeMessage_QUERY_COMPLETED  ChaincodeMessage_Type = 15
-	ChaincodeMessage_QUERY_ERROR      ChaincodeMessage_Type = 16
+	ChaincodeMessage_UNDEFINED               ChaincodeMessage_Type = 0
+	ChaincodeMessage_REGISTER                ChaincodeMessage_Type = 1
+	ChaincodeMessage_REGISTERED              ChaincodeMessage_Type = 2
+	ChaincodeMessage_INIT                    ChaincodeMessage_Type = 3
+	ChaincodeMessage_READY                   ChaincodeMessage_Type = 4
+	ChaincodeMessage_TRANSACTION             ChaincodeMessage_Type = 5
+	ChaincodeMessage_COMPLETED               ChaincodeMessage_Type = 6
+	ChaincodeMessage_ERROR                   ChaincodeMessage_Type = 7
+	ChaincodeMessage_GET_STATE               ChaincodeMessage_Type = 8
+	ChaincodeMessage_PUT_STATE               ChaincodeMessage_Type = 9
+	ChaincodeMessage_DEL_STATE               ChaincodeMessage_Type = 10
+	ChaincodeMessage_INVOKE_CHAINCODE        ChaincodeMessage_Type = 11
+	ChaincodeMessage_INVOKE_QUERY            ChaincodeMessage_Type = 12
+	ChaincodeMessage_RESPONSE                ChaincodeMessage_Type = 13
+	ChaincodeMessage_QUERY                   ChaincodeMessage_Type = 14
+	ChaincodeMessage_QUERY_COMPLETED         ChaincodeMessage_Type = 15
+	ChaincodeMessage_QUERY_ERROR             ChaincodeMessage_Type = 16
+	ChaincodeMessage_RANGE_QUERY_STATE       ChaincodeMessage_Type = 17
+	ChaincodeMessage_RANGE_QUERY_STATE_NEXT  ChaincodeMessage_Type = 18
+	ChaincodeMessage_RANGE_QUERY_STATE_CLOSE ChaincodeMessage_Type = 19
 )
 
 var ChaincodeMessage_Type_name = map[int32]string{
@@ -103,31 +106,59 @@ var ChaincodeMessage_Type_name = map[int32]string{
 	14: "QUERY",
 	15: "QUERY_COMPLETED",
 	16: "QUERY_ERROR",
+	17: "RANGE_QUERY_STATE",
+	18: "RANGE_QUERY_STATE_NEXT",
+	19: "RANGE_QUERY_STATE_CLOSE",
 }
 var ChaincodeMessage_Type_value = map[string]int32{
-	"UNDEFINED":        0,
-	"REGISTER":         1,
-	"REGISTERED":       2,
-	"INIT":             3,
-	"READY":            4,
-	"TRANSACTION":      5,
-	"COMPLETED":        6,
-	"ERROR":            7,
-	"GET_STATE":        8,
-	"PUT_STATE":        9,
-	"DEL_STATE":        10,
-	"INVOKE_CHAINCODE": 11,
-	"INVOKE_QUERY":     12,
-	"RESPONSE":         13,
-	"QUERY":            14,
-	"QUERY_COMPLETED":  15,
-	"QUERY_ERROR":      16,
+	"UNDEFINED":               0,
+	"REGISTER":                1,
+	"REGISTERED":              2,
+	"INIT":                    3,
+	"READY":                   4,
+	"TRANSACTION":             5,
+	"COMPLETED":               6,
+	"ERROR":                   7,
+	"GET_STATE":               8,
+	"PUT_STATE":               9,
+	"DEL_STATE":               10,
+	"INVOKE_CHAINCODE":        11,
+	"INVOKE_QUERY":            12,
+	"RESPONSE":                13,
+	"QUERY":                   14,
+	"QUERY_COMPLETED":         15,
+	"QUERY_ERROR":             16,
+	"RANGE_QUERY_STATE":       17,
+	"RANGE_QUERY_STATE_NEXT":  18,
+	"RANGE_QUERY_STATE_CLOSE": 19,
 }
 
 func (x ChaincodeMessage_Type) String() string {
 	return proto.EnumName(ChaincodeMessage_Type_name, int32(x))
 }
 
+// A terminated chaincode can no longer be invoked or queried. Its
+// state is kept for audit.
+type ChaincodeRegistryEntry_Status int32
+
+const (
+	ChaincodeRegistryEntry_ACTIVE     ChaincodeRegistryEntry_Status = 0
+	ChaincodeRegistryEntry_TERMINATED ChaincodeRegistryEntry_Status = 1
+)
+
+var ChaincodeRegistryEntry_Status_name = map[int32]string{
+	0: "ACTIVE",
+	1: "TERMINATED",
+}
+var ChaincodeRegistryEntry_Status_value = map[string]int32{
+	"ACTIVE":     0,
+	"TERMINATED": 1,
+}
+
+func (x ChaincodeRegistryEntry_Status) String() string {
+	return proto.EnumName(ChaincodeRegistryEntry_Status_name, int32(x))
+}
+
 // ChaincodeID contains the path as specified by the deploy transaction
 // that created it as well as the hashCode that is generated by the
 // system for the path. From the user level (ie, CLI, REST API and so on)
@@ -157,6 +188,17 @@ func (m *ChaincodeInput) Reset()         { *m = ChaincodeInput{} }
 func (m *ChaincodeInput) String() string { return proto.CompactTextString(m) }
 func (*ChaincodeInput) ProtoMessage()    {}
 
+// Requires the invocations of a chaincode to be endorsed by at least
+// threshold of the organizations, e.g. 2 of {orgA, orgB, orgC}.
+type EndorsementPolicy struct {
+	Threshold     uint32   `protobuf:"varint,1,opt,name=threshold" json:"threshold,omitempty"`
+	Organizations []string `protobuf:"bytes,2,rep,name=organizations" json:"organizations,omitempty"`
+}
+
+func (m *EndorsementPolicy) Reset()         { *m = EndorsementPolicy{} }
+func (m *EndorsementPolicy) String() string { return proto.CompactTextString(m) }
+func (*EndorsementPolicy) ProtoMessage()    {}
+
 // Carries the chaincode specification. This is the actual metadata required for
 // defining a chaincode.
 type ChaincodeSpec struct {
@@ -166,6 +208,10 @@ type ChaincodeSpec struct {
 	Timeout              int32                `protobuf:"varint,4,opt,name=timeout" json:"timeout,omitempty"`
 	SecureContext        string               `protobuf:"bytes,5,opt,name=secureContext" json:"secureContext,omitempty"`
 	ConfidentialityLevel ConfidentialityLevel `protobuf:"varint,6,opt,name=confidentialityLevel,enum=protos.ConfidentialityLevel" json:"confidentialityLevel,omitempty"`
+	// Names of the TCert attributes the invoker reveals to the chaincode.
+	Attributes []string `protobuf:"bytes,7,rep,name=attributes" json:"attributes,omitempty"`
+	// Endorsements required by the invocations of the chaincode. Set at deploy time.
+	EndorsementPolicy *EndorsementPolicy `protobuf:"bytes,8,opt,name=endorsementPolicy" json:"endorsementPolicy,omitempty"`
 }
 
 func (m *ChaincodeSpec) Reset()         { *m = ChaincodeSpec{} }
@@ -186,6 +232,13 @@ func (m *ChaincodeSpec) GetCtorMsg() *ChaincodeInput {
 	return nil
 }
 
+func (m *ChaincodeSpec) GetEndorsementPolicy() *EndorsementPolicy {
+	if m != nil {
+		return m.EndorsementPolicy
+	}
+	return nil
+}
+
 // Specify the deployment of a chaincode.
 // TODO: Define `codePackage`.
 type ChaincodeDeploymentSpec struct {
@@ -281,11 +334,58 @@ func (m *ChaincodeExecutionContext) GetTimestamp() *google_protobuf.Timestamp {
 	return nil
 }
 
+// ChaincodeEvent is set by a chaincode through stub.SetEvent while
+// executing a transaction. It is delivered to the event hub consumers once
+// the transaction has been committed.
+type ChaincodeEvent struct {
+	ChaincodeID string `protobuf:"bytes,1,opt,name=chaincodeID" json:"chaincodeID,omitempty"`
+	TxUuid      string `protobuf:"bytes,2,opt,name=txUuid" json:"txUuid,omitempty"`
+	EventName   string `protobuf:"bytes,3,opt,name=eventName" json:"eventName,omitempty"`
+	Payload     []byte `protobuf:"bytes,4,opt,name=payload,proto3" json:"payload,omitempty"`
+}
+
+func (m *ChaincodeEvent) Reset()         { *m = ChaincodeEvent{} }
+func (m *ChaincodeEvent) String() string { return proto.CompactTextString(m) }
+func (*ChaincodeEvent) ProtoMessage()    {}
+
+// AttributeKey carries the key decrypting the named attribute embedded in
+// the transaction certificate.
+type AttributeKey struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Key  []byte `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+}
+
+func (m *AttributeKey) Reset()         { *m = AttributeKey{} }
+func (m *AttributeKey) String() string { return proto.CompactTextString(m) }
+func (*AttributeKey) ProtoMessage()    {}
+
+// ChaincodeSecurityContext carries the certificate of the caller and the
+// attribute keys it revealed, so that the chaincode can enforce access
+// control. Used only with TRANSACTION and QUERY messages.
+type ChaincodeSecurityContext struct {
+	CallerCert    []byte          `protobuf:"bytes,1,opt,name=callerCert,proto3" json:"callerCert,omitempty"`
+	AttributeKeys []*AttributeKey `protobuf:"bytes,2,rep,name=attributeKeys" json:"attributeKeys,omitempty"`
+}
+
+func (m *ChaincodeSecurityContext) Reset()         { *m = ChaincodeSecurityContext{} }
+func (m *ChaincodeSecurityContext) String() string { return proto.CompactTextString(m) }
+func (*ChaincodeSecurityContext) ProtoMessage()    {}
+
+func (m *ChaincodeSecurityContext) GetAttributeKeys() []*AttributeKey {
+	if m != nil {
+		return m.AttributeKeys
+	}
+	return nil
+}
+
 type ChaincodeMessage struct {
 	Type      ChaincodeMessage_Type      `protobuf:"varint,1,opt,name=type,enum=protos.ChaincodeMessage_Type" json:"type,omitempty"`
 	Timestamp *google_protobuf.Timestamp `protobuf:"bytes,2,opt,name=timestamp" json:"timestamp,omitempty"`
 	Payload   []byte                     `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
 	Uuid      string                     `protobuf:"bytes,4,opt,name=uuid" json:"uuid,omitempty"`
+	// event emmited by chaincode. Used only with COMPLETED message
+	ChaincodeEvent  *ChaincodeEvent           `protobuf:"bytes,5,opt,name=chaincodeEvent" json:"chaincodeEvent,omitempty"`
+	SecurityContext *ChaincodeSecurityContext `protobuf:"bytes,6,opt,name=securityContext" json:"securityContext,omitempty"`
 }
 
 func (m *ChaincodeMessage) Reset()         { *m = ChaincodeMessage{} }
@@ -299,6 +399,20 @@ func (m *ChaincodeMessage) GetTimestamp() *google_protobuf.Timestamp {
 	return nil
 }
 
+func (m *ChaincodeMessage) GetChaincodeEvent() *ChaincodeEvent {
+	if m != nil {
+		return m.ChaincodeEvent
+	}
+	return nil
+}
+
+func (m *ChaincodeMessage) GetSecurityContext() *ChaincodeSecurityContext {
+	if m != nil {
+		return m.SecurityContext
+	}
+	return nil
+}
+
 type PutStateInfo struct {
 	Key   string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
 	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
@@ -308,10 +422,132 @@ func (m *PutStateInfo) Reset()         { *m = PutStateInfo{} }
 func (m *PutStateInfo) String() string { return proto.CompactTextString(m) }
 func (*PutStateInfo) ProtoMessage()    {}
 
+// Carries the key range requested by RangeQueryState. An empty startKey or
+// endKey leaves that end of the range open.
+type RangeQueryState struct {
+	StartKey string `protobuf:"bytes,1,opt,name=startKey" json:"startKey,omitempty"`
+	EndKey   string `protobuf:"bytes,2,opt,name=endKey" json:"endKey,omitempty"`
+}
+
+func (m *RangeQueryState) Reset()         { *m = RangeQueryState{} }
+func (m *RangeQueryState) String() string { return proto.CompactTextString(m) }
+func (*RangeQueryState) ProtoMessage()    {}
+
+type RangeQueryStateNext struct {
+	ID string `protobuf:"bytes,1,opt,name=ID" json:"ID,omitempty"`
+}
+
+func (m *RangeQueryStateNext) Reset()         { *m = RangeQueryStateNext{} }
+func (m *RangeQueryStateNext) String() string { return proto.CompactTextString(m) }
+func (*RangeQueryStateNext) ProtoMessage()    {}
+
+type RangeQueryStateClose struct {
+	ID string `protobuf:"bytes,1,opt,name=ID" json:"ID,omitempty"`
+}
+
+func (m *RangeQueryStateClose) Reset()         { *m = RangeQueryStateClose{} }
+func (m *RangeQueryStateClose) String() string { return proto.CompactTextString(m) }
+func (*RangeQueryStateClose) ProtoMessage()    {}
+
+type RangeQueryStateKeyValue struct {
+	Key   string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *RangeQueryStateKeyValue) Reset()         { *m = RangeQueryStateKeyValue{} }
+func (m *RangeQueryStateKeyValue) String() string { return proto.CompactTextString(m) }
+func (*RangeQueryStateKeyValue) ProtoMessage()    {}
+
+// Returned by the peer for RANGE_QUERY_STATE and RANGE_QUERY_STATE_NEXT. ID
+// identifies the open iterator on the peer for subsequent next/close calls.
+type RangeQueryStateResponse struct {
+	KeysAndValues []*RangeQueryStateKeyValue `protobuf:"bytes,1,rep,name=keysAndValues" json:"keysAndValues,omitempty"`
+	HasMore       bool                       `protobuf:"varint,2,opt,name=hasMore" json:"hasMore,omitempty"`
+	ID            string                     `protobuf:"bytes,3,opt,name=ID" json:"ID,omitempty"`
+}
+
+func (m *RangeQueryStateResponse) Reset()         { *m = RangeQueryStateResponse{} }
+func (m *RangeQueryStateResponse) String() string { return proto.CompactTextString(m) }
+func (*RangeQueryStateResponse) ProtoMessage()    {}
+
+func (m *RangeQueryStateResponse) GetKeysAndValues() []*RangeQueryStateKeyValue {
+	if m != nil {
+		return m.KeysAndValues
+	}
+	return nil
+}
+
+// ChaincodeVersion records a version of the code of a chaincode. Version 1
+// is installed by the deploy transaction, the following versions by the
+// update transactions.
+type ChaincodeVersion struct {
+	Version   uint32                     `protobuf:"varint,1,opt,name=version" json:"version,omitempty"`
+	Uuid      string                     `protobuf:"bytes,2,opt,name=uuid" json:"uuid,omitempty"`
+	Path      string                     `protobuf:"bytes,3,opt,name=path" json:"path,omitempty"`
+	Timestamp *google_protobuf.Timestamp `protobuf:"bytes,4,opt,name=timestamp" json:"timestamp,omitempty"`
+	// execution timeout in milliseconds set by the deploy or update, 0 for
+	// the network default
+	Timeout int32 `protobuf:"varint,5,opt,name=timeout" json:"timeout,omitempty"`
+}
+
+func (m *ChaincodeVersion) Reset()         { *m = ChaincodeVersion{} }
+func (m *ChaincodeVersion) String() string { return proto.CompactTextString(m) }
+func (*ChaincodeVersion) ProtoMessage()    {}
+
+func (m *ChaincodeVersion) GetTimestamp() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+// ChaincodeRegistryEntry is kept by the validators in the ledger state for
+// every deployed chaincode. versions is ordered oldest first.
+type ChaincodeRegistryEntry struct {
+	Name     string                        `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Versions []*ChaincodeVersion           `protobuf:"bytes,2,rep,name=versions" json:"versions,omitempty"`
+	Status   ChaincodeRegistryEntry_Status `protobuf:"varint,3,opt,name=status,enum=protos.ChaincodeRegistryEntry_Status" json:"status,omitempty"`
+	// Transaction that terminated the chaincode
+	TerminationUuid string             `protobuf:"bytes,4,opt,name=terminationUuid" json:"terminationUuid,omitempty"`
+	Type            ChaincodeSpec_Type `protobuf:"varint,5,opt,name=type,enum=protos.ChaincodeSpec_Type" json:"type,omitempty"`
+	// Certificate of the deployer, empty when security is disabled
+	DeployerCert []byte `protobuf:"bytes,6,opt,name=deployerCert,proto3" json:"deployerCert,omitempty"`
+	// Number of the block the deploy transaction is part of
+	DeployBlock uint64 `protobuf:"varint,7,opt,name=deployBlock" json:"deployBlock,omitempty"`
+}
+
+func (m *ChaincodeRegistryEntry) Reset()         { *m = ChaincodeRegistryEntry{} }
+func (m *ChaincodeRegistryEntry) String() string { return proto.CompactTextString(m) }
+func (*ChaincodeRegistryEntry) ProtoMessage()    {}
+
+func (m *ChaincodeRegistryEntry) GetVersions() []*ChaincodeVersion {
+	if m != nil {
+		return m.Versions
+	}
+	return nil
+}
+
+// ChaincodeRegistry lists entries of the chaincode registry, ordered by name.
+type ChaincodeRegistry struct {
+	Entries []*ChaincodeRegistryEntry `protobuf:"bytes,1,rep,name=entries" json:"entries,omitempty"`
+}
+
+func (m *ChaincodeRegistry) Reset()         { *m = ChaincodeRegistry{} }
+func (m *ChaincodeRegistry) String() string { return proto.CompactTextString(m) }
+func (*ChaincodeRegistry) ProtoMessage()    {}
+
+func (m *ChaincodeRegistry) GetEntries() []*ChaincodeRegistryEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterEnum("protos.ConfidentialityLevel", ConfidentialityLevel_name, ConfidentialityLevel_value)
 	proto.RegisterEnum("protos.ChaincodeSpec_Type", ChaincodeSpec_Type_name, ChaincodeSpec_Type_value)
 	proto.RegisterEnum("protos.ChaincodeMessage_Type", ChaincodeMessage_Type_name, ChaincodeMessage_Type_value)
+	proto.RegisterEnum("protos.ChaincodeRegistryEntry_Status", ChaincodeRegistryEntry_Status_name, ChaincodeRegistryEntry_Status_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
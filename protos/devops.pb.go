@@ -87,10 +87,19 @@ type DevopsClient interface {
 	Build(ctx context.Context, in *ChaincodeSpec, opts ...grpc.CallOption) (*ChaincodeDeploymentSpec, error)
 	// Deploy the chaincode package to the chain.
 	Deploy(ctx context.Context, in *ChaincodeSpec, opts ...grpc.CallOption) (*ChaincodeDeploymentSpec, error)
+	// Update the code of a deployed chaincode. The chaincodeID carries the
+	// name of the chaincode and the path of its new code.
+	Update(ctx context.Context, in *ChaincodeSpec, opts ...grpc.CallOption) (*ChaincodeDeploymentSpec, error)
+	// Terminate a deployed chaincode. It can no longer be invoked or
+	// queried but its state remains readable.
+	Terminate(ctx context.Context, in *ChaincodeSpec, opts ...grpc.CallOption) (*Response, error)
 	// Invoke chaincode.
 	Invoke(ctx context.Context, in *ChaincodeInvocationSpec, opts ...grpc.CallOption) (*Response, error)
 	// Invoke chaincode.
 	Query(ctx context.Context, in *ChaincodeInvocationSpec, opts ...grpc.CallOption) (*Response, error)
+	// List the chaincodes deployed on the network from the chaincode
+	// registry, or only the chaincode named by the chaincodeID if set.
+	GetChaincodes(ctx context.Context, in *ChaincodeID, opts ...grpc.CallOption) (*ChaincodeRegistry, error)
 }
 
 type devopsClient struct {
@@ -128,6 +137,24 @@ func (c *devopsClient) Deploy(ctx context.Context, in *ChaincodeSpec, opts ...gr
 	return out, nil
 }
 
+func (c *devopsClient) Update(ctx context.Context, in *ChaincodeSpec, opts ...grpc.CallOption) (*ChaincodeDeploymentSpec, error) {
+	out := new(ChaincodeDeploymentSpec)
+	err := grpc.Invoke(ctx, "/protos.Devops/Update", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *devopsClient) Terminate(ctx context.Context, in *ChaincodeSpec, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := grpc.Invoke(ctx, "/protos.Devops/Terminate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *devopsClient) Invoke(ctx context.Context, in *ChaincodeInvocationSpec, opts ...grpc.CallOption) (*Response, error) {
 	out := new(Response)
 	err := grpc.Invoke(ctx, "/protos.Devops/Invoke", in, out, c.cc, opts...)
@@ -146,6 +173,15 @@ func (c *devopsClient) Query(ctx context.Context, in *ChaincodeInvocationSpec, o
 	return out, nil
 }
 
+func (c *devopsClient) GetChaincodes(ctx context.Context, in *ChaincodeID, opts ...grpc.CallOption) (*ChaincodeRegistry, error) {
+	out := new(ChaincodeRegistry)
+	err := grpc.Invoke(ctx, "/protos.Devops/GetChaincodes", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Devops service
 
 type DevopsServer interface {
@@ -156,10 +192,19 @@ type DevopsServer interface {
 	Build(context.Context, *ChaincodeSpec) (*ChaincodeDeploymentSpec, error)
 	// Deploy the chaincode package to the chain.
 	Deploy(context.Context, *ChaincodeSpec) (*ChaincodeDeploymentSpec, error)
+	// Update the code of a deployed chaincode. The chaincodeID carries the
+	// name of the chaincode and the path of its new code.
+	Update(context.Context, *ChaincodeSpec) (*ChaincodeDeploymentSpec, error)
+	// Terminate a deployed chaincode. It can no longer be invoked or
+	// queried but its state remains readable.
+	Terminate(context.Context, *ChaincodeSpec) (*Response, error)
 	// Invoke chaincode.
 	Invoke(context.Context, *ChaincodeInvocationSpec) (*Response, error)
 	// Invoke chaincode.
 	Query(context.Context, *ChaincodeInvocationSpec) (*Response, error)
+	// List the chaincodes deployed on the network from the chaincode
+	// registry, or only the chaincode named by the chaincodeID if set.
+	GetChaincodes(context.Context, *ChaincodeID) (*ChaincodeRegistry, error)
 }
 
 func RegisterDevopsServer(s *grpc.Server, srv DevopsServer) {
@@ -202,6 +247,30 @@ func _Devops_Deploy_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return out, nil
 }
 
+func _Devops_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(ChaincodeSpec)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(DevopsServer).Update(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _Devops_Terminate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(ChaincodeSpec)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(DevopsServer).Terminate(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func _Devops_Invoke_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
 	in := new(ChaincodeInvocationSpec)
 	if err := dec(in); err != nil {
@@ -226,6 +295,18 @@ func _Devops_Query_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return out, nil
 }
 
+func _Devops_GetChaincodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(ChaincodeID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(DevopsServer).GetChaincodes(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _Devops_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.Devops",
 	HandlerType: (*DevopsServer)(nil),
@@ -242,6 +323,14 @@ var _Devops_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Deploy",
 			Handler:    _Devops_Deploy_Handler,
 		},
+		{
+			MethodName: "Update",
+			Handler:    _Devops_Update_Handler,
+		},
+		{
+			MethodName: "Terminate",
+			Handler:    _Devops_Terminate_Handler,
+		},
 		{
 			MethodName: "Invoke",
 			Handler:    _Devops_Invoke_Handler,
@@ -250,6 +339,10 @@ var _Devops_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Query",
 			Handler:    _Devops_Query_Handler,
 		},
+		{
+			MethodName: "GetChaincodes",
+			Handler:    _Devops_GetChaincodes_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{},
 }
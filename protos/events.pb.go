@@ -44,15 +44,35 @@ func (x Interest_ResponseType) String() string {
 	return proto.EnumName(Interest_ResponseType_name, int32(x))
 }
 
+// ChaincodeReg restricts the chaincode events of interest to a chaincode
+// and, if set, to an event name
+type ChaincodeReg struct {
+	ChaincodeID string `protobuf:"bytes,1,opt,name=chaincodeID" json:"chaincodeID,omitempty"`
+	EventName   string `protobuf:"bytes,2,opt,name=eventName" json:"eventName,omitempty"`
+}
+
+func (m *ChaincodeReg) Reset()         { *m = ChaincodeReg{} }
+func (m *ChaincodeReg) String() string { return proto.CompactTextString(m) }
+func (*ChaincodeReg) ProtoMessage()    {}
+
 type Interest struct {
 	EventType    string                `protobuf:"bytes,1,opt,name=eventType" json:"eventType,omitempty"`
 	ResponseType Interest_ResponseType `protobuf:"varint,2,opt,name=responseType,enum=protos.Interest_ResponseType" json:"responseType,omitempty"`
+	// only used with the "chaincode" event type
+	ChaincodeRegInfo *ChaincodeReg `protobuf:"bytes,3,opt,name=chaincodeRegInfo" json:"chaincodeRegInfo,omitempty"`
 }
 
 func (m *Interest) Reset()         { *m = Interest{} }
 func (m *Interest) String() string { return proto.CompactTextString(m) }
 func (*Interest) ProtoMessage()    {}
 
+func (m *Interest) GetChaincodeRegInfo() *ChaincodeReg {
+	if m != nil {
+		return m.ChaincodeRegInfo
+	}
+	return nil
+}
+
 // ---------- consumer events ---------
 // Register is sent by consumers for registering events
 // string type - "register"
@@ -93,6 +113,7 @@ type OpenchainEvent struct {
 	//	*OpenchainEvent_Register
 	//	*OpenchainEvent_Block
 	//	*OpenchainEvent_Generic
+	//	*OpenchainEvent_ChaincodeEvent
 	Event isOpenchainEvent_Event `protobuf_oneof:"Event"`
 }
 
@@ -113,10 +134,14 @@ type OpenchainEvent_Block struct {
 type OpenchainEvent_Generic struct {
 	Generic *Generic `protobuf:"bytes,3,opt,name=generic,oneof"`
 }
+type OpenchainEvent_ChaincodeEvent struct {
+	ChaincodeEvent *ChaincodeEvent `protobuf:"bytes,4,opt,name=chaincodeEvent,oneof"`
+}
 
-func (*OpenchainEvent_Register) isOpenchainEvent_Event() {}
-func (*OpenchainEvent_Block) isOpenchainEvent_Event()    {}
-func (*OpenchainEvent_Generic) isOpenchainEvent_Event()  {}
+func (*OpenchainEvent_Register) isOpenchainEvent_Event()       {}
+func (*OpenchainEvent_Block) isOpenchainEvent_Event()          {}
+func (*OpenchainEvent_Generic) isOpenchainEvent_Event()        {}
+func (*OpenchainEvent_ChaincodeEvent) isOpenchainEvent_Event() {}
 
 func (m *OpenchainEvent) GetEvent() isOpenchainEvent_Event {
 	if m != nil {
@@ -146,12 +171,20 @@ func (m *OpenchainEvent) GetGeneric() *Generic {
 	return nil
 }
 
+func (m *OpenchainEvent) GetChaincodeEvent() *ChaincodeEvent {
+	if x, ok := m.GetEvent().(*OpenchainEvent_ChaincodeEvent); ok {
+		return x.ChaincodeEvent
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*OpenchainEvent) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), []interface{}) {
 	return _OpenchainEvent_OneofMarshaler, _OpenchainEvent_OneofUnmarshaler, []interface{}{
 		(*OpenchainEvent_Register)(nil),
 		(*OpenchainEvent_Block)(nil),
 		(*OpenchainEvent_Generic)(nil),
+		(*OpenchainEvent_ChaincodeEvent)(nil),
 	}
 }
 
@@ -174,6 +207,11 @@ func _OpenchainEvent_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Generic); err != nil {
 			return err
 		}
+	case *OpenchainEvent_ChaincodeEvent:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.ChaincodeEvent); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("OpenchainEvent.Event has unexpected type %T", x)
@@ -208,6 +246,14 @@ func _OpenchainEvent_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto
 		err := b.DecodeMessage(msg)
 		m.Event = &OpenchainEvent_Generic{msg}
 		return true, err
+	case 4: // Event.chaincodeEvent
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ChaincodeEvent)
+		err := b.DecodeMessage(msg)
+		m.Event = &OpenchainEvent_ChaincodeEvent{msg}
+		return true, err
 	default:
 		return false, nil
 	}
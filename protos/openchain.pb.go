@@ -51,6 +51,29 @@ func (x Transaction_Type) String() string {
 	return proto.EnumName(Transaction_Type_name, int32(x))
 }
 
+type TransactionReceipt_Status int32
+
+const (
+	TransactionReceipt_PENDING   TransactionReceipt_Status = 0
+	TransactionReceipt_COMMITTED TransactionReceipt_Status = 1
+	TransactionReceipt_FAILED    TransactionReceipt_Status = 2
+)
+
+var TransactionReceipt_Status_name = map[int32]string{
+	0: "PENDING",
+	1: "COMMITTED",
+	2: "FAILED",
+}
+var TransactionReceipt_Status_value = map[string]int32{
+	"PENDING":   0,
+	"COMMITTED": 1,
+	"FAILED":    2,
+}
+
+func (x TransactionReceipt_Status) String() string {
+	return proto.EnumName(TransactionReceipt_Status_name, int32(x))
+}
+
 type PeerEndpoint_Type int32
 
 const (
@@ -168,6 +191,18 @@ func (x Response_StatusCode) String() string {
 	return proto.EnumName(Response_StatusCode_name, int32(x))
 }
 
+// Endorsement is the approval of a transaction by the holder of cert.
+// signature is computed with the enrollment key of cert over the transaction
+// without cert, signature and endorsements.
+type Endorsement struct {
+	Cert      []byte `protobuf:"bytes,1,opt,name=cert,proto3" json:"cert,omitempty"`
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *Endorsement) Reset()         { *m = Endorsement{} }
+func (m *Endorsement) String() string { return proto.CompactTextString(m) }
+func (*Endorsement) ProtoMessage()    {}
+
 // Transaction defines a function call to a contract.
 // `args` is an array of type string so that the chaincode writer can choose
 // whatever format they wish for the arguments for their chaincode.
@@ -185,6 +220,8 @@ type Transaction struct {
 	EncryptedPayload     []byte                     `protobuf:"bytes,9,opt,name=encryptedPayload,proto3" json:"encryptedPayload,omitempty"`
 	Cert                 []byte                     `protobuf:"bytes,10,opt,name=cert,proto3" json:"cert,omitempty"`
 	Signature            []byte                     `protobuf:"bytes,11,opt,name=signature,proto3" json:"signature,omitempty"`
+	AttributeKeys        []*AttributeKey            `protobuf:"bytes,12,rep,name=attributeKeys" json:"attributeKeys,omitempty"`
+	Endorsements         []*Endorsement             `protobuf:"bytes,13,rep,name=endorsements" json:"endorsements,omitempty"`
 }
 
 func (m *Transaction) Reset()         { *m = Transaction{} }
@@ -205,6 +242,20 @@ func (m *Transaction) GetTimestamp() *google_protobuf.Timestamp {
 	return nil
 }
 
+func (m *Transaction) GetAttributeKeys() []*AttributeKey {
+	if m != nil {
+		return m.AttributeKeys
+	}
+	return nil
+}
+
+func (m *Transaction) GetEndorsements() []*Endorsement {
+	if m != nil {
+		return m.Endorsements
+	}
+	return nil
+}
+
 // TransactionBlock carries a batch of transactions.
 type TransactionBlock struct {
 	Transactions []*Transaction `protobuf:"bytes,1,rep,name=transactions" json:"transactions,omitempty"`
@@ -226,16 +277,44 @@ func (m *TransactionBlock) GetTransactions() []*Transaction {
 // uuid - The unique identifier of this transaction.
 // result - The return value of the transaction.
 // error - Any errors that occured as a result of running the transaction.
+// chaincodeEvent - The event set by the chaincode, if any.
 type TransactionResult struct {
-	Uuid   string `protobuf:"bytes,1,opt,name=uuid" json:"uuid,omitempty"`
-	Result []byte `protobuf:"bytes,2,opt,name=result,proto3" json:"result,omitempty"`
-	Error  string `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+	Uuid           string          `protobuf:"bytes,1,opt,name=uuid" json:"uuid,omitempty"`
+	Result         []byte          `protobuf:"bytes,2,opt,name=result,proto3" json:"result,omitempty"`
+	Error          string          `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+	ChaincodeEvent *ChaincodeEvent `protobuf:"bytes,4,opt,name=chaincodeEvent" json:"chaincodeEvent,omitempty"`
 }
 
 func (m *TransactionResult) Reset()         { *m = TransactionResult{} }
 func (m *TransactionResult) String() string { return proto.CompactTextString(m) }
 func (*TransactionResult) ProtoMessage()    {}
 
+func (m *TransactionResult) GetChaincodeEvent() *ChaincodeEvent {
+	if m != nil {
+		return m.ChaincodeEvent
+	}
+	return nil
+}
+
+// TransactionReceipt tells a client what became of its transaction.
+// uuid - The unique identifier of the transaction.
+// status - PENDING while the transaction is waiting to be committed, then
+// COMMITTED if it ran successfully or FAILED if it did not.
+// blockNumber - The number of the block the transaction was committed in.
+// txIndex - The index of the transaction within the block.
+// error - Why the transaction failed, if it did.
+type TransactionReceipt struct {
+	Uuid        string                    `protobuf:"bytes,1,opt,name=uuid" json:"uuid,omitempty"`
+	Status      TransactionReceipt_Status `protobuf:"varint,2,opt,name=status,enum=protos.TransactionReceipt_Status" json:"status,omitempty"`
+	BlockNumber uint64                    `protobuf:"varint,3,opt,name=blockNumber" json:"blockNumber,omitempty"`
+	TxIndex     uint64                    `protobuf:"varint,4,opt,name=txIndex" json:"txIndex,omitempty"`
+	Error       string                    `protobuf:"bytes,5,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *TransactionReceipt) Reset()         { *m = TransactionReceipt{} }
+func (m *TransactionReceipt) String() string { return proto.CompactTextString(m) }
+func (*TransactionReceipt) ProtoMessage()    {}
+
 // Block carries The data that describes a block in the blockchain.
 // timestamp - The time at which the block or transaction order
 // was proposed. This may not be used by all consensus modules.
@@ -285,10 +364,14 @@ func (m *Block) GetNonHashData() *NonHashData {
 // the block hash when verifying the blockchain.
 // localLedgerCommitTimestamp - The time at which the block was added
 // to the ledger on the local peer.
-// transactionResults - The results of transactions.
+// transactionResults - The results of transactions, including the events
+// set by the chaincode.
+// prunedBlockHash - The hash of the block before its transactions were
+// pruned. Only set on pruned blocks.
 type NonHashData struct {
 	LocalLedgerCommitTimestamp *google_protobuf.Timestamp `protobuf:"bytes,1,opt,name=localLedgerCommitTimestamp" json:"localLedgerCommitTimestamp,omitempty"`
 	TransactionResults         []*TransactionResult       `protobuf:"bytes,2,rep,name=transactionResults" json:"transactionResults,omitempty"`
+	PrunedBlockHash            []byte                     `protobuf:"bytes,3,opt,name=prunedBlockHash,proto3" json:"prunedBlockHash,omitempty"`
 }
 
 func (m *NonHashData) Reset()         { *m = NonHashData{} }
@@ -361,6 +444,10 @@ func (m *PeersMessage) GetPeers() []*PeerEndpoint {
 type HelloMessage struct {
 	PeerEndpoint *PeerEndpoint `protobuf:"bytes,1,opt,name=peerEndpoint" json:"peerEndpoint,omitempty"`
 	BlockNumber  uint64        `protobuf:"varint,2,opt,name=blockNumber" json:"blockNumber,omitempty"`
+	// Enrollment certificate of the sender, and its signature of this message
+	// (signature unset) bound to the TLS certificate of the connection.
+	Cert      []byte `protobuf:"bytes,3,opt,name=cert,proto3" json:"cert,omitempty"`
+	Signature []byte `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
 }
 
 func (m *HelloMessage) Reset()         { *m = HelloMessage{} }
@@ -426,9 +513,12 @@ func (m *BlockState) GetBlock() *Block {
 // in which blocks are returned is defined by the start and end values. For
 // example, if start=3 and end=5, the order of blocks will be 3, 4, 5.
 // If start=5 and end=3, the order will be 5, 4, 3.
+// Blocks numbered below pruneBelow are sent pruned, with only their header and
+// hash, for a peer that bootstraps from a state snapshot.
 type SyncBlockRange struct {
-	Start uint64 `protobuf:"varint,1,opt,name=start" json:"start,omitempty"`
-	End   uint64 `protobuf:"varint,2,opt,name=end" json:"end,omitempty"`
+	Start      uint64 `protobuf:"varint,1,opt,name=start" json:"start,omitempty"`
+	End        uint64 `protobuf:"varint,2,opt,name=end" json:"end,omitempty"`
+	PruneBelow uint64 `protobuf:"varint,3,opt,name=pruneBelow" json:"pruneBelow,omitempty"`
 }
 
 func (m *SyncBlockRange) Reset()         { *m = SyncBlockRange{} }
@@ -530,6 +620,7 @@ func (m *SyncStateDeltas) GetRange() *SyncBlockRange {
 
 func init() {
 	proto.RegisterEnum("protos.Transaction_Type", Transaction_Type_name, Transaction_Type_value)
+	proto.RegisterEnum("protos.TransactionReceipt_Status", TransactionReceipt_Status_name, TransactionReceipt_Status_value)
 	proto.RegisterEnum("protos.PeerEndpoint_Type", PeerEndpoint_Type_name, PeerEndpoint_Type_value)
 	proto.RegisterEnum("protos.OpenchainMessage_Type", OpenchainMessage_Type_name, OpenchainMessage_Type_value)
 	proto.RegisterEnum("protos.Response_StatusCode", Response_StatusCode_name, Response_StatusCode_value)
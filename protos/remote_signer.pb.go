@@ -0,0 +1,147 @@
+// Code generated by protoc-gen-go.
+// source: remote_signer.proto
+// DO NOT EDIT!
+
+package protos
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// KeyImportRequest carries a DER encoded EC private key.
+type KeyImportRequest struct {
+	Alias      string `protobuf:"bytes,1,opt,name=alias" json:"alias,omitempty"`
+	PrivateKey []byte `protobuf:"bytes,2,opt,name=privateKey,proto3" json:"privateKey,omitempty"`
+}
+
+func (m *KeyImportRequest) Reset()         { *m = KeyImportRequest{} }
+func (m *KeyImportRequest) String() string { return proto.CompactTextString(m) }
+func (*KeyImportRequest) ProtoMessage()    {}
+
+type KeyImportResponse struct {
+}
+
+func (m *KeyImportResponse) Reset()         { *m = KeyImportResponse{} }
+func (m *KeyImportResponse) String() string { return proto.CompactTextString(m) }
+func (*KeyImportResponse) ProtoMessage()    {}
+
+// SignRequest carries the SHA3-384 digest of the message to sign.
+type SignRequest struct {
+	Alias  string `protobuf:"bytes,1,opt,name=alias" json:"alias,omitempty"`
+	Digest []byte `protobuf:"bytes,2,opt,name=digest,proto3" json:"digest,omitempty"`
+}
+
+func (m *SignRequest) Reset()         { *m = SignRequest{} }
+func (m *SignRequest) String() string { return proto.CompactTextString(m) }
+func (*SignRequest) ProtoMessage()    {}
+
+// SignResponse carries the ASN.1 encoded ECDSA signature of the digest.
+type SignResponse struct {
+	Signature []byte `protobuf:"bytes,1,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *SignResponse) Reset()         { *m = SignResponse{} }
+func (m *SignResponse) String() string { return proto.CompactTextString(m) }
+func (*SignResponse) ProtoMessage()    {}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// Client API for RemoteSigner service
+
+type RemoteSignerClient interface {
+	// Import a private key under the given alias.
+	ImportKey(ctx context.Context, in *KeyImportRequest, opts ...grpc.CallOption) (*KeyImportResponse, error)
+	// Sign a digest with the private key stored under the given alias.
+	Sign(ctx context.Context, in *SignRequest, opts ...grpc.CallOption) (*SignResponse, error)
+}
+
+type remoteSignerClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewRemoteSignerClient(cc *grpc.ClientConn) RemoteSignerClient {
+	return &remoteSignerClient{cc}
+}
+
+func (c *remoteSignerClient) ImportKey(ctx context.Context, in *KeyImportRequest, opts ...grpc.CallOption) (*KeyImportResponse, error) {
+	out := new(KeyImportResponse)
+	err := grpc.Invoke(ctx, "/protos.RemoteSigner/ImportKey", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *remoteSignerClient) Sign(ctx context.Context, in *SignRequest, opts ...grpc.CallOption) (*SignResponse, error) {
+	out := new(SignResponse)
+	err := grpc.Invoke(ctx, "/protos.RemoteSigner/Sign", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for RemoteSigner service
+
+type RemoteSignerServer interface {
+	// Import a private key under the given alias.
+	ImportKey(context.Context, *KeyImportRequest) (*KeyImportResponse, error)
+	// Sign a digest with the private key stored under the given alias.
+	Sign(context.Context, *SignRequest) (*SignResponse, error)
+}
+
+func RegisterRemoteSignerServer(s *grpc.Server, srv RemoteSignerServer) {
+	s.RegisterService(&_RemoteSigner_serviceDesc, srv)
+}
+
+func _RemoteSigner_ImportKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(KeyImportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(RemoteSignerServer).ImportKey(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _RemoteSigner_Sign_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(SignRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(RemoteSignerServer).Sign(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var _RemoteSigner_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "protos.RemoteSigner",
+	HandlerType: (*RemoteSignerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ImportKey",
+			Handler:    _RemoteSigner_ImportKey_Handler,
+		},
+		{
+			MethodName: "Sign",
+			Handler:    _RemoteSigner_Sign_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
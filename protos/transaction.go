@@ -81,6 +81,17 @@ func NewChaincodeDeployTransaction(chaincodeDeploymentSpec *ChaincodeDeploymentS
 	return transaction, nil
 }
 
+// NewChaincodeUpdateTransaction is used to replace the code of a deployed chaincode.
+// The ChaincodeID of chaincodeDeploymentSpec names the chaincode to update.
+func NewChaincodeUpdateTransaction(chaincodeDeploymentSpec *ChaincodeDeploymentSpec, uuid string) (*Transaction, error) {
+	transaction, err := NewChaincodeDeployTransaction(chaincodeDeploymentSpec, uuid)
+	if err != nil {
+		return nil, err
+	}
+	transaction.Type = Transaction_CHAINCODE_UPDATE
+	return transaction, nil
+}
+
 // NewChaincodeExecute is used to deploy chaincode.
 func NewChaincodeExecute(chaincodeInvocationSpec *ChaincodeInvocationSpec, uuid string, typ Transaction_Type) (*Transaction, error) {
 	transaction := new(Transaction)